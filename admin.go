@@ -0,0 +1,153 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aqaliarept/vuokraovi-bot/state"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// maxImportBytes caps the size of an uploaded state file, matching Telegram's bot download limit
+const maxImportBytes = 20 << 20
+
+// parseAdminIDs parses a comma separated list of admin chat IDs
+func parseAdminIDs(value string) ([]int64, error) {
+	var ids []int64
+	for _, item := range splitList(value) {
+		id, err := strconv.ParseInt(item, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid admin chat ID %q", item)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// isAdmin reports whether the chat belongs to a bot administrator
+func isAdmin(config BotConfig, chatID int64) bool {
+	for _, id := range config.AdminChatIDs {
+		if id == chatID {
+			return true
+		}
+	}
+	return false
+}
+
+// isImportMessage reports whether the message is an uploaded document captioned /import
+func isImportMessage(message *tgbotapi.Message) bool {
+	if message.Document == nil {
+		return false
+	}
+	fields := strings.Fields(message.Caption)
+	return len(fields) > 0 && strings.SplitN(fields[0], "@", 2)[0] == "/import"
+}
+
+// replyAdminOnly tells a non-admin user that the command is restricted
+func replyAdminOnly(bot *tgbotapi.BotAPI, chatID int64, lang string) {
+	msg := tgbotapi.NewMessage(chatID, translate(lang, "admin_only"))
+	msg.ReplyMarkup = createMainKeyboard(lang)
+	sendMessage(bot, msg)
+}
+
+// handleExportCommand handles the /export command, sending the bot state as a JSON document
+func handleExportCommand(bot *tgbotapi.BotAPI, botState *state.BotState, message *tgbotapi.Message, config BotConfig) {
+	chatID := message.Chat.ID
+	lang := userLanguage(botState, chatID)
+	if !isAdmin(config, chatID) {
+		replyAdminOnly(bot, chatID, lang)
+		return
+	}
+
+	data, err := botState.Export()
+	if err != nil {
+		log.Printf("Error exporting bot state: %v", err)
+		sendMessage(bot, tgbotapi.NewMessage(chatID, translate(lang, "export_failed")))
+		return
+	}
+
+	doc := tgbotapi.NewDocument(chatID, tgbotapi.FileBytes{
+		Name:  fmt.Sprintf("vuokraovi_state_%s.json", time.Now().Format("20060102_150405")),
+		Bytes: data,
+	})
+	doc.Caption = translate(lang, "export_caption", len(botState.GetAllUsers()), len(botState.GetKnownOffers()))
+	if _, err := sendMessage(bot, doc); err != nil {
+		log.Printf("Error sending state export to admin %d: %v", chatID, err)
+	}
+}
+
+// handleImportCommand handles /import, replacing the bot state with an uploaded export.
+// The export is either attached to the message with /import as caption or the message replied to.
+func handleImportCommand(bot *tgbotapi.BotAPI, botState *state.BotState, message *tgbotapi.Message, config BotConfig) {
+	chatID := message.Chat.ID
+	lang := userLanguage(botState, chatID)
+	if !isAdmin(config, chatID) {
+		replyAdminOnly(bot, chatID, lang)
+		return
+	}
+
+	document := message.Document
+	if document == nil && message.ReplyToMessage != nil {
+		document = message.ReplyToMessage.Document
+	}
+	if document == nil {
+		sendMessage(bot, tgbotapi.NewMessage(chatID, translate(lang, "import_usage")))
+		return
+	}
+	if document.FileSize > maxImportBytes {
+		sendMessage(bot, tgbotapi.NewMessage(chatID, translate(lang, "import_too_large")))
+		return
+	}
+
+	data, err := downloadDocument(bot, document.FileID)
+	if err != nil {
+		log.Printf("Error downloading state import: %v", err)
+		sendMessage(bot, tgbotapi.NewMessage(chatID, translate(lang, "import_download_failed")))
+		return
+	}
+
+	if err := botState.Import(data); err != nil {
+		log.Printf("Rejected state import from admin %d: %v", chatID, err)
+		sendMessage(bot, tgbotapi.NewMessage(chatID, translate(lang, "import_failed", err)))
+		return
+	}
+
+	log.Printf("Bot state imported by admin %d", chatID)
+	// The import may have changed the admin's own language
+	lang = userLanguage(botState, chatID)
+	sendMessage(bot, tgbotapi.NewMessage(chatID, translate(lang, "import_done",
+		len(botState.GetAllUsers()), len(botState.GetKnownOffers()))))
+}
+
+// downloadDocument downloads an uploaded file from Telegram
+func downloadDocument(bot *tgbotapi.BotAPI, fileID string) ([]byte, error) {
+	fileURL, err := bot.GetFileDirectURL(fileID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting file URL: %w", err)
+	}
+
+	client := &http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Get(fileURL)
+	if err != nil {
+		return nil, fmt.Errorf("error downloading file: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxImportBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("error reading file: %w", err)
+	}
+	if len(data) > maxImportBytes {
+		return nil, fmt.Errorf("file is larger than %d bytes", maxImportBytes)
+	}
+	return data, nil
+}
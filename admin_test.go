@@ -0,0 +1,60 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+func TestParseAdminIDs(t *testing.T) {
+	ids, err := parseAdminIDs(" 1, -100200 ,,")
+	if err != nil || len(ids) != 2 || ids[0] != 1 || ids[1] != -100200 {
+		t.Errorf("parseAdminIDs = %v, %v", ids, err)
+	}
+	if _, err := parseAdminIDs("1,admin"); err == nil {
+		t.Error("parseAdminIDs accepted a name")
+	}
+}
+
+func TestAdminCommandsAreGuarded(t *testing.T) {
+	bot, fake := newFakeTelegram(t)
+	botState := newTestBotState(t)
+	botState.AddUser(&tgbotapi.User{FirstName: "User"}, 2)
+	botState.SetUserLanguage(2, "fi")
+	config := BotConfig{AdminChatIDs: []int64{1}}
+
+	for _, command := range []string{"/export", "/import"} {
+		fake.reset()
+		handleMessage(bot, botState, userMessage(2, command), config)
+		if sent := fake.calls("sendMessage"); len(sent) != 1 || sent[0].Get("text") != translate("fi", "admin_only") {
+			t.Errorf("%s by a user: sent %v, want the admin only message", command, sent)
+		}
+		if docs := fake.calls("sendDocument"); len(docs) != 0 {
+			t.Errorf("%s by a user sent a document", command)
+		}
+	}
+}
+
+func TestExportCommandSendsStateDocument(t *testing.T) {
+	bot, fake := newFakeTelegram(t)
+	botState := newTestBotState(t)
+	botState.ApplyOffers(numberedOffers(3))
+	config := BotConfig{AdminChatIDs: []int64{1}}
+
+	handleMessage(bot, botState, userMessage(1, "/export"), config)
+
+	docs := fake.calls("sendDocument")
+	if len(docs) != 1 || docs[0].Get("chat_id") != "1" {
+		t.Fatalf("sent documents %v, want one to the admin", docs)
+	}
+	if caption := docs[0].Get("caption"); caption != translate("en", "export_caption", 1, 3) {
+		t.Errorf("caption = %q", caption)
+	}
+
+	fake.reset()
+	handleMessage(bot, botState, userMessage(1, "/import"), config)
+	if sent := fake.calls("sendMessage"); len(sent) != 1 || !strings.HasPrefix(sent[0].Get("text"), "Send a file created by /export") {
+		t.Errorf("/import without a file: sent %v, want the usage", sent)
+	}
+}
@@ -1,11 +1,21 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"log"
+	"log/slog"
+	"math/rand"
+	"net/http"
+	"net/url"
 	"os"
+	"os/signal"
+	"path/filepath"
+	"sync"
+	"syscall"
 	"time"
 
+	"github.com/aqaliarept/vuokraovi-bot/scraper"
 	"github.com/aqaliarept/vuokraovi-bot/state"
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 )
@@ -16,11 +26,101 @@ type BotConfig struct {
 	UpdateInterval time.Duration
 	DataDir        string
 	FormDataFile   string
+	FormFileSet    bool        // the form data file was given explicitly, so search options apply on top of it
+	Searches       []Search    // named searches fetched in every update instead of FormDataFile
+	FormOptions    FormOptions // search options replacing the matching keys of the form data
 	MaxPages       int
+	Locale         string
+	RequestTimeout time.Duration
+	ProxyURL       string
+	UserAgentsFile string
+	SelectorsFile  string
+	PersistCookies bool
+	StoreBackend   string        // "json" (default) or "sqlite"
+	DelistAfter    int           // consecutive missed updates before an offer is delisted
+	MaxSeenOffers  int           // seen links kept per user, 0 keeps them all
+	NotifyDelisted bool          // notify users when an offer they have seen is delisted
+	OfferMaxAge    time.Duration // drop offers first seen longer ago than this, 0 keeps them forever
+	FeedAddr       string        // address serving the per-user RSS feeds, empty to disable
+	FeedURL        string        // public base URL of the feed server shown to users
+	WebhookURL     string        // public URL receiving updates, empty to use long polling
+	ListenAddr     string        // address the webhook server listens on
+	MetricsAddr    string        // address serving Prometheus metrics, empty to disable
+	HealthAddr     string        // address serving the health endpoints, empty to disable
+	DryRun         bool          // fetch and classify offers without notifying users or saving the state
+	AdminChatIDs   []int64       // chats allowed to use the admin commands
+
+	// MessagesPerSecond limits the messages sent to Telegram across all chats (0 = no limit)
+	MessagesPerSecond float64
+
+	// FallbackThreshold is the fraction of offers missing a price that triggers the fallback selectors
+	FallbackThreshold float64
+
+	// MaxOffers caps the number of offers collected per update (0 = no limit)
+	MaxOffers int
+
+	// PageCap caps the pages fetched per update even without a page limit (0 = the scraper's default)
+	PageCap int
+
+	// SaveInterval saves the state at most this often instead of on every change (0 = every change)
+	SaveInterval time.Duration
+
+	// PageDelay is the pause between two result pages of an update
+	PageDelay time.Duration
+
+	// UpdateJitter shifts each update interval randomly by up to this fraction of it (0 = exact)
+	UpdateJitter float64
+
+	// NotificationTemplate is a text/template rendering each offer of the new offers
+	// notifications (empty for the default layout)
+	NotificationTemplate string
+
+	// MaxOffersPerNotification is the number of offers listed in one new offers
+	// notification, the rest are summed up in a note (0 = default 10)
+	MaxOffersPerNotification int
+
+	// MoreOffersText replaces the translated note below a cut notification, %d is
+	// the number of offers left out (empty for the translated note)
+	MoreOffersText string
+
+	// WebhookOut is a URL receiving the new offers of every update as JSON (empty to disable)
+	WebhookOut string
+	// WebhookOutSecret signs the bodies posted to WebhookOut with HMAC-SHA256 (empty to not sign)
+	WebhookOutSecret string
+
+	// PageCacheTTL serves result pages fetched less than this long ago from memory,
+	// e.g. for repeated /search commands (0 = no cache)
+	PageCacheTTL time.Duration
 }
 
 // RunBot starts the bot and runs it indefinitely
 func RunBot(config BotConfig) error {
+	if config.NotificationTemplate != "" {
+		tmpl, err := parseNotificationTemplate(config.NotificationTemplate)
+		if err != nil {
+			return err
+		}
+		notificationTemplate = tmpl
+	}
+	if config.MaxOffersPerNotification > 0 {
+		maxOffersPerNotification = config.MaxOffersPerNotification
+	}
+	moreOffersText = config.MoreOffersText
+	if config.PageCacheTTL > 0 {
+		pageCache = scraper.NewPageCache(config.PageCacheTTL)
+	}
+	// Keep a second process from writing the same data directory
+	lock, err := state.LockDir(config.DataDir)
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock()
+
+	pageValidators = scraper.NewValidators()
+	if err := pageValidators.Load(filepath.Join(config.DataDir, validatorsFile)); err != nil {
+		log.Printf("Warning: Failed to load page validators: %v", err)
+	}
+
 	// Initialize bot
 	bot, err := tgbotapi.NewBotAPI(config.Token)
 	if err != nil {
@@ -29,27 +129,181 @@ func RunBot(config BotConfig) error {
 
 	log.Printf("Authorized on account %s", bot.Self.UserName)
 
+	messageLimiter = newRateLimiter(config.MessagesPerSecond)
+
 	// Initialize bot state
-	botState := state.NewBotState(config.DataDir)
-	if err := botState.LoadState(); err != nil {
-		log.Printf("Warning: Failed to load bot state: %v", err)
+	store, err := newStateStore(config)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	botState, err := state.NewBotStateWithStore(store)
+	if err != nil {
+		return err
+	}
+	if config.DelistAfter > 0 {
+		botState.SetDelistAfter(config.DelistAfter)
+	}
+	botState.SetMaxSeenOffers(config.MaxSeenOffers)
+	if config.SaveInterval > 0 {
+		botState.SetSaveInterval(config.SaveInterval)
+		// The last flush has to finish before the store is closed
+		stopFlush := make(chan struct{})
+		flushed := make(chan struct{})
+		defer func() {
+			close(stopFlush)
+			<-flushed
+		}()
+		go func() {
+			defer close(flushed)
+			botState.AutoFlush(stopFlush, func(err error) {
+				log.Printf("Error saving bot state: %v", err)
+			})
+		}()
 	}
 
 	// Set up updates channel
-	u := tgbotapi.NewUpdate(0)
-	u.Timeout = 60
-	updates := bot.GetUpdatesChan(u)
+	updates, err := updatesChannel(bot, config)
+	if err != nil {
+		return err
+	}
 
 	// Start periodic update goroutine
 	go periodicUpdate(bot, botState, config)
 
-	// Process updates
-	for update := range updates {
-		if update.Message != nil {
-			handleMessage(bot, botState, update.Message, config)
+	if config.FeedAddr != "" {
+		go startFeedServer(botState, config)
+	}
+	if config.MetricsAddr != "" {
+		go startMetricsServer(config)
+	}
+	if config.HealthAddr != "" {
+		go startHealthServer(config)
+	}
+
+	// Process updates until the process is interrupted or terminated, then return
+	// so that the deferred calls save the state and release the data directory
+	botRunning.Store(true)
+	defer botRunning.Store(false)
+	processUpdates(bot, botState, updates, shutdownSignal(), config)
+
+	return nil
+}
+
+// processUpdates processes the updates until the channel is closed or shutdown is
+func processUpdates(bot *tgbotapi.BotAPI, botState *state.BotState, updates tgbotapi.UpdatesChannel, shutdown <-chan struct{}, config BotConfig) {
+	for {
+		select {
+		case <-shutdown:
+			return
+		case update, ok := <-updates:
+			if !ok {
+				return
+			}
+			processUpdate(bot, botState, update, config)
+		}
+	}
+}
+
+// shutdownSignal returns a channel that is closed when the process is interrupted
+// or terminated
+func shutdownSignal() <-chan struct{} {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	shutdown := make(chan struct{})
+	go func() {
+		sig := <-signals
+		log.Printf("Received %v, shutting down", sig)
+		signal.Stop(signals)
+		close(shutdown)
+	}()
+	return shutdown
+}
+
+// processUpdate dispatches an update received through polling or the webhook
+func processUpdate(bot *tgbotapi.BotAPI, botState *state.BotState, update tgbotapi.Update, config BotConfig) {
+	if update.Message != nil {
+		handleMessage(bot, botState, update.Message, config)
+	} else if update.CallbackQuery != nil {
+		handleCallbackQuery(bot, botState, update.CallbackQuery)
+	}
+}
+
+// updatesChannel receives updates through a webhook when WebhookURL is set
+// and falls back to long polling otherwise
+func updatesChannel(bot *tgbotapi.BotAPI, config BotConfig) (tgbotapi.UpdatesChannel, error) {
+	if config.WebhookURL == "" {
+		u := tgbotapi.NewUpdate(0)
+		u.Timeout = 60
+		return bot.GetUpdatesChan(u), nil
+	}
+
+	webhookURL, err := url.Parse(config.WebhookURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid webhook URL: %w", err)
+	}
+	webhook, err := tgbotapi.NewWebhook(config.WebhookURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid webhook URL: %w", err)
+	}
+	if _, err := bot.Request(webhook); err != nil {
+		return nil, fmt.Errorf("failed to set webhook: %w", err)
+	}
+
+	pattern := webhookURL.Path
+	if pattern == "" {
+		pattern = "/"
+	}
+	updates := make(chan tgbotapi.Update, bot.Buffer)
+	mux := http.NewServeMux()
+	mux.Handle(pattern, webhookHandler(bot, updates))
+
+	go func() {
+		log.Printf("Listening for webhook updates on %s%s", config.ListenAddr, pattern)
+		if err := http.ListenAndServe(config.ListenAddr, mux); err != nil {
+			log.Fatalf("Error serving webhook: %v", err)
+		}
+	}()
+
+	return updates, nil
+}
+
+// webhookHandler passes the updates posted by Telegram to the updates channel
+func webhookHandler(bot *tgbotapi.BotAPI, updates chan<- tgbotapi.Update) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		update, err := bot.HandleUpdate(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
 		}
+		updates <- *update
+	})
+}
+
+// newStateStore creates the persistence backend selected in the config
+func newStateStore(config BotConfig) (state.Store, error) {
+	switch config.StoreBackend {
+	case "", "json":
+		return state.NewJSONStore(config.DataDir), nil
+	case "sqlite":
+		store, err := state.NewSQLiteStore(filepath.Join(config.DataDir, "bot_state.db"))
+		if err != nil {
+			return nil, fmt.Errorf("failed to open SQLite store: %w", err)
+		}
+		return store, nil
+	default:
+		return nil, fmt.Errorf("unknown store backend %q (valid values: json, sqlite)", config.StoreBackend)
 	}
+}
 
+// runUpdate updates the offers and notifies users, marking the bot ready once an
+// update has succeeded
+func runUpdate(bot *tgbotapi.BotAPI, botState *state.BotState, config BotConfig) error {
+	if err := updateAndNotify(bot, botState, config); err != nil {
+		return err
+	}
+	botReady.Store(true)
 	return nil
 }
 
@@ -58,16 +312,16 @@ func periodicUpdate(bot *tgbotapi.BotAPI, botState *state.BotState, config BotCo
 	// Start with a small delay to allow bot to initialize
 	time.Sleep(5 * time.Second)
 
-	// Create a ticker for periodic updates
-	ticker := time.NewTicker(config.UpdateInterval)
-	defer ticker.Stop()
+	// Create a timer for periodic updates, drawing a new jittered interval each cycle
+	timer := time.NewTimer(jitteredInterval(config.UpdateInterval, config.UpdateJitter, rand.Float64))
+	defer timer.Stop()
 
 	// Create a channel for the initial update
 	initialUpdateDone := make(chan struct{})
 
 	// Start initial update in a separate goroutine
 	go func() {
-		if err := updateAndNotify(bot, botState, config); err != nil {
+		if err := runUpdate(bot, botState, config); err != nil {
 			log.Printf("Error during initial update: %v", err)
 		}
 		close(initialUpdateDone)
@@ -76,188 +330,627 @@ func periodicUpdate(bot *tgbotapi.BotAPI, botState *state.BotState, config BotCo
 	// Wait for initial update to complete or timeout
 	select {
 	case <-initialUpdateDone:
-		log.Println("Initial update completed successfully")
+		log.Println("Initial update completed")
 	case <-time.After(30 * time.Second):
 		log.Println("Initial update timed out, continuing with periodic updates")
 	}
 
 	// Continue with periodic updates
-	for range ticker.C {
-		if err := updateAndNotify(bot, botState, config); err != nil {
+	for range timer.C {
+		timer.Reset(jitteredInterval(config.UpdateInterval, config.UpdateJitter, rand.Float64))
+		if err := runUpdate(bot, botState, config); err != nil {
 			log.Printf("Error during periodic update: %v", err)
 			continue
 		}
+
+		if config.OfferMaxAge > 0 && !config.DryRun {
+			if removed := botState.CleanupOldOffers(config.OfferMaxAge); removed > 0 {
+				log.Printf("Removed %d offers older than %v", removed, config.OfferMaxAge)
+			}
+		}
+	}
+}
+
+// jitteredInterval returns the interval shifted by a random amount of up to
+// ±jitter of its length, e.g. 30 minutes ± 10% with a jitter of 0.1. random returns
+// values in [0, 1); jitter is limited to [0, 1) so the interval stays positive.
+func jitteredInterval(interval time.Duration, jitter float64, random func() float64) time.Duration {
+	if jitter <= 0 {
+		return interval
 	}
+	if jitter >= 1 {
+		jitter = 0.99
+	}
+	offset := jitter * (2*random() - 1)
+	return time.Duration(float64(interval) * (1 + offset))
 }
 
+// updateMutex serializes periodic and on-demand updates
+var updateMutex sync.Mutex
+
 // updateAndNotify updates the rental offers and notifies users about new offers
 func updateAndNotify(bot *tgbotapi.BotAPI, botState *state.BotState, config BotConfig) error {
+	updateMutex.Lock()
+	defer updateMutex.Unlock()
+
 	log.Println("Checking for new rental offers...")
 
 	// Fetch rental offers
-	offers, err := fetchRentalOffers(config.FormDataFile, config.MaxPages)
+	fetchStart := time.Now()
+	offers, err := fetchOffers(config)
+	elapsed := time.Since(fetchStart)
+	fetchDuration.Observe(elapsed.Seconds())
+	if err != nil {
+		fetchErrorsTotal.Inc()
+	}
+	if errors.Is(err, scraper.ErrBlocked) {
+		log.Printf("Warning: the site served a block/challenge page, skipping this update: %v", err)
+		return nil
+	}
 	if err != nil {
 		return fmt.Errorf("error fetching rental offers: %v", err)
 	}
+	offersFetchedTotal.Add(float64(len(offers)))
+	slog.Info("Fetched rental offers", "offers_found", len(offers), "duration_ms", elapsed.Milliseconds())
+
+	if config.DryRun {
+		logDryRun(botState.PreviewOffers(offers))
+		return nil
+	}
 
-	// Update offers in state and get new ones
-	newOffers := botState.UpdateOffers(offers)
+	// Update offers in state and classify them
+	result := botState.ApplyOffers(offers)
+	newOffers := append(result.New, result.Relisted...)
 	if len(newOffers) > 0 {
 		log.Printf("Found %d new rental offers", len(newOffers))
-		notifyUsers(bot, botState, newOffers)
+		newOffersTotal.Add(float64(len(newOffers)))
+		if config.WebhookOut != "" {
+			if err := postNewOffers(config.WebhookOut, config.WebhookOutSecret, newOffers, time.Now()); err != nil {
+				log.Printf("Error posting new offers to the webhook: %v", err)
+			}
+		}
 	} else {
 		log.Println("No new rental offers found")
 	}
+	// Notify even without new offers to deliver offers held back during quiet hours
+	notifyUsers(bot, botState, newOffers)
+
+	// Stop messaging the chats that refused the notifications
+	if removed, err := botState.RemoveUnreachableUsers(); err != nil {
+		log.Printf("Error removing unreachable users: %v", err)
+	} else if removed > 0 {
+		log.Printf("Removed %d users who blocked the bot", removed)
+	}
+
+	if len(result.PriceChanges) > 0 {
+		log.Printf("Found %d price changes", len(result.PriceChanges))
+		notifyPriceChanges(bot, botState, result.PriceChanges)
+	}
+
+	if len(result.Removed) > 0 {
+		log.Printf("%d rental offers are no longer available", len(result.Removed))
+		if config.NotifyDelisted {
+			notifyDelisted(bot, botState, result)
+		}
+	}
 
 	return nil
 }
 
-// fetchRentalOffers fetches rental offers using the WebSite struct
-func fetchRentalOffers(formDataFile string, maxPages int) ([]state.RentalOffer, error) {
+// logDryRun logs the offers an update would have reported in dry-run mode
+func logDryRun(result state.UpdateResult) {
+	log.Printf("Dry run: %d new, %d relisted, %d changed, %d price changes, %d delisted offers",
+		len(result.New), len(result.Relisted), len(result.Changed), len(result.PriceChanges), len(result.Removed))
+	for _, offer := range result.New {
+		log.Printf("Dry run: new offer %s - %s, %s (%s)", offer.Title, offer.Address, offer.Price, offer.Link)
+	}
+	for _, offer := range result.Relisted {
+		log.Printf("Dry run: relisted offer %s - %s, %s (%s)", offer.Title, offer.Address, offer.Price, offer.Link)
+	}
+	for _, change := range result.PriceChanges {
+		log.Printf("Dry run: price change %s: %s -> %s (%s)", change.Offer.Title, change.OldPrice, change.NewPrice, change.Offer.Link)
+	}
+	for _, offer := range result.Removed {
+		log.Printf("Dry run: delisted offer %s (%s)", offer.Title, offer.Link)
+	}
+}
+
+// notifyDelisted notifies users that offers they have seen are no longer available
+func notifyDelisted(bot *tgbotapi.BotAPI, botState *state.BotState, result state.UpdateResult) {
+	for chatID, user := range botState.GetAllUsers() {
+		if !user.Notifications {
+			continue
+		}
+
+		message := ""
+		for _, offer := range result.Removed {
+			if !seenBy(result.SeenBy[offer.Link], chatID) {
+				continue
+			}
+			message += fmt.Sprintf("*%s*\n", escapeMarkdownV2(offer.Title))
+			message += fmt.Sprintf("📍 %s\n", escapeMarkdownV2(offer.Address))
+			message += fmt.Sprintf("💰 %s\n\n", escapeMarkdownV2(offer.Price))
+		}
+
+		if message == "" {
+			continue
+		}
+
+		msg := tgbotapi.NewMessage(chatID, escapeMarkdownV2Bold(translate(user.Language, "delisted_header"))+message)
+		msg.ParseMode = "MarkdownV2"
+
+		if _, err := sendMessage(bot, msg); err != nil {
+			log.Printf("Error sending delisted message to user %d: %v", chatID, err)
+		}
+	}
+}
+
+// seenBy reports whether chatID is one of the chats
+func seenBy(chatIDs []int64, chatID int64) bool {
+	for _, id := range chatIDs {
+		if id == chatID {
+			return true
+		}
+	}
+	return false
+}
+
+// notifyPriceChanges notifies users who have seen an offer that its price has changed
+func notifyPriceChanges(bot *tgbotapi.BotAPI, botState *state.BotState, changes []state.PriceChange) {
+	users := botState.GetAllUsers()
+
+	for chatID, user := range users {
+		if !user.Notifications {
+			continue
+		}
+
+		message := ""
+		for _, change := range changes {
+			if !user.SeenOffers.Has(change.Offer.Link) {
+				continue
+			}
+
+			icon := "💸"
+			oldValue, oldOK := state.ParsePrice(change.OldPrice)
+			newValue, newOK := state.ParsePrice(change.NewPrice)
+			if oldOK && newOK {
+				if newValue < oldValue {
+					icon = "📉"
+				} else if newValue > oldValue {
+					icon = "📈"
+				}
+			}
+
+			message += fmt.Sprintf("*%s*\n", escapeMarkdownV2(change.Offer.Title))
+			message += fmt.Sprintf("📍 %s\n", escapeMarkdownV2(change.Offer.Address))
+			message += fmt.Sprintf("%s %s → %s\n", icon, escapeMarkdownV2(change.OldPrice), escapeMarkdownV2(change.NewPrice))
+			message += fmt.Sprintf("🔗 [%s](%s)\n\n", escapeMarkdownV2(translate(user.Language, "view_details")), escapeMarkdownV2URL(change.Offer.Link))
+		}
+
+		if message == "" {
+			continue
+		}
+
+		msg := tgbotapi.NewMessage(chatID, escapeMarkdownV2Bold(translate(user.Language, "price_changes_header"))+message)
+		msg.ParseMode = "MarkdownV2"
+		msg.DisableWebPagePreview = true
+
+		if _, err := sendMessage(bot, msg); err != nil {
+			log.Printf("Error sending price change message to user %d: %v", chatID, err)
+		}
+	}
+}
+
+// fetchOffers fetches the offers for updates and manual searches; tests replace it
+var fetchOffers = fetchRentalOffers
+
+// pageCache is shared by the website clients of all fetches, nil when disabled
+var pageCache *scraper.PageCache
+
+// pageValidators makes the fetches conditional on the previous responses of the
+// pages, persisted in validatorsFile of the data directory (nil outside RunBot)
+var pageValidators *scraper.Validators
+
+// validatorsFile stores the page validators in the data directory
+const validatorsFile = "page_validators.json"
+
+// fetchRentalOffers fetches rental offers using the scraper.WebSite struct
+func fetchRentalOffers(config BotConfig) ([]state.RentalOffer, error) {
 	// Create website client
-	website, err := NewWebSite(false) // verbose=false for bot mode
+	website, err := scraper.New(false) // verbose=false for bot mode
 	if err != nil {
 		return nil, fmt.Errorf("error creating website client: %w", err)
 	}
+	if config.Locale != "" {
+		website.Locale = config.Locale
+	}
+	website.Timeout = config.RequestTimeout
+	if err := website.SetProxy(config.ProxyURL); err != nil {
+		return nil, fmt.Errorf("error configuring proxy: %w", err)
+	}
+	if config.UserAgentsFile != "" {
+		if website.UserAgents, err = scraper.LoadUserAgents(config.UserAgentsFile); err != nil {
+			return nil, err
+		}
+	}
+	website.FallbackThreshold = config.FallbackThreshold
+	website.MaxOffers = config.MaxOffers
+	website.PageCap = config.PageCap
+	website.PageDelay = config.PageDelay
+	website.Cache = pageCache
+	website.Validators = pageValidators
+	if config.SelectorsFile != "" {
+		if website.Parser, err = scraper.LoadParserConfig(config.SelectorsFile); err != nil {
+			return nil, err
+		}
+	}
 
-	// Read form data from file
-	formData, err := os.ReadFile(formDataFile)
-	if err != nil {
-		return nil, fmt.Errorf("error reading form data from %s: %w", formDataFile, err)
+	// Read the form data of every search from its file or build it from the search options
+	searches := botSearches(config)
+	forms := make([]string, len(searches))
+	for i, search := range searches {
+		if forms[i], err = searchFormData(config, search); err != nil {
+			return nil, err
+		}
+	}
+
+	// Restore the session from the previous run
+	cookieFile := filepath.Join(config.DataDir, "cookies.json")
+	if config.PersistCookies {
+		if err := website.LoadCookies(cookieFile); err != nil {
+			log.Printf("Warning: Failed to load cookies: %v", err)
+		}
 	}
 
-	// Fetch offers using the website client
-	offers, err := website.FetchRentalOffers(string(formData), maxPages)
+	// Fetch the offers of each search using the website client. A failed search fails
+	// the whole update, as its offers would otherwise look delisted.
+	results := make([][]state.RentalOffer, len(searches))
+	for i, search := range searches {
+		offers, fetchErr := website.FetchRentalOffers(forms[i], config.MaxPages)
+		if fetchErr != nil {
+			err = fmt.Errorf("error fetching rental offers: %w", fetchErr)
+			if search.Name != "" {
+				err = fmt.Errorf("search %s: %w", search.Name, err)
+			}
+			break
+		}
+		if website.TotalResults > len(offers) {
+			log.Printf("Fetched %d of %d matching offers, -limit or -max-offers truncates the results", len(offers), website.TotalResults)
+		}
+		results[i] = toStateOffers(offers)
+	}
+	if config.PersistCookies {
+		if err := website.SaveCookies(cookieFile); err != nil {
+			log.Printf("Warning: Failed to save cookies: %v", err)
+		}
+	}
+	if pageValidators != nil {
+		if err := pageValidators.Save(filepath.Join(config.DataDir, validatorsFile)); err != nil {
+			log.Printf("Warning: Failed to save page validators: %v", err)
+		}
+	}
 	if err != nil {
-		return nil, fmt.Errorf("error fetching rental offers: %w", err)
+		return nil, err
 	}
 
-	// Convert RentalOffer to state.RentalOffer
+	return mergeSearchResults(searches, results), nil
+}
+
+// toStateOffers converts scraped offers to the offers stored in the bot state
+func toStateOffers(offers []scraper.RentalOffer) []state.RentalOffer {
 	stateOffers := make([]state.RentalOffer, len(offers))
 	for i, offer := range offers {
 		stateOffers[i] = state.RentalOffer{
-			Title:     offer.Title,
-			Address:   offer.Address,
-			Price:     offer.Price,
-			Size:      offer.Size,
-			Rooms:     offer.Rooms,
-			Available: offer.Available,
-			Link:      offer.Link,
+			Title:          offer.Title,
+			Address:        offer.Address,
+			Price:          offer.Price,
+			PriceEUR:       offer.PriceEUR,
+			PriceIsMonthly: offer.PriceIsMonthly,
+			BuildingType:   offer.BuildingType,
+			Size:           offer.Size,
+			SizeSqm:        offer.SizeSqm,
+			Rooms:          offer.Rooms,
+			RoomCount:      offer.RoomCount,
+			Amenities:      offer.Amenities,
+			Available:      offer.Available,
+			AvailableFrom:  offer.AvailableFrom,
+			AvailableNow:   offer.AvailableNow,
+			Lat:            offer.Lat,
+			Lng:            offer.Lng,
+			Link:           offer.Link,
+			ImageURL:       offer.ImageURL,
+		}
+	}
+
+	return stateOffers
+}
+
+// matchingOffers returns the offers matching one of the user's active profiles,
+// or the user's filters when no profile is active
+func matchingOffers(botState *state.BotState, chatID int64, offers []state.RentalOffer) []state.RentalOffer {
+	if profiles := botState.GetProfiles(chatID); state.HasActiveProfile(profiles) {
+		return state.FilterOffersByProfiles(offers, profiles)
+	}
+	filters, _ := botState.GetUserFilters(chatID)
+	return state.FilterOffers(offers, filters)
+}
+
+// unseenOffers returns the offers the user has not seen yet, e.g. through /search
+func unseenOffers(user *state.UserState, offers []state.RentalOffer) []state.RentalOffer {
+	unseen := make([]state.RentalOffer, 0, len(offers))
+	for _, offer := range offers {
+		if !user.SeenOffers.Has(offer.Link) {
+			unseen = append(unseen, offer)
 		}
 	}
+	return unseen
+}
 
-	return stateOffers, nil
+// defaultMaxOffersPerNotification is the number of offers listed in one new offers
+// notification unless configured otherwise
+const defaultMaxOffersPerNotification = 10
+
+var (
+	// maxOffersPerNotification is the number of offers listed in one notification
+	maxOffersPerNotification = defaultMaxOffersPerNotification
+	// moreOffersText is the note on the offers left out of a notification, empty
+	// for the translated note
+	moreOffersText string
+)
+
+// moreOffersNote returns the note on the count offers left out of a notification
+func moreOffersNote(lang string, count int) string {
+	if moreOffersText == "" {
+		return translate(lang, "more_offers", count)
+	}
+	return "\n" + fmt.Sprintf(moreOffersText, count)
 }
 
 // notifyUsers notifies users about new rental offers
 func notifyUsers(bot *tgbotapi.BotAPI, botState *state.BotState, newOffers []state.RentalOffer) {
 	users := botState.GetAllUsers()
 
-	for chatID := range users {
-		if !botState.GetUserNotificationsEnabled(chatID) {
+	now := time.Now()
+	matcher := newOfferMatcher(newOffers)
+
+	for chatID, user := range users {
+		if !botState.GetUserNotificationsEnabled(chatID) || user.Unreachable {
+			continue
+		}
+		// Skip paused users, the pause simply runs out at its end time
+		if user.PausedUntil.After(now) {
 			continue
 		}
 
-		// Prepare message
-		message := fmt.Sprintf("🏠 *New Rental Offers*\n\nFound %d new rental offers:\n\n", len(newOffers))
+		profiles := botState.GetProfiles(chatID)
+		userOffers := unseenOffers(user, matcher.match(user))
 
-		// Add offers to message
-		for i, offer := range newOffers {
-			if i >= 10 {
-				message += fmt.Sprintf("\n...and %d more offers. Use /list to see all offers.", len(newOffers)-10)
-				break
-			}
+		// Hold the offers back until the user's quiet hours are over
+		if user.QuietHours.Contains(now) {
+			botState.QueueOffers(chatID, userOffers)
+			continue
+		}
 
-			message += fmt.Sprintf("*%s*\n", offer.Title)
-			message += fmt.Sprintf("📍 %s\n", offer.Address)
-			message += fmt.Sprintf("💰 %s\n", offer.Price)
-			message += fmt.Sprintf("🛏 %s\n", offer.Rooms)
-			message += fmt.Sprintf("📐 %s\n", offer.Size)
-			if offer.Available != "" {
-				message += fmt.Sprintf("📅 %s\n", offer.Available)
+		// Collect the offers for the user's digest until it is due
+		if user.Digest.Enabled() {
+			botState.QueueOffers(chatID, userOffers)
+			if user.Digest.Due(now) {
+				sendDigest(bot, botState, chatID, now)
 			}
-			message += fmt.Sprintf("🔗 [View Details](%s)\n\n", offer.Link)
+			continue
+		}
 
-			// Mark offer as seen by this user
-			botState.MarkOfferAsSeen(chatID, offer.Link)
+		// Skip the offers another update is sending or has just sent to the user
+		userOffers = append(botState.TakePendingOffers(chatID), userOffers...)
+		userOffers = botState.ClaimNotification(chatID, userOffers, now)
+		if len(userOffers) == 0 {
+			continue
+		}
+
+		// Prepare one text per offer, listing at most maxOffersPerNotification offers
+		lang := user.Language
+		header := escapeMarkdownV2Bold(translate(lang, "new_offers", len(userOffers)))
+		shown := userOffers
+		if len(shown) > maxOffersPerNotification {
+			shown = shown[:maxOffersPerNotification]
+		}
+		texts := make([]string, len(shown))
+		for i, offer := range shown {
+			if len(user.DisplayFields) > 0 {
+				texts[i] = renderOfferFields(offer, user.DisplayFields, profileTags(profiles, offer), lang)
+			} else {
+				texts[i] = renderNotification(notificationTemplate, offer, profileTags(profiles, offer), lang)
+			}
+		}
+		if len(userOffers) > len(shown) {
+			texts[len(texts)-1] += escapeMarkdownV2(moreOffersNote(lang, len(userOffers)-len(shown)))
 		}
 
 		// Create keyboard with list button
 		keyboard := tgbotapi.NewInlineKeyboardMarkup(
 			tgbotapi.NewInlineKeyboardRow(
-				tgbotapi.NewInlineKeyboardButtonData("View All Offers 📋", "list_all"),
+				tgbotapi.NewInlineKeyboardButtonData(translate(lang, "button_view_all"), "list_all"),
 			),
 		)
 
-		// Send message
-		msg := tgbotapi.NewMessage(chatID, message)
-		msg.ParseMode = "Markdown"
-		msg.DisableWebPagePreview = true
-		msg.ReplyMarkup = keyboard
-
-		if _, err := bot.Send(msg); err != nil {
-			log.Printf("Error sending message to user %d: %v", chatID, err)
-		} else {
-			botState.UpdateUserLastNotified(chatID, time.Now())
+		// Send the offers with the photo of the first one. Offers that could not be
+		// sent stay unseen and are queued for the next cycle, the ones delivered
+		// before the error count as seen.
+		sent, err := sendOfferMessages(bot, chatID, header, texts, userOffers[0].ImageURL, keyboard)
+		if err != nil {
+			botState.ReleaseNotification(chatID, userOffers[sent:])
+			recordDelivery(botState, chatID, shown[:sent])
+			handleNotificationError(botState, chatID, userOffers[sent:], err)
+			continue
 		}
+		// The offers cut from the message may be sent by a later notification
+		botState.ReleaseNotification(chatID, userOffers[len(shown):])
+		notificationsSentTotal.Inc()
+		// Only the offers listed in the message count as seen
+		recordDelivery(botState, chatID, shown)
 	}
 }
 
+// recordDelivery marks the offers as delivered to the user
+func recordDelivery(botState *state.BotState, chatID int64, offers []state.RentalOffer) {
+	if len(offers) == 0 {
+		return
+	}
+	links := make([]string, len(offers))
+	for i, offer := range offers {
+		links[i] = offer.Link
+	}
+	botState.RecordDelivery(chatID, links, time.Now())
+}
+
+// handleNotificationError handles a notification that could not be sent. Users who
+// blocked the bot or whose chat is gone are flagged for removal, otherwise the
+// offers are kept for the next attempt.
+func handleNotificationError(botState *state.BotState, chatID int64, offers []state.RentalOffer, err error) {
+	if isChatUnreachable(err) {
+		log.Printf("User %d can no longer be reached, removing them: %v", chatID, err)
+		botState.MarkUserUnreachable(chatID)
+		return
+	}
+	log.Printf("Error sending message to user %d: %v", chatID, err)
+	botState.QueueOffers(chatID, offers)
+}
+
 // handleMessage handles incoming messages
 func handleMessage(bot *tgbotapi.BotAPI, botState *state.BotState, message *tgbotapi.Message, config BotConfig) {
-	// Add or update user
+	// Add or update user, remembering whether this is their first message
+	_, known := botState.GetUser(message.Chat.ID)
 	botState.AddUser(message.From, message.Chat.ID)
 
+	lang := userLanguage(botState, message.Chat.ID)
+
+	// Commands may carry arguments or a bot mention, so match them by name
+	text := message.Text
+	if message.IsCommand() {
+		text = "/" + message.Command()
+	} else if isImportMessage(message) {
+		// Documents carry the command in their caption
+		text = "/import"
+	} else if key, ok := buttonKey(text); ok {
+		// Buttons are matched by key, whatever language their label is in
+		text = key
+	}
+
 	// Handle commands and button presses
-	switch message.Text {
+	switch text {
 	case "/start":
-		handleStartCommand(bot, botState, message, config)
-	case "List Offers 📋", "/list":
+		if known {
+			handleStartCommand(bot, botState, message, config)
+		} else {
+			// Ask new users what they are looking for before showing any offers
+			startOnboarding(bot, botState, message)
+		}
+	case "/cancel":
+		handleCancelCommand(bot, botState, message)
+	case "button_list", "/list":
 		handleListCommand(bot, botState, message)
-	case "Reset 🔄", "/reset":
+	case "button_reset", "/reset":
 		handleResetCommand(bot, botState, message)
-	case "Notifications 🔔", "/notifications":
+	case "/undo":
+		handleUndoCommand(bot, botState, message)
+	case "button_notifications", "/notifications":
 		handleNotificationsCommand(bot, botState, message)
-	case "Status 📊", "/status":
+	case "button_status", "/status":
 		handleStatusCommand(bot, botState, message, config)
-	case "Help ❓", "/help":
-		handleHelpCommand(bot, message)
+	case "button_help", "/help":
+		handleHelpCommand(bot, botState, message)
+	case "/photos":
+		handlePhotosCommand(bot, botState, message)
+	case "/filter":
+		handleFilterCommand(bot, botState, message)
+	case "/search":
+		handleSearchCommand(bot, botState, message, config)
+	case "/query":
+		handleQueryCommand(bot, botState, message, config)
+	case "/pause":
+		handlePauseCommand(bot, botState, message)
+	case "/resume":
+		handleResumeCommand(bot, botState, message)
+	case "/searches":
+		handleSearchesCommand(bot, botState, message, config)
+	case "/fav":
+		handleFavCommand(bot, botState, message)
+	case "/unfav":
+		handleUnfavCommand(bot, botState, message)
+	case "/favorites":
+		handleFavoritesCommand(bot, botState, message)
+	case "/share":
+		handleShareCommand(bot, botState, message)
+	case "/map":
+		handleMapCommand(bot, botState, message)
+	case "/offer":
+		handleOfferCommand(bot, botState, message)
+	case "/recent":
+		handleRecentCommand(bot, botState, message)
+	case "/unseen":
+		handleUnseenCommand(bot, botState, message)
+	case "/diff":
+		handleDiffCommand(bot, botState, message)
+	case "/top":
+		handleTopCommand(bot, botState, message)
+	case "/show":
+		handleShowCommand(bot, botState, message)
+	case "/profile":
+		handleProfileCommand(bot, botState, message)
+	case "/feed":
+		handleFeedCommand(bot, botState, message, config)
+	case "/quiet":
+		handleQuietCommand(bot, botState, message)
+	case "/digest":
+		handleDigestCommand(bot, botState, message)
+	case "/lang":
+		handleLangCommand(bot, botState, message)
+	case "/stats":
+		handleStatsCommand(bot, botState, message)
 	case "/clear":
 		handleClearCommand(bot, botState, message, config)
-	case "Enable Notifications 🔔":
+	case "/export":
+		handleExportCommand(bot, botState, message, config)
+	case "/import":
+		handleImportCommand(bot, botState, message, config)
+	case "button_enable_notifications":
 		toggleNotifications(bot, botState, message.Chat.ID, true)
-	case "Disable Notifications 🔕":
+	case "button_disable_notifications":
 		toggleNotifications(bot, botState, message.Chat.ID, false)
-	case "Back to Main Menu ↩️":
-		msg := tgbotapi.NewMessage(message.Chat.ID, "Main menu:")
-		msg.ReplyMarkup = createMainKeyboard()
-		bot.Send(msg)
-	case "Yes, Clear Data ✅":
+	case "button_back":
+		msg := tgbotapi.NewMessage(message.Chat.ID, translate(lang, "main_menu"))
+		msg.ReplyMarkup = createMainKeyboard(lang)
+		sendMessage(bot, msg)
+	case "button_clear_yes":
 		handleClearConfirm(bot, botState, message.Chat.ID, config)
-	case "No, Keep Data ❌":
-		msg := tgbotapi.NewMessage(message.Chat.ID, "Data clearing cancelled. Your data is safe.")
-		msg.ReplyMarkup = createMainKeyboard()
-		bot.Send(msg)
+	case "button_clear_no":
+		msg := tgbotapi.NewMessage(message.Chat.ID, translate(lang, "clear_cancelled"))
+		msg.ReplyMarkup = createMainKeyboard(lang)
+		sendMessage(bot, msg)
 	default:
-		msg := tgbotapi.NewMessage(message.Chat.ID, "Please use the buttons below or commands to interact with me:")
-		msg.ReplyMarkup = createMainKeyboard()
-		bot.Send(msg)
+		// Free text answers the question of the conversation the user is in
+		if handleConversationReply(bot, botState, message) {
+			return
+		}
+		msg := tgbotapi.NewMessage(message.Chat.ID, translate(lang, "use_buttons"))
+		msg.ReplyMarkup = createMainKeyboard(lang)
+		sendMessage(bot, msg)
 	}
 }
 
-// createMainKeyboard creates the main keyboard markup
-func createMainKeyboard() tgbotapi.ReplyKeyboardMarkup {
+// createMainKeyboard creates the main keyboard markup in the given language
+func createMainKeyboard(lang string) tgbotapi.ReplyKeyboardMarkup {
 	return tgbotapi.NewReplyKeyboard(
 		tgbotapi.NewKeyboardButtonRow(
-			tgbotapi.NewKeyboardButton("List Offers 📋"),
-			tgbotapi.NewKeyboardButton("Reset 🔄"),
+			tgbotapi.NewKeyboardButton(translate(lang, "button_list")),
+			tgbotapi.NewKeyboardButton(translate(lang, "button_reset")),
 		),
 		tgbotapi.NewKeyboardButtonRow(
-			tgbotapi.NewKeyboardButton("Notifications 🔔"),
-			tgbotapi.NewKeyboardButton("Status 📊"),
+			tgbotapi.NewKeyboardButton(translate(lang, "button_notifications")),
+			tgbotapi.NewKeyboardButton(translate(lang, "button_status")),
 		),
 		tgbotapi.NewKeyboardButtonRow(
-			tgbotapi.NewKeyboardButton("Help ❓"),
+			tgbotapi.NewKeyboardButton(translate(lang, "button_help")),
 		),
 	)
 }
@@ -266,30 +959,27 @@ func createMainKeyboard() tgbotapi.ReplyKeyboardMarkup {
 func toggleNotifications(bot *tgbotapi.BotAPI, botState *state.BotState, chatID int64, enable bool) {
 	botState.SetUserNotifications(chatID, enable)
 
-	var message string
+	lang := userLanguage(botState, chatID)
+	message := translate(lang, "notifications_disabled")
 	if enable {
-		message = "✅ Notifications are now enabled. You will receive updates about new rental offers."
-	} else {
-		message = "🔕 Notifications are now disabled. You will not receive updates about new rental offers."
+		message = translate(lang, "notifications_enabled")
 	}
 
 	msg := tgbotapi.NewMessage(chatID, message)
-	msg.ReplyMarkup = createMainKeyboard()
-	bot.Send(msg)
+	msg.ReplyMarkup = createMainKeyboard(lang)
+	sendMessage(bot, msg)
 }
 
 // handleStartCommand handles the /start command
 func handleStartCommand(bot *tgbotapi.BotAPI, botState *state.BotState, message *tgbotapi.Message, config BotConfig) {
 	chatID := message.Chat.ID
 
-	// Welcome message
-	welcomeMsg := fmt.Sprintf("👋 Welcome to the Vuokraovi Rental Bot, %s!\n\n", message.From.FirstName)
-	welcomeMsg += "I will notify you about new rental offers from Vuokraovi.com.\n\n"
-	welcomeMsg += "Use the buttons below or type commands to interact with me:"
+	lang := userLanguage(botState, chatID)
 
-	msg := tgbotapi.NewMessage(chatID, welcomeMsg)
-	msg.ReplyMarkup = createMainKeyboard()
-	bot.Send(msg)
+	// Welcome message
+	msg := tgbotapi.NewMessage(chatID, translate(lang, "welcome", message.From.FirstName))
+	msg.ReplyMarkup = createMainKeyboard(lang)
+	sendMessage(bot, msg)
 
 	// Send all current offers to the new user
 	offers := make([]state.RentalOffer, 0)
@@ -298,72 +988,59 @@ func handleStartCommand(bot *tgbotapi.BotAPI, botState *state.BotState, message
 	}
 
 	if len(offers) > 0 {
-		infoMsg := fmt.Sprintf("Here are the current %d rental offers:", len(offers))
-		bot.Send(tgbotapi.NewMessage(chatID, infoMsg))
+		infoMsg := translate(lang, "current_offers", len(offers))
+		sendMessage(bot, tgbotapi.NewMessage(chatID, infoMsg))
 
-		sendOffersList(bot, offers, chatID)
+		sendOffersList(bot, offers, chatID, lang)
 	}
 }
 
 // handleListCommand handles the /list command
 func handleListCommand(bot *tgbotapi.BotAPI, botState *state.BotState, message *tgbotapi.Message) {
-	offers := make([]state.RentalOffer, 0)
-	for _, offer := range botState.GetKnownOffers() {
-		offers = append(offers, offer)
-	}
-
-	if len(offers) == 0 {
-		msg := tgbotapi.NewMessage(message.Chat.ID, "No rental offers available at the moment.")
-		msg.ReplyMarkup = createMainKeyboard()
-		bot.Send(msg)
-		return
-	}
-
-	infoMsg := fmt.Sprintf("Here are the current %d rental offers:", len(offers))
-	bot.Send(tgbotapi.NewMessage(message.Chat.ID, infoMsg))
+	sendListPage(bot, botState, message.Chat.ID)
+}
 
-	sendOffersList(bot, offers, message.Chat.ID)
+// formatOfferDetails formats an offer for offer lists as MarkdownV2
+func formatOfferDetails(offer state.RentalOffer, lang string) string {
+	return renderOfferFields(offer, offerFields, "", lang)
 }
 
-// sendOffersList sends a list of offers to a chat
-func sendOffersList(bot *tgbotapi.BotAPI, offers []state.RentalOffer, chatID int64) {
-	// Split offers into chunks to avoid message size limits
-	chunkSize := 5
-	for i := 0; i < len(offers); i += chunkSize {
-		end := i + chunkSize
-		if end > len(offers) {
-			end = len(offers)
+// listedAgo describes how long ago an offer was first seen, e.g. "Listed 2 days ago"
+func listedAgo(firstSeen, now time.Time, lang string) string {
+	age := now.Sub(firstSeen)
+	switch {
+	case age < time.Hour:
+		return translate(lang, "listed_just_now")
+	case age < 24*time.Hour:
+		hours := int(age.Hours())
+		if hours == 1 {
+			return translate(lang, "listed_hour")
 		}
-
-		chunk := offers[i:end]
-		message := ""
-
-		for _, offer := range chunk {
-			message += fmt.Sprintf("*%s*\n", offer.Title)
-			message += fmt.Sprintf("📍 %s\n", offer.Address)
-			message += fmt.Sprintf("💰 %s\n", offer.Price)
-			message += fmt.Sprintf("🛏 %s\n", offer.Rooms)
-			message += fmt.Sprintf("📐 %s\n", offer.Size)
-			if offer.Available != "" {
-				message += fmt.Sprintf("📅 %s\n", offer.Available)
-			}
-			message += fmt.Sprintf("🔗 [View Details](%s)\n\n", offer.Link)
+		return translate(lang, "listed_hours", hours)
+	default:
+		days := int(age.Hours() / 24)
+		if days == 1 {
+			return translate(lang, "listed_day")
 		}
+		return translate(lang, "listed_days", days)
+	}
+}
 
-		// For the last chunk, add the main keyboard
-		var markup interface{} = nil
-		if end >= len(offers) {
-			markup = createMainKeyboard()
-		}
+// sendOffersList sends a list of offers to a chat, as many per message as fit in
+// Telegram's length limit
+func sendOffersList(bot *tgbotapi.BotAPI, offers []state.RentalOffer, chatID int64, lang string) {
+	if len(offers) == 0 {
+		return
+	}
 
-		msg := tgbotapi.NewMessage(chatID, message)
-		msg.ParseMode = "Markdown"
-		msg.DisableWebPagePreview = true
-		msg.ReplyMarkup = markup
-		bot.Send(msg)
+	texts := make([]string, len(offers))
+	for i, offer := range offers {
+		texts[i] = formatOfferDetails(offer, lang)
+	}
 
-		// Add a small delay to avoid hitting rate limits
-		time.Sleep(500 * time.Millisecond)
+	// sendMessage paces the messages through the shared rate limiter
+	if _, err := sendOfferMessages(bot, chatID, "", texts, offers[0].ImageURL, createMainKeyboard(lang)); err != nil {
+		log.Printf("Error sending offers to user %d: %v", chatID, err)
 	}
 }
 
@@ -371,29 +1048,47 @@ func sendOffersList(bot *tgbotapi.BotAPI, offers []state.RentalOffer, chatID int
 func handleResetCommand(bot *tgbotapi.BotAPI, botState *state.BotState, message *tgbotapi.Message) {
 	botState.ResetUserState(message.Chat.ID)
 
-	msg := tgbotapi.NewMessage(message.Chat.ID, "✅ Your state has been reset. You will now receive all available offers again.")
-	msg.ReplyMarkup = createMainKeyboard()
-	bot.Send(msg)
+	lang := userLanguage(botState, message.Chat.ID)
+	msg := tgbotapi.NewMessage(message.Chat.ID, translate(lang, "reset_done"))
+	msg.ReplyMarkup = createMainKeyboard(lang)
+	sendMessage(bot, msg)
 
 	// Send all current offers to the user
 	handleListCommand(bot, botState, message)
 }
 
+// handleUndoCommand handles the /undo command, marking the offers of the user's
+// latest notification as unseen so they are sent again with the next update
+func handleUndoCommand(bot *tgbotapi.BotAPI, botState *state.BotState, message *tgbotapi.Message) {
+	chatID := message.Chat.ID
+	lang := userLanguage(botState, chatID)
+
+	text := translate(lang, "undo_nothing")
+	if count := botState.UndoLastNotification(chatID); count > 0 {
+		text = translate(lang, "undo_done", count)
+	}
+
+	msg := tgbotapi.NewMessage(chatID, text)
+	msg.ReplyMarkup = createMainKeyboard(lang)
+	sendMessage(bot, msg)
+}
+
 // handleNotificationsCommand handles the /notifications command
 func handleNotificationsCommand(bot *tgbotapi.BotAPI, botState *state.BotState, message *tgbotapi.Message) {
+	lang := userLanguage(botState, message.Chat.ID)
 	keyboard := tgbotapi.NewReplyKeyboard(
 		tgbotapi.NewKeyboardButtonRow(
-			tgbotapi.NewKeyboardButton("Enable Notifications 🔔"),
-			tgbotapi.NewKeyboardButton("Disable Notifications 🔕"),
+			tgbotapi.NewKeyboardButton(translate(lang, "button_enable_notifications")),
+			tgbotapi.NewKeyboardButton(translate(lang, "button_disable_notifications")),
 		),
 		tgbotapi.NewKeyboardButtonRow(
-			tgbotapi.NewKeyboardButton("Back to Main Menu ↩️"),
+			tgbotapi.NewKeyboardButton(translate(lang, "button_back")),
 		),
 	)
 
-	msg := tgbotapi.NewMessage(message.Chat.ID, "Do you want to receive notifications about new rental offers?")
+	msg := tgbotapi.NewMessage(message.Chat.ID, translate(lang, "notifications_prompt"))
 	msg.ReplyMarkup = keyboard
-	bot.Send(msg)
+	sendMessage(bot, msg)
 }
 
 // handleStatusCommand handles the /status command
@@ -411,74 +1106,91 @@ func handleStatusCommand(bot *tgbotapi.BotAPI, botState *state.BotState, message
 		notifications, _ = botState.GetUserNotifications(chatID)
 	}
 
-	statusText := fmt.Sprintf("Bot Status:\n\n"+
-		"• Total offers: %d\n"+
-		"• Your notifications: %s\n"+
-		"• Last update: %s\n"+
-		"• Update interval: %v",
+	lang := userLanguage(botState, chatID)
+	statusText := translate(lang, "status",
 		totalOffers,
-		map[bool]string{true: "Enabled ✅", false: "Disabled 🔕"}[notifications],
+		translate(lang, map[bool]string{true: "enabled", false: "disabled"}[notifications]),
 		lastUpdate.Format("2006-01-02 15:04:05"),
 		config.UpdateInterval)
 
 	msg := tgbotapi.NewMessage(chatID, statusText)
-	msg.ReplyMarkup = createMainKeyboard()
+	msg.ReplyMarkup = createMainKeyboard(lang)
+	msg.ParseMode = "Markdown"
+	sendMessage(bot, msg)
+}
+
+// formatUserStats formats the personal statistics of a user
+func formatUserStats(stats state.UserStats, lang string) string {
+	lastNotified := translate(lang, "never")
+	if !stats.LastNotified.IsZero() {
+		lastNotified = stats.LastNotified.Format("2006-01-02 15:04:05")
+	}
+
+	return translate(lang, "stats",
+		stats.SeenOffers,
+		stats.Favorites,
+		translate(lang, map[bool]string{true: "enabled", false: "disabled"}[stats.Notifications]),
+		lastNotified,
+		stats.KnownOffers)
+}
+
+// handleStatsCommand handles the /stats command
+func handleStatsCommand(bot *tgbotapi.BotAPI, botState *state.BotState, message *tgbotapi.Message) {
+	chatID := message.Chat.ID
+	lang := userLanguage(botState, chatID)
+
+	stats, exists := botState.GetUserStats(chatID)
+	if !exists {
+		msg := tgbotapi.NewMessage(chatID, translate(lang, "start_first"))
+		msg.ReplyMarkup = createMainKeyboard(lang)
+		sendMessage(bot, msg)
+		return
+	}
+
+	msg := tgbotapi.NewMessage(chatID, formatUserStats(stats, lang))
 	msg.ParseMode = "Markdown"
-	bot.Send(msg)
+	msg.ReplyMarkup = createMainKeyboard(lang)
+	sendMessage(bot, msg)
 }
 
 // handleHelpCommand handles the /help command
-func handleHelpCommand(bot *tgbotapi.BotAPI, message *tgbotapi.Message) {
-	helpText := "🤖 *Vuokraovi Rental Bot Commands*\n\n"
-	helpText += "/start - Start the bot and get current offers\n"
-	helpText += "/help - Show this help message\n"
-	helpText += "/list - List all current rental offers\n"
-	helpText += "/reset - Reset your state and get all offers again\n"
-	helpText += "/notifications - Toggle notifications on/off\n"
-	helpText += "/status - Show bot status information\n"
-	helpText += "/clear - Clear your data and reset all settings\n\n"
-	helpText += "You can also use the buttons below for quick access to commands:"
-
-	msg := tgbotapi.NewMessage(message.Chat.ID, helpText)
+func handleHelpCommand(bot *tgbotapi.BotAPI, botState *state.BotState, message *tgbotapi.Message) {
+	lang := userLanguage(botState, message.Chat.ID)
+	msg := tgbotapi.NewMessage(message.Chat.ID, translate(lang, "help"))
 	msg.ParseMode = "Markdown"
-	msg.ReplyMarkup = createMainKeyboard()
-	bot.Send(msg)
+	msg.ReplyMarkup = createMainKeyboard(lang)
+	sendMessage(bot, msg)
 }
 
 // handleClearCommand handles the /clear command
 func handleClearCommand(bot *tgbotapi.BotAPI, botState *state.BotState, message *tgbotapi.Message, config BotConfig) {
 	chatID := message.Chat.ID
+	lang := userLanguage(botState, chatID)
 	_, exists := botState.GetUser(chatID)
 	if !exists {
-		msg := tgbotapi.NewMessage(chatID, "Please start the bot first with /start")
-		msg.ReplyMarkup = createMainKeyboard()
-		bot.Send(msg)
+		msg := tgbotapi.NewMessage(chatID, translate(lang, "start_first"))
+		msg.ReplyMarkup = createMainKeyboard(lang)
+		sendMessage(bot, msg)
 		return
 	}
 
 	keyboard := tgbotapi.NewReplyKeyboard(
 		tgbotapi.NewKeyboardButtonRow(
-			tgbotapi.NewKeyboardButton("Yes, Clear Data ✅"),
-			tgbotapi.NewKeyboardButton("No, Keep Data ❌"),
+			tgbotapi.NewKeyboardButton(translate(lang, "button_clear_yes")),
+			tgbotapi.NewKeyboardButton(translate(lang, "button_clear_no")),
 		),
 	)
 
-	msg := tgbotapi.NewMessage(chatID, "⚠️ Are you sure you want to clear your data? This will:\n\n"+
-		"• Remove all your seen offers\n"+
-		"• Reset your notification settings\n"+
-		"• Clear your last active time\n\n"+
-		"This action cannot be undone.")
+	msg := tgbotapi.NewMessage(chatID, translate(lang, "clear_confirm"))
 	msg.ReplyMarkup = keyboard
-	bot.Send(msg)
+	sendMessage(bot, msg)
 }
 
 // handleClearConfirm handles the confirmation of clearing user data
 func handleClearConfirm(bot *tgbotapi.BotAPI, botState *state.BotState, chatID int64, config BotConfig) {
 	botState.ResetUserState(chatID)
-	msg := tgbotapi.NewMessage(chatID, "✅ Your data has been cleared successfully.\n\n"+
-		"• Seen offers have been reset\n"+
-		"• Notifications have been re-enabled\n\n"+
-		"You will now receive notifications for all offers again.")
-	msg.ReplyMarkup = createMainKeyboard()
-	bot.Send(msg)
+	lang := userLanguage(botState, chatID)
+	msg := tgbotapi.NewMessage(chatID, translate(lang, "clear_done"))
+	msg.ReplyMarkup = createMainKeyboard(lang)
+	sendMessage(bot, msg)
 }
@@ -0,0 +1,538 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aqaliarept/vuokraovi-bot/state"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// telegramRequest is a Bot API call received by fakeTelegram
+type telegramRequest struct {
+	method string
+	values url.Values
+}
+
+// fakeTelegram is a Bot API server recording the calls made to it
+type fakeTelegram struct {
+	mutex    sync.Mutex
+	requests []telegramRequest
+	// rateLimited is the number of sends still to be rejected with 429 Too Many Requests
+	rateLimited int
+	// failPhotos rejects all photos, like Telegram does for unreachable image URLs
+	failPhotos bool
+	// failSends rejects all sends with a server error while set
+	failSends bool
+	// failMessages rejects the text messages but not the photos with a server error
+	failMessages bool
+	// serverErrors is the number of sends still to be rejected with 502 Bad Gateway
+	serverErrors int
+	// blocked rejects all sends with 403 Forbidden, like Telegram does once the user blocked the bot
+	blocked bool
+}
+
+// newFakeTelegram starts a fake Bot API server and returns a bot talking to it
+func newFakeTelegram(t *testing.T) (*tgbotapi.BotAPI, *fakeTelegram) {
+	t.Helper()
+	fake := &fakeTelegram{}
+	srv := httptest.NewServer(http.HandlerFunc(fake.serve))
+	t.Cleanup(srv.Close)
+
+	// Retry failed sends right away
+	previousDelay := sendRetryDelay
+	sendRetryDelay = time.Millisecond
+	t.Cleanup(func() { sendRetryDelay = previousDelay })
+
+	bot, err := tgbotapi.NewBotAPIWithClient("test-token", srv.URL+"/bot%s/%s", srv.Client())
+	if err != nil {
+		t.Fatalf("error creating bot: %v", err)
+	}
+	// Only record the calls made by the code under test
+	fake.reset()
+	return bot, fake
+}
+
+// serve answers a Bot API call
+func (f *fakeTelegram) serve(w http.ResponseWriter, r *http.Request) {
+	// Parses URL encoded bodies as well, reporting only that they are not multipart
+	r.ParseMultipartForm(10 << 20)
+	method := r.URL.Path[strings.LastIndex(r.URL.Path, "/")+1:]
+
+	f.mutex.Lock()
+	f.requests = append(f.requests, telegramRequest{method: method, values: r.Form})
+	limited := f.rateLimited > 0 && strings.HasPrefix(method, "send")
+	if limited {
+		f.rateLimited--
+	}
+	failed := f.failPhotos && method == "sendPhoto"
+	broken := f.failSends && strings.HasPrefix(method, "send") || f.failMessages && method == "sendMessage"
+	badGateway := f.serverErrors > 0 && strings.HasPrefix(method, "send")
+	if badGateway {
+		f.serverErrors--
+	}
+	blocked := f.blocked && strings.HasPrefix(method, "send")
+	f.mutex.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	switch {
+	case limited:
+		fmt.Fprint(w, `{"ok":false,"error_code":429,"description":"Too Many Requests: retry after 1","parameters":{"retry_after":1}}`)
+	case blocked:
+		fmt.Fprint(w, `{"ok":false,"error_code":403,"description":"Forbidden: bot was blocked by the user"}`)
+	case badGateway:
+		fmt.Fprint(w, `{"ok":false,"error_code":502,"description":"Bad Gateway"}`)
+	case broken:
+		fmt.Fprint(w, `{"ok":false,"error_code":500,"description":"Internal Server Error"}`)
+	case failed:
+		fmt.Fprint(w, `{"ok":false,"error_code":400,"description":"Bad Request: wrong file identifier/HTTP URL specified"}`)
+	case method == "getMe":
+		fmt.Fprint(w, `{"ok":true,"result":{"id":1,"is_bot":true,"first_name":"Test","username":"test_bot"}}`)
+	case strings.HasPrefix(method, "send") || strings.HasPrefix(method, "edit"):
+		fmt.Fprintf(w, `{"ok":true,"result":{"message_id":1,"date":0,"chat":{"id":%s,"type":"private"}}}`, r.Form.Get("chat_id"))
+	default:
+		fmt.Fprint(w, `{"ok":true,"result":true}`)
+	}
+}
+
+// setFailSends makes all following sends fail or succeed again
+func (f *fakeTelegram) setFailSends(fail bool) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.failSends = fail
+}
+
+// reset forgets the recorded calls
+func (f *fakeTelegram) reset() {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.requests = nil
+}
+
+// calls returns the recorded calls of a method
+func (f *fakeTelegram) calls(method string) []url.Values {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	var values []url.Values
+	for _, r := range f.requests {
+		if r.method == method {
+			values = append(values, r.values)
+		}
+	}
+	return values
+}
+
+// newTestBotState creates an empty bot state persisted in a temporary directory
+func newTestBotState(t testing.TB) *state.BotState {
+	t.Helper()
+	botState, err := state.NewBotState(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return botState
+}
+
+// testOffer creates an offer with the given link and price
+func testOffer(link, price string) state.RentalOffer {
+	value, _ := state.ParsePrice(price)
+	return state.RentalOffer{
+		Title:    "Testikatu 1",
+		Address:  "Testikatu 1, Helsinki",
+		Price:    price,
+		PriceEUR: value,
+		Link:     link,
+	}
+}
+
+func TestNotifyPriceChangesOnlyTellsUsersWhoSawTheOffer(t *testing.T) {
+	bot, fake := newFakeTelegram(t)
+	botState := newTestBotState(t)
+	botState.AddUser(&tgbotapi.User{FirstName: "Seen"}, 1)
+	botState.AddUser(&tgbotapi.User{FirstName: "Unseen"}, 2)
+	botState.ApplyOffers([]state.RentalOffer{testOffer("https://example.com/a", "900 €/kk")})
+	botState.MarkOfferAsSeen(1, "https://example.com/a")
+
+	result := botState.ApplyOffers([]state.RentalOffer{testOffer("https://example.com/a", "850 €/kk")})
+	notifyPriceChanges(bot, botState, result.PriceChanges)
+
+	sent := fake.calls("sendMessage")
+	if len(sent) != 1 || sent[0].Get("chat_id") != "1" {
+		t.Fatalf("sent %v, want one message to chat 1", sent)
+	}
+	if text := sent[0].Get("text"); !strings.Contains(text, "📉 900 €/kk → 850 €/kk") {
+		t.Errorf("message %q does not show the price drop", text)
+	}
+}
+
+func TestNotifyDelistedTellsUsersWhoSawTheOffer(t *testing.T) {
+	bot, fake := newFakeTelegram(t)
+	botState := newTestBotState(t)
+	botState.SetDelistAfter(1)
+	botState.AddUser(&tgbotapi.User{FirstName: "Seen"}, 1)
+	botState.AddUser(&tgbotapi.User{FirstName: "Unseen"}, 2)
+	botState.ApplyOffers([]state.RentalOffer{testOffer("https://example.com/a", "900 €/kk")})
+	botState.MarkOfferAsSeen(1, "https://example.com/a")
+
+	notifyDelisted(bot, botState, botState.ApplyOffers(nil))
+
+	sent := fake.calls("sendMessage")
+	if len(sent) != 1 || sent[0].Get("chat_id") != "1" {
+		t.Fatalf("sent %v, want one message to chat 1", sent)
+	}
+	if text := sent[0].Get("text"); !strings.Contains(text, "No Longer Available") || !strings.Contains(text, "Testikatu 1") {
+		t.Errorf("unexpected message %q", text)
+	}
+}
+
+func TestDelistedAndPriceChangeMessagesAreTranslated(t *testing.T) {
+	bot, fake := newFakeTelegram(t)
+	botState := newTestBotState(t)
+	botState.SetDelistAfter(1)
+	botState.AddUser(&tgbotapi.User{FirstName: "Fi"}, 1)
+	botState.SetUserLanguage(1, "fi")
+	botState.ApplyOffers([]state.RentalOffer{testOffer("https://example.com/a", "900 €/kk")})
+	botState.MarkOfferAsSeen(1, "https://example.com/a")
+
+	notifyPriceChanges(bot, botState, botState.ApplyOffers([]state.RentalOffer{testOffer("https://example.com/a", "850 €/kk")}).PriceChanges)
+	notifyDelisted(bot, botState, botState.ApplyOffers(nil))
+
+	sent := fake.calls("sendMessage")
+	if len(sent) != 2 {
+		t.Fatalf("sent %d messages, want the price change and the delisting", len(sent))
+	}
+	if text := sent[0].Get("text"); !strings.Contains(text, "*Hintamuutokset*") || !strings.Contains(text, "[Näytä tiedot]") {
+		t.Errorf("price change message %q is not in Finnish", text)
+	}
+	if text := sent[1].Get("text"); !strings.Contains(text, "*Ei enää saatavilla*") {
+		t.Errorf("delisted message %q is not in Finnish", text)
+	}
+}
+
+func TestNotifyUsersAppliesFilters(t *testing.T) {
+	bot, fake := newFakeTelegram(t)
+	botState := newTestBotState(t)
+	botState.AddUser(&tgbotapi.User{FirstName: "Cheap"}, 1)
+	botState.AddUser(&tgbotapi.User{FirstName: "Everything"}, 2)
+	botState.SetUserFilters(1, state.Filters{MaxPrice: 900})
+	offers := []state.RentalOffer{testOffer("https://example.com/a", "800 €/kk"), testOffer("https://example.com/b", "1200 €/kk")}
+	botState.ApplyOffers(offers)
+
+	notifyUsers(bot, botState, offers)
+
+	if sent := fake.calls("sendMessage"); len(sent) != 2 {
+		t.Fatalf("sent %d messages, want one per user", len(sent))
+	}
+	if user, _ := botState.GetUser(1); !user.SeenOffers.Has("https://example.com/a") || user.SeenOffers.Has("https://example.com/b") {
+		t.Errorf("seen offers of the filtering user = %v, want only the matching offer", user.SeenOffers)
+	}
+	if user, _ := botState.GetUser(2); len(user.SeenOffers) != 2 {
+		t.Errorf("seen offers of the user without filters = %v, want both offers", user.SeenOffers)
+	}
+}
+
+func TestUndoResendsLastNotification(t *testing.T) {
+	bot, fake := newFakeTelegram(t)
+	botState := newTestBotState(t)
+	botState.AddUser(&tgbotapi.User{FirstName: "Test"}, 1)
+	first := []state.RentalOffer{testOffer("https://example.com/a", "800 €/kk"), testOffer("https://example.com/b", "900 €/kk")}
+	botState.ApplyOffers(first)
+	notifyUsers(bot, botState, first)
+	latest := testOffer("https://example.com/c", "1000 €/kk")
+	botState.ApplyOffers(append(first, latest))
+	notifyUsers(bot, botState, []state.RentalOffer{latest})
+
+	fake.reset()
+	handleMessage(bot, botState, userMessage(1, "/undo"), BotConfig{})
+	if sent := fake.calls("sendMessage"); len(sent) != 1 || sent[0].Get("text") != translate("en", "undo_done", 1) {
+		t.Fatalf("sent %v, want the undo confirmation", sent)
+	}
+	if user, _ := botState.GetUser(1); user.SeenOffers.Has(latest.Link) || !user.SeenOffers.Has("https://example.com/a") {
+		t.Errorf("seen offers = %v, want only the latest notification unseen", user.SeenOffers)
+	}
+
+	fake.reset()
+	notifyUsers(bot, botState, nil)
+	sent := fake.calls("sendMessage")
+	if len(sent) != 1 || !strings.Contains(sent[0].Get("text"), latest.Link) || strings.Contains(sent[0].Get("text"), "https://example.com/a") {
+		t.Fatalf("sent %v, want the latest offer notified again", sent)
+	}
+
+	fake.reset()
+	handleMessage(bot, botState, userMessage(1, "/undo"), BotConfig{})
+	handleMessage(bot, botState, userMessage(1, "/undo"), BotConfig{})
+	if sent := fake.calls("sendMessage"); len(sent) != 2 || sent[1].Get("text") != translate("en", "undo_nothing") {
+		t.Errorf("sent %v, want nothing left to undo after the second /undo", sent)
+	}
+}
+
+func TestFailedNotificationKeepsOffersUnseen(t *testing.T) {
+	bot, fake := newFakeTelegram(t)
+	botState := newTestBotState(t)
+	botState.AddUser(&tgbotapi.User{FirstName: "Test"}, 1)
+	offer := testOffer("https://example.com/a", "800 €/kk")
+	botState.ApplyOffers([]state.RentalOffer{offer})
+
+	fake.setFailSends(true)
+	notifyUsers(bot, botState, []state.RentalOffer{offer})
+	user, _ := botState.GetUser(1)
+	if user.SeenOffers.Has(offer.Link) || !user.LastNotified.IsZero() || len(user.LastNotification) != 0 {
+		t.Fatalf("user = %+v, want the offer unseen after the failed send", user)
+	}
+
+	// The next cycle finds no new offers but sends the one that failed
+	fake.setFailSends(false)
+	fake.reset()
+	notifyUsers(bot, botState, nil)
+	if sent := fake.calls("sendMessage"); len(sent) != 1 || !strings.Contains(sent[0].Get("text"), offer.Link) {
+		t.Fatalf("sent %v, want the offer notified again", sent)
+	}
+	if user, _ := botState.GetUser(1); !user.SeenOffers.Has(offer.Link) || user.LastNotified.IsZero() {
+		t.Errorf("user = %+v, want the offer seen after it was delivered", user)
+	}
+}
+
+func TestNotificationListsConfiguredNumberOfOffers(t *testing.T) {
+	bot, fake := newFakeTelegram(t)
+	botState := newTestBotState(t)
+	botState.AddUser(&tgbotapi.User{FirstName: "Test"}, 1)
+	previous := maxOffersPerNotification
+	maxOffersPerNotification = 5
+	defer func() { maxOffersPerNotification = previous }()
+
+	var offers []state.RentalOffer
+	for i := 0; i < 15; i++ {
+		offers = append(offers, testOffer(fmt.Sprintf("https://example.com/%d", i), "900 €/kk"))
+	}
+	notifyUsers(bot, botState, offers)
+
+	sent := fake.calls("sendMessage")
+	if len(sent) != 1 {
+		t.Fatalf("sent %d messages, want 1", len(sent))
+	}
+	text := sent[0].Get("text")
+	listed := strings.Count(text, "https://example.com/")
+	if listed != 5 || !strings.Contains(text, escapeMarkdownV2(translate("en", "more_offers", 10))) {
+		t.Errorf("message lists %d offers, want 5 and a note on 10 more:\n%s", listed, text)
+	}
+	if user, _ := botState.GetUser(1); len(user.SeenOffers) != 5 {
+		t.Errorf("%d offers marked as seen, want the 5 listed", len(user.SeenOffers))
+	}
+
+	previousText := moreOffersText
+	moreOffersText = "+%d more on the site"
+	defer func() { moreOffersText = previousText }()
+	if got := moreOffersNote("en", 10); got != "\n+10 more on the site" {
+		t.Errorf("custom note = %q", got)
+	}
+}
+
+func TestWebhookDispatchesUpdates(t *testing.T) {
+	bot, fake := newFakeTelegram(t)
+	botState := newTestBotState(t)
+	updates := make(chan tgbotapi.Update, 1)
+	srv := httptest.NewServer(webhookHandler(bot, updates))
+	defer srv.Close()
+
+	body := `{"update_id": 1, "message": {"message_id": 1, "date": 0, "text": "/help",
+		"from": {"id": 42, "first_name": "Test"}, "chat": {"id": 42, "type": "private"},
+		"entities": [{"type": "bot_command", "offset": 0, "length": 5}]}}`
+	resp, err := http.Post(srv.URL, "application/json", strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status %d, want 200", resp.StatusCode)
+	}
+
+	processUpdate(bot, botState, <-updates, BotConfig{})
+
+	sent := fake.calls("sendMessage")
+	if len(sent) != 1 || sent[0].Get("chat_id") != "42" {
+		t.Fatalf("sent %v, want the help message to chat 42", sent)
+	}
+	if _, exists := botState.GetUser(42); !exists {
+		t.Error("the sender was not added as a user")
+	}
+
+	resp, err = http.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("GET: status %d, want 400", resp.StatusCode)
+	}
+}
+
+func TestProcessUpdatesReturnsOnShutdown(t *testing.T) {
+	bot, _ := newFakeTelegram(t)
+	botState := newTestBotState(t)
+	updates := make(chan tgbotapi.Update)
+	shutdown := make(chan struct{})
+
+	done := make(chan struct{})
+	go func() {
+		processUpdates(bot, botState, updates, shutdown, BotConfig{})
+		close(done)
+	}()
+	close(shutdown)
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("processUpdates did not return after the shutdown")
+	}
+}
+
+func TestFormatUserStats(t *testing.T) {
+	stats := state.UserStats{
+		SeenOffers:    12,
+		Favorites:     3,
+		Notifications: true,
+		LastNotified:  time.Date(2024, 5, 1, 9, 30, 0, 0, time.UTC),
+		KnownOffers:   40,
+	}
+
+	want := "📈 *Your Statistics*\n\n" +
+		"• Offers seen: 12\n" +
+		"• Favorites: 3\n" +
+		"• Notifications: Enabled ✅\n" +
+		"• Last notified: 2024-05-01 09:30:00\n" +
+		"• Offers known by the bot: 40"
+	if got := formatUserStats(stats, "en"); got != want {
+		t.Errorf("formatUserStats = %q, want %q", got, want)
+	}
+
+	stats.LastNotified = time.Time{}
+	if got := formatUserStats(stats, "fi"); !strings.Contains(got, "Viimeisin ilmoitus: ei koskaan") {
+		t.Errorf("formatUserStats without notifications = %q, want \"ei koskaan\"", got)
+	}
+}
+
+func TestListedAgo(t *testing.T) {
+	now := time.Date(2024, 5, 10, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		age  time.Duration
+		lang string
+		want string
+	}{
+		{59 * time.Minute, "en", "Listed just now"},
+		{time.Hour, "en", "Listed 1 hour ago"},
+		{23 * time.Hour, "en", "Listed 23 hours ago"},
+		{24 * time.Hour, "en", "Listed 1 day ago"},
+		{72 * time.Hour, "en", "Listed 3 days ago"},
+		{30 * time.Minute, "fi", "Lisätty juuri nyt"},
+		{5 * time.Hour, "fi", "Lisätty 5 tuntia sitten"},
+		{48 * time.Hour, "fi", "Lisätty 2 päivää sitten"},
+	}
+	for _, tt := range tests {
+		if got := listedAgo(now.Add(-tt.age), now, tt.lang); got != tt.want {
+			t.Errorf("listedAgo(%v, %s) = %q, want %q", tt.age, tt.lang, got, tt.want)
+		}
+	}
+}
+
+func TestUpdateAndNotifyDryRunSendsNothing(t *testing.T) {
+	bot, fake := newFakeTelegram(t)
+	botState := newTestBotState(t)
+	botState.AddUser(&tgbotapi.User{FirstName: "Test"}, 1)
+	botState.ApplyOffers([]state.RentalOffer{testOffer("https://example.com/a", "900 €/kk")})
+	botState.MarkOfferAsSeen(1, "https://example.com/a")
+	offers := numberedOffers(3)
+	offers[0].Link = "https://example.com/a"
+	offers[0].Price, offers[0].PriceEUR = "850 €/kk", 850
+	stubFetch(t, offers)
+
+	if err := updateAndNotify(bot, botState, BotConfig{DryRun: true, NotifyDelisted: true}); err != nil {
+		t.Fatalf("updateAndNotify: %v", err)
+	}
+
+	fake.mutex.Lock()
+	for _, r := range fake.requests {
+		if strings.HasPrefix(r.method, "send") {
+			t.Errorf("dry run called %s", r.method)
+		}
+	}
+	fake.mutex.Unlock()
+	if known := botState.GetKnownOffers(); len(known) != 1 || known["https://example.com/a"].Price != "900 €/kk" {
+		t.Errorf("known offers = %v, want the state unchanged", known)
+	}
+	if user, _ := botState.GetUser(1); len(user.SeenOffers) != 1 {
+		t.Errorf("seen offers = %v, want them unchanged", user.SeenOffers)
+	}
+
+	// The same update without dry run does notify
+	if err := updateAndNotify(bot, botState, BotConfig{}); err != nil {
+		t.Fatalf("updateAndNotify: %v", err)
+	}
+	if sent := fake.calls("sendMessage"); len(sent) == 0 {
+		t.Error("the update without dry run sent nothing")
+	}
+}
+
+func TestJitteredIntervalStaysWithinBounds(t *testing.T) {
+	interval := 30 * time.Minute
+	if got := jitteredInterval(interval, 0, rand.Float64); got != interval {
+		t.Errorf("without jitter got %v, want %v", got, interval)
+	}
+
+	for _, tt := range []struct {
+		random float64
+		want   time.Duration
+	}{
+		{0, 27 * time.Minute},
+		{0.5, 30 * time.Minute},
+		{0.75, 31*time.Minute + 30*time.Second},
+	} {
+		if got := jitteredInterval(interval, 0.1, func() float64 { return tt.random }); got != tt.want {
+			t.Errorf("random %v: got %v, want %v", tt.random, got, tt.want)
+		}
+	}
+
+	for i := 0; i < 1000; i++ {
+		got := jitteredInterval(interval, 0.1, rand.Float64)
+		if got < 27*time.Minute || got > 33*time.Minute {
+			t.Fatalf("interval %v is outside 30m ± 10%%", got)
+		}
+	}
+}
+
+func TestConcurrentNotificationsSendEachOfferOnce(t *testing.T) {
+	bot, fake := newFakeTelegram(t)
+	botState := newTestBotState(t)
+	botState.AddUser(&tgbotapi.User{FirstName: "Test"}, 1)
+	a := testOffer("https://example.com/a", "900 €/kk")
+	b := testOffer("https://example.com/b", "900 €/kk")
+	b.Address = "Testikatu 2, Helsinki"
+	c := testOffer("https://example.com/c", "900 €/kk")
+	c.Address = "Testikatu 3, Helsinki"
+
+	// A scheduled update and a /search right after it, before either delivery is saved
+	var wg sync.WaitGroup
+	for _, offers := range [][]state.RentalOffer{{a, b}, {b, c}} {
+		wg.Add(1)
+		go func(offers []state.RentalOffer) {
+			defer wg.Done()
+			notifyUsers(bot, botState, offers)
+		}(offers)
+	}
+	wg.Wait()
+
+	var text string
+	for _, values := range fake.calls("sendMessage") {
+		text += values.Get("text")
+	}
+	for _, offer := range []state.RentalOffer{a, b, c} {
+		if n := strings.Count(text, offer.Link); n != 1 {
+			t.Errorf("%s was sent %d times, want once", offer.Link, n)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"log"
+
+	"github.com/aqaliarept/vuokraovi-bot/state"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// conversationHandler handles a free text reply of a user in a conversation. It
+// advances the conversation with botState.SetUserConversation or ends it with
+// endConversation.
+type conversationHandler func(bot *tgbotapi.BotAPI, botState *state.BotState, message *tgbotapi.Message, conversation state.Conversation)
+
+// conversationHandlers are the handlers of the multi-step commands by conversation
+// name. Every conversation needs a "<name>_cancelled" message for /cancel.
+var conversationHandlers = map[string]conversationHandler{
+	onboardingConversation: handleOnboardingReply,
+}
+
+// startConversation makes the next free text message of a user a reply to the
+// named conversation, beginning at the given step
+func startConversation(botState *state.BotState, chatID int64, name, step string) {
+	botState.SetUserConversation(chatID, &state.Conversation{Name: name, Step: step})
+}
+
+// endConversation ends the conversation of a user, their free text is no longer
+// taken as a reply
+func endConversation(botState *state.BotState, chatID int64) {
+	botState.SetUserConversation(chatID, nil)
+}
+
+// handleConversationReply routes a free text message to the conversation the user
+// is in. It reports whether the message was handled as a reply.
+func handleConversationReply(bot *tgbotapi.BotAPI, botState *state.BotState, message *tgbotapi.Message) bool {
+	if message.IsCommand() {
+		return false
+	}
+	user, exists := botState.GetUser(message.Chat.ID)
+	if !exists || user.Conversation == nil {
+		return false
+	}
+
+	handler, ok := conversationHandlers[user.Conversation.Name]
+	if !ok {
+		// The conversation was started by a version of the bot that knew it
+		log.Printf("Ending unknown conversation %q of chat %d", user.Conversation.Name, message.Chat.ID)
+		endConversation(botState, message.Chat.ID)
+		return false
+	}
+	handler(bot, botState, message, *user.Conversation)
+	return true
+}
+
+// handleCancelCommand handles the /cancel command, ending the conversation the user
+// is in without applying its answers
+func handleCancelCommand(bot *tgbotapi.BotAPI, botState *state.BotState, message *tgbotapi.Message) {
+	chatID := message.Chat.ID
+	lang := userLanguage(botState, chatID)
+
+	text := translate(lang, "cancel_nothing")
+	if user, exists := botState.GetUser(chatID); exists && user.Conversation != nil {
+		text = translate(lang, user.Conversation.Name+"_cancelled")
+		endConversation(botState, chatID)
+	}
+
+	msg := tgbotapi.NewMessage(chatID, text)
+	msg.ReplyMarkup = createMainKeyboard(lang)
+	sendMessage(bot, msg)
+}
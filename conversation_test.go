@@ -0,0 +1,74 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/aqaliarept/vuokraovi-bot/state"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// registerEchoConversation registers a conversation of two steps that collects the
+// replies of the user for the duration of the test
+func registerEchoConversation(t *testing.T) *[]string {
+	t.Helper()
+	var replies []string
+	conversationHandlers["echo"] = func(bot *tgbotapi.BotAPI, botState *state.BotState, message *tgbotapi.Message, conversation state.Conversation) {
+		replies = append(replies, conversation.Step+":"+message.Text)
+		if conversation.Step == "first" {
+			conversation.Step = "second"
+			botState.SetUserConversation(message.Chat.ID, &conversation)
+			return
+		}
+		endConversation(botState, message.Chat.ID)
+	}
+	t.Cleanup(func() { delete(conversationHandlers, "echo") })
+	return &replies
+}
+
+func TestConversationRoutesRepliesUntilItEnds(t *testing.T) {
+	bot, fake := newFakeTelegram(t)
+	botState := newTestBotState(t)
+	botState.AddUser(&tgbotapi.User{FirstName: "Test"}, 1)
+	replies := registerEchoConversation(t)
+
+	startConversation(botState, 1, "echo", "first")
+	handleMessage(bot, botState, userMessage(1, "one"), BotConfig{})
+	if user, _ := botState.GetUser(1); user.Conversation == nil || user.Conversation.Step != "second" {
+		t.Fatalf("conversation after the first reply = %+v, want step second", user.Conversation)
+	}
+
+	// Commands are not replies
+	handleMessage(bot, botState, userMessage(1, "/help"), BotConfig{})
+	handleMessage(bot, botState, userMessage(1, "two"), BotConfig{})
+	if user, _ := botState.GetUser(1); user.Conversation != nil {
+		t.Fatalf("conversation not ended after the last reply: %+v", user.Conversation)
+	}
+
+	fake.reset()
+	handleMessage(bot, botState, userMessage(1, "three"), BotConfig{})
+	want := []string{"first:one", "second:two"}
+	if len(*replies) != len(want) || (*replies)[0] != want[0] || (*replies)[1] != want[1] {
+		t.Errorf("replies = %v, want %v", *replies, want)
+	}
+	sent := fake.calls("sendMessage")
+	if len(sent) != 1 || sent[0]["text"][0] != translate("en", "use_buttons") {
+		t.Errorf("text outside a conversation got %v, want the default reply", sent)
+	}
+}
+
+func TestUnknownConversationIsEnded(t *testing.T) {
+	bot, fake := newFakeTelegram(t)
+	botState := newTestBotState(t)
+	botState.AddUser(&tgbotapi.User{FirstName: "Test"}, 1)
+
+	startConversation(botState, 1, "removed", "first")
+	handleMessage(bot, botState, userMessage(1, "hello"), BotConfig{})
+
+	if user, _ := botState.GetUser(1); user.Conversation != nil {
+		t.Errorf("unknown conversation kept: %+v", user.Conversation)
+	}
+	sent := fake.calls("sendMessage")
+	if len(sent) != 1 || sent[0]["text"][0] != translate("en", "use_buttons") {
+		t.Errorf("replied %v, want the default reply", sent)
+	}
+}
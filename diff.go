@@ -0,0 +1,82 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aqaliarept/vuokraovi-bot/state"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// maxDiffLines is the number of offers listed per change category of /diff
+const maxDiffLines = 15
+
+// diffOfferLine describes an offer in the /diff report
+func diffOfferLine(offer state.RentalOffer) string {
+	if offer.Title == "" {
+		return fmt.Sprintf("• %s, %s", offer.Link, offer.Price)
+	}
+	return fmt.Sprintf("• %s, %s\n  %s", offer.Title, offer.Price, offer.Link)
+}
+
+// diffSection lists the lines of a change category under its heading, at most
+// maxDiffLines of them
+func diffSection(lang, heading string, lines []string) string {
+	if len(lines) == 0 {
+		return ""
+	}
+	text := "\n\n" + translate(lang, heading, len(lines))
+	for i, line := range lines {
+		if i == maxDiffLines {
+			text += "\n" + translate(lang, "diff_more", len(lines)-maxDiffLines)
+			break
+		}
+		text += "\n" + line
+	}
+	return text
+}
+
+// formatSnapshotDiff formats the changes of the known offers as plain text
+func formatSnapshotDiff(diff state.SnapshotDiff, since time.Time, lang string) string {
+	when := since.In(pauseLocation()).Format("2006-01-02 15:04")
+	if diff.Empty() {
+		return translate(lang, "diff_none", when)
+	}
+
+	added := make([]string, len(diff.Added))
+	for i, offer := range diff.Added {
+		added[i] = diffOfferLine(offer)
+	}
+	removed := make([]string, len(diff.Removed))
+	for i, offer := range diff.Removed {
+		removed[i] = diffOfferLine(offer)
+	}
+	changed := make([]string, len(diff.PriceChanges))
+	for i, change := range diff.PriceChanges {
+		changed[i] = fmt.Sprintf("• %s: %s → %s\n  %s", change.Offer.Title, change.OldPrice, change.NewPrice, change.Offer.Link)
+	}
+
+	return translate(lang, "diff_header", when) +
+		diffSection(lang, "diff_added", added) +
+		diffSection(lang, "diff_removed", removed) +
+		diffSection(lang, "diff_price_changed", changed)
+}
+
+// handleDiffCommand handles the /diff command, reporting how the known offers
+// changed since the user's last /diff or notification
+func handleDiffCommand(bot *tgbotapi.BotAPI, botState *state.BotState, message *tgbotapi.Message) {
+	chatID := message.Chat.ID
+	lang := userLanguage(botState, chatID)
+
+	diff, since := botState.DiffSnapshot(chatID, time.Now())
+	text := translate(lang, "diff_first", len(botState.GetKnownOffers()))
+	if !since.IsZero() {
+		text = formatSnapshotDiff(diff, since, lang)
+	}
+
+	msg := tgbotapi.NewMessage(chatID, strings.TrimSpace(text))
+	msg.DisableWebPagePreview = true
+	msg.ReplyMarkup = createMainKeyboard(lang)
+	sendMessage(bot, msg)
+}
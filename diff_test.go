@@ -0,0 +1,54 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/aqaliarept/vuokraovi-bot/state"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+func TestDiffCommandReportsChangesSinceLastDiff(t *testing.T) {
+	bot, fake := newFakeTelegram(t)
+	botState := newTestBotState(t)
+	botState.SetDelistAfter(1)
+	botState.AddUser(&tgbotapi.User{FirstName: "Test"}, 1)
+
+	offer := func(link, address, price string) state.RentalOffer {
+		o := testOffer("https://example.com/"+link, price)
+		o.Address = address
+		return o
+	}
+	cheaper := offer("cheaper", "Katu 1, Helsinki", "1000 €/kk")
+	gone := offer("gone", "Katu 2, Helsinki", "800 €/kk")
+	botState.ApplyOffers([]state.RentalOffer{cheaper, gone})
+
+	handleMessage(bot, botState, userMessage(1, "/diff"), BotConfig{})
+	sent := fake.calls("sendMessage")
+	if len(sent) != 1 || sent[0].Get("text") != translate("en", "diff_first", 2) {
+		t.Fatalf("first /diff replied %v, want the snapshot message", sent)
+	}
+
+	cheaper.Price = "950 €/kk"
+	added := offer("added", "Katu 3, Helsinki", "700 €/kk")
+	botState.ApplyOffers([]state.RentalOffer{cheaper, added})
+
+	fake.reset()
+	handleMessage(bot, botState, userMessage(1, "/diff"), BotConfig{})
+	text := fake.calls("sendMessage")[0].Get("text")
+	for _, want := range []string{
+		translate("en", "diff_added", 1), added.Link,
+		translate("en", "diff_removed", 1), gone.Link,
+		translate("en", "diff_price_changed", 1), "1000 €/kk → 950 €/kk",
+	} {
+		if !strings.Contains(text, want) {
+			t.Errorf("diff lacks %q:\n%s", want, text)
+		}
+	}
+
+	fake.reset()
+	handleMessage(bot, botState, userMessage(1, "/diff"), BotConfig{})
+	if text := fake.calls("sendMessage")[0].Get("text"); !strings.HasPrefix(text, "No changes since") {
+		t.Errorf("diff without changes = %q", text)
+	}
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aqaliarept/vuokraovi-bot/state"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// maxDigestOffers is the maximum number of offers listed in one digest message
+const maxDigestOffers = 20
+
+// sendDigest sends the user's pending offers as one summary, split into several
+// messages only when it is too long for one
+func sendDigest(bot *tgbotapi.BotAPI, botState *state.BotState, chatID int64, now time.Time) {
+	offers := botState.TakePendingOffers(chatID)
+	botState.MarkDigestSent(chatID, now)
+	if len(offers) == 0 {
+		return
+	}
+
+	lang := userLanguage(botState, chatID)
+	profiles := botState.GetProfiles(chatID)
+	lines := []string{escapeMarkdownV2Bold(translate(lang, "digest_header", len(offers)))}
+	for i, offer := range offers {
+		if i >= maxDigestOffers {
+			lines = append(lines, escapeMarkdownV2(moreOffersNote(lang, len(offers)-maxDigestOffers)))
+			break
+		}
+		line := fmt.Sprintf("• [%s](%s) \\- %s, %s, %s", escapeMarkdownV2(offer.Title), escapeMarkdownV2URL(offer.Link),
+			escapeMarkdownV2(offer.Price), escapeMarkdownV2(offer.Rooms), escapeMarkdownV2(offer.Size))
+		if tags := profileTags(profiles, offer); tags != "" {
+			line += " " + escapeMarkdownV2(tags)
+		}
+		lines = append(lines, line+"\n")
+	}
+
+	// Long digests are sent as several messages within Telegram's length limit
+	for _, text := range splitMessages(lines) {
+		msg := tgbotapi.NewMessage(chatID, text)
+		msg.ParseMode = "MarkdownV2"
+		msg.DisableWebPagePreview = true
+
+		if _, err := sendMessage(bot, msg); err != nil {
+			// Keep the offers for the next digest
+			handleNotificationError(botState, chatID, offers, err)
+			return
+		}
+	}
+
+	notificationsSentTotal.Inc()
+	links := make([]string, len(offers))
+	for i, offer := range offers {
+		links[i] = offer.Link
+	}
+	botState.RecordDelivery(chatID, links, now)
+}
+
+// parseDigestArgs parses /digest arguments like "hourly" or "daily 09:00 Europe/Helsinki"
+func parseDigestArgs(args string, now time.Time) (state.DigestSchedule, error) {
+	fields := strings.Fields(args)
+	if len(fields) == 0 {
+		return state.DigestSchedule{}, fmt.Errorf("missing digest mode")
+	}
+
+	switch strings.ToLower(fields[0]) {
+	case "off":
+		return state.DigestSchedule{}, nil
+	case state.DigestHourly:
+		if len(fields) > 1 {
+			return state.DigestSchedule{}, fmt.Errorf("hourly digest takes no arguments")
+		}
+		return state.NewHourlyDigest(now), nil
+	case state.DigestDaily:
+		if len(fields) < 2 || len(fields) > 3 {
+			return state.DigestSchedule{}, fmt.Errorf("daily digest needs a time like 09:00 and an optional timezone")
+		}
+		parts := strings.SplitN(fields[1], ":", 2)
+		if len(parts) != 2 {
+			return state.DigestSchedule{}, fmt.Errorf("invalid time %q, expected HH:MM", fields[1])
+		}
+		hour, err := strconv.Atoi(parts[0])
+		if err != nil {
+			return state.DigestSchedule{}, fmt.Errorf("invalid time %q, expected HH:MM", fields[1])
+		}
+		minute, err := strconv.Atoi(parts[1])
+		if err != nil {
+			return state.DigestSchedule{}, fmt.Errorf("invalid time %q, expected HH:MM", fields[1])
+		}
+		timezone := defaultQuietTimezone
+		if len(fields) == 3 {
+			timezone = fields[2]
+		}
+		return state.NewDailyDigest(hour, minute, timezone, now)
+	default:
+		return state.DigestSchedule{}, fmt.Errorf("unknown digest mode %q", fields[0])
+	}
+}
+
+// handleDigestCommand handles the /digest command
+func handleDigestCommand(bot *tgbotapi.BotAPI, botState *state.BotState, message *tgbotapi.Message) {
+	chatID := message.Chat.ID
+	lang := userLanguage(botState, chatID)
+	args := strings.TrimSpace(message.CommandArguments())
+	usage := translate(lang, "digest_usage")
+
+	current, exists := botState.GetUserDigest(chatID)
+	if !exists {
+		msg := tgbotapi.NewMessage(chatID, translate(lang, "start_first"))
+		msg.ReplyMarkup = createMainKeyboard(lang)
+		sendMessage(bot, msg)
+		return
+	}
+
+	var text string
+	if args == "" {
+		text = describeDigest(current, lang) + "\n\n" + usage
+	} else if digest, err := parseDigestArgs(args, time.Now()); err != nil {
+		text = fmt.Sprintf("❌ %v\n\n%s", err, usage)
+	} else {
+		botState.SetUserDigest(chatID, digest)
+		text = "✅ " + describeDigest(digest, lang)
+	}
+
+	msg := tgbotapi.NewMessage(chatID, text)
+	msg.ReplyMarkup = createMainKeyboard(lang)
+	sendMessage(bot, msg)
+}
+
+// describeDigest describes a digest schedule in a human readable form
+func describeDigest(digest state.DigestSchedule, lang string) string {
+	switch digest.Mode {
+	case state.DigestHourly:
+		return translate(lang, "digest_hourly")
+	case state.DigestDaily:
+		return translate(lang, "digest_daily", digest.Hour, digest.Minute, digest.Timezone)
+	default:
+		return translate(lang, "digest_off")
+	}
+}
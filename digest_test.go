@@ -0,0 +1,57 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aqaliarept/vuokraovi-bot/state"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+func TestParseDigestArgs(t *testing.T) {
+	now := time.Date(2024, 5, 10, 12, 0, 0, 0, time.UTC)
+
+	if digest, err := parseDigestArgs("hourly", now); err != nil || digest.Mode != state.DigestHourly || !digest.LastSent.Equal(now) {
+		t.Errorf("hourly = %+v, %v", digest, err)
+	}
+	if digest, err := parseDigestArgs("daily 09:30 UTC", now); err != nil || digest.Hour != 9 || digest.Minute != 30 || digest.Timezone != "UTC" {
+		t.Errorf("daily = %+v, %v", digest, err)
+	}
+	if digest, err := parseDigestArgs("off", now); err != nil || digest.Enabled() {
+		t.Errorf("off = %+v, %v", digest, err)
+	}
+	for _, args := range []string{"", "weekly", "hourly 09:00", "daily", "daily 9", "daily 25:00", "daily 09:00 Nowhere/City"} {
+		if _, err := parseDigestArgs(args, now); err == nil {
+			t.Errorf("parseDigestArgs(%q) accepted bad input", args)
+		}
+	}
+}
+
+func TestDigestCollectsOffersUntilDue(t *testing.T) {
+	bot, fake := newFakeTelegram(t)
+	botState := newTestBotState(t)
+	botState.AddUser(&tgbotapi.User{FirstName: "Test"}, 1)
+	botState.SetUserDigest(1, state.NewHourlyDigest(time.Now()))
+
+	notifyUsers(bot, botState, botState.UpdateOffers([]state.RentalOffer{testOffer("https://example.com/a", "800 €/kk")}))
+	notifyUsers(bot, botState, botState.UpdateOffers([]state.RentalOffer{testOffer("https://example.com/a", "800 €/kk"), testOffer("https://example.com/b", "900 €/kk")}))
+	if sent := fake.calls("sendMessage"); len(sent) != 0 {
+		t.Fatalf("sent %d messages before the digest was due, want none", len(sent))
+	}
+
+	// An hour later the next update flushes both offers in one message
+	botState.SetUserDigest(1, state.NewHourlyDigest(time.Now().Add(-time.Hour)))
+	notifyUsers(bot, botState, nil)
+
+	sent := fake.calls("sendMessage")
+	if len(sent) != 1 || !strings.Contains(sent[0].Get("text"), "2 new rental offers") {
+		t.Fatalf("sent %v, want one digest with both offers", sent)
+	}
+	if digest, _ := botState.GetUserDigest(1); time.Since(digest.LastSent) > time.Minute {
+		t.Errorf("LastSent = %v, want the time of the digest", digest.LastSent)
+	}
+	if user, _ := botState.GetUser(1); len(user.SeenOffers) != 2 || len(user.PendingOffers) != 0 {
+		t.Errorf("after the digest: seen %v, pending %d, want both seen and none pending", user.SeenOffers, len(user.PendingOffers))
+	}
+}
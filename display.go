@@ -0,0 +1,133 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aqaliarept/vuokraovi-bot/state"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// offerFields are the offer fields /show can select, in the order of the full layout
+var offerFields = []string{"title", "address", "price", "rooms", "size", "available", "listed", "id", "link"}
+
+// optionalOfferFields can be selected with /show but are left out of the full layout
+var optionalOfferFields = []string{"ppsqm"}
+
+// selectableFields returns every field /show accepts
+func selectableFields() []string {
+	return append(append([]string{}, offerFields...), optionalOfferFields...)
+}
+
+// parseDisplayFields parses the comma separated field list of /show. "all" selects
+// every field and returns nil.
+func parseDisplayFields(args string) ([]string, error) {
+	args = strings.TrimSpace(args)
+	if strings.EqualFold(args, "all") {
+		return nil, nil
+	}
+
+	var fields []string
+	seen := make(map[string]bool)
+	for _, name := range strings.Split(args, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" || seen[name] {
+			continue
+		}
+		if !isOfferField(name) {
+			return nil, fmt.Errorf("unknown field %q, the fields are %s", name, strings.Join(selectableFields(), ", "))
+		}
+		seen[name] = true
+		fields = append(fields, name)
+	}
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("no fields given")
+	}
+	return fields, nil
+}
+
+// isOfferField reports whether a name is one of the selectableFields
+func isOfferField(name string) bool {
+	for _, field := range selectableFields() {
+		if field == name {
+			return true
+		}
+	}
+	return false
+}
+
+// renderOfferFields formats the given fields of an offer as MarkdownV2, in the order
+// they are listed, followed by the profile tags when there are any. Optional fields
+// the offer does not have are left out.
+func renderOfferFields(offer state.RentalOffer, fields []string, tags, lang string) string {
+	var message string
+	for _, field := range fields {
+		switch field {
+		case "title":
+			message += fmt.Sprintf("*%s*\n", escapeMarkdownV2(offer.Title))
+		case "address":
+			message += fmt.Sprintf("📍 %s\n", escapeMarkdownV2(offer.Address))
+		case "price":
+			message += fmt.Sprintf("💰 %s\n", escapeMarkdownV2(offer.Price))
+		case "rooms":
+			message += fmt.Sprintf("🛏 %s\n", escapeMarkdownV2(offer.Rooms))
+		case "size":
+			message += fmt.Sprintf("📐 %s\n", escapeMarkdownV2(offer.Size))
+		case "ppsqm":
+			if perSqm := offer.PricePerSqm(); perSqm > 0 {
+				message += fmt.Sprintf("📊 %s\n", escapeMarkdownV2(fmt.Sprintf("%.1f €/m²", perSqm)))
+			}
+		case "available":
+			if offer.Available != "" {
+				message += fmt.Sprintf("📅 %s\n", escapeMarkdownV2(offer.Available))
+			}
+		case "listed":
+			if !offer.FirstSeen.IsZero() {
+				message += fmt.Sprintf("🕒 %s\n", escapeMarkdownV2(listedAgo(offer.FirstSeen, time.Now(), lang)))
+			}
+		case "id":
+			message += fmt.Sprintf("🆔 `%s`\n", escapeMarkdownV2Code(state.OfferID(offer.Link)))
+		case "link":
+			message += fmt.Sprintf("🔗 [%s](%s)\n", escapeMarkdownV2(translate(lang, "view_details")), escapeMarkdownV2URL(offer.Link))
+		}
+	}
+	if tags != "" {
+		message += escapeMarkdownV2(tags) + "\n"
+	}
+	return message + "\n"
+}
+
+// displayFields returns the offer fields shown to a user, all of them by default
+func displayFields(botState *state.BotState, chatID int64) []string {
+	if user, exists := botState.GetUser(chatID); exists && len(user.DisplayFields) > 0 {
+		return user.DisplayFields
+	}
+	return offerFields
+}
+
+// handleShowCommand handles the /show command, choosing the offer fields of the
+// user's notifications and /list
+func handleShowCommand(bot *tgbotapi.BotAPI, botState *state.BotState, message *tgbotapi.Message) {
+	chatID := message.Chat.ID
+	lang := userLanguage(botState, chatID)
+	usage := translate(lang, "show_usage", strings.Join(selectableFields(), ","))
+
+	var text string
+	args := message.CommandArguments()
+	if strings.TrimSpace(args) == "" {
+		text = translate(lang, "show_current", strings.Join(displayFields(botState, chatID), ",")) + "\n\n" + usage
+	} else if fields, err := parseDisplayFields(args); err != nil {
+		text = fmt.Sprintf("❌ %v\n\n%s", err, usage)
+	} else {
+		botState.SetUserDisplayFields(chatID, fields)
+		text = translate(lang, "show_reset")
+		if fields != nil {
+			text = translate(lang, "show_set", strings.Join(fields, ","))
+		}
+	}
+
+	msg := tgbotapi.NewMessage(chatID, text)
+	msg.ReplyMarkup = createMainKeyboard(lang)
+	sendMessage(bot, msg)
+}
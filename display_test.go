@@ -0,0 +1,92 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/aqaliarept/vuokraovi-bot/state"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+func TestRenderOfferFieldsWithRestrictedFields(t *testing.T) {
+	offer := testOffer("https://example.com/a", "900 €/kk")
+	offer.Rooms = "2h+k"
+	offer.Size = "45 m²"
+
+	got := renderOfferFields(offer, []string{"price", "rooms", "link"}, "", "en")
+	want := "💰 900 €/kk\n🛏 2h\\+k\n🔗 [View Details](https://example.com/a)\n\n"
+	if got != want {
+		t.Errorf("renderOfferFields() = %q, want %q", got, want)
+	}
+}
+
+func TestRenderOfferFieldsShowsPricePerSqm(t *testing.T) {
+	offer := testOffer("https://example.com/a", "900 €/kk")
+	offer.PriceEUR, offer.SizeSqm = 900, 40
+
+	if got, want := renderOfferFields(offer, []string{"ppsqm"}, "", "en"), "📊 22\\.5 €/m²\n\n"; got != want {
+		t.Errorf("renderOfferFields() = %q, want %q", got, want)
+	}
+	if strings.Contains(formatOfferDetails(offer, "en"), "📊") {
+		t.Error("the full layout shows the price per m²")
+	}
+	offer.SizeSqm = 0
+	if got := renderOfferFields(offer, []string{"ppsqm"}, "", "en"); got != "\n" {
+		t.Errorf("offer without a size rendered %q", got)
+	}
+}
+
+func TestFormatOfferDetailsShowsAllFields(t *testing.T) {
+	offer := testOffer("https://example.com/a", "900 €/kk")
+	got := formatOfferDetails(offer, "en")
+	for _, part := range []string{"*Testikatu 1*", "📍", "💰", "🛏", "📐", "🆔", "🔗"} {
+		if !strings.Contains(got, part) {
+			t.Errorf("details lack %q:\n%s", part, got)
+		}
+	}
+}
+
+func TestParseDisplayFields(t *testing.T) {
+	fields, err := parseDisplayFields(" Price, rooms,price,link ")
+	if err != nil || !reflect.DeepEqual(fields, []string{"price", "rooms", "link"}) {
+		t.Errorf("parseDisplayFields() = %v, %v", fields, err)
+	}
+	if fields, err := parseDisplayFields("all"); err != nil || fields != nil {
+		t.Errorf("parseDisplayFields(all) = %v, %v, want nil", fields, err)
+	}
+	_, err = parseDisplayFields("price,colour")
+	if err == nil || !strings.Contains(err.Error(), `"colour"`) || !strings.Contains(err.Error(), "rooms") {
+		t.Errorf("unknown field error = %v, want it to name the field and the known fields", err)
+	}
+	if _, err := parseDisplayFields(","); err == nil {
+		t.Error("an empty field list was accepted")
+	}
+}
+
+func TestShowCommandRestrictsNotifications(t *testing.T) {
+	bot, fake := newFakeTelegram(t)
+	botState := newTestBotState(t)
+	botState.AddUser(&tgbotapi.User{FirstName: "Test"}, 1)
+
+	handleMessage(bot, botState, userMessage(1, "/show price,link"), BotConfig{})
+	if user, _ := botState.GetUser(1); !reflect.DeepEqual(user.DisplayFields, []string{"price", "link"}) {
+		t.Fatalf("display fields = %v", user.DisplayFields)
+	}
+
+	fake.reset()
+	notifyUsers(bot, botState, []state.RentalOffer{testOffer("https://example.com/a", "900 €/kk")})
+	sent := fake.calls("sendMessage")
+	if len(sent) != 1 {
+		t.Fatalf("sent %d messages, want 1", len(sent))
+	}
+	text := sent[0]["text"][0]
+	if !strings.Contains(text, "💰 900") || strings.Contains(text, "📍") {
+		t.Errorf("notification does not follow the display fields:\n%s", text)
+	}
+
+	handleMessage(bot, botState, userMessage(1, "/show all"), BotConfig{})
+	if user, _ := botState.GetUser(1); user.DisplayFields != nil {
+		t.Errorf("display fields after /show all = %v, want none", user.DisplayFields)
+	}
+}
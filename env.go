@@ -0,0 +1,51 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+)
+
+// envPrefix prefixes the environment variables standing in for the flags
+const envPrefix = "VUOKRAOVI_"
+
+// envNameOverrides names the variables of the flags whose own name is unclear alone
+var envNameOverrides = map[string]string{
+	"data": "DATA_DIR",
+	"form": "FORM_FILE",
+}
+
+// flagEnvName returns the environment variable of a flag, e.g. VUOKRAOVI_PAGE_CAP
+// for -page-cap
+func flagEnvName(name string) string {
+	if override, ok := envNameOverrides[name]; ok {
+		return envPrefix + override
+	}
+	return envPrefix + strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
+}
+
+// applyEnvFlags sets the flags not given on the command line from their environment
+// variables, so an explicit flag wins over the environment, which wins over the
+// default. lookup is os.LookupEnv outside of tests.
+func applyEnvFlags(flags *flag.FlagSet, lookup func(string) (string, bool)) error {
+	explicit := make(map[string]bool)
+	flags.Visit(func(f *flag.Flag) {
+		explicit[f.Name] = true
+	})
+
+	var err error
+	flags.VisitAll(func(f *flag.Flag) {
+		if err != nil || explicit[f.Name] {
+			return
+		}
+		name := flagEnvName(f.Name)
+		value, ok := lookup(name)
+		if !ok {
+			return
+		}
+		if setErr := flags.Set(f.Name, value); setErr != nil {
+			err = fmt.Errorf("invalid value %q for %s: %w", value, name, setErr)
+		}
+	})
+	return err
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"flag"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testFlags creates a flag set with flags of each kind, parsed from args
+func testFlags(t *testing.T, args ...string) (*flag.FlagSet, *int, *string, *bool, *time.Duration) {
+	t.Helper()
+	flags := flag.NewFlagSet("test", flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	interval := flags.Int("interval", 30, "")
+	dataDir := flags.String("data", "./data", "")
+	dryRun := flags.Bool("dry-run", false, "")
+	pageCacheTTL := flags.Duration("page-cache-ttl", 0, "")
+	if err := flags.Parse(args); err != nil {
+		t.Fatal(err)
+	}
+	return flags, interval, dataDir, dryRun, pageCacheTTL
+}
+
+// envLookup looks up variables in a map instead of the environment
+func envLookup(env map[string]string) func(string) (string, bool) {
+	return func(name string) (string, bool) {
+		value, ok := env[name]
+		return value, ok
+	}
+}
+
+func TestFlagEnvName(t *testing.T) {
+	for name, want := range map[string]string{
+		"interval":       "VUOKRAOVI_INTERVAL",
+		"page-cache-ttl": "VUOKRAOVI_PAGE_CACHE_TTL",
+		"data":           "VUOKRAOVI_DATA_DIR",
+		"form":           "VUOKRAOVI_FORM_FILE",
+	} {
+		if got := flagEnvName(name); got != want {
+			t.Errorf("flagEnvName(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestApplyEnvFlagsPrecedence(t *testing.T) {
+	env := envLookup(map[string]string{
+		"VUOKRAOVI_INTERVAL":       "15",
+		"VUOKRAOVI_DATA_DIR":       "/env/data",
+		"VUOKRAOVI_DRY_RUN":        "true",
+		"VUOKRAOVI_PAGE_CACHE_TTL": "2m",
+	})
+	// An explicit flag wins even when it repeats the default
+	flags, interval, dataDir, dryRun, pageCacheTTL := testFlags(t, "-interval", "5", "-data", "./data")
+
+	if err := applyEnvFlags(flags, env); err != nil {
+		t.Fatal(err)
+	}
+	if *interval != 5 || *dataDir != "./data" {
+		t.Errorf("explicit flags = %d, %q, want 5, ./data", *interval, *dataDir)
+	}
+	if !*dryRun || *pageCacheTTL != 2*time.Minute {
+		t.Errorf("environment flags = %v, %v, want true, 2m", *dryRun, *pageCacheTTL)
+	}
+
+	// Without flags or variables the defaults stay
+	flags, interval, dataDir, _, _ = testFlags(t)
+	if err := applyEnvFlags(flags, envLookup(nil)); err != nil {
+		t.Fatal(err)
+	}
+	if *interval != 30 || *dataDir != "./data" {
+		t.Errorf("defaults = %d, %q, want 30, ./data", *interval, *dataDir)
+	}
+}
+
+func TestApplyEnvFlagsRejectsInvalidValues(t *testing.T) {
+	flags, _, _, _, _ := testFlags(t)
+	err := applyEnvFlags(flags, envLookup(map[string]string{"VUOKRAOVI_INTERVAL": "often"}))
+	if err == nil || !strings.Contains(err.Error(), "VUOKRAOVI_INTERVAL") {
+		t.Errorf("error = %v, want it to name the variable", err)
+	}
+}
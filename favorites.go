@@ -0,0 +1,89 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/aqaliarept/vuokraovi-bot/state"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// handleFavCommand handles the /fav command
+func handleFavCommand(bot *tgbotapi.BotAPI, botState *state.BotState, message *tgbotapi.Message) {
+	chatID := message.Chat.ID
+	lang := userLanguage(botState, chatID)
+	offerID := strings.TrimSpace(message.CommandArguments())
+
+	var text string
+	if offerID == "" {
+		text = translate(lang, "fav_usage")
+	} else if offer, ok := botState.AddFavorite(chatID, offerID); ok {
+		text = translate(lang, "fav_added", offer.Title)
+	} else {
+		text = translate(lang, "offer_not_found", offerID)
+	}
+
+	msg := tgbotapi.NewMessage(chatID, text)
+	msg.ReplyMarkup = createMainKeyboard(lang)
+	sendMessage(bot, msg)
+}
+
+// handleUnfavCommand handles the /unfav command
+func handleUnfavCommand(bot *tgbotapi.BotAPI, botState *state.BotState, message *tgbotapi.Message) {
+	chatID := message.Chat.ID
+	lang := userLanguage(botState, chatID)
+	offerID := strings.TrimSpace(message.CommandArguments())
+
+	var text string
+	if offerID == "" {
+		text = translate(lang, "unfav_usage")
+	} else if botState.RemoveFavorite(chatID, offerID) {
+		text = translate(lang, "fav_removed", offerID)
+	} else {
+		text = translate(lang, "fav_missing", offerID)
+	}
+
+	msg := tgbotapi.NewMessage(chatID, text)
+	msg.ReplyMarkup = createMainKeyboard(lang)
+	sendMessage(bot, msg)
+}
+
+// handleFavoritesCommand handles the /favorites command
+func handleFavoritesCommand(bot *tgbotapi.BotAPI, botState *state.BotState, message *tgbotapi.Message) {
+	chatID := message.Chat.ID
+	lang := userLanguage(botState, chatID)
+
+	favorites := botState.GetFavorites(chatID)
+	if len(favorites) == 0 {
+		msg := tgbotapi.NewMessage(chatID, translate(lang, "favorites_empty"))
+		msg.ReplyMarkup = createMainKeyboard(lang)
+		sendMessage(bot, msg)
+		return
+	}
+
+	sendMessage(bot, tgbotapi.NewMessage(chatID, translate(lang, "favorites_list", len(favorites))))
+	sendOffersList(bot, favorites, chatID, lang)
+}
+
+// handleShareCommand handles the /share command, sending the links of the user's
+// favorites as plain text to copy and paste
+func handleShareCommand(bot *tgbotapi.BotAPI, botState *state.BotState, message *tgbotapi.Message) {
+	chatID := message.Chat.ID
+	lang := userLanguage(botState, chatID)
+
+	favorites := botState.GetFavorites(chatID)
+	if len(favorites) == 0 {
+		msg := tgbotapi.NewMessage(chatID, translate(lang, "favorites_empty"))
+		msg.ReplyMarkup = createMainKeyboard(lang)
+		sendMessage(bot, msg)
+		return
+	}
+
+	links := make([]string, len(favorites))
+	for i, offer := range favorites {
+		links[i] = offer.Link
+	}
+	msg := tgbotapi.NewMessage(chatID, translate(lang, "share_header", len(favorites))+"\n\n"+strings.Join(links, "\n"))
+	msg.DisableWebPagePreview = true
+	msg.ReplyMarkup = createMainKeyboard(lang)
+	sendMessage(bot, msg)
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/aqaliarept/vuokraovi-bot/state"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+func TestShareListsFavoriteLinksInInsertionOrder(t *testing.T) {
+	bot, fake := newFakeTelegram(t)
+	botState := newTestBotState(t)
+	botState.AddUser(&tgbotapi.User{FirstName: "Test"}, 1)
+	var offers []state.RentalOffer
+	for _, id := range []string{"101", "102", "103", "104"} {
+		offer := testOffer("https://example.com/asunto/"+id, "900 €/kk")
+		offer.Address = "Testikatu " + id + ", Helsinki"
+		offers = append(offers, offer)
+	}
+	botState.ApplyOffers(offers)
+	for _, id := range []string{"103", "101", "104"} {
+		botState.AddFavorite(1, id)
+	}
+
+	handleMessage(bot, botState, userMessage(1, "/share"), BotConfig{})
+
+	sent := fake.calls("sendMessage")
+	if len(sent) != 1 {
+		t.Fatalf("sent %d messages, want 1", len(sent))
+	}
+	if mode := sent[0].Get("parse_mode"); mode != "" {
+		t.Errorf("parse mode = %q, want plain text", mode)
+	}
+	lines := strings.Split(sent[0].Get("text"), "\n")
+	want := []string{
+		translate("en", "share_header", 3),
+		"",
+		"https://example.com/asunto/103",
+		"https://example.com/asunto/101",
+		"https://example.com/asunto/104",
+	}
+	if strings.Join(lines, "\n") != strings.Join(want, "\n") {
+		t.Errorf("shared text = %q, want %q", lines, want)
+	}
+}
+
+func TestShareWithoutFavorites(t *testing.T) {
+	bot, fake := newFakeTelegram(t)
+	botState := newTestBotState(t)
+	botState.AddUser(&tgbotapi.User{FirstName: "Test"}, 1)
+
+	handleMessage(bot, botState, userMessage(1, "/share"), BotConfig{})
+
+	sent := fake.calls("sendMessage")
+	if len(sent) != 1 || sent[0].Get("text") != translate("en", "favorites_empty") {
+		t.Errorf("replied %v, want the no favorites message", sent)
+	}
+}
@@ -0,0 +1,332 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/aqaliarept/vuokraovi-bot/state"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// parseFilterArgs parses /filter arguments like
+// "price=0-900 rooms>=2 city=Helsinki size>=30 ppsqm<=30 amenities=sauna near=60.17,24.94 radius=3km"
+// on top of the given filters
+func parseFilterArgs(args string, filters state.Filters) (state.Filters, error) {
+	for _, token := range strings.Fields(args) {
+		key, op, value, err := splitFilterToken(token)
+		if err != nil {
+			return filters, err
+		}
+
+		switch key {
+		case "price":
+			if op == "=" && strings.Contains(value, "-") {
+				parts := strings.SplitN(value, "-", 2)
+				min, err := parseFilterNumber(key, parts[0])
+				if err != nil {
+					return filters, err
+				}
+				max, err := parseFilterNumber(key, parts[1])
+				if err != nil {
+					return filters, err
+				}
+				if max > 0 && min > max {
+					return filters, fmt.Errorf("invalid price range %q: minimum is above maximum", value)
+				}
+				filters.MinPrice, filters.MaxPrice = min, max
+				continue
+			}
+			price, err := parseFilterNumber(key, value)
+			if err != nil {
+				return filters, err
+			}
+			switch op {
+			case ">=":
+				filters.MinPrice = price
+			case "<=":
+				filters.MaxPrice = price
+			default:
+				filters.MinPrice, filters.MaxPrice = price, price
+			}
+		case "rooms":
+			if op == "<=" {
+				return filters, fmt.Errorf("rooms only supports a minimum (rooms>=N)")
+			}
+			rooms, err := strconv.Atoi(value)
+			if err != nil || rooms < 0 {
+				return filters, fmt.Errorf("invalid number of rooms %q", value)
+			}
+			filters.MinRooms = rooms
+		case "size":
+			if op == "<=" {
+				return filters, fmt.Errorf("size only supports a minimum (size>=M2)")
+			}
+			size, err := parseFilterNumber(key, value)
+			if err != nil {
+				return filters, err
+			}
+			filters.MinSize = size
+		case "ppsqm":
+			if op != "<=" {
+				return filters, fmt.Errorf("ppsqm only supports a maximum (ppsqm<=EUR)")
+			}
+			perSqm, err := parseFilterNumber(key, value)
+			if err != nil {
+				return filters, err
+			}
+			filters.MaxPricePerSqm = perSqm
+		case "city":
+			if op != "=" {
+				return filters, fmt.Errorf("city only supports city=NAME")
+			}
+			filters.City = value
+		case "city.include", "city.exclude", "district.include", "district.exclude":
+			if op != "=" {
+				return filters, fmt.Errorf("%s only supports %s=NAME,NAME", key, key)
+			}
+			var places []string
+			if !strings.EqualFold(value, "none") {
+				places = state.NormalizePlaces(strings.Split(value, ","))
+			}
+			switch key {
+			case "city.include":
+				filters.IncludeCities = places
+			case "city.exclude":
+				filters.ExcludeCities = places
+			case "district.include":
+				filters.IncludeDistricts = places
+			case "district.exclude":
+				filters.ExcludeDistricts = places
+			}
+		case "amenities":
+			if op != "=" {
+				return filters, fmt.Errorf("amenities only supports amenities=NAME,NAME")
+			}
+			amenities, err := parseAmenities(value)
+			if err != nil {
+				return filters, err
+			}
+			filters.Amenities = amenities
+		case "near":
+			if op != "=" {
+				return filters, fmt.Errorf("near only supports near=LAT,LNG")
+			}
+			if strings.EqualFold(value, "none") {
+				filters.NearLat, filters.NearLng, filters.RadiusKm, filters.KeepUnlocated = 0, 0, 0, false
+				continue
+			}
+			lat, lng, err := parseNearPoint(value)
+			if err != nil {
+				return filters, err
+			}
+			filters.NearLat, filters.NearLng = lat, lng
+		case "radius":
+			if op == ">=" {
+				return filters, fmt.Errorf("radius only supports a maximum (radius=KM or radius<=KM)")
+			}
+			radius, err := parseRadius(value)
+			if err != nil {
+				return filters, err
+			}
+			filters.RadiusKm = radius
+		case "unlocated":
+			switch {
+			case op == "=" && strings.EqualFold(value, "keep"):
+				filters.KeepUnlocated = true
+			case op == "=" && strings.EqualFold(value, "drop"):
+				filters.KeepUnlocated = false
+			default:
+				return filters, fmt.Errorf("unlocated only supports unlocated=keep or unlocated=drop")
+			}
+		default:
+			return filters, fmt.Errorf("unknown filter %q", key)
+		}
+	}
+
+	hasPoint := filters.NearLat != 0 || filters.NearLng != 0
+	if hasPoint && filters.RadiusKm == 0 {
+		return filters, fmt.Errorf("near needs a radius, e.g. radius=3km")
+	}
+	if !hasPoint && filters.RadiusKm > 0 {
+		return filters, fmt.Errorf("radius needs a point, e.g. near=60.17,24.94")
+	}
+
+	return filters, nil
+}
+
+// parseNearPoint parses a "LAT,LNG" point in decimal degrees
+func parseNearPoint(value string) (float64, float64, error) {
+	latText, lngText, ok := strings.Cut(value, ",")
+	if !ok {
+		return 0, 0, fmt.Errorf("invalid point %q, use near=LAT,LNG", value)
+	}
+	lat, latErr := strconv.ParseFloat(latText, 64)
+	lng, lngErr := strconv.ParseFloat(lngText, 64)
+	if latErr != nil || lngErr != nil || lat < -90 || lat > 90 || lng < -180 || lng > 180 || (lat == 0 && lng == 0) {
+		return 0, 0, fmt.Errorf("invalid point %q, use near=LAT,LNG", value)
+	}
+	return lat, lng, nil
+}
+
+// parseRadius parses a positive radius in kilometers, such as "3", "3km", "2,5km" or "500m"
+func parseRadius(value string) (float64, error) {
+	lower := strings.ToLower(value)
+	scale := 1.0
+	switch {
+	case strings.HasSuffix(lower, "km"):
+		lower = strings.TrimSuffix(lower, "km")
+	case strings.HasSuffix(lower, "m"):
+		lower, scale = strings.TrimSuffix(lower, "m"), 0.001
+	}
+	radius, err := parseFilterNumber("radius", lower)
+	if err != nil || radius == 0 {
+		return 0, fmt.Errorf("invalid radius %q", value)
+	}
+	return radius * scale, nil
+}
+
+// parseAmenities parses a comma separated list of amenity names or keywords into
+// amenity names. "none" clears the amenities.
+func parseAmenities(value string) ([]string, error) {
+	if strings.EqualFold(value, "none") {
+		return nil, nil
+	}
+	var amenities []string
+	for _, word := range strings.Split(value, ",") {
+		if strings.TrimSpace(word) == "" {
+			continue
+		}
+		amenity, ok := state.AmenityName(word)
+		if !ok {
+			return nil, fmt.Errorf("unknown amenity %q (known: %s)", word, strings.Join(amenityNames(), ", "))
+		}
+		if !slices.Contains(amenities, amenity) {
+			amenities = append(amenities, amenity)
+		}
+	}
+	return amenities, nil
+}
+
+// amenityNames returns the names of the known amenities in alphabetical order
+func amenityNames() []string {
+	names := make([]string, 0, len(state.AmenityKeywords))
+	for name := range state.AmenityKeywords {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// splitFilterToken splits a token like "rooms>=2" into its key, operator and value
+func splitFilterToken(token string) (string, string, string, error) {
+	for _, op := range []string{">=", "<=", "="} {
+		if idx := strings.Index(token, op); idx > 0 {
+			value := token[idx+len(op):]
+			if value == "" {
+				return "", "", "", fmt.Errorf("missing value in %q", token)
+			}
+			return strings.ToLower(token[:idx]), op, value, nil
+		}
+	}
+	return "", "", "", fmt.Errorf("invalid filter %q", token)
+}
+
+// parseFilterNumber parses a non-negative number, accepting a decimal comma
+func parseFilterNumber(key, value string) (float64, error) {
+	number, err := strconv.ParseFloat(strings.Replace(value, ",", ".", 1), 64)
+	if err != nil || number < 0 {
+		return 0, fmt.Errorf("invalid %s %q", key, value)
+	}
+	return number, nil
+}
+
+// formatFilters describes the filters in a human readable form
+func formatFilters(filters state.Filters, lang string) string {
+	if filters.IsEmpty() {
+		return translate(lang, "filters_none")
+	}
+
+	text := translate(lang, "filters_header")
+	if filters.City != "" {
+		text += translate(lang, "filter_city", filters.City)
+	}
+	switch {
+	case filters.MinPrice > 0 && filters.MaxPrice > 0:
+		text += translate(lang, "filter_price_range", filters.MinPrice, filters.MaxPrice)
+	case filters.MinPrice > 0:
+		text += translate(lang, "filter_price_min", filters.MinPrice)
+	case filters.MaxPrice > 0:
+		text += translate(lang, "filter_price_max", filters.MaxPrice)
+	}
+	if filters.MinRooms > 0 {
+		text += translate(lang, "filter_rooms", filters.MinRooms)
+	}
+	if filters.MinSize > 0 {
+		text += translate(lang, "filter_size", filters.MinSize)
+	}
+	if filters.MaxPricePerSqm > 0 {
+		text += translate(lang, "filter_ppsqm", filters.MaxPricePerSqm)
+	}
+	for _, places := range []struct {
+		key  string
+		list []string
+	}{
+		{"filter_cities_include", filters.IncludeCities},
+		{"filter_cities_exclude", filters.ExcludeCities},
+		{"filter_districts_include", filters.IncludeDistricts},
+		{"filter_districts_exclude", filters.ExcludeDistricts},
+	} {
+		if len(places.list) > 0 {
+			text += translate(lang, places.key, strings.Join(places.list, ", "))
+		}
+	}
+	if len(filters.Amenities) > 0 {
+		text += translate(lang, "filter_amenities", strings.Join(filters.Amenities, ", "))
+	}
+	if filters.RadiusKm > 0 {
+		text += translate(lang, "filter_near", filters.RadiusKm, filters.NearLat, filters.NearLng)
+		if filters.KeepUnlocated {
+			text += translate(lang, "filter_unlocated")
+		}
+	}
+	return text
+}
+
+// handleFilterCommand handles the /filter command
+func handleFilterCommand(bot *tgbotapi.BotAPI, botState *state.BotState, message *tgbotapi.Message) {
+	chatID := message.Chat.ID
+	lang := userLanguage(botState, chatID)
+	args := strings.TrimSpace(message.CommandArguments())
+
+	current, exists := botState.GetUserFilters(chatID)
+	if !exists {
+		msg := tgbotapi.NewMessage(chatID, translate(lang, "start_first"))
+		msg.ReplyMarkup = createMainKeyboard(lang)
+		sendMessage(bot, msg)
+		return
+	}
+
+	var text string
+	switch {
+	case args == "":
+		text = formatFilters(current, lang) + "\n\n" + translate(lang, "filter_usage")
+	case strings.EqualFold(args, "clear"):
+		botState.SetUserFilters(chatID, state.Filters{})
+		text = translate(lang, "filters_cleared")
+	default:
+		filters, err := parseFilterArgs(args, current)
+		if err != nil {
+			text = fmt.Sprintf("❌ %v\n\n%s", err, translate(lang, "filter_usage"))
+			break
+		}
+		botState.SetUserFilters(chatID, filters)
+		text = "✅ " + formatFilters(filters, lang)
+	}
+
+	msg := tgbotapi.NewMessage(chatID, text)
+	msg.ReplyMarkup = createMainKeyboard(lang)
+	sendMessage(bot, msg)
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/aqaliarept/vuokraovi-bot/state"
+)
+
+func TestParseFilterArgs(t *testing.T) {
+	tests := []struct {
+		args string
+		want state.Filters
+	}{
+		{"price=0-900", state.Filters{MaxPrice: 900}},
+		{"price=500-900", state.Filters{MinPrice: 500, MaxPrice: 900}},
+		{"price>=500", state.Filters{MinPrice: 500}},
+		{"price<=750,5", state.Filters{MaxPrice: 750.5}},
+		{"price=800", state.Filters{MinPrice: 800, MaxPrice: 800}},
+		{"rooms>=2 size>=30", state.Filters{MinRooms: 2, MinSize: 30}},
+		{"rooms=3", state.Filters{MinRooms: 3}},
+		{"Price=0-900 ROOMS>=2 city=Helsinki size>=30", state.Filters{City: "Helsinki", MaxPrice: 900, MinRooms: 2, MinSize: 30}},
+		{"amenities=Sauna,balcony", state.Filters{Amenities: []string{"sauna", "parveke"}}},
+		{"amenities=sauna,s amenities=none", state.Filters{}},
+		{"city.include=Helsinki,Espoo district.exclude=Kallio", state.Filters{IncludeCities: []string{"helsinki", "espoo"}, ExcludeDistricts: []string{"kallio"}}},
+		{"district.include=kallio district.include=none city.exclude=vantaa", state.Filters{ExcludeCities: []string{"vantaa"}}},
+		{"near=60.17,24.94 radius=3km", state.Filters{NearLat: 60.17, NearLng: 24.94, RadiusKm: 3}},
+		{"near=60.17,24.94 radius<=2,5KM unlocated=keep", state.Filters{NearLat: 60.17, NearLng: 24.94, RadiusKm: 2.5, KeepUnlocated: true}},
+		{"near=60.17,24.94 radius=500m", state.Filters{NearLat: 60.17, NearLng: 24.94, RadiusKm: 0.5}},
+		{"ppsqm<=30", state.Filters{MaxPricePerSqm: 30}},
+		{"price<=900 PPSQM<=22,5", state.Filters{MaxPrice: 900, MaxPricePerSqm: 22.5}},
+		{"radius=4 near=60.17,24.94 near=none", state.Filters{}},
+	}
+
+	for _, tt := range tests {
+		got, err := parseFilterArgs(tt.args, state.Filters{})
+		if err != nil {
+			t.Errorf("parseFilterArgs(%q): %v", tt.args, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parseFilterArgs(%q) = %+v, want %+v", tt.args, got, tt.want)
+		}
+	}
+}
+
+func TestParseFilterArgsKeepsOtherFilters(t *testing.T) {
+	current := state.Filters{City: "Tampere", MaxPrice: 900}
+
+	got, err := parseFilterArgs("rooms>=2", current)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (state.Filters{City: "Tampere", MaxPrice: 900, MinRooms: 2}); !reflect.DeepEqual(got, want) {
+		t.Errorf("parseFilterArgs = %+v, want %+v", got, want)
+	}
+}
+
+func TestParseFilterArgsRejectsBadInput(t *testing.T) {
+	tests := []struct {
+		args    string
+		message string
+	}{
+		{"color=red", "unknown filter"},
+		{"price", "invalid filter"},
+		{"price=", "missing value"},
+		{"price=cheap", "invalid price"},
+		{"price=900-500", "minimum is above maximum"},
+		{"price>=-5", "invalid price"},
+		{"rooms<=3", "only supports a minimum"},
+		{"rooms>=two", "invalid number of rooms"},
+		{"size<=50", "only supports a minimum"},
+		{"ppsqm>=20", "only supports a maximum"},
+		{"ppsqm=20", "only supports a maximum"},
+		{"ppsqm<=cheap", "invalid ppsqm"},
+		{"city>=Helsinki", "city only supports"},
+		{"amenities=sauna,pool", "unknown amenity"},
+		{"amenities>=sauna", "amenities only supports"},
+		{"near=60.17", "invalid point"},
+		{"near=95,24.94 radius=3km", "invalid point"},
+		{"near=60.17,24.94", "near needs a radius"},
+		{"radius=3km", "radius needs a point"},
+		{"near=60.17,24.94 radius=0", "invalid radius"},
+		{"near=60.17,24.94 radius=far", "invalid radius"},
+		{"near=60.17,24.94 radius>=3", "radius only supports a maximum"},
+		{"unlocated=maybe", "unlocated only supports"},
+		{"district.exclude>=kallio", "district.exclude only supports"},
+	}
+
+	for _, tt := range tests {
+		_, err := parseFilterArgs(tt.args, state.Filters{})
+		if err == nil {
+			t.Errorf("parseFilterArgs(%q) accepted bad input", tt.args)
+			continue
+		}
+		if !strings.Contains(err.Error(), tt.message) {
+			t.Errorf("parseFilterArgs(%q) error = %q, want it to mention %q", tt.args, err, tt.message)
+		}
+	}
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// maxRoomAmount is the highest room amount choice of the search form, meaning "5 or more"
+const maxRoomAmount = 5
+
+// FormOptions holds the search criteria assembled into the search form body
+type FormOptions struct {
+	Base     string   // URL-encoded form data the options are applied on top of, may be empty
+	City     string   // value of the location field, a name or the site's classified location
+	MinPrice int      // minimum monthly rent in euros, 0 = not set
+	MaxPrice int      // maximum monthly rent in euros, 0 = not set
+	MinRooms int      // minimum number of rooms, 0 = not set
+	Types    []string // the site's realty type codes, e.g. "3" and "4"
+}
+
+// IsEmpty reports whether no search criterion is set
+func (o FormOptions) IsEmpty() bool {
+	return o.City == "" && o.MinPrice == 0 && o.MaxPrice == 0 && o.MinRooms == 0 && len(o.Types) == 0
+}
+
+// BuildFormData returns the URL-encoded search form body for the options.
+// The criteria replace the matching keys of the base form data; without a base
+// a minimal search form is built.
+func BuildFormData(opts FormOptions) (string, error) {
+	values := url.Values{
+		"method":           {"search"},
+		"type":             {"full"},
+		"location.country": {"finland"},
+	}
+	if base := strings.TrimSpace(opts.Base); base != "" {
+		var err error
+		if values, err = url.ParseQuery(base); err != nil {
+			return "", fmt.Errorf("form data is not URL-encoded: %w", err)
+		}
+	}
+
+	if opts.City != "" {
+		values.Set("location.classifiedLocation", opts.City)
+	}
+	if opts.MinPrice > 0 {
+		values.Set("rent.rentMin", strconv.Itoa(opts.MinPrice))
+	}
+	if opts.MaxPrice > 0 {
+		values.Set("rent.rentMax", strconv.Itoa(opts.MaxPrice))
+	}
+	if opts.MinPrice > 0 && opts.MaxPrice > 0 && opts.MinPrice > opts.MaxPrice {
+		return "", fmt.Errorf("minimum price %d is above maximum price %d", opts.MinPrice, opts.MaxPrice)
+	}
+	if opts.MinRooms > 0 {
+		// The form selects room amounts one by one, so select all from the minimum up
+		values.Del("building.roomAmount")
+		for rooms := opts.MinRooms; rooms <= maxRoomAmount; rooms++ {
+			values.Add("building.roomAmount", strconv.Itoa(rooms))
+		}
+		if opts.MinRooms > maxRoomAmount {
+			values.Add("building.roomAmount", strconv.Itoa(maxRoomAmount))
+		}
+	}
+	if len(opts.Types) > 0 {
+		values.Del("building.realtyType")
+		for _, realtyType := range opts.Types {
+			values.Add("building.realtyType", realtyType)
+		}
+	}
+
+	return values.Encode(), nil
+}
+
+// splitList splits a comma separated flag value, dropping empty items
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// requiredFormKeys are the search form fields every valid form body contains
+var requiredFormKeys = []string{"method", "type"}
+
+// ValidateFormData checks that formData is a URL-encoded search form body
+func ValidateFormData(formData string) error {
+	if strings.TrimSpace(formData) == "" {
+		return fmt.Errorf("form data is empty")
+	}
+	if strings.ContainsAny(formData, " \t\r\n") {
+		return fmt.Errorf("form data is not URL-encoded: it contains whitespace")
+	}
+
+	values, err := url.ParseQuery(formData)
+	if err != nil {
+		return fmt.Errorf("form data is not URL-encoded: %w", err)
+	}
+
+	var missing []string
+	for _, key := range requiredFormKeys {
+		if _, ok := values[key]; !ok {
+			missing = append(missing, key)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("form data is missing the search keys %s", strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// readFormData reads and validates the form data file, ignoring surrounding whitespace
+func readFormData(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("error reading form data from %s: %w", path, err)
+	}
+
+	formData := strings.TrimSpace(string(data))
+	if err := ValidateFormData(formData); err != nil {
+		return "", fmt.Errorf("invalid form data in %s: %w", path, err)
+	}
+	return formData, nil
+}
+
+// loadFormData returns the search form body: the form data file alone, or the
+// search options applied on top of it when useFile is set and on a minimal form otherwise
+func loadFormData(path string, useFile bool, opts FormOptions) (string, error) {
+	if opts.IsEmpty() {
+		return readFormData(path)
+	}
+	if useFile {
+		base, err := readFormData(path)
+		if err != nil {
+			return "", err
+		}
+		opts.Base = base
+	}
+
+	formData, err := BuildFormData(opts)
+	if err != nil {
+		return "", fmt.Errorf("invalid search options: %w", err)
+	}
+	if err := ValidateFormData(formData); err != nil {
+		return "", fmt.Errorf("invalid search options: %w", err)
+	}
+	return formData, nil
+}
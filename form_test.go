@@ -0,0 +1,151 @@
+package main
+
+import (
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestValidateFormData(t *testing.T) {
+	tests := []struct {
+		name     string
+		formData string
+		wantErr  string
+	}{
+		{"empty", "", "empty"},
+		{"blank", " \n\t", "empty"},
+		{"not encoded", "method: search\ntype: full", "not URL-encoded"},
+		{"invalid escape", "method=search&type=%zz", "not URL-encoded"},
+		{"missing keys", "location.country=finland", "missing the search keys method, type"},
+		{"missing one key", "method=search&rent.rentMax=900", "missing the search keys type"},
+		{"valid", "method=search&type=full&location.country=finland", ""},
+	}
+
+	for _, tt := range tests {
+		err := ValidateFormData(tt.formData)
+		switch {
+		case tt.wantErr == "" && err != nil:
+			t.Errorf("%s: unexpected error %v", tt.name, err)
+		case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+			t.Errorf("%s: error %v, want one mentioning %q", tt.name, err, tt.wantErr)
+		}
+	}
+}
+
+func TestReadFormDataTrimsAndValidatesFile(t *testing.T) {
+	dir := t.TempDir()
+	valid := filepath.Join(dir, "valid.txt")
+	os.WriteFile(valid, []byte("method=search&type=full\n"), 0644)
+	invalid := filepath.Join(dir, "invalid.txt")
+	os.WriteFile(invalid, []byte("<html>not a form</html>"), 0644)
+
+	if formData, err := readFormData(valid); err != nil || formData != "method=search&type=full" {
+		t.Errorf("readFormData(valid) = %q, %v", formData, err)
+	}
+	if _, err := readFormData(invalid); err == nil || !strings.Contains(err.Error(), invalid) {
+		t.Errorf("readFormData(invalid) error = %v, want one naming the file", err)
+	}
+	if _, err := readFormData(filepath.Join(dir, "missing.txt")); err == nil {
+		t.Error("readFormData accepted a missing file")
+	}
+}
+
+func TestFetchRentalOffersRejectsInvalidFormData(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "form_data.txt")
+	os.WriteFile(path, nil, 0644)
+
+	// The form data is checked before any request is made, so no server is needed
+	_, err := fetchRentalOffers(BotConfig{FormDataFile: path, DataDir: dir})
+	if err == nil || !strings.Contains(err.Error(), "form data is empty") {
+		t.Errorf("fetchRentalOffers error = %v, want the empty form data reported", err)
+	}
+}
+
+func TestBuildFormDataWithoutBase(t *testing.T) {
+	formData, err := BuildFormData(FormOptions{City: "Helsinki", MaxPrice: 900, MinRooms: 4, Types: []string{"3", "4"}})
+	if err != nil {
+		t.Fatalf("BuildFormData: %v", err)
+	}
+	values, _ := url.ParseQuery(formData)
+
+	if values.Get("method") != "search" || values.Get("type") != "full" || values.Get("location.country") != "finland" {
+		t.Errorf("form %v lacks the minimal search fields", values)
+	}
+	if values.Get("location.classifiedLocation") != "Helsinki" || values.Get("rent.rentMax") != "900" || values.Has("rent.rentMin") {
+		t.Errorf("form %v does not carry the options", values)
+	}
+	if rooms := values["building.roomAmount"]; strings.Join(rooms, ",") != "4,5" {
+		t.Errorf("room amounts = %v, want 4 and up", rooms)
+	}
+	if types := values["building.realtyType"]; strings.Join(types, ",") != "3,4" {
+		t.Errorf("realty types = %v, want 3 and 4", types)
+	}
+	if err := ValidateFormData(formData); err != nil {
+		t.Errorf("the built form is invalid: %v", err)
+	}
+}
+
+func TestBuildFormDataOverridesBase(t *testing.T) {
+	base := "method=search&type=full&rent.rentMax=2000&rent.rentMin=100&building.roomAmount=1&building.roomAmount=2&location.classifiedLocation=Espoo&extra=kept"
+	formData, err := BuildFormData(FormOptions{Base: base, City: "Helsinki", MaxPrice: 900, MinRooms: 3})
+	if err != nil {
+		t.Fatalf("BuildFormData: %v", err)
+	}
+	values, _ := url.ParseQuery(formData)
+
+	if values.Get("location.classifiedLocation") != "Helsinki" || values.Get("rent.rentMax") != "900" {
+		t.Errorf("form %v, want the options to replace the base values", values)
+	}
+	if values.Get("rent.rentMin") != "100" || values.Get("extra") != "kept" {
+		t.Errorf("form %v, want the keys without options kept", values)
+	}
+	if rooms := values["building.roomAmount"]; strings.Join(rooms, ",") != "3,4,5" {
+		t.Errorf("room amounts = %v, want the base choices replaced", rooms)
+	}
+}
+
+func TestBuildFormDataEncodesSpecialCharacters(t *testing.T) {
+	formData, err := BuildFormData(FormOptions{City: "Hämeenlinna & Järvenpää=1"})
+	if err != nil {
+		t.Fatalf("BuildFormData: %v", err)
+	}
+	if strings.ContainsAny(formData, " ä") || !strings.Contains(formData, "H%C3%A4meenlinna+%26+J%C3%A4rvenp%C3%A4%C3%A4%3D1") {
+		t.Errorf("form %q does not encode the city", formData)
+	}
+	values, _ := url.ParseQuery(formData)
+	if values.Get("location.classifiedLocation") != "Hämeenlinna & Järvenpää=1" {
+		t.Errorf("decoded city = %q", values.Get("location.classifiedLocation"))
+	}
+}
+
+func TestBuildFormDataRejectsInvalidInput(t *testing.T) {
+	if _, err := BuildFormData(FormOptions{MinPrice: 1000, MaxPrice: 900}); err == nil {
+		t.Error("a minimum price above the maximum was accepted")
+	}
+	if _, err := BuildFormData(FormOptions{Base: "method=%zz", City: "Helsinki"}); err == nil {
+		t.Error("a base that is not URL-encoded was accepted")
+	}
+}
+
+func TestLoadFormDataAppliesOptionsOnTopOfFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "form_data.txt")
+	os.WriteFile(path, []byte("method=search&type=full&rent.rentMax=2000&extra=kept"), 0644)
+	opts := FormOptions{MaxPrice: 900}
+
+	formData, err := loadFormData(path, true, opts)
+	if err != nil {
+		t.Fatalf("loadFormData: %v", err)
+	}
+	if values, _ := url.ParseQuery(formData); values.Get("rent.rentMax") != "900" || values.Get("extra") != "kept" {
+		t.Errorf("form %v, want the file with the option applied", values)
+	}
+
+	// Without an explicit -form the default file is ignored
+	formData, err = loadFormData(filepath.Join(t.TempDir(), "missing.txt"), false, opts)
+	if values, _ := url.ParseQuery(formData); err != nil || values.Has("extra") || values.Get("rent.rentMax") != "900" {
+		t.Errorf("loadFormData without file = %v, %v, want a minimal form", values, err)
+	}
+}
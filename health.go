@@ -0,0 +1,43 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"sync/atomic"
+)
+
+var (
+	// botRunning is set while the bot processes updates
+	botRunning atomic.Bool
+	// botReady is set once an offer update has succeeded
+	botReady atomic.Bool
+)
+
+// healthHandler serves /healthz and /readyz for container orchestration
+func healthHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		writeProbe(w, botRunning.Load())
+	})
+	mux.HandleFunc("/readyz", func(w http.ResponseWriter, r *http.Request) {
+		writeProbe(w, botRunning.Load() && botReady.Load())
+	})
+	return mux
+}
+
+// writeProbe answers a probe with 200 when ok and 503 otherwise
+func writeProbe(w http.ResponseWriter, ok bool) {
+	if !ok {
+		http.Error(w, "not ready", http.StatusServiceUnavailable)
+		return
+	}
+	w.Write([]byte("ok"))
+}
+
+// startHealthServer serves the health endpoints on the configured address
+func startHealthServer(config BotConfig) {
+	log.Printf("Serving health checks on %s", config.HealthAddr)
+	if err := http.ListenAndServe(config.HealthAddr, healthHandler()); err != nil {
+		log.Printf("Error serving health checks: %v", err)
+	}
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/aqaliarept/vuokraovi-bot/state"
+)
+
+// probe returns the status code of a health endpoint
+func probe(path string) int {
+	rec := httptest.NewRecorder()
+	healthHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+	return rec.Code
+}
+
+func TestHealthEndpoints(t *testing.T) {
+	t.Cleanup(func() {
+		botRunning.Store(false)
+		botReady.Store(false)
+	})
+
+	if code := probe("/healthz"); code != http.StatusServiceUnavailable {
+		t.Errorf("/healthz before start = %d, want 503", code)
+	}
+
+	botRunning.Store(true)
+	if code := probe("/healthz"); code != http.StatusOK {
+		t.Errorf("/healthz while running = %d, want 200", code)
+	}
+	if code := probe("/readyz"); code != http.StatusServiceUnavailable {
+		t.Errorf("/readyz before the initial update = %d, want 503", code)
+	}
+
+	botReady.Store(true)
+	if code := probe("/readyz"); code != http.StatusOK {
+		t.Errorf("/readyz after the initial update = %d, want 200", code)
+	}
+}
+
+func TestRunUpdateMarksReadyOnlyOnSuccess(t *testing.T) {
+	t.Cleanup(func() { botReady.Store(false) })
+	bot, _ := newFakeTelegram(t)
+	botState := newTestBotState(t)
+
+	original := fetchOffers
+	t.Cleanup(func() { fetchOffers = original })
+	fetchOffers = func(BotConfig) ([]state.RentalOffer, error) { return nil, errors.New("site unreachable") }
+	if err := runUpdate(bot, botState, BotConfig{}); err == nil {
+		t.Fatal("runUpdate succeeded although fetching failed")
+	}
+	if botReady.Load() {
+		t.Error("a failed update marked the bot ready")
+	}
+
+	stubFetch(t, numberedOffers(2))
+	if err := runUpdate(bot, botState, BotConfig{}); err != nil {
+		t.Fatalf("runUpdate: %v", err)
+	}
+	if !botReady.Load() {
+		t.Error("a successful update did not mark the bot ready")
+	}
+}
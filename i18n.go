@@ -0,0 +1,587 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/aqaliarept/vuokraovi-bot/state"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// defaultLanguage is used for users without a language and for missing translations
+const defaultLanguage = "en"
+
+// catalog holds the bot messages keyed by language and message key.
+// Messages may contain fmt verbs filled in by translate.
+var catalog = map[string]map[string]string{
+	"en": {
+		"welcome": "👋 Welcome to the Vuokraovi Rental Bot, %s!\n\n" +
+			"I will notify you about new rental offers from Vuokraovi.com.\n\n" +
+			"Use the buttons below or type commands to interact with me:",
+		"current_offers":         "Here are the current %d rental offers:",
+		"main_menu":              "Main menu:",
+		"use_buttons":            "Please use the buttons below or commands to interact with me:",
+		"start_first":            "Please start the bot first with /start",
+		"notifications_enabled":  "✅ Notifications are now enabled. You will receive updates about new rental offers.",
+		"notifications_disabled": "🔕 Notifications are now disabled. You will not receive updates about new rental offers.",
+		"notifications_prompt":   "Do you want to receive notifications about new rental offers?",
+		"reset_done":             "✅ Your state has been reset. You will now receive all available offers again.",
+		"status": "Bot Status:\n\n" +
+			"• Total offers: %d\n" +
+			"• Your notifications: %s\n" +
+			"• Last update: %s\n" +
+			"• Update interval: %v",
+		"enabled":  "Enabled ✅",
+		"disabled": "Disabled 🔕",
+		"help": "🤖 *Vuokraovi Rental Bot Commands*\n\n" +
+			"/start - Start the bot and get current offers\n" +
+			"/help - Show this help message\n" +
+			"/list - List all current rental offers\n" +
+			"/recent 3 - List offers first seen in the last days (default 1)\n" +
+			"/unseen - List matching offers you have not seen yet\n" +
+			"/diff - Show what changed since your last check\n" +
+			"/top - Show the cheapest and most expensive offers\n" +
+			"/show price,rooms,link - Choose the offer fields shown\n" +
+			"/reset - Reset your state and get all offers again\n" +
+			"/undo - Get the offers of your last notification again with the next update\n" +
+			"/notifications - Toggle notifications on/off\n" +
+			"/status - Show bot status information\n" +
+			"/photos - Download photos of offers matching your filters as a zip (/photos fav for favorites)\n" +
+			"/filter - Show or set your search filters\n" +
+			"/profile - Manage named search profiles (add, list, use, stop, del)\n" +
+			"/search - Search for offers right now\n" +
+			"/query - Show the search request sent to the site\n" +
+			"/searches - Show the bot's searches or choose the ones you get offers of\n" +
+			"/fav <id> - Add an offer to your favorites\n" +
+			"/unfav <id> - Remove an offer from your favorites\n" +
+			"/favorites - List your favorite offers\n" +
+			"/share - Get the links of your favorites to share\n" +
+			"/map <id> - Show where an offer is on a map\n" +
+			"/offer <id> - Show the details of an offer again\n" +
+			"/feed - Get your personal RSS feed link\n" +
+			"/quiet 22-08 Europe/Helsinki - Set quiet hours without notifications\n" +
+			"/digest daily 09:00 - Receive new offers as one summary (hourly, daily HH:MM or off)\n" +
+			"/pause 7d - Pause notifications for a while (e.g. 7d, 12h)\n" +
+			"/resume - End a pause early\n" +
+			"/cancel - Stop answering the bot's questions\n" +
+			"/stats - Show your personal statistics\n" +
+			"/lang fi - Change the language (en, fi)\n" +
+			"/clear - Clear your data and reset all settings\n\n" +
+			"You can also use the buttons below for quick access to commands:",
+		"clear_confirm": "⚠️ Are you sure you want to clear your data? This will:\n\n" +
+			"• Remove all your seen offers\n" +
+			"• Reset your notification settings\n" +
+			"• Clear your last active time\n\n" +
+			"This action cannot be undone.",
+		"clear_done": "✅ Your data has been cleared successfully.\n\n" +
+			"• Seen offers have been reset\n" +
+			"• Notifications have been re-enabled\n\n" +
+			"You will now receive notifications for all offers again.",
+		"clear_cancelled":  "Data clearing cancelled. Your data is safe.",
+		"lang_set":         "✅ Language set to English.",
+		"lang_usage":       "Usage: /lang <language>. Available languages: %s",
+		"lang_unsupported": "Language %q is not available. Available languages: %s",
+		"stats": "📈 *Your Statistics*\n\n" +
+			"• Offers seen: %d\n" +
+			"• Favorites: %d\n" +
+			"• Notifications: %s\n" +
+			"• Last notified: %s\n" +
+			"• Offers known by the bot: %d",
+		"never":                        "never",
+		"button_list":                  "List Offers 📋",
+		"button_reset":                 "Reset 🔄",
+		"button_notifications":         "Notifications 🔔",
+		"button_status":                "Status 📊",
+		"button_help":                  "Help ❓",
+		"button_enable_notifications":  "Enable Notifications 🔔",
+		"button_disable_notifications": "Disable Notifications 🔕",
+		"button_back":                  "Back to Main Menu ↩️",
+		"button_clear_yes":             "Yes, Clear Data ✅",
+		"button_clear_no":              "No, Keep Data ❌",
+		"button_view_all":              "View All Offers 📋",
+		"button_prev":                  "⬅️ Prev",
+		"button_next":                  "Next ➡️",
+		"new_offers":                   "🏠 *New Rental Offers*\n\nFound %d new rental offers:\n\n",
+		"delisted_header":              "🚫 *No Longer Available*\n\n",
+		"price_changes_header":         "💰 *Price Changes*\n\n",
+		"more_offers":                  "\n...and %d more offers. Use /list to see all offers.",
+		"list_header":                  "📋 *%d rental offers* (page %d/%d)\n\n",
+		"list_empty":                   "No rental offers available at the moment.",
+		"fav_usage":                    "Usage: /fav <offer ID>. The ID is shown with each offer in /list.",
+		"fav_added":                    "⭐ Added %s to your favorites.",
+		"offer_not_found":              "Offer %s was not found.",
+		"unfav_usage":                  "Usage: /unfav <offer ID>",
+		"map_usage":                    "Usage: /map <offer ID>. The ID is shown with each offer in /list.",
+		"map_no_address":               "Offer %s has no address to show on a map.",
+		"map_links":                    "📍 %s\n\nGoogle Maps: %s\nOpenStreetMap: %s",
+		"fav_removed":                  "Removed offer %s from your favorites.",
+		"fav_missing":                  "Offer %s is not in your favorites.",
+		"favorites_empty":              "You have no favorite offers yet. Use /fav <offer ID> to add one.",
+		"favorites_list":               "Your %d favorite offers:",
+		"photos_usage":                 "Usage: /photos for offers matching your filters, /photos fav for your favorites.",
+		"photos_empty":                 "No offer photos available at the moment.",
+		"photos_running":               "Your photos are still being collected, please wait.",
+		"photos_collecting":            "Collecting photos of %d offers, this may take a moment...",
+		"photos_failed":                "Sorry, the photos could not be downloaded right now.",
+		"photos_caption":               "📷 %d offer photos",
+		"filter_usage": "Accepted filters:\n" +
+			"• price=MIN-MAX, price>=MIN, price<=MAX\n" +
+			"• rooms>=N (or rooms=N)\n" +
+			"• size>=M2 (or size=M2)\n" +
+			"• ppsqm<=EUR (rent per m²)\n" +
+			"• city=NAME\n" +
+			"• city.include=NAME,NAME, city.exclude=..., district.include=..., district.exclude=... (or =none)\n" +
+			"• amenities=sauna,parveke (or amenities=none)\n" +
+			"• near=LAT,LNG radius=3km (or near=none), unlocated=keep to keep offers without a location\n\n" +
+			"Example: /filter price=0-900 rooms>=2 city=Helsinki size>=30\n" +
+			"Use /filter clear to remove all filters.",
+		"filters_none":       "No filters set, you are notified about all new offers.",
+		"filters_header":     "Your filters:\n",
+		"filter_city":        "📍 City: %s\n",
+		"filter_price_range": "💰 Price: %g-%g €\n",
+		"filter_price_min":   "💰 Price: at least %g €\n",
+		"filter_price_max":   "💰 Price: at most %g €\n",
+		"filter_rooms":       "🛏 Rooms: at least %d\n",
+		"filter_size":        "📐 Size: at least %g m²\n",
+		"filter_ppsqm":       "📊 Rent: at most %g €/m²\n",
+		"filter_amenities":   "✨ Amenities: %s\n",
+		"filter_near":        "📌 Within %g km of %g, %g\n",
+		"filter_unlocated":   "📌 Offers without a location are kept\n",
+		"query_header":       "🔎 The bot searches with this request:\n\n",
+		"query_failed":       "❌ The search request could not be built: %v",
+		"filters_cleared":    "✅ Filters cleared. You will be notified about all new offers.",
+		"search_wait":        "⏳ Please wait %d seconds before searching again.",
+		"search_started":     "🔍 Searching for offers...",
+		"search_failed":      "Sorry, the search failed. Please try again later.",
+		"search_no_match":    "No offers match your filters at the moment.",
+		"search_found":       "Found %d offers matching your filters:",
+		"quiet_current":      "🌙 Quiet hours: %02d:00-%02d:00 (%s)",
+		"quiet_none":         "No quiet hours set.",
+		"quiet_usage":        "Usage: /quiet 22-08 Europe/Helsinki",
+		"quiet_off_hint":     "Use /quiet off to disable them.",
+		"quiet_disabled":     "✅ Quiet hours disabled.",
+		"quiet_set":          "🌙 Quiet hours set to %02d:00-%02d:00 (%s). New offers found during this time are sent when it ends.",
+		"digest_usage":       "Usage: /digest hourly, /digest daily 09:00 [timezone] or /digest off",
+		"digest_hourly":      "📬 New offers are sent as an hourly digest.",
+		"digest_daily":       "📬 New offers are sent as a daily digest at %02d:%02d (%s).",
+		"digest_off":         "Digest mode is off, new offers are sent right away.",
+		"digest_header":      "📬 *Offer Digest*\n\n%d new rental offers since the last digest:\n\n",
+		"feed_disabled":      "RSS feeds are not enabled on this bot.",
+		"feed_link":          "📰 Your personal RSS feed of offers matching your filters:\n%s",
+		"view_details":       "View Details",
+		"listed_just_now":    "Listed just now",
+		"listed_hour":        "Listed 1 hour ago",
+		"listed_hours":       "Listed %d hours ago",
+		"listed_day":         "Listed 1 day ago",
+		"listed_days":        "Listed %d days ago",
+		"recent_usage":       "Usage: /recent [days], e.g. /recent 3",
+		"recent_none_day":    "No new offers in the last day. Try /recent 7 to look further back.",
+		"recent_none_days":   "No new offers in the last %d days. Check back later!",
+		"recent_found":       "%d offers first seen in the last %d day(s):",
+		"profile_usage": "Usage:\n" +
+			"• /profile add <name> [filters] - save a profile with the given filters, or your current /filter filters\n" +
+			"• /profile list - show your profiles\n" +
+			"• /profile use <name> - activate a profile\n" +
+			"• /profile stop <name> - deactivate a profile\n" +
+			"• /profile del <name> - delete a profile\n\n" +
+			"Example: /profile add espoo city=Espoo price<=1000 rooms>=2\n" +
+			"New offers matching any active profile are sent to you, tagged with the profile name.",
+		"profiles_none":              "You have no search profiles yet.",
+		"profiles_header":            "Your search profiles:\n\n",
+		"profile_active":             "▶️ active",
+		"profile_inactive":           "⏸ inactive",
+		"profile_missing_name":       "❌ Missing profile name.",
+		"profile_invalid_name":       "❌ Invalid profile name %q. Use up to 32 letters, digits or dashes.",
+		"profile_limit":              "❌ You can save at most %d profiles. Delete one with /profile del <name>.",
+		"profile_saved":              "✅ Profile %s saved and activated.",
+		"profile_updated":            "✅ Profile %s updated and activated.",
+		"profile_not_found":          "Profile %s was not found.",
+		"profile_activated":          "▶️ Profile %s is now active.",
+		"profile_deactivated":        "⏸ Profile %s is now inactive.",
+		"profile_deleted":            "🗑 Profile %s deleted.",
+		"profile_unknown_subcommand": "❌ Unknown subcommand %q.",
+		"admin_only":                 "⛔ This command is only available to the bot administrators.",
+		"export_failed":              "Sorry, the state could not be exported.",
+		"export_caption":             "💾 Bot state with %d users and %d offers",
+		"import_usage":               "Send a file created by /export with /import as its caption, or reply /import to such a file.",
+		"import_too_large":           "❌ The file is too large to import.",
+		"import_download_failed":     "Sorry, the file could not be downloaded.",
+		"import_failed":              "❌ Import failed, the current state is kept: %v",
+		"import_done":                "✅ Imported the bot state with %d users and %d offers.",
+
+		"filter_cities_include":    "🏙 Cities: %s\n",
+		"filter_cities_exclude":    "🏙 Not in cities: %s\n",
+		"filter_districts_include": "🏘 Districts: %s\n",
+		"filter_districts_exclude": "🏘 Not in districts: %s\n",
+
+		"offer_usage": "Usage: /offer <offer ID>. The ID is shown with each offer in /list.",
+
+		"undo_done":    "↩️ %d offers of your last notification are marked as unseen and will be sent again with the next update.",
+		"undo_nothing": "There is no notification to undo.",
+
+		"searches_none":    "This bot runs a single search, you get all of its offers.",
+		"searches_header":  "🔎 Searches (✅ = you get its new offers):\n\n",
+		"searches_footer":  "\nChoose with /searches name1,name2 or get all of them with /searches all.",
+		"searches_unknown": "❌ Unknown search %s. Available searches: %s",
+		"searches_set":     "✅ You now get the new offers of: %s",
+		"searches_all":     "✅ You now get the new offers of all searches.",
+
+		"pause_usage":       "Usage: /pause 7d, /pause 12h or /pause 30m. Without a duration notifications are paused for 7 days.",
+		"pause_set":         "⏸ Notifications are paused until %s. Use /resume to continue earlier.",
+		"resume_done":       "▶️ Your pause has ended, new offers are sent again.",
+		"resume_not_paused": "Your notifications are not paused.",
+
+		"onboarding_welcome": "👋 Welcome to the Vuokraovi Rental Bot, %s!\n\n" +
+			"I will notify you about new rental offers from Vuokraovi.com. First, three quick questions about what you are looking for. " +
+			"Answer \"any\" to skip a question, or /cancel to skip the setup.",
+		"onboarding_city":      "🏙 Which city are you looking in? (e.g. Helsinki)",
+		"onboarding_price":     "💰 What is your price range in €/month? (e.g. 800-1200, or just 1200 for a maximum)",
+		"onboarding_rooms":     "🛏 How many rooms do you need at least? (e.g. 2)",
+		"onboarding_done":      "✅ All set! You will be notified about new offers matching your filters. Change them any time with /filter.",
+		"onboarding_no_offers": "No current offers match your filters yet, I will let you know when one appears.",
+		"onboarding_cancelled": "Setup skipped, you are notified about all new offers. Use /filter to set filters later.",
+		"cancel_nothing":       "There is nothing to cancel.",
+
+		"unseen_none":  "✅ You have seen every current offer matching your filters.",
+		"unseen_found": "%d matching offers you have not seen yet:",
+
+		"show_usage":   "Usage: /show price,rooms,link chooses the offer fields of your notifications and /list, /show all shows every field.\nFields: %s",
+		"show_current": "Offers show: %s",
+		"show_set":     "✅ Offers now show: %s",
+		"show_reset":   "✅ Offers show every field again.",
+
+		"share_header": "Your %d favorite offers:",
+
+		"diff_first":         "📸 Saved a snapshot of the %d current offers. Use /diff again later to see what changed.",
+		"diff_none":          "No changes since %s.",
+		"diff_header":        "Changes since %s:",
+		"diff_added":         "➕ New (%d):",
+		"diff_removed":       "➖ Removed (%d):",
+		"diff_price_changed": "💱 Price changed (%d):",
+		"diff_more":          "...and %d more",
+
+		"top_none":      "No current offers with a known price match your filters.",
+		"top_cheapest":  "💸 The %d cheapest matching offers:",
+		"top_expensive": "💎 The %d most expensive matching offers:",
+	},
+	"fi": {
+		"welcome": "👋 Tervetuloa Vuokraovi-bottiin, %s!\n\n" +
+			"Ilmoitan sinulle uusista vuokra-asunnoista Vuokraovi.comissa.\n\n" +
+			"Käytä alla olevia painikkeita tai komentoja:",
+		"current_offers":         "Tässä ovat nykyiset %d vuokra-asuntoa:",
+		"main_menu":              "Päävalikko:",
+		"use_buttons":            "Käytä alla olevia painikkeita tai komentoja:",
+		"start_first":            "Käynnistä botti ensin komennolla /start",
+		"notifications_enabled":  "✅ Ilmoitukset ovat nyt päällä. Saat tiedon uusista vuokra-asunnoista.",
+		"notifications_disabled": "🔕 Ilmoitukset ovat nyt pois päältä. Et saa tietoa uusista vuokra-asunnoista.",
+		"notifications_prompt":   "Haluatko ilmoituksia uusista vuokra-asunnoista?",
+		"reset_done":             "✅ Tilasi on nollattu. Saat nyt kaikki saatavilla olevat asunnot uudelleen.",
+		"status": "Botin tila:\n\n" +
+			"• Asuntoja yhteensä: %d\n" +
+			"• Ilmoituksesi: %s\n" +
+			"• Viimeisin päivitys: %s\n" +
+			"• Päivitysväli: %v",
+		"enabled":  "Päällä ✅",
+		"disabled": "Pois päältä 🔕",
+		"help": "🤖 *Vuokraovi-botin komennot*\n\n" +
+			"/start - Käynnistä botti ja näytä nykyiset asunnot\n" +
+			"/help - Näytä tämä ohje\n" +
+			"/list - Listaa kaikki nykyiset vuokra-asunnot\n" +
+			"/recent 3 - Listaa viime päivinä löytyneet asunnot (oletus 1)\n" +
+			"/unseen - Listaa vastaavat asunnot, joita et ole vielä nähnyt\n" +
+			"/diff - Näytä muutokset edellisen tarkistuksen jälkeen\n" +
+			"/top - Näytä edullisimmat ja kalleimmat asunnot\n" +
+			"/show price,rooms,link - Valitse näytettävät asuntojen kentät\n" +
+			"/reset - Nollaa tilasi ja saat kaikki asunnot uudelleen\n" +
+			"/undo - Saat viimeisimmän ilmoituksen asunnot uudelleen seuraavan päivityksen yhteydessä\n" +
+			"/notifications - Ilmoitukset päälle/pois\n" +
+			"/status - Näytä botin tila\n" +
+			"/photos - Lataa suodattimiasi vastaavien asuntojen kuvat zip-tiedostona (/photos fav suosikeille)\n" +
+			"/filter - Näytä tai aseta hakusuodattimet\n" +
+			"/profile - Hallitse nimettyjä hakuprofiileja (add, list, use, stop, del)\n" +
+			"/search - Hae asuntoja heti\n" +
+			"/query - Näytä sivustolle lähetettävä hakupyyntö\n" +
+			"/searches - Näytä botin haut tai valitse, minkä hakujen asuntoja saat\n" +
+			"/fav <id> - Lisää asunto suosikkeihin\n" +
+			"/unfav <id> - Poista asunto suosikeista\n" +
+			"/favorites - Listaa suosikkisi\n" +
+			"/share - Hae suosikkiesi linkit jaettavaksi\n" +
+			"/map <id> - Näytä asunnon sijainti kartalla\n" +
+			"/offer <id> - Näytä asunnon tiedot uudelleen\n" +
+			"/feed - Hae henkilökohtainen RSS-syötteesi\n" +
+			"/quiet 22-08 Europe/Helsinki - Aseta hiljaiset tunnit ilman ilmoituksia\n" +
+			"/digest daily 09:00 - Saat uudet asunnot yhtenä koosteena (hourly, daily HH:MM tai off)\n" +
+			"/pause 7d - Keskeytä ilmoitukset joksikin aikaa (esim. 7d, 12h)\n" +
+			"/resume - Lopeta tauko etuajassa\n" +
+			"/cancel - Lopeta botin kysymyksiin vastaaminen\n" +
+			"/stats - Näytä omat tilastosi\n" +
+			"/lang en - Vaihda kieltä (en, fi)\n" +
+			"/clear - Poista tietosi ja nollaa asetukset\n\n" +
+			"Voit myös käyttää alla olevia painikkeita:",
+		"clear_confirm": "⚠️ Haluatko varmasti poistaa tietosi? Tämä:\n\n" +
+			"• Poistaa kaikki näkemäsi asunnot\n" +
+			"• Nollaa ilmoitusasetuksesi\n" +
+			"• Tyhjentää viimeisimmän aktiivisuusaikasi\n\n" +
+			"Toimintoa ei voi perua.",
+		"clear_done": "✅ Tietosi on poistettu.\n\n" +
+			"• Nähdyt asunnot on nollattu\n" +
+			"• Ilmoitukset on otettu uudelleen käyttöön\n\n" +
+			"Saat nyt ilmoitukset kaikista asunnoista uudelleen.",
+		"clear_cancelled":  "Tietojen poisto peruttu. Tietosi ovat tallessa.",
+		"lang_set":         "✅ Kieleksi on asetettu suomi.",
+		"lang_usage":       "Käyttö: /lang <kieli>. Saatavilla olevat kielet: %s",
+		"lang_unsupported": "Kieli %q ei ole saatavilla. Saatavilla olevat kielet: %s",
+		"stats": "📈 *Tilastosi*\n\n" +
+			"• Nähdyt asunnot: %d\n" +
+			"• Suosikit: %d\n" +
+			"• Ilmoitukset: %s\n" +
+			"• Viimeisin ilmoitus: %s\n" +
+			"• Botin tuntemat asunnot: %d",
+		"never":                        "ei koskaan",
+		"button_list":                  "Listaa asunnot 📋",
+		"button_reset":                 "Nollaa 🔄",
+		"button_notifications":         "Ilmoitukset 🔔",
+		"button_status":                "Tila 📊",
+		"button_help":                  "Ohje ❓",
+		"button_enable_notifications":  "Ilmoitukset päälle 🔔",
+		"button_disable_notifications": "Ilmoitukset pois 🔕",
+		"button_back":                  "Takaisin päävalikkoon ↩️",
+		"button_clear_yes":             "Kyllä, poista tiedot ✅",
+		"button_clear_no":              "Ei, säilytä tiedot ❌",
+		"button_view_all":              "Näytä kaikki asunnot 📋",
+		"button_prev":                  "⬅️ Edellinen",
+		"button_next":                  "Seuraava ➡️",
+		"new_offers":                   "🏠 *Uusia vuokra-asuntoja*\n\nLöytyi %d uutta vuokra-asuntoa:\n\n",
+		"delisted_header":              "🚫 *Ei enää saatavilla*\n\n",
+		"price_changes_header":         "💰 *Hintamuutokset*\n\n",
+		"more_offers":                  "\n...ja %d muuta asuntoa. Näet kaikki asunnot komennolla /list.",
+		"list_header":                  "📋 *%d vuokra-asuntoa* (sivu %d/%d)\n\n",
+		"list_empty":                   "Vuokra-asuntoja ei ole tällä hetkellä saatavilla.",
+		"fav_usage":                    "Käyttö: /fav <asunnon ID>. ID näkyy jokaisen asunnon kohdalla /list-listauksessa.",
+		"fav_added":                    "⭐ %s lisätty suosikkeihin.",
+		"offer_not_found":              "Asuntoa %s ei löytynyt.",
+		"unfav_usage":                  "Käyttö: /unfav <asunnon ID>",
+		"map_usage":                    "Käyttö: /map <asunnon ID>. ID näkyy jokaisen asunnon kohdalla /list-listauksessa.",
+		"map_no_address":               "Asunnolla %s ei ole osoitetta, jota näyttää kartalla.",
+		"map_links":                    "📍 %s\n\nGoogle Maps: %s\nOpenStreetMap: %s",
+		"fav_removed":                  "Asunto %s poistettu suosikeista.",
+		"fav_missing":                  "Asunto %s ei ole suosikeissasi.",
+		"favorites_empty":              "Sinulla ei ole vielä suosikkeja. Lisää asunto komennolla /fav <asunnon ID>.",
+		"favorites_list":               "Suosikkisi (%d):",
+		"photos_usage":                 "Käyttö: /photos suodattimiasi vastaaville asunnoille, /photos fav suosikeillesi.",
+		"photos_empty":                 "Asuntojen kuvia ei ole tällä hetkellä saatavilla.",
+		"photos_running":               "Kuviasi kerätään vielä, odota hetki.",
+		"photos_collecting":            "Kerätään %d asunnon kuvia, tämä voi kestää hetken...",
+		"photos_failed":                "Valitettavasti kuvia ei voitu ladata juuri nyt.",
+		"photos_caption":               "📷 %d asunnon kuvat",
+		"filter_usage": "Käytettävissä olevat suodattimet:\n" +
+			"• price=MIN-MAX, price>=MIN, price<=MAX\n" +
+			"• rooms>=N (tai rooms=N)\n" +
+			"• size>=M2 (tai size=M2)\n" +
+			"• ppsqm<=EUR (vuokra per m²)\n" +
+			"• city=NIMI\n" +
+			"• city.include=NIMI,NIMI, city.exclude=..., district.include=..., district.exclude=... (tai =none)\n" +
+			"• amenities=sauna,parveke (tai amenities=none)\n" +
+			"• near=LAT,LNG radius=3km (tai near=none), unlocated=keep pitää asunnot ilman sijaintia\n\n" +
+			"Esimerkki: /filter price=0-900 rooms>=2 city=Helsinki size>=30\n" +
+			"Poista kaikki suodattimet komennolla /filter clear.",
+		"filters_none":       "Suodattimia ei ole asetettu, saat ilmoitukset kaikista uusista asunnoista.",
+		"filters_header":     "Suodattimesi:\n",
+		"filter_city":        "📍 Kaupunki: %s\n",
+		"filter_price_range": "💰 Hinta: %g-%g €\n",
+		"filter_price_min":   "💰 Hinta: vähintään %g €\n",
+		"filter_price_max":   "💰 Hinta: enintään %g €\n",
+		"filter_rooms":       "🛏 Huoneita: vähintään %d\n",
+		"filter_size":        "📐 Koko: vähintään %g m²\n",
+		"filter_ppsqm":       "📊 Vuokra: enintään %g €/m²\n",
+		"filter_amenities":   "✨ Varusteet: %s\n",
+		"filter_near":        "📌 Enintään %g km päässä pisteestä %g, %g\n",
+		"filter_unlocated":   "📌 Asunnot ilman sijaintia pidetään\n",
+		"query_header":       "🔎 Botti hakee tällä pyynnöllä:\n\n",
+		"query_failed":       "❌ Hakupyyntöä ei voitu muodostaa: %v",
+		"filters_cleared":    "✅ Suodattimet poistettu. Saat ilmoitukset kaikista uusista asunnoista.",
+		"search_wait":        "⏳ Odota %d sekuntia ennen uutta hakua.",
+		"search_started":     "🔍 Haetaan asuntoja...",
+		"search_failed":      "Valitettavasti haku epäonnistui. Yritä myöhemmin uudelleen.",
+		"search_no_match":    "Suodattimiasi vastaavia asuntoja ei ole tällä hetkellä.",
+		"search_found":       "Löytyi %d suodattimiasi vastaavaa asuntoa:",
+		"quiet_current":      "🌙 Hiljaiset tunnit: %02d:00-%02d:00 (%s)",
+		"quiet_none":         "Hiljaisia tunteja ei ole asetettu.",
+		"quiet_usage":        "Käyttö: /quiet 22-08 Europe/Helsinki",
+		"quiet_off_hint":     "Poista ne käytöstä komennolla /quiet off.",
+		"quiet_disabled":     "✅ Hiljaiset tunnit poistettu käytöstä.",
+		"quiet_set":          "🌙 Hiljaiset tunnit asetettu: %02d:00-%02d:00 (%s). Tänä aikana löytyneet uudet asunnot lähetetään niiden päätyttyä.",
+		"digest_usage":       "Käyttö: /digest hourly, /digest daily 09:00 [aikavyöhyke] tai /digest off",
+		"digest_hourly":      "📬 Uudet asunnot lähetetään koosteena kerran tunnissa.",
+		"digest_daily":       "📬 Uudet asunnot lähetetään päivittäisenä koosteena klo %02d:%02d (%s).",
+		"digest_off":         "Koosteet ovat pois päältä, uudet asunnot lähetetään heti.",
+		"digest_header":      "📬 *Asuntokooste*\n\n%d uutta vuokra-asuntoa edellisen koosteen jälkeen:\n\n",
+		"feed_disabled":      "RSS-syötteet eivät ole käytössä tässä botissa.",
+		"feed_link":          "📰 Henkilökohtainen RSS-syötteesi suodattimiasi vastaavista asunnoista:\n%s",
+		"view_details":       "Näytä tiedot",
+		"listed_just_now":    "Lisätty juuri nyt",
+		"listed_hour":        "Lisätty tunti sitten",
+		"listed_hours":       "Lisätty %d tuntia sitten",
+		"listed_day":         "Lisätty päivä sitten",
+		"listed_days":        "Lisätty %d päivää sitten",
+		"recent_usage":       "Käyttö: /recent [päivät], esim. /recent 3",
+		"recent_none_day":    "Ei uusia asuntoja viimeisen päivän aikana. Kokeile /recent 7 katsoaksesi kauemmas taaksepäin.",
+		"recent_none_days":   "Ei uusia asuntoja viimeisen %d päivän aikana. Katso myöhemmin uudelleen!",
+		"recent_found":       "%d asuntoa löytyi viimeisen %d päivän aikana:",
+		"profile_usage": "Käyttö:\n" +
+			"• /profile add <nimi> [suodattimet] - tallenna profiili annetuilla suodattimilla tai nykyisillä /filter-suodattimillasi\n" +
+			"• /profile list - näytä profiilisi\n" +
+			"• /profile use <nimi> - ota profiili käyttöön\n" +
+			"• /profile stop <nimi> - poista profiili käytöstä\n" +
+			"• /profile del <nimi> - poista profiili\n\n" +
+			"Esimerkki: /profile add espoo city=Espoo price<=1000 rooms>=2\n" +
+			"Saat uudet asunnot, jotka vastaavat mitä tahansa käytössä olevaa profiilia, profiilin nimellä merkittyinä.",
+		"profiles_none":              "Sinulla ei ole vielä hakuprofiileja.",
+		"profiles_header":            "Hakuprofiilisi:\n\n",
+		"profile_active":             "▶️ käytössä",
+		"profile_inactive":           "⏸ pois käytöstä",
+		"profile_missing_name":       "❌ Profiilin nimi puuttuu.",
+		"profile_invalid_name":       "❌ Virheellinen profiilin nimi %q. Käytä enintään 32 kirjainta, numeroa tai viivaa.",
+		"profile_limit":              "❌ Voit tallentaa enintään %d profiilia. Poista jokin komennolla /profile del <nimi>.",
+		"profile_saved":              "✅ Profiili %s tallennettu ja otettu käyttöön.",
+		"profile_updated":            "✅ Profiili %s päivitetty ja otettu käyttöön.",
+		"profile_not_found":          "Profiilia %s ei löytynyt.",
+		"profile_activated":          "▶️ Profiili %s on nyt käytössä.",
+		"profile_deactivated":        "⏸ Profiili %s on nyt pois käytöstä.",
+		"profile_deleted":            "🗑 Profiili %s poistettu.",
+		"profile_unknown_subcommand": "❌ Tuntematon alikomento %q.",
+		"admin_only":                 "⛔ Tämä komento on vain botin ylläpitäjien käytettävissä.",
+		"export_failed":              "Valitettavasti tilaa ei voitu viedä.",
+		"export_caption":             "💾 Botin tila: %d käyttäjää ja %d asuntoa",
+		"import_usage":               "Lähetä /export-komennolla luotu tiedosto kuvatekstillä /import tai vastaa tällaiseen tiedostoon komennolla /import.",
+		"import_too_large":           "❌ Tiedosto on liian suuri tuotavaksi.",
+		"import_download_failed":     "Valitettavasti tiedostoa ei voitu ladata.",
+		"import_failed":              "❌ Tuonti epäonnistui, nykyinen tila säilytetään: %v",
+		"import_done":                "✅ Botin tila tuotu: %d käyttäjää ja %d asuntoa.",
+
+		"filter_cities_include":    "🏙 Kaupungit: %s\n",
+		"filter_cities_exclude":    "🏙 Ei kaupungeissa: %s\n",
+		"filter_districts_include": "🏘 Kaupunginosat: %s\n",
+		"filter_districts_exclude": "🏘 Ei kaupunginosissa: %s\n",
+
+		"offer_usage": "Käyttö: /offer <asunnon ID>. ID näkyy jokaisen asunnon kohdalla /list-listauksessa.",
+
+		"undo_done":    "↩️ Viimeisimmän ilmoituksen %d asuntoa on merkitty näkemättömiksi, ja ne lähetetään uudelleen seuraavan päivityksen yhteydessä.",
+		"undo_nothing": "Ei peruttavaa ilmoitusta.",
+
+		"searches_none":    "Botilla on vain yksi haku, saat kaikki sen asunnot.",
+		"searches_header":  "🔎 Haut (✅ = saat sen uudet asunnot):\n\n",
+		"searches_footer":  "\nValitse komennolla /searches nimi1,nimi2 tai saat kaikki komennolla /searches all.",
+		"searches_unknown": "❌ Tuntematon haku %s. Haut: %s",
+		"searches_set":     "✅ Saat nyt näiden hakujen uudet asunnot: %s",
+		"searches_all":     "✅ Saat nyt kaikkien hakujen uudet asunnot.",
+
+		"pause_usage":       "Käyttö: /pause 7d, /pause 12h tai /pause 30m. Ilman kestoa ilmoitukset keskeytetään 7 päiväksi.",
+		"pause_set":         "⏸ Ilmoitukset on keskeytetty %s asti. Jatka aiemmin komennolla /resume.",
+		"resume_done":       "▶️ Tauko on päättynyt, uudet asunnot lähetetään taas.",
+		"resume_not_paused": "Ilmoituksiasi ei ole keskeytetty.",
+
+		"onboarding_welcome": "👋 Tervetuloa Vuokraovi-bottiin, %s!\n\n" +
+			"Ilmoitan sinulle uusista vuokra-asunnoista Vuokraovi.comissa. Ensin kolme nopeaa kysymystä siitä, mitä etsit. " +
+			"Vastaa \"kaikki\" ohittaaksesi kysymyksen tai /cancel ohittaaksesi asetukset.",
+		"onboarding_city":      "🏙 Mistä kaupungista etsit asuntoa? (esim. Helsinki)",
+		"onboarding_price":     "💰 Mikä on hintahaarukkasi €/kk? (esim. 800-1200 tai pelkkä 1200 enimmäishinnaksi)",
+		"onboarding_rooms":     "🛏 Montako huonetta tarvitset vähintään? (esim. 2)",
+		"onboarding_done":      "✅ Valmista! Saat ilmoitukset suodattimiasi vastaavista uusista asunnoista. Voit muuttaa niitä milloin tahansa komennolla /filter.",
+		"onboarding_no_offers": "Mikään nykyinen asunto ei vielä vastaa suodattimiasi, ilmoitan kun sellainen löytyy.",
+		"onboarding_cancelled": "Asetukset ohitettu, saat ilmoitukset kaikista uusista asunnoista. Aseta suodattimet myöhemmin komennolla /filter.",
+		"cancel_nothing":       "Ei peruttavaa.",
+
+		"unseen_none":  "✅ Olet nähnyt kaikki suodattimiasi vastaavat nykyiset asunnot.",
+		"unseen_found": "%d suodattimiasi vastaavaa asuntoa, joita et ole vielä nähnyt:",
+
+		"show_usage":   "Käyttö: /show price,rooms,link valitsee ilmoitusten ja /list-listan asuntojen kentät, /show all näyttää kaikki kentät.\nKentät: %s",
+		"show_current": "Asunnoista näytetään: %s",
+		"show_set":     "✅ Asunnoista näytetään nyt: %s",
+		"show_reset":   "✅ Asunnoista näytetään taas kaikki kentät.",
+
+		"share_header": "Suosikkisi (%d):",
+
+		"diff_first":         "📸 Tallensin tilannekuvan %d nykyisestä asunnosta. Näet muutokset myöhemmin komennolla /diff.",
+		"diff_none":          "Ei muutoksia %s jälkeen.",
+		"diff_header":        "Muutokset %s jälkeen:",
+		"diff_added":         "➕ Uudet (%d):",
+		"diff_removed":       "➖ Poistuneet (%d):",
+		"diff_price_changed": "💱 Hinta muuttunut (%d):",
+		"diff_more":          "...ja %d muuta",
+
+		"top_none":      "Mikään nykyinen asunto, jonka hinta tiedetään, ei vastaa suodattimiasi.",
+		"top_cheapest":  "💸 %d edullisinta vastaavaa asuntoa:",
+		"top_expensive": "💎 %d kalleinta vastaavaa asuntoa:",
+	},
+}
+
+// translate returns the message for key in the given language, falling back to
+// English when the language or the key is missing
+func translate(lang, key string, args ...interface{}) string {
+	text, ok := catalog[lang][key]
+	if !ok {
+		text, ok = catalog[defaultLanguage][key]
+	}
+	if !ok {
+		return key
+	}
+	if len(args) == 0 {
+		return text
+	}
+	return fmt.Sprintf(text, args...)
+}
+
+// supportedLanguages returns the languages of the catalog in alphabetical order
+func supportedLanguages() []string {
+	languages := make([]string, 0, len(catalog))
+	for lang := range catalog {
+		languages = append(languages, lang)
+	}
+	sort.Strings(languages)
+	return languages
+}
+
+// userLanguage returns the language chosen by the user
+func userLanguage(botState *state.BotState, chatID int64) string {
+	if lang := botState.GetUserLanguage(chatID); lang != "" {
+		return lang
+	}
+	return defaultLanguage
+}
+
+// buttonKey returns the catalog key of a keyboard button label in any language,
+// so that buttons sent before a language change keep working
+func buttonKey(label string) (string, bool) {
+	for _, messages := range catalog {
+		for key, text := range messages {
+			if strings.HasPrefix(key, "button_") && text == label {
+				return key, true
+			}
+		}
+	}
+	return "", false
+}
+
+// handleLangCommand handles the /lang command
+func handleLangCommand(bot *tgbotapi.BotAPI, botState *state.BotState, message *tgbotapi.Message) {
+	chatID := message.Chat.ID
+	lang := strings.ToLower(strings.TrimSpace(message.CommandArguments()))
+	available := strings.Join(supportedLanguages(), ", ")
+
+	var text string
+	switch {
+	case lang == "":
+		text = translate(userLanguage(botState, chatID), "lang_usage", available)
+	case catalog[lang] == nil:
+		text = translate(userLanguage(botState, chatID), "lang_unsupported", lang, available)
+	default:
+		botState.SetUserLanguage(chatID, lang)
+		text = translate(lang, "lang_set")
+	}
+
+	msg := tgbotapi.NewMessage(chatID, text)
+	msg.ReplyMarkup = createMainKeyboard(userLanguage(botState, chatID))
+	sendMessage(bot, msg)
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// userMessage creates a message sent by the user of the chat, marking a leading
+// /command as a bot command like Telegram does
+func userMessage(chatID int64, text string) *tgbotapi.Message {
+	message := &tgbotapi.Message{
+		MessageID: 1,
+		From:      &tgbotapi.User{ID: chatID, FirstName: "Test"},
+		Chat:      &tgbotapi.Chat{ID: chatID},
+		Text:      text,
+	}
+	if strings.HasPrefix(text, "/") {
+		length := len(strings.Fields(text)[0])
+		message.Entities = []tgbotapi.MessageEntity{{Type: "bot_command", Offset: 0, Length: length}}
+	}
+	return message
+}
+
+// replyKeyboardLabels returns the button labels of the reply keyboard sent with a message
+func replyKeyboardLabels(t *testing.T, values map[string][]string) []string {
+	t.Helper()
+	var keyboard tgbotapi.ReplyKeyboardMarkup
+	if err := json.Unmarshal([]byte(values["reply_markup"][0]), &keyboard); err != nil {
+		t.Fatalf("invalid reply markup: %v", err)
+	}
+	var labels []string
+	for _, row := range keyboard.Keyboard {
+		for _, button := range row {
+			labels = append(labels, button.Text)
+		}
+	}
+	return labels
+}
+
+func TestCatalogLanguagesHaveTheSameKeys(t *testing.T) {
+	for lang, messages := range catalog {
+		for key := range catalog[defaultLanguage] {
+			if _, ok := messages[key]; !ok {
+				t.Errorf("%s: missing translation of %q", lang, key)
+			}
+		}
+		for key := range messages {
+			if _, ok := catalog[defaultLanguage][key]; !ok {
+				t.Errorf("%s: %q has no English message", lang, key)
+			}
+		}
+	}
+}
+
+func TestButtonKeyMatchesLabelsOfAllLanguages(t *testing.T) {
+	for lang, messages := range catalog {
+		for key, label := range messages {
+			if !strings.HasPrefix(key, "button_") {
+				continue
+			}
+			if got, ok := buttonKey(label); !ok || got != key {
+				t.Errorf("%s: buttonKey(%q) = %q, %v, want %q", lang, label, got, ok, key)
+			}
+		}
+	}
+	if _, ok := buttonKey("hello"); ok {
+		t.Error("buttonKey matched a plain message")
+	}
+}
+
+func TestLangCommandSwitchesMessagesAndKeyboard(t *testing.T) {
+	bot, fake := newFakeTelegram(t)
+	botState := newTestBotState(t)
+
+	handleMessage(bot, botState, userMessage(1, "/start"), BotConfig{})
+	if text := fake.calls("sendMessage")[0].Get("text"); !strings.HasPrefix(text, "👋 Welcome") {
+		t.Fatalf("welcome = %q, want English", text)
+	}
+
+	handleMessage(bot, botState, userMessage(1, "/lang fi"), BotConfig{})
+	fake.reset()
+	handleMessage(bot, botState, userMessage(1, "/start"), BotConfig{})
+
+	sent := fake.calls("sendMessage")
+	if text := sent[0].Get("text"); !strings.HasPrefix(text, "👋 Tervetuloa") {
+		t.Errorf("welcome = %q, want Finnish after /lang fi", text)
+	}
+	if labels := replyKeyboardLabels(t, sent[0]); labels[0] != translate("fi", "button_list") {
+		t.Errorf("keyboard = %v, want Finnish labels", labels)
+	}
+
+	// Finnish and English labels both work
+	for _, label := range []string{translate("fi", "button_status"), translate("en", "button_status")} {
+		fake.reset()
+		handleMessage(bot, botState, userMessage(1, label), BotConfig{})
+		if text := fake.calls("sendMessage")[0].Get("text"); !strings.HasPrefix(text, "Botin tila") {
+			t.Errorf("%q: reply %q, want the Finnish status", label, text)
+		}
+	}
+}
+
+func TestLocalizedHandlerReplies(t *testing.T) {
+	bot, fake := newFakeTelegram(t)
+	botState := newTestBotState(t)
+	botState.AddUser(&tgbotapi.User{FirstName: "Test"}, 1)
+	botState.SetUserLanguage(1, "fi")
+
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"/filter price<=900", "✅ Suodattimesi:\n💰 Hinta: enintään 900 €"},
+		{"/fav", translate("fi", "fav_usage")},
+		{"/favorites", translate("fi", "favorites_empty")},
+		{"/list", translate("fi", "list_empty")},
+		{"/photos", translate("fi", "photos_empty")},
+		{"/quiet off", translate("fi", "quiet_disabled")},
+		{"/digest", translate("fi", "digest_off")},
+		{"/feed", translate("fi", "feed_disabled")},
+	}
+	for _, tt := range tests {
+		fake.reset()
+		handleMessage(bot, botState, userMessage(1, tt.text), BotConfig{})
+		sent := fake.calls("sendMessage")
+		if len(sent) == 0 || !strings.HasPrefix(sent[0].Get("text"), tt.want) {
+			t.Errorf("%s: sent %v, want a reply starting with %q", tt.text, sent, tt.want)
+		}
+	}
+}
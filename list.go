@@ -0,0 +1,176 @@
+package main
+
+import (
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/aqaliarept/vuokraovi-bot/state"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+const (
+	// listPageSize is the number of offers shown on one /list page
+	listPageSize = 5
+	// listCallbackPrefix prefixes the callback data of the /list navigation buttons
+	listCallbackPrefix = "list:"
+)
+
+// sortedKnownOffers returns the known offers in a stable order so pages do not shift between clicks
+func sortedKnownOffers(botState *state.BotState) []state.RentalOffer {
+	offers := make([]state.RentalOffer, 0)
+	for _, offer := range botState.GetKnownOffers() {
+		offers = append(offers, offer)
+	}
+	sort.Slice(offers, func(i, j int) bool {
+		return offers[i].Link < offers[j].Link
+	})
+	return offers
+}
+
+// pageOffers returns the offers on the given page, clamping the page to the valid range.
+// It also returns the clamped page and the total number of pages.
+func pageOffers(offers []state.RentalOffer, page, size int) ([]state.RentalOffer, int, int) {
+	pages := (len(offers) + size - 1) / size
+	if pages == 0 {
+		return nil, 0, 0
+	}
+	if page >= pages {
+		page = pages - 1
+	}
+	if page < 0 {
+		page = 0
+	}
+
+	start := page * size
+	end := start + size
+	if end > len(offers) {
+		end = len(offers)
+	}
+	return offers[start:end], page, pages
+}
+
+// encodeListCallback builds the callback data of a button opening the given page
+func encodeListCallback(page int) string {
+	return listCallbackPrefix + strconv.Itoa(page)
+}
+
+// decodeListCallback extracts the page from the callback data of a navigation button
+func decodeListCallback(data string) (int, bool) {
+	if !strings.HasPrefix(data, listCallbackPrefix) {
+		return 0, false
+	}
+	page, err := strconv.Atoi(strings.TrimPrefix(data, listCallbackPrefix))
+	if err != nil || page < 0 {
+		return 0, false
+	}
+	return page, true
+}
+
+// offerMarkers returns the markers put in front of an offer in /list: 🆕 when the
+// user has not seen it and ⭐ when it is one of their favorites
+func offerMarkers(user *state.UserState, offer state.RentalOffer) string {
+	if user == nil {
+		return ""
+	}
+	var markers string
+	if !user.SeenOffers.Has(offer.Link) {
+		markers += "🆕 "
+	}
+	if user.Favorites[offer.Link] {
+		markers += "⭐ "
+	}
+	return markers
+}
+
+// renderListPage builds the text and navigation buttons of a /list page with
+// the given offer fields, marking the offers new to the user and their favorites
+func renderListPage(offers []state.RentalOffer, page int, fields []string, user *state.UserState, lang string) (string, *tgbotapi.InlineKeyboardMarkup) {
+	pageItems, page, pages := pageOffers(offers, page, listPageSize)
+
+	text := escapeMarkdownV2Bold(translate(lang, "list_header", len(offers), page+1, pages))
+	for _, offer := range pageItems {
+		text += offerMarkers(user, offer) + renderOfferFields(offer, fields, "", lang)
+	}
+
+	var buttons []tgbotapi.InlineKeyboardButton
+	if page > 0 {
+		buttons = append(buttons, tgbotapi.NewInlineKeyboardButtonData(translate(lang, "button_prev"), encodeListCallback(page-1)))
+	}
+	if page < pages-1 {
+		buttons = append(buttons, tgbotapi.NewInlineKeyboardButtonData(translate(lang, "button_next"), encodeListCallback(page+1)))
+	}
+	if len(buttons) == 0 {
+		return text, nil
+	}
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(buttons)
+	return text, &keyboard
+}
+
+// sendListPage sends the first page of the known offers to a chat
+func sendListPage(bot *tgbotapi.BotAPI, botState *state.BotState, chatID int64) {
+	lang := userLanguage(botState, chatID)
+	offers := sortedKnownOffers(botState)
+	if len(offers) == 0 {
+		msg := tgbotapi.NewMessage(chatID, translate(lang, "list_empty"))
+		msg.ReplyMarkup = createMainKeyboard(lang)
+		sendMessage(bot, msg)
+		return
+	}
+
+	user, _ := botState.GetUser(chatID)
+	text, keyboard := renderListPage(offers, 0, displayFields(botState, chatID), user, lang)
+	msg := tgbotapi.NewMessage(chatID, text)
+	msg.ParseMode = "MarkdownV2"
+	msg.DisableWebPagePreview = true
+	if keyboard != nil {
+		msg.ReplyMarkup = *keyboard
+	}
+	sendMessage(bot, msg)
+}
+
+// editListPage replaces a /list message with another page
+func editListPage(bot *tgbotapi.BotAPI, botState *state.BotState, message *tgbotapi.Message, page int) {
+	lang := userLanguage(botState, message.Chat.ID)
+	offers := sortedKnownOffers(botState)
+	if len(offers) == 0 {
+		edit := tgbotapi.NewEditMessageText(message.Chat.ID, message.MessageID, translate(lang, "list_empty"))
+		sendMessage(bot, edit)
+		return
+	}
+
+	user, _ := botState.GetUser(message.Chat.ID)
+	text, keyboard := renderListPage(offers, page, displayFields(botState, message.Chat.ID), user, lang)
+	edit := tgbotapi.NewEditMessageText(message.Chat.ID, message.MessageID, text)
+	edit.ParseMode = "MarkdownV2"
+	edit.DisableWebPagePreview = true
+	edit.ReplyMarkup = keyboard
+	if _, err := sendMessage(bot, edit); err != nil {
+		log.Printf("Error editing offer list for user %d: %v", message.Chat.ID, err)
+	}
+}
+
+// handleCallbackQuery handles presses of inline keyboard buttons
+func handleCallbackQuery(bot *tgbotapi.BotAPI, botState *state.BotState, query *tgbotapi.CallbackQuery) {
+	// Answer the query so the client stops showing a spinner
+	defer func() {
+		if _, err := bot.Request(tgbotapi.NewCallback(query.ID, "")); err != nil {
+			log.Printf("Error answering callback query: %v", err)
+		}
+	}()
+
+	if query.Message == nil {
+		return
+	}
+
+	switch {
+	case query.Data == "list_all":
+		// "View All Offers" button attached to notifications
+		sendListPage(bot, botState, query.Message.Chat.ID)
+	default:
+		if page, ok := decodeListCallback(query.Data); ok {
+			editListPage(bot, botState, query.Message, page)
+		}
+	}
+}
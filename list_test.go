@@ -0,0 +1,155 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aqaliarept/vuokraovi-bot/state"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// numberedOffers creates n offers with distinct links
+func numberedOffers(n int) []state.RentalOffer {
+	offers := make([]state.RentalOffer, n)
+	for i := range offers {
+		offers[i] = testOffer(fmt.Sprintf("https://example.com/%02d", i), "800 €/kk")
+		// Distinct addresses, so the offers are not merged as duplicates
+		offers[i].Address = fmt.Sprintf("Testikatu %d, Helsinki", i)
+	}
+	return offers
+}
+
+func TestPageOffers(t *testing.T) {
+	offers := numberedOffers(12)
+
+	tests := []struct {
+		page      int
+		wantFirst string
+		wantLen   int
+		wantPage  int
+	}{
+		{0, "https://example.com/00", 5, 0},
+		{1, "https://example.com/05", 5, 1},
+		{2, "https://example.com/10", 2, 2},
+		{7, "https://example.com/10", 2, 2},
+		{-1, "https://example.com/00", 5, 0},
+	}
+
+	for _, tt := range tests {
+		items, page, pages := pageOffers(offers, tt.page, 5)
+		if pages != 3 || page != tt.wantPage || len(items) != tt.wantLen || items[0].Link != tt.wantFirst {
+			t.Errorf("page %d: got %d items from %s on page %d of %d", tt.page, len(items), items[0].Link, page, pages)
+		}
+	}
+
+	if items, page, pages := pageOffers(nil, 0, 5); items != nil || page != 0 || pages != 0 {
+		t.Errorf("no offers: got %v, page %d of %d", items, page, pages)
+	}
+}
+
+func TestListCallbackRoundTrip(t *testing.T) {
+	for _, page := range []int{0, 1, 42} {
+		if got, ok := decodeListCallback(encodeListCallback(page)); !ok || got != page {
+			t.Errorf("decode(encode(%d)) = %d, %v", page, got, ok)
+		}
+	}
+	for _, data := range []string{"list_all", "list:", "list:-1", "list:x", "fav:1"} {
+		if _, ok := decodeListCallback(data); ok {
+			t.Errorf("decodeListCallback(%q) accepted invalid data", data)
+		}
+	}
+}
+
+func TestRenderListPageButtons(t *testing.T) {
+	offers := numberedOffers(12)
+
+	if _, keyboard := renderListPage(offers, 0, offerFields, nil, "en"); keyboard == nil || len(keyboard.InlineKeyboard[0]) != 1 || *keyboard.InlineKeyboard[0][0].CallbackData != "list:1" {
+		t.Errorf("first page should only link to the next page: %+v", keyboard)
+	}
+	text, keyboard := renderListPage(offers, 1, offerFields, nil, "en")
+	if keyboard == nil || len(keyboard.InlineKeyboard[0]) != 2 || !strings.Contains(text, "page 2/3") {
+		t.Errorf("middle page: %q, %+v", text, keyboard)
+	}
+	if _, keyboard := renderListPage(numberedOffers(3), 0, offerFields, nil, "en"); keyboard != nil {
+		t.Errorf("a single page should have no buttons: %+v", keyboard)
+	}
+}
+
+func TestRenderListPageMarksNewAndFavoriteOffers(t *testing.T) {
+	offers := numberedOffers(4)
+	for i := range offers {
+		offers[i].Title = fmt.Sprintf("Offer %d", i)
+	}
+	user := &state.UserState{
+		SeenOffers: state.SeenTimes{offers[0].Link: time.Now(), offers[1].Link: time.Now()},
+		Favorites:  map[string]bool{offers[1].Link: true, offers[2].Link: true},
+	}
+
+	text, _ := renderListPage(offers, 0, []string{"title"}, user, "en")
+	for _, want := range []string{
+		"\n*Offer 0*\n",
+		"\n⭐ *Offer 1*\n",
+		"\n🆕 ⭐ *Offer 2*\n",
+		"\n🆕 *Offer 3*\n",
+	} {
+		if !strings.Contains(text, want) {
+			t.Errorf("list lacks %q:\n%s", want, text)
+		}
+	}
+	if got := strings.Count(text, "🆕"); got != 2 {
+		t.Errorf("%d offers marked as new, want 2", got)
+	}
+	if got := strings.Count(text, "⭐"); got != 2 {
+		t.Errorf("%d offers marked as favorites, want 2", got)
+	}
+
+	if text, _ := renderListPage(offers, 0, []string{"title"}, nil, "en"); strings.Contains(text, "🆕") {
+		t.Errorf("offers marked without a user:\n%s", text)
+	}
+}
+
+// callbackQuery creates a button press on a bot message in chat 1
+func callbackQuery(data string) *tgbotapi.CallbackQuery {
+	return &tgbotapi.CallbackQuery{
+		ID:      "query",
+		Data:    data,
+		Message: &tgbotapi.Message{MessageID: 7, Chat: &tgbotapi.Chat{ID: 1}},
+	}
+}
+
+func TestListAllCallbackSendsList(t *testing.T) {
+	bot, fake := newFakeTelegram(t)
+	botState := newTestBotState(t)
+	botState.ApplyOffers(numberedOffers(12))
+
+	handleCallbackQuery(bot, botState, callbackQuery("list_all"))
+
+	sent := fake.calls("sendMessage")
+	if len(sent) != 1 || sent[0].Get("chat_id") != "1" || !strings.Contains(sent[0].Get("text"), "page 1/3") {
+		t.Fatalf("sent %v, want the first list page", sent)
+	}
+	if answers := fake.calls("answerCallbackQuery"); len(answers) != 1 || answers[0].Get("callback_query_id") != "query" {
+		t.Errorf("answers = %v, want the query answered", answers)
+	}
+}
+
+func TestListNavigationEditsMessage(t *testing.T) {
+	bot, fake := newFakeTelegram(t)
+	botState := newTestBotState(t)
+	botState.ApplyOffers(numberedOffers(12))
+
+	handleCallbackQuery(bot, botState, callbackQuery(encodeListCallback(2)))
+
+	edits := fake.calls("editMessageText")
+	if len(edits) != 1 || edits[0].Get("message_id") != "7" || !strings.Contains(edits[0].Get("text"), "page 3/3") {
+		t.Fatalf("edits = %v, want message 7 showing the last page", edits)
+	}
+	if sent := fake.calls("sendMessage"); len(sent) != 0 {
+		t.Errorf("navigation sent %d new messages, want none", len(sent))
+	}
+	if answers := fake.calls("answerCallbackQuery"); len(answers) != 1 {
+		t.Errorf("the query was answered %d times, want once", len(answers))
+	}
+}
@@ -1,51 +1,199 @@
 package main
 
 import (
+	"encoding/csv"
+	"encoding/json"
 	"flag"
 	"fmt"
+	"io"
 	"log"
+	"log/slog"
 	"os"
+	"path/filepath"
+	"sort"
 	"strings"
 	"time"
 
+	"github.com/aqaliarept/vuokraovi-bot/scraper"
+	"github.com/aqaliarept/vuokraovi-bot/state"
 	"github.com/fatih/color"
 )
 
-// RentalOffer represents a rental property listing
-// This should match the definition in parser.go
-type RentalOffer struct {
-	Title     string
-	Address   string
-	Price     string
-	Size      string
-	Rooms     string
-	Available string
-	Link      string
-}
-
 func main() {
 	// Define command-line flags
 	maxPagesPtr := flag.Int("limit", 0, "Maximum number of pages to query (0 = no limit)")
+	maxOffersPtr := flag.Int("max-offers", 0, "Maximum number of offers to collect (0 = no limit)")
+	pageCapPtr := flag.Int("page-cap", scraper.DefaultPageCap, "Pages fetched at most even without -limit, guarding against endless paging")
 	verbosePtr := flag.Bool("verbose", false, "Enable verbose logging")
 	formDataFilePtr := flag.String("form", "form_data.txt", "Path to form data file")
+	delayPtr := flag.Duration("delay", 500*time.Millisecond, "Pause between two result pages, e.g. 1s (0 = no pause)")
+	timeoutPtr := flag.Duration("timeout", 30*time.Second, "Timeout for a single HTTP request (0 = no timeout)")
+	proxyPtr := flag.String("proxy", "", "Proxy URL for requests (http://, https:// or socks5://)")
+	userAgentsPtr := flag.String("user-agents", "", "Path to a file with user agents to rotate, one per line")
+	localePtr := flag.String("locale", "fi", "Site locale to scrape (fi, sv or en)")
+	selectorsPtr := flag.String("selectors", "", "Path to a JSON file overriding the HTML selectors")
+	sortPtr := flag.String("sort", "", "Sort results by price, size, rooms or available (default: scrape order)")
+	descPtr := flag.Bool("desc", false, "Sort results in descending order")
+	outPathPtr := flag.String("out", "", "Write results to this file instead of stdout")
+	outputPtr := flag.String("output", "text", "Output format in console mode: text, json, csv or rss")
+	logFormatPtr := flag.String("log-format", "text", "Log format: text or json")
+	cityPtr := flag.String("city", "", "Search location, overriding the form data")
+	minPricePtr := flag.Int("min-price", 0, "Minimum monthly rent in euros, overriding the form data")
+	maxPricePtr := flag.Int("max-price", 0, "Maximum monthly rent in euros, overriding the form data")
+	minRoomsPtr := flag.Int("min-rooms", 0, "Minimum number of rooms, overriding the form data")
+	typePtr := flag.String("type", "", "Comma separated realty type codes of the site, overriding the form data")
+	printURLPtr := flag.Bool("print-url", false, "Print the search request URL and the decoded form fields without fetching")
+	requirePtr := flag.String("require", "", "Comma separated amenities the offers must all have, e.g. sauna,parveke")
+	includeCityPtr := flag.String("include-city", "", "Comma separated cities to keep offers of")
+	excludeCityPtr := flag.String("exclude-city", "", "Comma separated cities to drop offers of")
+	includeDistrictPtr := flag.String("include-district", "", "Comma separated districts to keep offers of")
+	excludeDistrictPtr := flag.String("exclude-district", "", "Comma separated districts to drop offers of")
+	fallbackThresholdPtr := flag.Float64("fallback-threshold", 0.5, "Fraction of offers missing a price that triggers the fallback selectors (0 = disabled)")
 
 	// Bot mode flags
 	botModePtr := flag.Bool("bot", false, "Run in Telegram bot mode")
 	token := os.Getenv("TELEGRAM_BOT_TOKEN")
 	updateIntervalPtr := flag.Int("interval", 30, "Update interval in minutes (for bot mode)")
+	jitterPtr := flag.Float64("jitter", 0, "Randomly shift each update interval by up to this fraction, e.g. 0.1 for ±10% (for bot mode)")
 	dataDirPtr := flag.String("data", "./data", "Directory to store persistent data (for bot mode)")
+	storePtr := flag.String("store", "json", "State storage backend: json or sqlite (for bot mode)")
+	saveIntervalPtr := flag.Duration("save-interval", 0, "Save the state at most this often instead of on every change, e.g. 10s, 0 = every change (for bot mode)")
+	delistAfterPtr := flag.Int("delist-after", 3, "Consecutive updates an offer may be missing before it is delisted (for bot mode)")
+	maxSeenPtr := flag.Int("max-seen", state.DefaultMaxSeenOffers, "Seen offers remembered per user before the oldest are forgotten, 0 = no limit (for bot mode)")
+	offerMaxAgePtr := flag.Duration("offer-max-age", 90*24*time.Hour, "Drop delisted offers first seen longer ago than this, 0 = keep forever (for bot mode)")
+	dryRunPtr := flag.Bool("dry-run", false, "Fetch and log new offers without notifying users or saving the state (for bot mode)")
+	notifyDelistedPtr := flag.Bool("notify-delisted", false, "Notify users when an offer they have seen is delisted (for bot mode)")
+	feedAddrPtr := flag.String("feed-addr", "", "Address to serve per-user RSS feeds on, e.g. :8080 (for bot mode)")
+	feedURLPtr := flag.String("feed-url", "", "Public base URL of the RSS feed server (for bot mode)")
+	webhookURLPtr := flag.String("webhook-url", "", "Public URL for receiving updates via webhook instead of long polling (for bot mode)")
+	listenAddrPtr := flag.String("listen", ":8443", "Address the webhook server listens on (for bot mode)")
+	metricsAddrPtr := flag.String("metrics-addr", "", "Address to serve Prometheus metrics on, e.g. :9090 (for bot mode)")
+	pageCacheTTLPtr := flag.Duration("page-cache-ttl", 0, "Serve result pages fetched less than this long ago from memory, e.g. 2m, 0 = no cache (for bot mode)")
+	webhookOutPtr := flag.String("webhook-out", "", "URL receiving the new offers of every update as JSON, signed with $WEBHOOK_OUT_SECRET (for bot mode)")
+	healthAddrPtr := flag.String("health-addr", "", "Address to serve /healthz and /readyz on, e.g. :8081 (for bot mode)")
+	messagesPerSecondPtr := flag.Float64("messages-per-second", defaultMessagesPerSecond, "Maximum Telegram messages sent per second across all chats, 0 = no limit (for bot mode)")
+	maxOffersPerNotificationPtr := flag.Int("max-offers-per-notification", defaultMaxOffersPerNotification, "Offers listed in one new offers notification, the rest are summed up (for bot mode)")
+	moreOffersTextPtr := flag.String("more-offers-text", "", "Note below a cut notification, %d is the number of offers left out (for bot mode, default: translated note)")
+	notificationTemplatePtr := flag.String("notification-template", "", "Path to a text/template file rendering each offer of the notifications (for bot mode)")
+	var searches []Search
+	flag.Func("search", "Named search as name=path/to/form_data.txt, repeat for several searches users can subscribe to (for bot mode)", func(value string) error {
+		var err error
+		searches, err = addSearch(searches, value)
+		return err
+	})
+	adminsPtr := flag.String("admins", "", "Comma separated chat IDs allowed to use /export and /import (for bot mode)")
+	persistCookiesPtr := flag.Bool("persist-cookies", false, "Persist site cookies in the data directory across restarts (for bot mode)")
 
 	flag.Parse()
+	if err := applyEnvFlags(flag.CommandLine, os.LookupEnv); err != nil {
+		log.Fatalf("Error: %v", err)
+	}
+
+	// Search options are applied on top of the form data file only when -form is given
+	formFileSet := false
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == "form" {
+			formFileSet = true
+		}
+	})
+	formOptions := FormOptions{
+		City:     *cityPtr,
+		MinPrice: *minPricePtr,
+		MaxPrice: *maxPricePtr,
+		MinRooms: *minRoomsPtr,
+		Types:    splitList(*typePtr),
+	}
+
+	if err := scraper.ValidateLocale(*localePtr); err != nil {
+		log.Fatalf("Invalid -locale: %v", err)
+	}
+	requiredAmenities, err := parseAmenities(*requirePtr)
+	if err != nil {
+		log.Fatalf("Invalid -require: %v", err)
+	}
+	resultFilters := state.Filters{
+		Amenities:        requiredAmenities,
+		IncludeCities:    state.NormalizePlaces(splitList(*includeCityPtr)),
+		ExcludeCities:    state.NormalizePlaces(splitList(*excludeCityPtr)),
+		IncludeDistricts: state.NormalizePlaces(splitList(*includeDistrictPtr)),
+		ExcludeDistricts: state.NormalizePlaces(splitList(*excludeDistrictPtr)),
+	}
+	if *sortPtr != "" && offerSortKeys[*sortPtr] == nil {
+		log.Fatalf("Invalid -sort %q (valid values: price, size, rooms, available)", *sortPtr)
+	}
+	if *outputPtr != "text" && *outputPtr != "json" && *outputPtr != "csv" && *outputPtr != "rss" {
+		log.Fatalf("Invalid -output %q (valid values: text, json, csv, rss)", *outputPtr)
+	}
+	if err := setupLogging(*logFormatPtr, os.Stderr); err != nil {
+		log.Fatalf("Invalid -log-format: %v", err)
+	}
 
 	// Check if bot mode is enabled
 	if *botModePtr {
+		if *jitterPtr < 0 || *jitterPtr >= 1 {
+			log.Fatalf("Invalid -jitter %v (must be at least 0 and below 1)", *jitterPtr)
+		}
+		if *maxOffersPerNotificationPtr < 1 {
+			log.Fatalf("Invalid -max-offers-per-notification %d (must be at least 1)", *maxOffersPerNotificationPtr)
+		}
+		if *moreOffersTextPtr != "" && strings.Count(*moreOffersTextPtr, "%d") != 1 {
+			log.Fatalf("Invalid -more-offers-text %q (must contain %%d once)", *moreOffersTextPtr)
+		}
+		adminChatIDs, err := parseAdminIDs(*adminsPtr)
+		if err != nil {
+			log.Fatalf("Invalid -admins: %v", err)
+		}
+		var notificationTemplate string
+		if *notificationTemplatePtr != "" {
+			if notificationTemplate, err = loadNotificationTemplate(*notificationTemplatePtr); err != nil {
+				log.Fatalf("Error: %v", err)
+			}
+		}
+
 		// Create bot config
 		config := BotConfig{
 			Token:          token,
 			UpdateInterval: time.Duration(*updateIntervalPtr) * time.Minute,
 			DataDir:        *dataDirPtr,
 			FormDataFile:   *formDataFilePtr,
+			FormFileSet:    formFileSet,
+			FormOptions:    formOptions,
+			Searches:       searches,
 			MaxPages:       *maxPagesPtr,
+			Locale:         *localePtr,
+			RequestTimeout: *timeoutPtr,
+			ProxyURL:       *proxyPtr,
+			UserAgentsFile: *userAgentsPtr,
+			SelectorsFile:  *selectorsPtr,
+			PersistCookies: *persistCookiesPtr,
+			StoreBackend:   *storePtr,
+			DelistAfter:    *delistAfterPtr,
+			MaxSeenOffers:  *maxSeenPtr,
+			NotifyDelisted: *notifyDelistedPtr,
+			OfferMaxAge:    *offerMaxAgePtr,
+			FeedAddr:       *feedAddrPtr,
+			FeedURL:        *feedURLPtr,
+			WebhookURL:     *webhookURLPtr,
+			ListenAddr:     *listenAddrPtr,
+			MetricsAddr:    *metricsAddrPtr,
+			HealthAddr:     *healthAddrPtr,
+			DryRun:         *dryRunPtr,
+			AdminChatIDs:   adminChatIDs,
+
+			FallbackThreshold: *fallbackThresholdPtr,
+			MaxOffers:         *maxOffersPtr,
+			PageCap:           *pageCapPtr,
+			SaveInterval:      *saveIntervalPtr,
+			PageDelay:         *delayPtr,
+			UpdateJitter:      *jitterPtr,
+			MessagesPerSecond: *messagesPerSecondPtr,
+
+			NotificationTemplate:     notificationTemplate,
+			WebhookOut:               *webhookOutPtr,
+			WebhookOutSecret:         os.Getenv("WEBHOOK_OUT_SECRET"),
+			PageCacheTTL:             *pageCacheTTLPtr,
+			MaxOffersPerNotification: *maxOffersPerNotificationPtr,
+			MoreOffersText:           *moreOffersTextPtr,
 		}
 
 		// Run bot
@@ -57,34 +205,217 @@ func main() {
 	}
 
 	// Console mode (original functionality)
-	// Set up logging
-	log.SetOutput(os.Stdout)
-	log.SetFlags(log.LstdFlags | log.Lshortfile)
+	// Set up logging, keeping stdout clean for machine-readable output.
+	// JSON logs always go to stderr as set up by setupLogging.
+	if *logFormatPtr == "text" {
+		if *outputPtr == "text" {
+			log.SetOutput(os.Stdout)
+		}
+		log.SetFlags(log.LstdFlags | log.Lshortfile)
+	}
 
 	// Create website client
-	website, err := NewWebSite(*verbosePtr)
+	website, err := scraper.New(*verbosePtr)
 	if err != nil {
 		log.Fatalf("Error creating website client: %v", err)
 	}
+	website.Locale = *localePtr
+	website.Timeout = *timeoutPtr
+	if err := website.SetProxy(*proxyPtr); err != nil {
+		log.Fatalf("Error configuring proxy: %v", err)
+	}
+	if *userAgentsPtr != "" {
+		if website.UserAgents, err = scraper.LoadUserAgents(*userAgentsPtr); err != nil {
+			log.Fatalf("Error loading user agents: %v", err)
+		}
+	}
+	website.FallbackThreshold = *fallbackThresholdPtr
+	website.MaxOffers = *maxOffersPtr
+	website.PageCap = *pageCapPtr
+	website.PageDelay = *delayPtr
+	if *selectorsPtr != "" {
+		if website.Parser, err = scraper.LoadParserConfig(*selectorsPtr); err != nil {
+			log.Fatalf("Error loading selectors: %v", err)
+		}
+	}
 
-	// Read form data from file
-	formData, err := os.ReadFile(*formDataFilePtr)
+	// Read form data from file or build it from the search options
+	formData, err := loadFormData(*formDataFilePtr, formFileSet, formOptions)
 	if err != nil {
-		log.Fatalf("Error reading form data from %s: %v", *formDataFilePtr, err)
+		log.Fatalf("Error: %v", err)
+	}
+
+	if *printURLPtr {
+		preview, err := queryPreview(website.InitialURL(), formData)
+		if err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+		fmt.Println(preview)
+		return
 	}
 
 	// Fetch rental offers
-	offers, err := website.FetchRentalOffers(string(formData), *maxPagesPtr)
+	offers, err := website.FetchRentalOffers(formData, *maxPagesPtr)
 	if err != nil {
 		log.Fatalf("Error fetching rental offers: %v", err)
 	}
+	if website.TotalResults > len(offers) {
+		log.Printf("Showing %d of %d matching offers, raise -limit or -max-offers to see more", len(offers), website.TotalResults)
+	}
+
+	offers = keepMatching(offers, resultFilters)
+	if *sortPtr != "" {
+		sortOffers(offers, *sortPtr, *descPtr)
+	}
 
 	// Print results
-	printResults(offers)
+	if err := writeResults(*outPathPtr, *outputPtr, offers); err != nil {
+		log.Fatalf("Error writing results: %v", err)
+	}
+}
+
+// setupLogging configures the logger for the given -log-format. "text" keeps the
+// standard human-readable logger, while "json" writes every log line, including
+// those of the log package, as a JSON object to w so it can be ingested by log
+// collectors such as Loki or ELK
+func setupLogging(format string, w io.Writer) error {
+	switch format {
+	case "text":
+		return nil
+	case "json":
+		slog.SetDefault(slog.New(slog.NewJSONHandler(w, nil)))
+		return nil
+	default:
+		return fmt.Errorf("unknown format %q (valid values: text, json)", format)
+	}
+}
+
+// writeResults writes the offers in the given format to path, or to stdout when path is empty
+func writeResults(path, format string, offers []scraper.RentalOffer) error {
+	if path == "" {
+		return formatResults(os.Stdout, format, offers)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create output directory: %w", err)
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create output file: %w", err)
+	}
+
+	// Files should not contain terminal color codes
+	color.NoColor = true
+
+	if err := formatResults(file, format, offers); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
+
+// formatResults writes the offers to w in the given format
+func formatResults(w io.Writer, format string, offers []scraper.RentalOffer) error {
+	switch format {
+	case "json":
+		return writeJSON(w, offers)
+	case "csv":
+		return writeCSV(w, offers)
+	case "rss":
+		data, err := buildRSSFeed("Vuokraovi rental offers", toStateOffers(offers), time.Now())
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(data)
+		return err
+	}
+	printResults(w, offers)
+	return nil
+}
+
+// writeJSON writes the rental offers as an indented JSON array
+func writeJSON(w io.Writer, offers []scraper.RentalOffer) error {
+	if offers == nil {
+		offers = []scraper.RentalOffer{}
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(offers)
+}
+
+// writeCSV writes the rental offers as CSV with a header row
+func writeCSV(w io.Writer, offers []scraper.RentalOffer) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{"Title", "Address", "Price", "Size", "Rooms", "Available", "Link"}); err != nil {
+		return err
+	}
+	for _, offer := range offers {
+		record := []string{offer.Title, offer.Address, offer.Price, offer.Size, offer.Rooms, offer.Available, offer.Link}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// offerSortKeys returns the numeric value offers are sorted by for each -sort key.
+// A zero value means the value is missing. Immediately available offers sort
+// before any move-in date.
+var offerSortKeys = map[string]func(scraper.RentalOffer) float64{
+	"price": func(o scraper.RentalOffer) float64 { return o.PriceEUR },
+	"size":  func(o scraper.RentalOffer) float64 { return o.SizeSqm },
+	"rooms": func(o scraper.RentalOffer) float64 { return float64(o.RoomCount) },
+	"available": func(o scraper.RentalOffer) float64 {
+		if o.AvailableNow {
+			return 1
+		}
+		if o.AvailableFrom.IsZero() {
+			return 0
+		}
+		return float64(o.AvailableFrom.Unix())
+	},
+}
+
+// keepMatching keeps the offers matching the filters given on the command line,
+// such as -require and -include-city, preserving their order
+func keepMatching(offers []scraper.RentalOffer, filters state.Filters) []scraper.RentalOffer {
+	if filters.IsEmpty() {
+		return offers
+	}
+	kept := make([]scraper.RentalOffer, 0, len(offers))
+	for i, offer := range toStateOffers(offers) {
+		if filters.Matches(offer) {
+			kept = append(kept, offers[i])
+		}
+	}
+	return kept
+}
+
+// sortOffers sorts the offers in place by the given key.
+// Offers missing the value always sort last, and ties keep their scrape order.
+func sortOffers(offers []scraper.RentalOffer, key string, desc bool) {
+	value := offerSortKeys[key]
+	if value == nil {
+		return
+	}
+	sort.SliceStable(offers, func(i, j int) bool {
+		a, b := value(offers[i]), value(offers[j])
+		if a == 0 || b == 0 {
+			return a != 0 && b == 0
+		}
+		if desc {
+			return a > b
+		}
+		return a < b
+	})
 }
 
-// printResults prints the rental offers to the console
-func printResults(offers []RentalOffer) {
+// printResults prints the rental offers in a human readable form
+func printResults(w io.Writer, offers []scraper.RentalOffer) {
 	titleColor := color.New(color.FgCyan, color.Bold)
 	addressColor := color.New(color.FgYellow)
 	priceColor := color.New(color.FgGreen, color.Bold)
@@ -92,25 +423,25 @@ func printResults(offers []RentalOffer) {
 	detailsColor := color.New(color.FgWhite)
 	linkColor := color.New(color.FgBlue, color.Underline)
 
-	fmt.Printf("\nFound %d rental offers:\n\n", len(offers))
+	fmt.Fprintf(w, "\nFound %d rental offers:\n\n", len(offers))
 
 	for i, offer := range offers {
-		fmt.Printf("--- Offer #%d ---\n", i+1)
+		fmt.Fprintf(w, "--- Offer #%d ---\n", i+1)
 
 		if offer.Title != "" {
-			titleColor.Printf("Title: %s\n", offer.Title)
+			titleColor.Fprintf(w, "Title: %s\n", offer.Title)
 		}
 
 		if offer.Address != "" {
-			addressColor.Printf("Address: %s\n", offer.Address)
+			addressColor.Fprintf(w, "Address: %s\n", offer.Address)
 		}
 
 		if offer.Price != "" {
-			priceColor.Printf("Price: %s\n", offer.Price)
+			priceColor.Fprintf(w, "Price: %s\n", offer.Price)
 		}
 
 		if offer.Rooms != "" {
-			roomsColor.Printf("Rooms: %s\n", offer.Rooms)
+			roomsColor.Fprintf(w, "Rooms: %s\n", offer.Rooms)
 		}
 
 		details := []string{}
@@ -122,13 +453,13 @@ func printResults(offers []RentalOffer) {
 		}
 
 		if len(details) > 0 {
-			detailsColor.Printf("%s\n", strings.Join(details, " | "))
+			detailsColor.Fprintf(w, "%s\n", strings.Join(details, " | "))
 		}
 
 		if offer.Link != "" {
-			linkColor.Printf("Link: %s\n", offer.Link)
+			linkColor.Fprintf(w, "Link: %s\n", offer.Link)
 		}
 
-		fmt.Println()
+		fmt.Fprintln(w)
 	}
 }
@@ -0,0 +1,201 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aqaliarept/vuokraovi-bot/scraper"
+	"github.com/aqaliarept/vuokraovi-bot/state"
+)
+
+// testOffers are console results containing characters CSV has to quote
+var testOffers = []scraper.RentalOffer{
+	{Title: "Kerrostalo", Address: "Mannerheimintie 1, Helsinki", Price: "900 €/kk", Size: "40 m²", Rooms: "2h+k", Available: "Heti", Link: "https://www.vuokraovi.com/vuokra-asunto/helsinki/1"},
+	{Title: `Rivitalo "Koti"`, Address: "Hämeenkatu 2 B, Tampere", Price: "1 200 €/kk", Size: "75 m²", Rooms: "3h, k, s", Link: "https://www.vuokraovi.com/vuokra-asunto/tampere/2"},
+}
+
+func TestWriteCSVMatchesFixture(t *testing.T) {
+	want, err := os.ReadFile("testdata/offers.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := writeCSV(&buf, testOffers); err != nil {
+		t.Fatalf("writeCSV: %v", err)
+	}
+	if buf.String() != string(want) {
+		t.Errorf("CSV output differs from testdata/offers.csv:\n%s", buf.String())
+	}
+}
+
+// titles returns the titles of the offers
+func titles(offers []scraper.RentalOffer) []string {
+	result := make([]string, len(offers))
+	for i, offer := range offers {
+		result[i] = offer.Title
+	}
+	return result
+}
+
+func TestSortOffers(t *testing.T) {
+	june := time.Date(2024, 6, 1, 0, 0, 0, 0, time.Local)
+	july := time.Date(2024, 7, 1, 0, 0, 0, 0, time.Local)
+	offers := []scraper.RentalOffer{
+		{Title: "a", PriceEUR: 1200, SizeSqm: 30, RoomCount: 1, AvailableFrom: july},
+		{Title: "missing"},
+		{Title: "b", PriceEUR: 850, SizeSqm: 75, RoomCount: 3, AvailableFrom: june},
+		{Title: "c", PriceEUR: 950, SizeSqm: 40, RoomCount: 2},
+		{Title: "now", AvailableNow: true},
+	}
+
+	tests := []struct {
+		key  string
+		desc bool
+		want string
+	}{
+		{"price", false, "b c a missing now"},
+		{"price", true, "a c b missing now"},
+		{"size", false, "a c b missing now"},
+		{"size", true, "b c a missing now"},
+		{"rooms", false, "a c b missing now"},
+		{"rooms", true, "b c a missing now"},
+		{"available", false, "now b a missing c"},
+		{"available", true, "a b now missing c"},
+		{"unknown", false, "a missing b c now"},
+	}
+
+	for _, tt := range tests {
+		sorted := append([]scraper.RentalOffer(nil), offers...)
+		sortOffers(sorted, tt.key, tt.desc)
+		if got := strings.Join(titles(sorted), " "); got != tt.want {
+			t.Errorf("sortOffers(%s, desc=%v) = %s, want %s", tt.key, tt.desc, got, tt.want)
+		}
+	}
+}
+
+func TestWriteResultsJSONRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "results", "offers.json")
+	offers := append([]scraper.RentalOffer(nil), testOffers...)
+	offers[0].PriceEUR = 900
+	offers[0].AvailableFrom = time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	if err := writeResults(path, "json", offers); err != nil {
+		t.Fatalf("writeResults: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []scraper.RentalOffer
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("invalid JSON output: %v", err)
+	}
+	if !reflect.DeepEqual(got, offers) {
+		t.Errorf("read back %+v, want %+v", got, offers)
+	}
+}
+
+func TestWriteResultsFailsForUnwritablePath(t *testing.T) {
+	// A regular file cannot be used as the parent directory
+	parent := filepath.Join(t.TempDir(), "file")
+	os.WriteFile(parent, nil, 0644)
+
+	if err := writeResults(filepath.Join(parent, "offers.json"), "json", testOffers); err == nil {
+		t.Error("writeResults succeeded for a path below a file")
+	}
+}
+
+func TestJSONLogFormatWritesStructuredLines(t *testing.T) {
+	previous := slog.Default()
+	var buf bytes.Buffer
+	if err := setupLogging("json", &buf); err != nil {
+		t.Fatalf("setupLogging: %v", err)
+	}
+	t.Cleanup(func() {
+		slog.SetDefault(previous)
+		log.SetOutput(os.Stderr)
+		log.SetFlags(log.LstdFlags)
+	})
+
+	log.Printf("Checking for new rental offers...")
+	slog.Info("Fetched rental offers", "offers_found", 3)
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("got %d log lines, want 2:\n%s", len(lines), buf.String())
+	}
+	for _, line := range lines {
+		var entry map[string]any
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatalf("log line %q is not valid JSON: %v", line, err)
+		}
+		for _, key := range []string{"time", "level", "msg"} {
+			if _, ok := entry[key]; !ok {
+				t.Errorf("log line %q has no %q", line, key)
+			}
+		}
+		if entry["msg"] == "Fetched rental offers" && entry["offers_found"] != float64(3) {
+			t.Errorf("offers_found = %v, want 3", entry["offers_found"])
+		}
+	}
+}
+
+func TestSetupLoggingRejectsUnknownFormats(t *testing.T) {
+	if err := setupLogging("text", io.Discard); err != nil {
+		t.Errorf("setupLogging(text) = %v", err)
+	}
+	if err := setupLogging("xml", io.Discard); err == nil {
+		t.Error("setupLogging(xml) succeeded")
+	}
+}
+
+func TestKeepMatchingRequiresAllAmenities(t *testing.T) {
+	offers := []scraper.RentalOffer{
+		{Title: "a", Amenities: []string{"sauna", "parveke"}},
+		{Title: "b", Amenities: []string{"sauna"}},
+		{Title: "c"},
+		{Title: "d", Amenities: []string{"parveke", "kt", "sauna"}},
+	}
+
+	if got := titles(keepMatching(offers, state.Filters{Amenities: []string{"sauna", "parveke"}})); !reflect.DeepEqual(got, []string{"a", "d"}) {
+		t.Errorf("keepMatching = %v, want [a d]", got)
+	}
+	if got := keepMatching(offers, state.Filters{}); len(got) != 4 {
+		t.Errorf("no required amenities kept %d offers, want all 4", len(got))
+	}
+}
+
+func TestKeepMatchingFiltersCitiesAndDistricts(t *testing.T) {
+	offers := []scraper.RentalOffer{
+		{Title: "kallio", Link: "https://www.vuokraovi.com/vuokra-asunto/helsinki/kallio/kerrostalo/1"},
+		{Title: "etu-töölö", Link: "https://www.vuokraovi.com/vuokra-asunto/helsinki/etu-töölö/kerrostalo/2"},
+		{Title: "hervanta", Link: "https://www.vuokraovi.com/vuokra-asunto/tampere/hervanta/kerrostalo/3"},
+	}
+
+	tests := []struct {
+		name    string
+		filters state.Filters
+		want    []string
+	}{
+		{"include district", state.Filters{IncludeDistricts: state.NormalizePlaces([]string{"Kallio", "Hervanta"})}, []string{"kallio", "hervanta"}},
+		{"exclude district", state.Filters{ExcludeDistricts: state.NormalizePlaces([]string{"Etu Töölö"})}, []string{"kallio", "hervanta"}},
+		{"include city, exclude district", state.Filters{IncludeCities: []string{"helsinki"}, ExcludeDistricts: []string{"kallio"}}, []string{"etu-töölö"}},
+		{"exclude city", state.Filters{ExcludeCities: []string{"helsinki"}}, []string{"hervanta"}},
+	}
+	for _, tt := range tests {
+		if got := titles(keepMatching(offers, tt.filters)); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: kept %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
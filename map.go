@@ -0,0 +1,59 @@
+package main
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/aqaliarept/vuokraovi-bot/state"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// mapQuery returns the map search query of an address, adding the country so
+// street names shared with other countries resolve to the Finnish one
+func mapQuery(address string) string {
+	address = strings.Join(strings.Fields(address), " ")
+	if !strings.Contains(strings.ToLower(address), "finland") {
+		address += ", Finland"
+	}
+	return address
+}
+
+// googleMapsURL returns a Google Maps search link for the address
+func googleMapsURL(address string) string {
+	return "https://www.google.com/maps/search/?api=1&query=" + url.QueryEscape(mapQuery(address))
+}
+
+// openStreetMapURL returns an OpenStreetMap search link for the address
+func openStreetMapURL(address string) string {
+	return "https://www.openstreetmap.org/search?query=" + url.QueryEscape(mapQuery(address))
+}
+
+// handleMapCommand handles the /map command, replying with map links for the
+// address of an offer
+func handleMapCommand(bot *tgbotapi.BotAPI, botState *state.BotState, message *tgbotapi.Message) {
+	chatID := message.Chat.ID
+	lang := userLanguage(botState, chatID)
+	offerID := strings.TrimSpace(message.CommandArguments())
+
+	var text string
+	var offer state.RentalOffer
+	found := false
+	if offerID == "" {
+		text = translate(lang, "map_usage")
+	} else if offer, found = botState.FindOffer(offerID); !found {
+		text = translate(lang, "offer_not_found", offerID)
+	} else if strings.TrimSpace(offer.Address) == "" {
+		text = translate(lang, "map_no_address", offerID)
+	} else {
+		text = translate(lang, "map_links", offer.Address, googleMapsURL(offer.Address), openStreetMapURL(offer.Address))
+	}
+
+	msg := tgbotapi.NewMessage(chatID, text)
+	msg.ReplyMarkup = createMainKeyboard(lang)
+	sendMessage(bot, msg)
+
+	// Offers with coordinates are also pinned on Telegram's own map
+	if found && offer.HasLocation() {
+		sendMessage(bot, tgbotapi.NewLocation(chatID, offer.Lat, offer.Lng))
+	}
+}
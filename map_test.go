@@ -0,0 +1,86 @@
+package main
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/aqaliarept/vuokraovi-bot/state"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+func TestMapURLsAreWellFormed(t *testing.T) {
+	address := "Hämeenkatu 2 B, Tampere & Co"
+
+	tests := []struct {
+		link  string
+		host  string
+		path  string
+		param string
+	}{
+		{googleMapsURL(address), "www.google.com", "/maps/search/", "query"},
+		{openStreetMapURL(address), "www.openstreetmap.org", "/search", "query"},
+	}
+	for _, tt := range tests {
+		parsed, err := url.Parse(tt.link)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.link, err)
+		}
+		if parsed.Scheme != "https" || parsed.Host != tt.host || parsed.Path != tt.path {
+			t.Errorf("%s: unexpected scheme, host or path", tt.link)
+		}
+		if got := parsed.Query().Get(tt.param); got != "Hämeenkatu 2 B, Tampere & Co, Finland" {
+			t.Errorf("%s: query = %q, want the address with the country", tt.link, got)
+		}
+	}
+
+	if got := mapQuery("  Mannerheimintie 1,\n Helsinki, Finland "); got != "Mannerheimintie 1, Helsinki, Finland" {
+		t.Errorf("mapQuery = %q, want the address once with the country", got)
+	}
+}
+
+func TestMapCommandReplies(t *testing.T) {
+	bot, fake := newFakeTelegram(t)
+	botState := newTestBotState(t)
+	botState.AddUser(&tgbotapi.User{FirstName: "Test"}, 1)
+	offer := testOffer("https://www.vuokraovi.com/vuokra-asunto/helsinki/kallio/kerrostalo/123", "900 €/kk")
+	noAddress := testOffer("https://www.vuokraovi.com/vuokra-asunto/helsinki/kallio/kerrostalo/456", "800 €/kk")
+	noAddress.Address = ""
+	botState.ApplyOffers([]state.RentalOffer{offer, noAddress})
+
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"/map 123", translate("en", "map_links", offer.Address, googleMapsURL(offer.Address), openStreetMapURL(offer.Address))},
+		{"/map 456", translate("en", "map_no_address", "456")},
+		{"/map 999", translate("en", "offer_not_found", "999")},
+		{"/map", translate("en", "map_usage")},
+	}
+	for _, tt := range tests {
+		fake.reset()
+		handleMessage(bot, botState, userMessage(1, tt.text), BotConfig{})
+		sent := fake.calls("sendMessage")
+		if len(sent) != 1 || sent[0].Get("text") != tt.want {
+			t.Errorf("%s: sent %v, want %q", tt.text, sent, tt.want)
+		}
+	}
+}
+
+func TestMapCommandPinsOffersWithCoordinates(t *testing.T) {
+	bot, fake := newFakeTelegram(t)
+	botState := newTestBotState(t)
+	botState.AddUser(&tgbotapi.User{FirstName: "Test"}, 1)
+	offer := testOffer("https://www.vuokraovi.com/vuokra-asunto/helsinki/kallio/kerrostalo/123", "900 €/kk")
+	offer.Lat, offer.Lng = 60.1841, 24.9507
+	botState.ApplyOffers([]state.RentalOffer{offer})
+
+	handleMessage(bot, botState, userMessage(1, "/map 123"), BotConfig{})
+
+	if sent := fake.calls("sendMessage"); len(sent) != 1 {
+		t.Errorf("sent %d messages, want the map links", len(sent))
+	}
+	pins := fake.calls("sendLocation")
+	if len(pins) != 1 || pins[0].Get("latitude") != "60.184100" || pins[0].Get("longitude") != "24.950700" {
+		t.Errorf("sent locations %v, want a pin at the offer's coordinates", pins)
+	}
+}
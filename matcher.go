@@ -0,0 +1,75 @@
+package main
+
+import (
+	"encoding/json"
+
+	"github.com/aqaliarept/vuokraovi-bot/state"
+)
+
+// offerMatcher picks the new offers each user is notified about. Users with the same
+// searches, filters and active profiles get the same offers, so the batch is
+// filtered once per distinct set of criteria instead of once per user.
+type offerMatcher struct {
+	offers  []state.RentalOffer
+	matched map[string][]state.RentalOffer
+}
+
+// newOfferMatcher creates a matcher for a batch of new offers
+func newOfferMatcher(offers []state.RentalOffer) *offerMatcher {
+	return &offerMatcher{offers: offers, matched: make(map[string][]state.RentalOffer)}
+}
+
+// matchCriteria is everything deciding which offers a user gets, see matchingOffers
+type matchCriteria struct {
+	Searches []string        `json:"searches,omitempty"`
+	Filters  state.Filters   `json:"filters"`
+	Profiles []state.Profile `json:"profiles,omitempty"`
+}
+
+// criteriaKey identifies the user's matching criteria, false when they cannot be encoded
+func criteriaKey(user *state.UserState) (string, bool) {
+	criteria := matchCriteria{Searches: user.Searches}
+	// Active profiles replace the filters, inactive ones do not matter
+	if state.HasActiveProfile(user.Profiles) {
+		for _, profile := range user.Profiles {
+			if profile.Active {
+				criteria.Profiles = append(criteria.Profiles, profile)
+			}
+		}
+	} else {
+		criteria.Filters = user.Filters
+	}
+	key, err := json.Marshal(criteria)
+	if err != nil {
+		return "", false
+	}
+	return string(key), true
+}
+
+// match returns the offers of the batch the user subscribed to and that match their
+// filters or profiles. The result is shared between users and must not be modified.
+func (m *offerMatcher) match(user *state.UserState) []state.RentalOffer {
+	// Nothing to filter, e.g. when an update found no new offers
+	if len(m.offers) == 0 {
+		return nil
+	}
+	key, ok := criteriaKey(user)
+	if !ok {
+		return filterUserOffers(user, m.offers)
+	}
+	matched, exists := m.matched[key]
+	if !exists {
+		matched = filterUserOffers(user, m.offers)
+		m.matched[key] = matched
+	}
+	return matched
+}
+
+// filterUserOffers filters offers by the user's searches and filters or profiles
+func filterUserOffers(user *state.UserState, offers []state.RentalOffer) []state.RentalOffer {
+	offers = subscribedOffers(user, offers)
+	if state.HasActiveProfile(user.Profiles) {
+		return state.FilterOffersByProfiles(offers, user.Profiles)
+	}
+	return state.FilterOffers(offers, user.Filters)
+}
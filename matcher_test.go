@@ -0,0 +1,94 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/aqaliarept/vuokraovi-bot/state"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// matcherOffers creates count offers with varying prices, sizes and searches
+func matcherOffers(count int) []state.RentalOffer {
+	offers := make([]state.RentalOffer, count)
+	for i := range offers {
+		offers[i] = testOffer(fmt.Sprintf("https://example.com/%d", i), fmt.Sprintf("%d €/kk", 500+i*10))
+		offers[i].Address = fmt.Sprintf("Testikatu %d, Helsinki", i)
+		offers[i].SizeSqm = float64(20 + i%50)
+		if i%3 == 0 {
+			offers[i].Searches = []string{"espoo"}
+		}
+	}
+	return offers
+}
+
+// matcherCriteria are the filters given to the users of the matcher tests in turn
+var matcherCriteria = []state.Filters{
+	{},
+	{MaxPrice: 900},
+	{MinSize: 40},
+	{MaxPrice: 1200, MinSize: 30},
+	{MaxPricePerSqm: 25},
+}
+
+// addMatcherUsers adds count users with the matcherCriteria
+func addMatcherUsers(botState *state.BotState, count int) {
+	for i := 0; i < count; i++ {
+		chatID := int64(i + 1)
+		botState.AddUser(&tgbotapi.User{ID: chatID, FirstName: "Test"}, chatID)
+		botState.SetUserFilters(chatID, matcherCriteria[i%len(matcherCriteria)])
+	}
+}
+
+func TestOfferMatcherMatchesLikeMatchingOffers(t *testing.T) {
+	botState := newTestBotState(t)
+	addMatcherUsers(botState, 12)
+	botState.SetUserSearches(2, []string{"espoo"})
+	botState.SaveProfile(3, "big", state.Filters{MinSize: 60})
+	botState.SaveProfile(4, "cheap", state.Filters{MaxPrice: 700})
+	botState.SaveProfile(4, "small", state.Filters{MinSize: 25})
+	botState.SetProfileActive(4, "small", false)
+
+	offers := matcherOffers(60)
+	matcher := newOfferMatcher(offers)
+	for chatID, user := range botState.GetAllUsers() {
+		want := matchingOffers(botState, chatID, subscribedOffers(user, offers))
+		if got := matcher.match(user); !reflect.DeepEqual(linksOf(got), linksOf(want)) {
+			t.Errorf("user %d: matched %v, want %v", chatID, linksOf(got), linksOf(want))
+		}
+	}
+	if len(matcher.matched) > len(matcherCriteria)+3 {
+		t.Errorf("matcher filtered %d times for %d distinct criteria", len(matcher.matched), len(matcherCriteria)+3)
+	}
+}
+
+func TestOfferMatcherWithoutNewOffers(t *testing.T) {
+	matcher := newOfferMatcher(nil)
+	if got := matcher.match(&state.UserState{}); got != nil {
+		t.Errorf("matched %v without new offers", got)
+	}
+}
+
+func BenchmarkMatchNewOffers(b *testing.B) {
+	botState := newTestBotState(b)
+	addMatcherUsers(botState, 500)
+	users := botState.GetAllUsers()
+	offers := matcherOffers(200)
+
+	b.Run("per user", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			for chatID, user := range users {
+				matchingOffers(botState, chatID, subscribedOffers(user, offers))
+			}
+		}
+	})
+	b.Run("matcher", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			matcher := newOfferMatcher(offers)
+			for _, user := range users {
+				matcher.match(user)
+			}
+		}
+	})
+}
@@ -0,0 +1,232 @@
+package main
+
+import (
+	"log"
+	"strings"
+	"unicode/utf16"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+const (
+	// maxCaptionLength is Telegram's limit for photo captions
+	maxCaptionLength = 1024
+	// maxMessageLength is Telegram's limit for text messages
+	maxMessageLength = 4096
+)
+
+var (
+	// markdownV2Escaper escapes every character MarkdownV2 reserves for formatting
+	markdownV2Escaper = newEscaper("\\_*[]()~`>#+-=|{}.!")
+	// markdownV2CodeEscaper escapes the characters reserved inside code entities
+	markdownV2CodeEscaper = newEscaper("\\`")
+	// markdownV2URLEscaper escapes the characters reserved inside link targets
+	markdownV2URLEscaper = newEscaper("\\)")
+)
+
+// newEscaper returns a replacer prefixing each of the characters with a backslash
+func newEscaper(chars string) *strings.Replacer {
+	var pairs []string
+	for _, c := range chars {
+		pairs = append(pairs, string(c), "\\"+string(c))
+	}
+	return strings.NewReplacer(pairs...)
+}
+
+// escapeMarkdownV2 escapes text so that Telegram shows it as is in a MarkdownV2
+// message, e.g. "Katu 1 (A)" becomes "Katu 1 \(A\)". Unlike tgbotapi.EscapeText
+// it also escapes backslashes.
+func escapeMarkdownV2(text string) string {
+	return markdownV2Escaper.Replace(text)
+}
+
+// escapeMarkdownV2Code escapes text shown inside a `code` entity
+func escapeMarkdownV2Code(text string) string {
+	return markdownV2CodeEscaper.Replace(text)
+}
+
+// escapeMarkdownV2URL escapes the target of a [text](url) link
+func escapeMarkdownV2URL(link string) string {
+	return markdownV2URLEscaper.Replace(link)
+}
+
+// escapeMarkdownV2Bold escapes a translated text like escapeMarkdownV2 but keeps
+// its *bold* markers
+func escapeMarkdownV2Bold(text string) string {
+	parts := strings.Split(text, "*")
+	for i, part := range parts {
+		parts[i] = escapeMarkdownV2(part)
+	}
+	return strings.Join(parts, "*")
+}
+
+// truncateCaption shortens a MarkdownV2 text to the caption limit by dropping whole
+// lines, so that no formatting entity is cut in half
+func truncateCaption(text string) string {
+	if messageLength(text) <= maxCaptionLength {
+		return text
+	}
+
+	const ellipsis = "\n…"
+	lines := strings.Split(text, "\n")
+	caption := ""
+	for _, line := range lines {
+		next := caption + line + "\n"
+		if messageLength(next)+messageLength(ellipsis) > maxCaptionLength {
+			break
+		}
+		caption = next
+	}
+	return strings.TrimRight(caption, "\n") + ellipsis
+}
+
+// offerMessage builds a photo message with the text as caption when an image is
+// available, and a plain text message otherwise
+func offerMessage(chatID int64, text, imageURL string, markup interface{}) tgbotapi.Chattable {
+	if imageURL != "" {
+		photo := tgbotapi.NewPhoto(chatID, tgbotapi.FileURL(imageURL))
+		photo.Caption = truncateCaption(text)
+		photo.ParseMode = "MarkdownV2"
+		photo.ReplyMarkup = markup
+		return photo
+	}
+
+	msg := tgbotapi.NewMessage(chatID, text)
+	msg.ParseMode = "MarkdownV2"
+	msg.DisableWebPagePreview = true
+	msg.ReplyMarkup = markup
+	return msg
+}
+
+// messageLength returns the length of a text as Telegram counts it against its
+// limits, in UTF-16 code units, so emoji count twice. The formatting characters are
+// counted too, which keeps the estimate on the safe side.
+func messageLength(text string) int {
+	return len(utf16.Encode([]rune(text)))
+}
+
+// splitMessages joins the parts into as few texts as possible without exceeding
+// maxMessageLength. Parts are kept whole unless a single part is over the limit,
+// in which case it is split between lines.
+func splitMessages(parts []string) []string {
+	texts, _ := splitMessageParts(parts)
+	return texts
+}
+
+// splitMessageParts works like splitMessages and also returns, for each text, the
+// number of parts that are complete once it and the texts before it are sent
+func splitMessageParts(parts []string) ([]string, []int) {
+	var texts []string
+	var complete []int
+	text := ""
+	for i, part := range parts {
+		for _, piece := range splitLongText(part, maxMessageLength) {
+			if text != "" && messageLength(text)+messageLength(piece) > maxMessageLength {
+				texts = append(texts, text)
+				// The part being split is only complete with its last piece
+				complete = append(complete, i)
+				text = ""
+			}
+			text += piece
+		}
+	}
+	if text != "" {
+		texts = append(texts, text)
+		complete = append(complete, len(parts))
+	}
+	return texts, complete
+}
+
+// splitLongText splits a text longer than max into pieces of at most max between
+// lines, cutting only lines that do not fit on their own
+func splitLongText(text string, max int) []string {
+	if messageLength(text) <= max {
+		return []string{text}
+	}
+
+	var pieces []string
+	piece := ""
+	for _, line := range strings.SplitAfter(text, "\n") {
+		for messageLength(line) > max {
+			var head string
+			head, line = cutAtLength(line, max)
+			if piece != "" {
+				pieces = append(pieces, piece)
+				piece = ""
+			}
+			pieces = append(pieces, head)
+		}
+		if piece != "" && messageLength(piece)+messageLength(line) > max {
+			pieces = append(pieces, piece)
+			piece = ""
+		}
+		piece += line
+	}
+	if piece != "" {
+		pieces = append(pieces, piece)
+	}
+	return pieces
+}
+
+// cutAtLength splits a text after the last rune that keeps the head within max,
+// moving the cut before a MarkdownV2 escape so that it is not split from the
+// character it escapes
+func cutAtLength(text string, max int) (string, string) {
+	length := 0
+	for i, r := range text {
+		length += messageLength(string(r))
+		if length > max {
+			// An odd number of backslashes before the cut ends with an escape
+			backslashes := 0
+			for backslashes < i && text[i-1-backslashes] == '\\' {
+				backslashes++
+			}
+			if backslashes%2 == 1 && i > 1 {
+				i--
+			}
+			return text[:i], text[i:]
+		}
+	}
+	return text, ""
+}
+
+// sendOfferMessages sends the header followed by the offer texts. When imageURL is
+// set, the photo is sent with only the header and the first text as caption, so
+// that no offer is hidden by the caption limit; the remaining texts follow as text
+// messages. It falls back to text messages when the photo cannot be sent, and
+// attaches markup to the last message. It returns how many of the texts were
+// delivered completely, which is all of them unless it returns an error.
+func sendOfferMessages(bot *tgbotapi.BotAPI, chatID int64, header string, texts []string, imageURL string, markup interface{}) (int, error) {
+	if len(texts) == 0 {
+		return 0, nil
+	}
+	texts = append([]string{header + texts[0]}, texts[1:]...)
+
+	sent := 0
+	if imageURL != "" {
+		var photoMarkup interface{}
+		if len(texts) == 1 {
+			photoMarkup = markup
+		}
+		_, err := sendMessage(bot, offerMessage(chatID, texts[0], imageURL, photoMarkup))
+		if err == nil {
+			sent = 1
+		} else {
+			log.Printf("Error sending photo %s to user %d, sending text instead: %v", imageURL, chatID, err)
+		}
+	}
+
+	messages, complete := splitMessageParts(texts[sent:])
+	delivered := sent
+	for i, text := range messages {
+		var textMarkup interface{}
+		if i == len(messages)-1 {
+			textMarkup = markup
+		}
+		if _, err := sendMessage(bot, offerMessage(chatID, text, "", textMarkup)); err != nil {
+			return delivered, err
+		}
+		delivered = sent + complete[i]
+	}
+	return len(texts), nil
+}
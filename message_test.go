@@ -0,0 +1,234 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/aqaliarept/vuokraovi-bot/state"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+func TestOfferMessageUsesPhotoOnlyWithImage(t *testing.T) {
+	if _, ok := offerMessage(1, "*Offer*", "https://img.example.com/1.jpg", nil).(tgbotapi.PhotoConfig); !ok {
+		t.Error("an offer with an image should produce a PhotoConfig")
+	}
+	if _, ok := offerMessage(1, "*Offer*", "", nil).(tgbotapi.MessageConfig); !ok {
+		t.Error("an offer without an image should produce a MessageConfig")
+	}
+
+	photo := offerMessage(1, strings.Repeat("line of the offer details\n", 100), "https://img.example.com/1.jpg", nil).(tgbotapi.PhotoConfig)
+	if length := len([]rune(photo.Caption)); length > maxCaptionLength {
+		t.Errorf("caption has %d characters, want at most %d", length, maxCaptionLength)
+	}
+}
+
+func TestSendOfferMessagesCaptionsOnlyTheFirstOffer(t *testing.T) {
+	bot, fake := newFakeTelegram(t)
+	texts := []string{"*First*\n\n", "*Second*\n\n", "*Third*\n\n"}
+
+	if _, err := sendOfferMessages(bot, 1, "Header\n\n", texts, "https://img.example.com/1.jpg", createMainKeyboard("en")); err != nil {
+		t.Fatalf("sendOfferMessages: %v", err)
+	}
+
+	photos := fake.calls("sendPhoto")
+	if len(photos) != 1 || photos[0].Get("caption") != "Header\n\n*First*\n\n" || photos[0].Get("reply_markup") != "" {
+		t.Fatalf("photos = %v, want one photo captioned with the first offer only and no keyboard", photos)
+	}
+	messages := fake.calls("sendMessage")
+	if len(messages) != 1 || messages[0].Get("text") != "*Second*\n\n*Third*\n\n" || messages[0].Get("reply_markup") == "" {
+		t.Errorf("messages = %v, want the other offers in one message with the keyboard", messages)
+	}
+}
+
+func TestSendOfferMessagesFallsBackToText(t *testing.T) {
+	bot, fake := newFakeTelegram(t)
+	fake.failPhotos = true
+
+	if _, err := sendOfferMessages(bot, 1, "Header\n\n", []string{"*First*\n\n", "*Second*\n\n"}, "https://img.example.com/missing.jpg", nil); err != nil {
+		t.Fatalf("sendOfferMessages: %v", err)
+	}
+	messages := fake.calls("sendMessage")
+	if len(messages) != 1 || messages[0].Get("text") != "Header\n\n*First*\n\n*Second*\n\n" {
+		t.Errorf("messages = %v, want all offers as text", messages)
+	}
+}
+
+func TestSplitMessagesStaysBelowLimit(t *testing.T) {
+	part := strings.Repeat("x", 1500) + "\n"
+	texts := splitMessages([]string{part, part, part, part, part})
+	if len(texts) != 3 {
+		t.Fatalf("got %d messages, want 3", len(texts))
+	}
+	for _, text := range texts {
+		if messageLength(text) > maxMessageLength || strings.Count(text, "\n") == 0 {
+			t.Errorf("message of %d characters splits a part or exceeds the limit", len([]rune(text)))
+		}
+	}
+}
+
+func TestNotifyUsersMarksOnlyListedOffersAsSeen(t *testing.T) {
+	bot, fake := newFakeTelegram(t)
+	botState := newTestBotState(t)
+	botState.AddUser(&tgbotapi.User{FirstName: "Test"}, 1)
+	offers := make([]state.RentalOffer, 12)
+	for i := range offers {
+		offers[i] = testOffer(fmt.Sprintf("https://example.com/%d", i), "800 €/kk")
+		offers[i].ImageURL = "https://img.example.com/1.jpg"
+	}
+	botState.ApplyOffers(offers)
+
+	notifyUsers(bot, botState, offers)
+
+	if photos := fake.calls("sendPhoto"); len(photos) != 1 || strings.Count(photos[0].Get("caption"), "View Details") != 1 {
+		t.Errorf("photos = %v, want one photo showing a single offer", photos)
+	}
+	if user, _ := botState.GetUser(1); len(user.SeenOffers) != 10 {
+		t.Errorf("%d offers marked as seen, want the 10 listed ones", len(user.SeenOffers))
+	}
+}
+
+func TestNotifyUsersQueuesOnlyUnsentOffers(t *testing.T) {
+	bot, fake := newFakeTelegram(t)
+	fake.failMessages = true
+	botState := newTestBotState(t)
+	botState.AddUser(&tgbotapi.User{FirstName: "Test"}, 1)
+	offers := make([]state.RentalOffer, 3)
+	for i := range offers {
+		offers[i] = testOffer(fmt.Sprintf("https://example.com/%d", i), "800 €/kk")
+		offers[i].ImageURL = "https://img.example.com/1.jpg"
+	}
+	botState.ApplyOffers(offers)
+
+	notifyUsers(bot, botState, offers)
+
+	if photos := fake.calls("sendPhoto"); len(photos) != 1 {
+		t.Fatalf("photos = %v, want the first offer sent as a photo", photos)
+	}
+	user, _ := botState.GetUser(1)
+	if len(user.SeenOffers) != 1 {
+		t.Errorf("%d offers marked as seen, want only the one in the photo", len(user.SeenOffers))
+	}
+	if got := linksOf(user.PendingOffers); !reflect.DeepEqual(got, linksOf(offers[1:])) {
+		t.Errorf("queued %v, want only the offers that failed to send %v", got, linksOf(offers[1:]))
+	}
+}
+
+func TestEscapeMarkdownV2(t *testing.T) {
+	tests := []struct {
+		name   string
+		escape func(string) string
+		text   string
+		want   string
+	}{
+		{"text", escapeMarkdownV2, `Katu 1_A (piha) [B]*, 2h+k. Vapaa!`, `Katu 1\_A \(piha\) \[B\]\*, 2h\+k\. Vapaa\!`},
+		{"backslash", escapeMarkdownV2, `A\B`, `A\\B`},
+		{"code", escapeMarkdownV2Code, "id_1`2.", "id_1\\`2."},
+		{"url", escapeMarkdownV2URL, "https://example.com/a_(1)", `https://example.com/a_(1\)`},
+		{"bold", escapeMarkdownV2Bold, "📋 *12 offers* (page 1/2)", `📋 *12 offers* \(page 1/2\)`},
+	}
+	for _, tt := range tests {
+		if got := tt.escape(tt.text); got != tt.want {
+			t.Errorf("%s: escaped %q to %q, want %q", tt.name, tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestSendOffersListEscapesSpecialCharacters(t *testing.T) {
+	bot, fake := newFakeTelegram(t)
+	offer := testOffer("https://example.com/a_(1)", "1 200 €/kk")
+	offer.Title = "Katu_1 *uusi*"
+	offer.Address = "Katu 1 [A-rappu] (piha), Helsinki."
+
+	sendOffersList(bot, []state.RentalOffer{offer}, 1, "en")
+
+	sent := fake.calls("sendMessage")
+	if len(sent) != 1 || sent[0].Get("parse_mode") != "MarkdownV2" {
+		t.Fatalf("sent %v, want one MarkdownV2 message", sent)
+	}
+	text := sent[0].Get("text")
+	for _, want := range []string{
+		`*Katu\_1 \*uusi\**`,
+		`📍 Katu 1 \[A\-rappu\] \(piha\), Helsinki\.`,
+		`[View Details](https://example.com/a_(1\))`,
+	} {
+		if !strings.Contains(text, want) {
+			t.Errorf("text %q does not contain %q", text, want)
+		}
+	}
+}
+
+func TestSendOffersListSplitsLongOffersByLength(t *testing.T) {
+	bot, fake := newFakeTelegram(t)
+	offers := make([]state.RentalOffer, 4)
+	for i := range offers {
+		offers[i] = testOffer(fmt.Sprintf("https://example.com/%d", i), "800 €/kk")
+		offers[i].Address = strings.Repeat("Pitkä osoite 🏠 ", 80) + fmt.Sprintf("nro %d", i)
+	}
+
+	sendOffersList(bot, offers, 1, "en")
+
+	sent := fake.calls("sendMessage")
+	if len(sent) < 2 {
+		t.Fatalf("sent %d messages, want the long offers split over several", len(sent))
+	}
+	all := ""
+	for _, msg := range sent {
+		text := msg.Get("text")
+		if length := messageLength(text); length > maxMessageLength {
+			t.Errorf("message of %d characters exceeds the limit", length)
+		}
+		all += text
+	}
+	for i := range offers {
+		if !strings.Contains(all, fmt.Sprintf("nro %d", i)) {
+			t.Errorf("offer %d is missing from the messages", i)
+		}
+	}
+}
+
+func TestSplitMessagesSplitsOversizedPart(t *testing.T) {
+	part := strings.Repeat("line of the offer 🏠\n", 400) + strings.Repeat("y", 5000)
+	texts := splitMessages([]string{part})
+	if len(texts) < 3 || strings.Join(texts, "") != part {
+		t.Fatalf("got %d messages, want the part split over several without losing text", len(texts))
+	}
+	for _, text := range texts {
+		if length := messageLength(text); length > maxMessageLength {
+			t.Errorf("message of %d characters exceeds the limit", length)
+		}
+	}
+}
+
+func TestSplitMessagePartsCountsCompleteParts(t *testing.T) {
+	short := strings.Repeat("x", 1500) + "\n"
+	long := strings.Repeat("y", 5000) + "\n"
+	texts, complete := splitMessageParts([]string{short, long, short})
+	if len(texts) != len(complete) {
+		t.Fatalf("got %d texts and %d counts", len(texts), len(complete))
+	}
+	// The long part is only complete once its last piece is sent
+	want := []int{1, 1, 3}
+	if !reflect.DeepEqual(complete, want) {
+		t.Errorf("complete parts = %v, want %v", complete, want)
+	}
+}
+
+func TestSplitLongTextKeepsEscapesWhole(t *testing.T) {
+	for _, text := range []string{
+		strings.Repeat("a", 9) + `\.` + strings.Repeat("b", 20),
+		strings.Repeat("a", 8) + `\\\.` + strings.Repeat("b", 20),
+	} {
+		pieces := splitLongText(text, 10)
+		if strings.Join(pieces, "") != text {
+			t.Fatalf("pieces %q lose text of %q", pieces, text)
+		}
+		for _, piece := range pieces {
+			trailing := len(piece) - len(strings.TrimRight(piece, `\`))
+			if trailing%2 == 1 || messageLength(piece) > 10 {
+				t.Errorf("piece %q splits an escape or exceeds the limit", piece)
+			}
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// metricsRegistry holds the bot metrics exposed on the metrics server
+var metricsRegistry = prometheus.NewRegistry()
+
+var (
+	offersFetchedTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "offers_fetched_total",
+		Help: "Total number of offers fetched from the site.",
+	})
+	newOffersTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "new_offers_total",
+		Help: "Total number of new or relisted offers found.",
+	})
+	notificationsSentTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "notifications_sent_total",
+		Help: "Total number of new offer notifications sent to users.",
+	})
+	fetchErrorsTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "fetch_errors_total",
+		Help: "Total number of failed offer fetches.",
+	})
+	fetchDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Name:    "fetch_duration_seconds",
+		Help:    "Duration of fetching all offer pages.",
+		Buckets: prometheus.ExponentialBuckets(0.5, 2, 10),
+	})
+)
+
+func init() {
+	metricsRegistry.MustRegister(
+		offersFetchedTotal,
+		newOffersTotal,
+		notificationsSentTotal,
+		fetchErrorsTotal,
+		fetchDuration,
+	)
+}
+
+// metricsHandler serves the bot metrics in the Prometheus text format
+func metricsHandler() http.Handler {
+	return promhttp.HandlerFor(metricsRegistry, promhttp.HandlerOpts{})
+}
+
+// startMetricsServer serves the Prometheus metrics at /metrics on the configured address
+func startMetricsServer(config BotConfig) {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", metricsHandler())
+
+	log.Printf("Serving metrics on %s", config.MetricsAddr)
+	if err := http.ListenAndServe(config.MetricsAddr, mux); err != nil {
+		log.Printf("Error serving metrics: %v", err)
+	}
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"bufio"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/aqaliarept/vuokraovi-bot/state"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// scrapeMetric returns the value of a metric without labels from the metrics endpoint
+func scrapeMetric(t *testing.T, name string) float64 {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	metricsHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	scanner := bufio.NewScanner(rec.Body)
+	for scanner.Scan() {
+		if value, ok := strings.CutPrefix(scanner.Text(), name+" "); ok {
+			number, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				t.Fatalf("invalid value of %s: %q", name, value)
+			}
+			return number
+		}
+	}
+	t.Fatalf("metric %s not exposed", name)
+	return 0
+}
+
+func TestUpdateIncrementsMetrics(t *testing.T) {
+	bot, _ := newFakeTelegram(t)
+	botState := newTestBotState(t)
+	botState.AddUser(&tgbotapi.User{FirstName: "Test"}, 1)
+	stubFetch(t, []state.RentalOffer{testOffer("https://example.com/a", "800 €/kk"), testOffer("https://example.com/b", "900 €/kk")})
+
+	fetched := scrapeMetric(t, "offers_fetched_total")
+	found := scrapeMetric(t, "new_offers_total")
+	notified := scrapeMetric(t, "notifications_sent_total")
+	fetches := scrapeMetric(t, "fetch_duration_seconds_count")
+
+	if err := updateAndNotify(bot, botState, BotConfig{}); err != nil {
+		t.Fatalf("updateAndNotify: %v", err)
+	}
+
+	if got := scrapeMetric(t, "offers_fetched_total") - fetched; got != 2 {
+		t.Errorf("offers_fetched_total increased by %g, want 2", got)
+	}
+	if got := scrapeMetric(t, "new_offers_total") - found; got != 2 {
+		t.Errorf("new_offers_total increased by %g, want 2", got)
+	}
+	if got := scrapeMetric(t, "notifications_sent_total") - notified; got != 1 {
+		t.Errorf("notifications_sent_total increased by %g, want 1", got)
+	}
+	if got := scrapeMetric(t, "fetch_duration_seconds_count") - fetches; got != 1 {
+		t.Errorf("fetch_duration_seconds_count increased by %g, want 1", got)
+	}
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"log"
+	"strings"
+
+	"github.com/aqaliarept/vuokraovi-bot/state"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// handleOfferCommand handles the /offer command, sending the full details of a
+// listed offer again so offers that have scrolled off can be viewed
+func handleOfferCommand(bot *tgbotapi.BotAPI, botState *state.BotState, message *tgbotapi.Message) {
+	chatID := message.Chat.ID
+	lang := userLanguage(botState, chatID)
+	offerID := strings.TrimSpace(message.CommandArguments())
+
+	if offerID == "" {
+		msg := tgbotapi.NewMessage(chatID, translate(lang, "offer_usage"))
+		msg.ReplyMarkup = createMainKeyboard(lang)
+		sendMessage(bot, msg)
+		return
+	}
+
+	offer, found := botState.FindKnownOffer(offerID)
+	if !found {
+		msg := tgbotapi.NewMessage(chatID, translate(lang, "offer_not_found", offerID))
+		msg.ReplyMarkup = createMainKeyboard(lang)
+		sendMessage(bot, msg)
+		return
+	}
+
+	texts := []string{formatOfferDetails(offer, lang)}
+	if _, err := sendOfferMessages(bot, chatID, "", texts, offer.ImageURL, createMainKeyboard(lang)); err != nil {
+		log.Printf("Error sending offer %s to user %d: %v", offerID, chatID, err)
+	}
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/aqaliarept/vuokraovi-bot/state"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+func TestOfferCommandResendsDetails(t *testing.T) {
+	bot, fake := newFakeTelegram(t)
+	botState := newTestBotState(t)
+	botState.AddUser(&tgbotapi.User{FirstName: "Test"}, 1)
+	offer := testOffer("https://www.vuokraovi.com/vuokra-asunto/helsinki/kallio/kerrostalo/123", "900 €/kk")
+	withPhoto := testOffer("https://www.vuokraovi.com/vuokra-asunto/helsinki/kallio/kerrostalo/456", "800 €/kk")
+	withPhoto.Address = "Toinenkatu 2, Helsinki"
+	withPhoto.ImageURL = "https://img.example.com/456.jpg"
+	botState.ApplyOffers([]state.RentalOffer{offer, withPhoto})
+
+	handleMessage(bot, botState, userMessage(1, "/offer 123"), BotConfig{})
+	sent := fake.calls("sendMessage")
+	if len(sent) != 1 || !strings.Contains(sent[0].Get("text"), offer.Address) || !strings.Contains(sent[0].Get("text"), offer.Link) {
+		t.Errorf("sent %v, want the details and link of offer 123", sent)
+	}
+
+	fake.reset()
+	handleMessage(bot, botState, userMessage(1, "/offer 456"), BotConfig{})
+	photos := fake.calls("sendPhoto")
+	if len(photos) != 1 || photos[0].Get("photo") != withPhoto.ImageURL || !strings.Contains(photos[0].Get("caption"), withPhoto.Address) {
+		t.Errorf("sent photos %v, want offer 456 captioned on its image", photos)
+	}
+}
+
+func TestOfferCommandRepliesNotFound(t *testing.T) {
+	bot, fake := newFakeTelegram(t)
+	botState := newTestBotState(t)
+	botState.AddUser(&tgbotapi.User{FirstName: "Test"}, 1)
+
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"/offer 999", translate("en", "offer_not_found", "999")},
+		{"/offer", translate("en", "offer_usage")},
+	}
+	for _, tt := range tests {
+		fake.reset()
+		handleMessage(bot, botState, userMessage(1, tt.text), BotConfig{})
+		sent := fake.calls("sendMessage")
+		if len(sent) != 1 || sent[0].Get("text") != tt.want {
+			t.Errorf("%s: sent %v, want %q", tt.text, sent, tt.want)
+		}
+	}
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/aqaliarept/vuokraovi-bot/state"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// onboardingConversation is the conversation asking a new user what they are looking for
+const onboardingConversation = "onboarding"
+
+// Questions asked by the onboarding, in order
+const (
+	onboardingCity  = "city"
+	onboardingPrice = "price"
+	onboardingRooms = "rooms"
+)
+
+// anyAnswers skip an onboarding question without setting its filter
+var anyAnswers = []string{"any", "kaikki", "-"}
+
+// isAnyAnswer reports whether an onboarding answer skips the question
+func isAnyAnswer(answer string) bool {
+	for _, word := range anyAnswers {
+		if strings.EqualFold(answer, word) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseOnboardingPrice parses a price range answer like "800-1200", "800-", "-1200"
+// or "1200", where a single number is the maximum
+func parseOnboardingPrice(answer string) (float64, float64, error) {
+	answer = strings.ReplaceAll(strings.TrimSuffix(strings.TrimSpace(answer), "€"), " ", "")
+	minText, maxText, isRange := strings.Cut(answer, "-")
+	if !isRange {
+		minText, maxText = "", answer
+	}
+
+	var min, max float64
+	var err error
+	if minText != "" {
+		if min, err = parseFilterNumber("price", minText); err != nil {
+			return 0, 0, err
+		}
+	}
+	if maxText != "" {
+		if max, err = parseFilterNumber("price", maxText); err != nil {
+			return 0, 0, err
+		}
+	}
+	if min == 0 && max == 0 {
+		return 0, 0, fmt.Errorf("invalid price range %q", answer)
+	}
+	if max > 0 && min > max {
+		return 0, 0, fmt.Errorf("invalid price range %q: minimum is above maximum", answer)
+	}
+	return min, max, nil
+}
+
+// startOnboarding welcomes a new user and asks the first onboarding question
+func startOnboarding(bot *tgbotapi.BotAPI, botState *state.BotState, message *tgbotapi.Message) {
+	chatID := message.Chat.ID
+	lang := userLanguage(botState, chatID)
+
+	startConversation(botState, chatID, onboardingConversation, onboardingCity)
+
+	text := translate(lang, "onboarding_welcome", message.From.FirstName) + "\n\n" + translate(lang, "onboarding_city")
+	sendMessage(bot, tgbotapi.NewMessage(chatID, text))
+}
+
+// advanceOnboarding applies an answer to the onboarding in progress and returns the
+// next question to ask, or an empty step once all questions are answered
+func advanceOnboarding(onboarding state.Conversation, answer string) (state.Conversation, error) {
+	answer = strings.TrimSpace(answer)
+	skip := isAnyAnswer(answer)
+
+	switch onboarding.Step {
+	case onboardingCity:
+		if !skip {
+			onboarding.Filters.City = answer
+		}
+		onboarding.Step = onboardingPrice
+	case onboardingPrice:
+		if !skip {
+			min, max, err := parseOnboardingPrice(answer)
+			if err != nil {
+				return onboarding, err
+			}
+			onboarding.Filters.MinPrice, onboarding.Filters.MaxPrice = min, max
+		}
+		onboarding.Step = onboardingRooms
+	case onboardingRooms:
+		if !skip {
+			rooms, err := strconv.Atoi(answer)
+			if err != nil || rooms < 1 {
+				return onboarding, fmt.Errorf("invalid number of rooms %q", answer)
+			}
+			onboarding.Filters.MinRooms = rooms
+		}
+		onboarding.Step = ""
+	default:
+		return onboarding, fmt.Errorf("unknown onboarding step %q", onboarding.Step)
+	}
+	return onboarding, nil
+}
+
+// handleOnboardingReply handles a free text reply of a user during the onboarding
+func handleOnboardingReply(bot *tgbotapi.BotAPI, botState *state.BotState, message *tgbotapi.Message, onboarding state.Conversation) {
+	chatID := message.Chat.ID
+	lang := userLanguage(botState, chatID)
+
+	next, err := advanceOnboarding(onboarding, message.Text)
+	if err != nil {
+		text := fmt.Sprintf("❌ %v\n\n%s", err, translate(lang, "onboarding_"+onboarding.Step))
+		sendMessage(bot, tgbotapi.NewMessage(chatID, text))
+		return
+	}
+	if next.Step != "" {
+		botState.SetUserConversation(chatID, &next)
+		sendMessage(bot, tgbotapi.NewMessage(chatID, translate(lang, "onboarding_"+next.Step)))
+		return
+	}
+	finishOnboarding(bot, botState, chatID, next.Filters)
+}
+
+// finishOnboarding stores the filters collected by the onboarding and shows the
+// known offers matching them
+func finishOnboarding(bot *tgbotapi.BotAPI, botState *state.BotState, chatID int64, filters state.Filters) {
+	lang := userLanguage(botState, chatID)
+	botState.SetUserFilters(chatID, filters)
+	endConversation(botState, chatID)
+
+	msg := tgbotapi.NewMessage(chatID, translate(lang, "onboarding_done")+"\n\n"+formatFilters(filters, lang))
+	msg.ReplyMarkup = createMainKeyboard(lang)
+	sendMessage(bot, msg)
+
+	known := botState.GetKnownOffers()
+	offers := make([]state.RentalOffer, 0, len(known))
+	for _, offer := range known {
+		offers = append(offers, offer)
+	}
+	offers = matchingOffers(botState, chatID, offers)
+	if len(offers) == 0 {
+		sendMessage(bot, tgbotapi.NewMessage(chatID, translate(lang, "onboarding_no_offers")))
+		return
+	}
+	sendMessage(bot, tgbotapi.NewMessage(chatID, translate(lang, "current_offers", len(offers))))
+	sendOffersList(bot, offers, chatID, lang)
+}
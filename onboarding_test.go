@@ -0,0 +1,142 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/aqaliarept/vuokraovi-bot/state"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+func TestAdvanceOnboarding(t *testing.T) {
+	onboarding := state.Conversation{Name: onboardingConversation, Step: onboardingCity}
+
+	onboarding, err := advanceOnboarding(onboarding, " Helsinki ")
+	if err != nil || onboarding.Step != onboardingPrice || onboarding.Filters.City != "Helsinki" {
+		t.Fatalf("after city: %+v, %v", onboarding, err)
+	}
+
+	if _, err := advanceOnboarding(onboarding, "cheap"); err == nil {
+		t.Error("an invalid price range was accepted")
+	}
+	if _, err := advanceOnboarding(onboarding, "1200-800"); err == nil {
+		t.Error("a reversed price range was accepted")
+	}
+	onboarding, err = advanceOnboarding(onboarding, "800-1200")
+	if err != nil || onboarding.Step != onboardingRooms ||
+		onboarding.Filters.MinPrice != 800 || onboarding.Filters.MaxPrice != 1200 {
+		t.Fatalf("after price: %+v, %v", onboarding, err)
+	}
+
+	if _, err := advanceOnboarding(onboarding, "0"); err == nil {
+		t.Error("zero rooms were accepted")
+	}
+	onboarding, err = advanceOnboarding(onboarding, "Any")
+	if err != nil || onboarding.Step != "" || onboarding.Filters.MinRooms != 0 {
+		t.Fatalf("after rooms: %+v, %v", onboarding, err)
+	}
+}
+
+func TestParseOnboardingPrice(t *testing.T) {
+	tests := []struct {
+		answer   string
+		min, max float64
+	}{
+		{"800-1200", 800, 1200},
+		{"800 - 1200 €", 800, 1200},
+		{"800-", 800, 0},
+		{"-1200", 0, 1200},
+		{"1200", 0, 1200},
+	}
+	for _, tt := range tests {
+		min, max, err := parseOnboardingPrice(tt.answer)
+		if err != nil || min != tt.min || max != tt.max {
+			t.Errorf("parseOnboardingPrice(%q) = %v, %v, %v, want %v, %v", tt.answer, min, max, err, tt.min, tt.max)
+		}
+	}
+}
+
+func TestOnboardingStoresFiltersAndShowsMatchingOffers(t *testing.T) {
+	bot, fake := newFakeTelegram(t)
+	botState := newTestBotState(t)
+	cheap := testOffer("https://example.com/cheap", "700 €/kk")
+	cheap.Address = "Cheapkatu 1, Helsinki"
+	expensive := testOffer("https://example.com/expensive", "1500 €/kk")
+	botState.ApplyOffers([]state.RentalOffer{cheap, expensive})
+
+	handleMessage(bot, botState, userMessage(1, "/start"), BotConfig{})
+	user, _ := botState.GetUser(1)
+	if user.Conversation == nil || user.Conversation.Name != onboardingConversation || user.Conversation.Step != onboardingCity {
+		t.Fatalf("onboarding after /start = %+v, want the city question", user.Conversation)
+	}
+	if sent := fake.calls("sendMessage"); len(sent) != 1 {
+		t.Fatalf("sent %d messages after /start, want only the first question", len(sent))
+	}
+
+	for _, answer := range []string{"Helsinki", "-1000", "nonsense", "any"} {
+		handleMessage(bot, botState, userMessage(1, answer), BotConfig{})
+	}
+
+	user, _ = botState.GetUser(1)
+	if user.Conversation != nil {
+		t.Errorf("onboarding still in progress: %+v", user.Conversation)
+	}
+	if user.Filters.City != "Helsinki" || user.Filters.MinPrice != 0 || user.Filters.MaxPrice != 1000 || user.Filters.MinRooms != 0 {
+		t.Errorf("filters = %+v, want Helsinki up to 1000 €", user.Filters)
+	}
+
+	var texts []string
+	for _, values := range fake.calls("sendMessage") {
+		texts = append(texts, values["text"][0])
+	}
+	all := strings.Join(texts, "\n")
+	if !strings.Contains(all, "invalid number of rooms") {
+		t.Error("the invalid room count was not rejected")
+	}
+	if !strings.Contains(all, cheap.Link) || strings.Contains(all, expensive.Link) {
+		t.Errorf("the offers shown do not match the filters:\n%s", all)
+	}
+}
+
+func TestCancelDuringOnboardingKeepsFilters(t *testing.T) {
+	bot, fake := newFakeTelegram(t)
+	botState := newTestBotState(t)
+
+	handleMessage(bot, botState, userMessage(1, "/start"), BotConfig{})
+	handleMessage(bot, botState, userMessage(1, "Espoo"), BotConfig{})
+	handleMessage(bot, botState, userMessage(1, "/cancel"), BotConfig{})
+
+	user, _ := botState.GetUser(1)
+	if user.Conversation != nil {
+		t.Errorf("onboarding still in progress after /cancel: %+v", user.Conversation)
+	}
+	if !reflect.DeepEqual(user.Filters, state.Filters{}) {
+		t.Errorf("filters = %+v, want none after cancelling", user.Filters)
+	}
+
+	// Text after cancelling is no longer taken as an answer
+	fake.reset()
+	handleMessage(bot, botState, userMessage(1, "900"), BotConfig{})
+	if user, _ := botState.GetUser(1); !reflect.DeepEqual(user.Filters, state.Filters{}) {
+		t.Errorf("filters = %+v after cancelling, want none", user.Filters)
+	}
+
+	fake.reset()
+	handleMessage(bot, botState, userMessage(1, "/cancel"), BotConfig{})
+	sent := fake.calls("sendMessage")
+	if len(sent) != 1 || sent[0]["text"][0] != translate("en", "cancel_nothing") {
+		t.Errorf("second /cancel replied %v", sent)
+	}
+}
+
+func TestStartForKnownUserSkipsOnboarding(t *testing.T) {
+	bot, _ := newFakeTelegram(t)
+	botState := newTestBotState(t)
+	botState.AddUser(&tgbotapi.User{FirstName: "Test"}, 1)
+
+	handleMessage(bot, botState, userMessage(1, "/start"), BotConfig{})
+	if user, _ := botState.GetUser(1); user.Conversation != nil {
+		t.Errorf("a known user was onboarded: %+v", user.Conversation)
+	}
+}
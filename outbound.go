@@ -0,0 +1,93 @@
+package main
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/aqaliarept/vuokraovi-bot/state"
+)
+
+// signatureHeader carries the HMAC-SHA256 of an outbound webhook body
+const signatureHeader = "X-Vuokraovi-Signature"
+
+// outboundAttempts is how often the new offers are posted when the webhook fails
+const outboundAttempts = 3
+
+// outboundRetryDelay is the pause before the first repost, doubled for every further one
+var outboundRetryDelay = time.Second
+
+// outboundClient posts the outbound webhooks
+var outboundClient = &http.Client{Timeout: 10 * time.Second}
+
+// outboundPayload is the JSON body posted to the outbound webhook
+type outboundPayload struct {
+	Event   string              `json:"event"`
+	FoundAt time.Time           `json:"found_at"`
+	Offers  []state.RentalOffer `json:"offers"`
+}
+
+// signBody returns the signature of a body sent with the shared secret
+func signBody(body []byte, secret string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// postNewOffers posts the new offers of an update as JSON to the outbound webhook,
+// retrying connection and server errors with a growing delay
+func postNewOffers(url, secret string, offers []state.RentalOffer, now time.Time) error {
+	body, err := json.Marshal(outboundPayload{Event: "new_offers", FoundAt: now, Offers: offers})
+	if err != nil {
+		return fmt.Errorf("error encoding offers: %w", err)
+	}
+
+	var lastErr error
+	delay := outboundRetryDelay
+	for attempt := 1; attempt <= outboundAttempts; attempt++ {
+		if attempt > 1 {
+			log.Printf("Retrying webhook %s in %v (attempt %d/%d): %v", url, delay, attempt, outboundAttempts, lastErr)
+			time.Sleep(delay)
+			delay *= 2
+		}
+		var retry bool
+		retry, lastErr = postWebhook(url, secret, body)
+		if lastErr == nil || !retry {
+			return lastErr
+		}
+	}
+	return fmt.Errorf("giving up after %d attempts: %w", outboundAttempts, lastErr)
+}
+
+// postWebhook posts a signed body to the outbound webhook once. It reports whether
+// a failed post may succeed when retried: connection errors and server errors are
+// retried, client errors are not.
+func postWebhook(url, secret string, body []byte) (bool, error) {
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return false, fmt.Errorf("error creating webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if secret != "" {
+		req.Header.Set(signatureHeader, signBody(body, secret))
+	}
+
+	resp, err := outboundClient.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode >= 500, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+	return false, nil
+}
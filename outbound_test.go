@@ -0,0 +1,91 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aqaliarept/vuokraovi-bot/state"
+)
+
+func TestPostNewOffersSignsPayloadAndRetries(t *testing.T) {
+	previous := outboundRetryDelay
+	outboundRetryDelay = time.Millisecond
+	defer func() { outboundRetryDelay = previous }()
+
+	var mutex sync.Mutex
+	var bodies [][]byte
+	var signatures []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mutex.Lock()
+		defer mutex.Unlock()
+		bodies = append(bodies, body)
+		signatures = append(signatures, r.Header.Get(signatureHeader))
+		if r.Method != http.MethodPost || r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("got %s with content type %q, want a JSON POST", r.Method, r.Header.Get("Content-Type"))
+		}
+		// Fail the first attempt
+		if len(bodies) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer srv.Close()
+
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	offers := []state.RentalOffer{testOffer("https://example.com/a", "800 €/kk"), testOffer("https://example.com/b", "900 €/kk")}
+	if err := postNewOffers(srv.URL, "s3cret", offers, now); err != nil {
+		t.Fatalf("postNewOffers: %v", err)
+	}
+
+	if len(bodies) != 2 {
+		t.Fatalf("webhook called %d times, want a retry after the 503", len(bodies))
+	}
+	var payload struct {
+		Event   string    `json:"event"`
+		FoundAt time.Time `json:"found_at"`
+		Offers  []struct {
+			Link  string `json:"link"`
+			Price string `json:"price"`
+		} `json:"offers"`
+	}
+	if err := json.Unmarshal(bodies[1], &payload); err != nil {
+		t.Fatalf("invalid payload %s: %v", bodies[1], err)
+	}
+	if payload.Event != "new_offers" || !payload.FoundAt.Equal(now) || len(payload.Offers) != 2 ||
+		payload.Offers[0].Link != "https://example.com/a" || payload.Offers[1].Price != "900 €/kk" {
+		t.Errorf("payload = %+v", payload)
+	}
+	// Verify the signature the way a receiver would
+	mac := hmac.New(sha256.New, []byte("s3cret"))
+	mac.Write(bodies[1])
+	if want := "sha256=" + hex.EncodeToString(mac.Sum(nil)); signatures[1] != want {
+		t.Errorf("signature = %q, want %q", signatures[1], want)
+	}
+}
+
+func TestPostNewOffersDoesNotRetryClientErrors(t *testing.T) {
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if r.Header.Get(signatureHeader) != "" {
+			t.Error("an unsigned webhook got a signature")
+		}
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer srv.Close()
+
+	if err := postNewOffers(srv.URL, "", []state.RentalOffer{testOffer("https://example.com/a", "800 €/kk")}, time.Now()); err == nil {
+		t.Error("postNewOffers succeeded on a 404")
+	}
+	if calls != 1 {
+		t.Errorf("webhook called %d times, want no retry after a 404", calls)
+	}
+}
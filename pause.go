@@ -0,0 +1,85 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aqaliarept/vuokraovi-bot/state"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// defaultPauseDuration is used when /pause is given no duration
+const defaultPauseDuration = 7 * 24 * time.Hour
+
+// parsePauseDuration parses /pause arguments like "7d", "12h" or "1h30m"
+func parsePauseDuration(args string) (time.Duration, error) {
+	args = strings.TrimSpace(args)
+	if args == "" {
+		return defaultPauseDuration, nil
+	}
+
+	var duration time.Duration
+	if days, ok := strings.CutSuffix(args, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration %q, expected e.g. 7d, 12h or 30m", args)
+		}
+		duration = time.Duration(n) * 24 * time.Hour
+	} else {
+		parsed, err := time.ParseDuration(args)
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration %q, expected e.g. 7d, 12h or 30m", args)
+		}
+		duration = parsed
+	}
+	if duration <= 0 {
+		return 0, fmt.Errorf("the duration must be positive")
+	}
+	return duration, nil
+}
+
+// handlePauseCommand handles the /pause command
+func handlePauseCommand(bot *tgbotapi.BotAPI, botState *state.BotState, message *tgbotapi.Message) {
+	chatID := message.Chat.ID
+	lang := userLanguage(botState, chatID)
+
+	var text string
+	if duration, err := parsePauseDuration(message.CommandArguments()); err != nil {
+		text = fmt.Sprintf("❌ %v\n\n%s", err, translate(lang, "pause_usage"))
+	} else {
+		until := time.Now().Add(duration)
+		botState.SetUserPause(chatID, until)
+		text = translate(lang, "pause_set", until.In(pauseLocation()).Format("2006-01-02 15:04"))
+	}
+
+	msg := tgbotapi.NewMessage(chatID, text)
+	msg.ReplyMarkup = createMainKeyboard(lang)
+	sendMessage(bot, msg)
+}
+
+// handleResumeCommand handles the /resume command
+func handleResumeCommand(bot *tgbotapi.BotAPI, botState *state.BotState, message *tgbotapi.Message) {
+	chatID := message.Chat.ID
+	lang := userLanguage(botState, chatID)
+
+	text := translate(lang, "resume_not_paused")
+	if user, exists := botState.GetUser(chatID); exists && user.PausedUntil.After(time.Now()) {
+		text = translate(lang, "resume_done")
+	}
+	botState.SetUserPause(chatID, time.Time{})
+
+	msg := tgbotapi.NewMessage(chatID, text)
+	msg.ReplyMarkup = createMainKeyboard(lang)
+	sendMessage(bot, msg)
+}
+
+// pauseLocation returns the location the end of a pause is shown in
+func pauseLocation() *time.Location {
+	location, err := time.LoadLocation(defaultQuietTimezone)
+	if err != nil {
+		return time.UTC
+	}
+	return location
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aqaliarept/vuokraovi-bot/state"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+func TestParsePauseDuration(t *testing.T) {
+	tests := []struct {
+		args string
+		want time.Duration
+	}{
+		{"", defaultPauseDuration},
+		{"7d", 7 * 24 * time.Hour},
+		{"12h", 12 * time.Hour},
+		{"1h30m", 90 * time.Minute},
+	}
+	for _, tt := range tests {
+		if got, err := parsePauseDuration(tt.args); err != nil || got != tt.want {
+			t.Errorf("parsePauseDuration(%q) = %v, %v, want %v", tt.args, got, err, tt.want)
+		}
+	}
+	for _, args := range []string{"soon", "0d", "-2h", "d"} {
+		if _, err := parsePauseDuration(args); err == nil {
+			t.Errorf("parsePauseDuration(%q) was accepted", args)
+		}
+	}
+}
+
+func TestPausedUserIsNotifiedAfterThePauseExpires(t *testing.T) {
+	bot, fake := newFakeTelegram(t)
+	botState := newTestBotState(t)
+	botState.AddUser(&tgbotapi.User{FirstName: "Test"}, 1)
+
+	handleMessage(bot, botState, userMessage(1, "/pause 2h"), BotConfig{})
+	user, _ := botState.GetUser(1)
+	if until := time.Until(user.PausedUntil); until < time.Hour || until > 2*time.Hour {
+		t.Fatalf("paused until %v, want in about 2 hours", user.PausedUntil)
+	}
+
+	fake.reset()
+	notifyUsers(bot, botState, []state.RentalOffer{testOffer("https://example.com/1", "900 €/kk")})
+	if sent := fake.calls("sendMessage"); len(sent) != 0 {
+		t.Fatalf("a paused user was notified: %v", sent)
+	}
+
+	// The pause has run out by the next cycle
+	botState.SetUserPause(1, time.Now().Add(-time.Minute))
+	notifyUsers(bot, botState, []state.RentalOffer{testOffer("https://example.com/2", "900 €/kk")})
+	if sent := fake.calls("sendMessage"); len(sent) != 1 {
+		t.Errorf("sent %d messages after the pause expired, want 1", len(sent))
+	}
+}
+
+func TestResumeEndsPauseEarly(t *testing.T) {
+	bot, fake := newFakeTelegram(t)
+	botState := newTestBotState(t)
+	botState.AddUser(&tgbotapi.User{FirstName: "Test"}, 1)
+	botState.SetUserPause(1, time.Now().Add(7*24*time.Hour))
+
+	handleMessage(bot, botState, userMessage(1, "/resume"), BotConfig{})
+	if sent := fake.calls("sendMessage"); len(sent) != 1 || sent[0].Get("text") != translate("en", "resume_done") {
+		t.Fatalf("sent %v, want the resume confirmation", sent)
+	}
+	if user, _ := botState.GetUser(1); !user.PausedUntil.IsZero() {
+		t.Errorf("paused until %v after /resume", user.PausedUntil)
+	}
+
+	fake.reset()
+	notifyUsers(bot, botState, []state.RentalOffer{testOffer("https://example.com/1", "900 €/kk")})
+	if sent := fake.calls("sendMessage"); len(sent) != 1 {
+		t.Errorf("sent %d messages after /resume, want 1", len(sent))
+	}
+
+	fake.reset()
+	handleMessage(bot, botState, userMessage(1, "/resume"), BotConfig{})
+	if sent := fake.calls("sendMessage"); len(sent) != 1 || sent[0].Get("text") != translate("en", "resume_not_paused") {
+		t.Errorf("sent %v, want the not paused note", sent)
+	}
+}
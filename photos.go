@@ -0,0 +1,216 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aqaliarept/vuokraovi-bot/state"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+const (
+	// maxArchivePhotos is the maximum number of photos included in one archive
+	maxArchivePhotos = 50
+	// maxArchiveBytes is the maximum total size of the photos in one archive
+	maxArchiveBytes = 20 * 1024 * 1024
+	// photoDownloadDelay is the delay between two photo downloads
+	photoDownloadDelay = 300 * time.Millisecond
+)
+
+// imageFetcher downloads the image at the given URL
+type imageFetcher func(imageURL string) ([]byte, error)
+
+// httpImageFetcher downloads images over HTTP
+func httpImageFetcher(client *http.Client) imageFetcher {
+	return func(imageURL string) ([]byte, error) {
+		resp, err := client.Get(imageURL)
+		if err != nil {
+			return nil, fmt.Errorf("error downloading image: %w", err)
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+		}
+
+		// Read one byte more than allowed so oversized images can be detected
+		data, err := io.ReadAll(io.LimitReader(resp.Body, maxArchiveBytes+1))
+		if err != nil {
+			return nil, fmt.Errorf("error reading image: %w", err)
+		}
+		return data, nil
+	}
+}
+
+// buildPhotoArchive downloads the photos of the given offers and zips them in memory.
+// Offers without photos are skipped, and the archive is capped by maxImages and maxBytes;
+// photos that would exceed maxBytes are skipped too.
+// It returns the archive and the number of photos it contains.
+func buildPhotoArchive(offers []state.RentalOffer, fetch imageFetcher, delay time.Duration, maxImages int, maxBytes int) ([]byte, int, error) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+
+	count := 0
+	totalBytes := 0
+	for _, offer := range offers {
+		if offer.ImageURL == "" {
+			continue
+		}
+		if count >= maxImages {
+			break
+		}
+		if count > 0 && delay > 0 {
+			time.Sleep(delay)
+		}
+
+		data, err := fetch(offer.ImageURL)
+		if err != nil {
+			log.Printf("Error fetching photo %s: %v", offer.ImageURL, err)
+			continue
+		}
+		// A smaller photo further on may still fit
+		if totalBytes+len(data) > maxBytes {
+			log.Printf("Photo %s does not fit in the archive size limit, skipping it", offer.ImageURL)
+			continue
+		}
+
+		w, err := zw.Create(photoFileName(count+1, offer))
+		if err != nil {
+			return nil, 0, fmt.Errorf("error adding photo to archive: %w", err)
+		}
+		if _, err := w.Write(data); err != nil {
+			return nil, 0, fmt.Errorf("error writing photo to archive: %w", err)
+		}
+
+		count++
+		totalBytes += len(data)
+	}
+
+	if err := zw.Close(); err != nil {
+		return nil, 0, fmt.Errorf("error closing archive: %w", err)
+	}
+
+	return buf.Bytes(), count, nil
+}
+
+// photoFileName builds the name of a photo inside the archive
+func photoFileName(index int, offer state.RentalOffer) string {
+	ext := path.Ext(offer.ImageURL)
+	if ext == "" || len(ext) > 5 {
+		ext = ".jpg"
+	}
+
+	name := offer.Title
+	if name == "" {
+		name = "offer"
+	}
+
+	return fmt.Sprintf("%02d_%s%s", index, sanitizeFileName(name), ext)
+}
+
+// sanitizeFileName replaces characters that are not safe in file names
+func sanitizeFileName(name string) string {
+	result := []rune{}
+	for _, r := range name {
+		switch {
+		case r == ' ' || r == '-' || r == '_':
+			result = append(result, '_')
+		case r == '/' || r == '\\' || r == ':' || r == '*' || r == '?' || r == '"' || r == '<' || r == '>' || r == '|':
+			continue
+		default:
+			result = append(result, r)
+		}
+	}
+	return string(result)
+}
+
+// photoJobs holds the chats with a photo archive being built, so a user cannot start several at once
+var photoJobs sync.Map
+
+// photoOffers returns the offers whose photos /photos collects: the user's favorites
+// when favorites is set, and the known offers matching the user's filters otherwise
+func photoOffers(botState *state.BotState, chatID int64, favorites bool) []state.RentalOffer {
+	var candidates []state.RentalOffer
+	if favorites {
+		candidates = botState.GetFavorites(chatID)
+	} else {
+		candidates = matchingOffers(botState, chatID, sortedKnownOffers(botState))
+	}
+
+	offers := make([]state.RentalOffer, 0, len(candidates))
+	for _, offer := range candidates {
+		if offer.ImageURL != "" {
+			offers = append(offers, offer)
+		}
+	}
+	return offers
+}
+
+// handlePhotosCommand handles the /photos command. The archive is built in the
+// background so downloading the photos does not block other updates.
+func handlePhotosCommand(bot *tgbotapi.BotAPI, botState *state.BotState, message *tgbotapi.Message) {
+	chatID := message.Chat.ID
+	lang := userLanguage(botState, chatID)
+	args := strings.ToLower(strings.TrimSpace(message.CommandArguments()))
+
+	favorites := args == "fav" || args == "favorites"
+	if args != "" && !favorites {
+		msg := tgbotapi.NewMessage(chatID, translate(lang, "photos_usage"))
+		msg.ReplyMarkup = createMainKeyboard(lang)
+		sendMessage(bot, msg)
+		return
+	}
+
+	offers := photoOffers(botState, chatID, favorites)
+	if len(offers) == 0 {
+		msg := tgbotapi.NewMessage(chatID, translate(lang, "photos_empty"))
+		msg.ReplyMarkup = createMainKeyboard(lang)
+		sendMessage(bot, msg)
+		return
+	}
+
+	if _, running := photoJobs.LoadOrStore(chatID, true); running {
+		sendMessage(bot, tgbotapi.NewMessage(chatID, translate(lang, "photos_running")))
+		return
+	}
+
+	sendMessage(bot, tgbotapi.NewMessage(chatID, translate(lang, "photos_collecting", len(offers))))
+
+	go func() {
+		defer photoJobs.Delete(chatID)
+		client := &http.Client{Timeout: 30 * time.Second}
+		sendPhotoArchive(bot, chatID, offers, httpImageFetcher(client), lang)
+	}()
+}
+
+// sendPhotoArchive zips the photos of the offers and sends the archive to the chat
+func sendPhotoArchive(bot *tgbotapi.BotAPI, chatID int64, offers []state.RentalOffer, fetch imageFetcher, lang string) {
+	archive, count, err := buildPhotoArchive(offers, fetch, photoDownloadDelay, maxArchivePhotos, maxArchiveBytes)
+	if err != nil || count == 0 {
+		if err != nil {
+			log.Printf("Error building photo archive for user %d: %v", chatID, err)
+		}
+		msg := tgbotapi.NewMessage(chatID, translate(lang, "photos_failed"))
+		msg.ReplyMarkup = createMainKeyboard(lang)
+		sendMessage(bot, msg)
+		return
+	}
+
+	doc := tgbotapi.NewDocument(chatID, tgbotapi.FileBytes{
+		Name:  fmt.Sprintf("vuokraovi_photos_%s.zip", time.Now().Format("20060102")),
+		Bytes: archive,
+	})
+	doc.Caption = translate(lang, "photos_caption", count)
+	doc.ReplyMarkup = createMainKeyboard(lang)
+	if _, err := sendMessage(bot, doc); err != nil {
+		log.Printf("Error sending photo archive to user %d: %v", chatID, err)
+	}
+}
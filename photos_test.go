@@ -0,0 +1,134 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/aqaliarept/vuokraovi-bot/state"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// fakeImages is an image fetcher serving images from memory
+type fakeImages map[string][]byte
+
+func (f fakeImages) fetch(imageURL string) ([]byte, error) {
+	data, ok := f[imageURL]
+	if !ok {
+		return nil, errors.New("not found")
+	}
+	return data, nil
+}
+
+// readArchive returns the file names and contents of a zip archive
+func readArchive(t *testing.T, archive []byte) map[string]string {
+	t.Helper()
+	zr, err := zip.NewReader(bytes.NewReader(archive), int64(len(archive)))
+	if err != nil {
+		t.Fatalf("invalid zip archive: %v", err)
+	}
+	files := make(map[string]string)
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatalf("error opening %s: %v", f.Name, err)
+		}
+		data, _ := io.ReadAll(rc)
+		rc.Close()
+		files[f.Name] = string(data)
+	}
+	return files
+}
+
+func TestBuildPhotoArchiveSkipsOffersWithoutPhotos(t *testing.T) {
+	images := fakeImages{
+		"https://img.example.com/a.png": []byte("image a"),
+		"https://img.example.com/b":     []byte("image b"),
+	}
+	offers := []state.RentalOffer{
+		{Title: "Katu 1", ImageURL: "https://img.example.com/a.png"},
+		{Title: "No photo"},
+		{Title: "Katu 2/B", ImageURL: "https://img.example.com/b"},
+		{Title: "Broken", ImageURL: "https://img.example.com/missing.jpg"},
+	}
+
+	archive, count, err := buildPhotoArchive(offers, images.fetch, 0, 10, 1024)
+	if err != nil {
+		t.Fatalf("buildPhotoArchive: %v", err)
+	}
+	if count != 2 {
+		t.Fatalf("count = %d, want 2", count)
+	}
+
+	files := readArchive(t, archive)
+	if files["01_Katu_1.png"] != "image a" || files["02_Katu_2B.jpg"] != "image b" || len(files) != 2 {
+		t.Errorf("unexpected archive contents: %v", files)
+	}
+}
+
+func TestBuildPhotoArchiveCapsImagesAndSize(t *testing.T) {
+	images := fakeImages{
+		"https://img.example.com/1.jpg": bytes.Repeat([]byte("x"), 40),
+		"https://img.example.com/2.jpg": bytes.Repeat([]byte("x"), 40),
+		"https://img.example.com/3.jpg": bytes.Repeat([]byte("x"), 40),
+	}
+	offers := []state.RentalOffer{
+		{Title: "1", ImageURL: "https://img.example.com/1.jpg"},
+		{Title: "2", ImageURL: "https://img.example.com/2.jpg"},
+		{Title: "3", ImageURL: "https://img.example.com/3.jpg"},
+	}
+
+	if _, count, _ := buildPhotoArchive(offers, images.fetch, 0, 2, 1024); count != 2 {
+		t.Errorf("image cap: count = %d, want 2", count)
+	}
+	if _, count, _ := buildPhotoArchive(offers, images.fetch, 0, 10, 100); count != 2 {
+		t.Errorf("size cap: count = %d, want 2", count)
+	}
+}
+
+func TestBuildPhotoArchiveSkipsOversizedPhoto(t *testing.T) {
+	images := fakeImages{
+		"https://img.example.com/1.jpg": bytes.Repeat([]byte("x"), 40),
+		"https://img.example.com/2.jpg": bytes.Repeat([]byte("x"), 200),
+		"https://img.example.com/3.jpg": bytes.Repeat([]byte("x"), 40),
+	}
+	offers := []state.RentalOffer{
+		{Title: "1", ImageURL: "https://img.example.com/1.jpg"},
+		{Title: "2", ImageURL: "https://img.example.com/2.jpg"},
+		{Title: "3", ImageURL: "https://img.example.com/3.jpg"},
+	}
+
+	archive, count, err := buildPhotoArchive(offers, images.fetch, 0, 10, 100)
+	if err != nil {
+		t.Fatalf("buildPhotoArchive: %v", err)
+	}
+	files := readArchive(t, archive)
+	if count != 2 || len(files["01_1.jpg"]) != 40 || len(files["02_3.jpg"]) != 40 || len(files) != 2 {
+		t.Errorf("count = %d, files = %v, want the photos around the oversized one", count, files)
+	}
+}
+
+func TestPhotoOffersUsesFiltersOrFavorites(t *testing.T) {
+	botState, err := state.NewBotState(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	const chatID = 42
+	botState.AddUser(&tgbotapi.User{FirstName: "Test"}, chatID)
+	botState.ApplyOffers([]state.RentalOffer{
+		{Title: "Cheap", Address: "Katu 1, Helsinki", Price: "800 €", PriceEUR: 800, Link: "https://example.com/1", ImageURL: "https://img.example.com/1.jpg"},
+		{Title: "Expensive", Address: "Katu 2, Helsinki", Price: "1500 €", PriceEUR: 1500, Link: "https://example.com/2", ImageURL: "https://img.example.com/2.jpg"},
+		{Title: "No photo", Address: "Katu 3, Helsinki", Price: "700 €", PriceEUR: 700, Link: "https://example.com/3"},
+	})
+	botState.SetUserFilters(chatID, state.Filters{MaxPrice: 1000})
+	botState.AddFavorite(chatID, "2")
+
+	if got := photoOffers(botState, chatID, false); len(got) != 1 || got[0].Title != "Cheap" {
+		t.Errorf("matched photo offers = %v, want only Cheap", got)
+	}
+	if got := photoOffers(botState, chatID, true); len(got) != 1 || got[0].Title != "Expensive" {
+		t.Errorf("favorite photo offers = %v, want only Expensive", got)
+	}
+}
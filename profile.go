@@ -0,0 +1,125 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/aqaliarept/vuokraovi-bot/state"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// maxProfiles is the number of search profiles a user may save
+const maxProfiles = 10
+
+// profileNamePattern restricts profile names to characters that are safe in Markdown messages
+var profileNamePattern = regexp.MustCompile(`^[\p{L}0-9-]{1,32}$`)
+
+// formatProfiles describes the user's search profiles
+func formatProfiles(profiles []state.Profile, lang string) string {
+	if len(profiles) == 0 {
+		return translate(lang, "profiles_none")
+	}
+
+	text := translate(lang, "profiles_header")
+	for _, profile := range profiles {
+		status := translate(lang, "profile_inactive")
+		if profile.Active {
+			status = translate(lang, "profile_active")
+		}
+		text += fmt.Sprintf("🏷 %s (%s)\n%s\n", profile.Name, status, formatFilters(profile.Filters, lang))
+	}
+	return text
+}
+
+// profileTags formats the names of the active profiles matching the offer
+func profileTags(profiles []state.Profile, offer state.RentalOffer) string {
+	names := state.MatchingProfiles(profiles, offer)
+	if len(names) == 0 {
+		return ""
+	}
+	return "🏷 " + strings.Join(names, ", ")
+}
+
+// handleProfileCommand handles the /profile command
+func handleProfileCommand(bot *tgbotapi.BotAPI, botState *state.BotState, message *tgbotapi.Message) {
+	chatID := message.Chat.ID
+	lang := userLanguage(botState, chatID)
+	args := strings.Fields(message.CommandArguments())
+
+	current, exists := botState.GetUserFilters(chatID)
+	if !exists {
+		msg := tgbotapi.NewMessage(chatID, translate(lang, "start_first"))
+		msg.ReplyMarkup = createMainKeyboard(lang)
+		sendMessage(bot, msg)
+		return
+	}
+
+	var text string
+	switch {
+	case len(args) == 0:
+		text = translate(lang, "profile_usage")
+	case args[0] == "list":
+		text = formatProfiles(botState.GetProfiles(chatID), lang)
+	case len(args) < 2:
+		text = translate(lang, "profile_missing_name") + "\n\n" + translate(lang, "profile_usage")
+	case !profileNamePattern.MatchString(args[1]):
+		text = translate(lang, "profile_invalid_name", args[1])
+	default:
+		text = runProfileSubcommand(botState, chatID, args[0], args[1], strings.Join(args[2:], " "), current, lang)
+	}
+
+	msg := tgbotapi.NewMessage(chatID, text)
+	msg.ReplyMarkup = createMainKeyboard(lang)
+	sendMessage(bot, msg)
+}
+
+// runProfileSubcommand runs a /profile subcommand operating on the named profile and returns the reply
+func runProfileSubcommand(botState *state.BotState, chatID int64, subcommand, name, filterArgs string, current state.Filters, lang string) string {
+	switch subcommand {
+	case "add":
+		filters := current
+		if filterArgs != "" {
+			var err error
+			if filters, err = parseFilterArgs(filterArgs, state.Filters{}); err != nil {
+				return fmt.Sprintf("❌ %v\n\n%s", err, translate(lang, "filter_usage"))
+			}
+		}
+		profiles := botState.GetProfiles(chatID)
+		if len(profiles) >= maxProfiles && !hasProfile(profiles, name) {
+			return translate(lang, "profile_limit", maxProfiles)
+		}
+		created, _ := botState.SaveProfile(chatID, name, filters)
+		if created {
+			return translate(lang, "profile_saved", name) + "\n" + formatFilters(filters, lang)
+		}
+		return translate(lang, "profile_updated", name) + "\n" + formatFilters(filters, lang)
+	case "use":
+		if !botState.SetProfileActive(chatID, name, true) {
+			return translate(lang, "profile_not_found", name)
+		}
+		return translate(lang, "profile_activated", name)
+	case "stop":
+		if !botState.SetProfileActive(chatID, name, false) {
+			return translate(lang, "profile_not_found", name)
+		}
+		return translate(lang, "profile_deactivated", name)
+	case "del", "delete":
+		if !botState.DeleteProfile(chatID, name) {
+			return translate(lang, "profile_not_found", name)
+		}
+		return translate(lang, "profile_deleted", name)
+	default:
+		return translate(lang, "profile_unknown_subcommand", subcommand) + "\n\n" + translate(lang, "profile_usage")
+	}
+}
+
+// hasProfile reports whether a profile with the given name exists, ignoring case
+func hasProfile(profiles []state.Profile, name string) bool {
+	for _, profile := range profiles {
+		if strings.EqualFold(profile.Name, name) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/aqaliarept/vuokraovi-bot/state"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// profileOffers creates offers in Espoo and Helsinki
+func profileOffers() []state.RentalOffer {
+	espoo := testOffer("https://example.com/espoo", "950 €/kk")
+	espoo.Address = "Tapiontori 1, Espoo"
+	helsinki := testOffer("https://example.com/helsinki", "1200 €/kk")
+	return []state.RentalOffer{espoo, helsinki}
+}
+
+func TestProfileCommands(t *testing.T) {
+	bot, fake := newFakeTelegram(t)
+	botState := newTestBotState(t)
+
+	// handleMessage adds the sender first, so call the handler directly
+	handleProfileCommand(bot, botState, userMessage(1, "/profile add espoo"))
+	if text := fake.calls("sendMessage")[0].Get("text"); text != translate("en", "start_first") {
+		t.Errorf("reply before /start = %q, want the start hint", text)
+	}
+
+	botState.AddUser(&tgbotapi.User{FirstName: "Test"}, 1)
+	botState.SetUserLanguage(1, "fi")
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"/profile add espoo city=Espoo price<=1000", translate("fi", "profile_saved", "espoo")},
+		{"/profile add espoo city=Espoo", translate("fi", "profile_updated", "espoo")},
+		{"/profile stop espoo", translate("fi", "profile_deactivated", "espoo")},
+		{"/profile use espoo", translate("fi", "profile_activated", "espoo")},
+		{"/profile list", translate("fi", "profiles_header") + "🏷 espoo (" + translate("fi", "profile_active") + ")"},
+		{"/profile use tampere", translate("fi", "profile_not_found", "tampere")},
+		{"/profile add bad!name", translate("fi", "profile_invalid_name", "bad!name")},
+		{"/profile add", translate("fi", "profile_missing_name")},
+		{"/profile rename espoo", translate("fi", "profile_unknown_subcommand", "rename")},
+		{"/profile del espoo", translate("fi", "profile_deleted", "espoo")},
+		{"/profile list", translate("fi", "profiles_none")},
+	}
+	for _, tt := range tests {
+		fake.reset()
+		handleMessage(bot, botState, userMessage(1, tt.text), BotConfig{})
+		if sent := fake.calls("sendMessage"); len(sent) != 1 || !strings.HasPrefix(sent[0].Get("text"), tt.want) {
+			t.Errorf("%s: sent %v, want a reply starting with %q", tt.text, sent, tt.want)
+		}
+	}
+}
+
+func TestNotifyUsersTagsMatchingProfiles(t *testing.T) {
+	bot, fake := newFakeTelegram(t)
+	botState := newTestBotState(t)
+	botState.AddUser(&tgbotapi.User{FirstName: "Test"}, 1)
+	// The profiles replace the plain filters, which would exclude Espoo
+	botState.SetUserFilters(1, state.Filters{City: "Helsinki"})
+	botState.SaveProfile(1, "espoo", state.Filters{City: "Espoo"})
+	botState.SaveProfile(1, "cheap", state.Filters{MaxPrice: 1000})
+	offers := profileOffers()
+	botState.ApplyOffers(offers)
+
+	notifyUsers(bot, botState, offers)
+
+	sent := fake.calls("sendMessage")
+	if len(sent) != 1 {
+		t.Fatalf("sent %d messages, want one", len(sent))
+	}
+	text := sent[0].Get("text")
+	if !strings.Contains(text, "Tapiontori 1, Espoo") || strings.Contains(text, "Testikatu 1, Helsinki") {
+		t.Errorf("message %q, want only the offer matching the profiles", text)
+	}
+	if !strings.Contains(text, "🏷 espoo, cheap") {
+		t.Errorf("message %q does not tag the matching profiles", text)
+	}
+}
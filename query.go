@@ -0,0 +1,93 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/aqaliarept/vuokraovi-bot/scraper"
+	"github.com/aqaliarept/vuokraovi-bot/state"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// formSummary describes the search form body as one "key = value" line per field
+// in the order of the form, with the values decoded. Empty fields and the "_field=on"
+// markers the site adds for every checkbox are left out.
+func formSummary(formData string) (string, error) {
+	var lines []string
+	for _, pair := range strings.Split(formData, "&") {
+		if pair == "" {
+			continue
+		}
+		rawKey, rawValue, _ := strings.Cut(pair, "=")
+		key, err := url.QueryUnescape(rawKey)
+		if err != nil {
+			return "", fmt.Errorf("invalid form field %q: %w", rawKey, err)
+		}
+		value, err := url.QueryUnescape(rawValue)
+		if err != nil {
+			return "", fmt.Errorf("invalid value of form field %s: %w", key, err)
+		}
+		if value == "" || strings.HasPrefix(key, "_") {
+			continue
+		}
+		lines = append(lines, key+" = "+value)
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+// queryPreview describes the search sent to the site: the initial request and the
+// decoded form fields
+func queryPreview(initialURL, formData string) (string, error) {
+	summary, err := formSummary(formData)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("POST %s\n\n%s", initialURL, summary), nil
+}
+
+// handleQueryCommand handles the /query command, showing the search the bot sends
+// to the site without fetching it
+func handleQueryCommand(bot *tgbotapi.BotAPI, botState *state.BotState, message *tgbotapi.Message, config BotConfig) {
+	chatID := message.Chat.ID
+	lang := userLanguage(botState, chatID)
+
+	text, err := botQueryPreview(config)
+	if err != nil {
+		text = translate(lang, "query_failed", err)
+	} else {
+		text = translate(lang, "query_header") + text
+	}
+
+	msg := tgbotapi.NewMessage(chatID, text)
+	msg.ReplyMarkup = createMainKeyboard(lang)
+	sendMessage(bot, msg)
+}
+
+// botQueryPreview returns the query previews of the searches configured for the bot
+func botQueryPreview(config BotConfig) (string, error) {
+	website, err := scraper.New(false)
+	if err != nil {
+		return "", fmt.Errorf("error creating website client: %w", err)
+	}
+	if config.Locale != "" {
+		website.Locale = config.Locale
+	}
+
+	var previews []string
+	for _, search := range botSearches(config) {
+		formData, err := searchFormData(config, search)
+		if err != nil {
+			return "", err
+		}
+		preview, err := queryPreview(website.InitialURL(), formData)
+		if err != nil {
+			return "", err
+		}
+		if search.Name != "" {
+			preview = "[" + search.Name + "]\n" + preview
+		}
+		previews = append(previews, preview)
+	}
+	return strings.Join(previews, "\n\n"), nil
+}
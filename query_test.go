@@ -0,0 +1,72 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aqaliarept/vuokraovi-bot/state"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+func TestFormSummaryListsDecodedFields(t *testing.T) {
+	formData := "method=search&type=full" +
+		"&location.classifiedLocation=i%3A0%7Cc%3AFI_PIRKANMAA_TAMPERE%7Ct%3AMUNICIPALITY%7Cn%3ATampere" +
+		"&rent.rentMin=&rent.rentMax=900&_features.vacantNow=on" +
+		"&building.roomAmount=2&_building.roomAmount=on&building.roomAmount=3&search=Hae+asuntoja"
+
+	got, err := formSummary(formData)
+	if err != nil {
+		t.Fatalf("formSummary: %v", err)
+	}
+	want := strings.Join([]string{
+		"method = search",
+		"type = full",
+		"location.classifiedLocation = i:0|c:FI_PIRKANMAA_TAMPERE|t:MUNICIPALITY|n:Tampere",
+		"rent.rentMax = 900",
+		"building.roomAmount = 2",
+		"building.roomAmount = 3",
+		"search = Hae asuntoja",
+	}, "\n")
+	if got != want {
+		t.Errorf("formSummary =\n%s\nwant\n%s", got, want)
+	}
+
+	if _, err := formSummary("method=search&type=%zz"); err == nil {
+		t.Error("formSummary accepted an invalid escape")
+	}
+}
+
+func TestQueryCommandShowsTheSearchRequest(t *testing.T) {
+	bot, fake := newFakeTelegram(t)
+	botState := newTestBotState(t)
+	path := filepath.Join(t.TempDir(), "form_data.txt")
+	os.WriteFile(path, []byte("method=search&type=full&rent.rentMax=900\n"), 0644)
+	config := BotConfig{FormDataFile: path, Locale: "en"}
+	original := fetchOffers
+	fetchOffers = func(BotConfig) ([]state.RentalOffer, error) {
+		t.Error("/query fetched the offers")
+		return nil, nil
+	}
+	t.Cleanup(func() { fetchOffers = original })
+
+	handleMessage(bot, botState, userMessage(1, "/query"), config)
+
+	sent := fake.calls("sendMessage")
+	if len(sent) != 1 {
+		t.Fatalf("sent %d messages, want 1", len(sent))
+	}
+	text := sent[0].Get("text")
+	for _, want := range []string{"POST https://www.vuokraovi.com/haku/vuokra-asunnot?locale=en", "rent.rentMax = 900"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("reply %q does not contain %q", text, want)
+		}
+	}
+
+	fake.reset()
+	handleQueryCommand(bot, botState, &tgbotapi.Message{Chat: &tgbotapi.Chat{ID: 1}}, BotConfig{FormDataFile: filepath.Join(t.TempDir(), "missing.txt")})
+	if text := fake.calls("sendMessage")[0].Get("text"); !strings.HasPrefix(text, "❌") {
+		t.Errorf("reply %q, want an error for a missing form data file", text)
+	}
+}
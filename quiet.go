@@ -0,0 +1,83 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	// Embed the timezone database, the container image does not ship one
+	_ "time/tzdata"
+
+	"github.com/aqaliarept/vuokraovi-bot/state"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// defaultQuietTimezone is used when /quiet is given no timezone
+const defaultQuietTimezone = "Europe/Helsinki"
+
+// parseQuietArgs parses /quiet arguments like "22-08 Europe/Helsinki"
+func parseQuietArgs(args string) (state.QuietHours, error) {
+	fields := strings.Fields(args)
+	if len(fields) == 0 || len(fields) > 2 {
+		return state.QuietHours{}, fmt.Errorf("expected a window like 22-08 and an optional timezone")
+	}
+
+	hours := strings.SplitN(fields[0], "-", 2)
+	if len(hours) != 2 {
+		return state.QuietHours{}, fmt.Errorf("invalid window %q, expected START-END like 22-08", fields[0])
+	}
+	start, err := strconv.Atoi(hours[0])
+	if err != nil {
+		return state.QuietHours{}, fmt.Errorf("invalid start hour %q", hours[0])
+	}
+	end, err := strconv.Atoi(hours[1])
+	if err != nil {
+		return state.QuietHours{}, fmt.Errorf("invalid end hour %q", hours[1])
+	}
+
+	timezone := defaultQuietTimezone
+	if len(fields) == 2 {
+		timezone = fields[1]
+	}
+	return state.NewQuietHours(start, end, timezone)
+}
+
+// handleQuietCommand handles the /quiet command
+func handleQuietCommand(bot *tgbotapi.BotAPI, botState *state.BotState, message *tgbotapi.Message) {
+	chatID := message.Chat.ID
+	lang := userLanguage(botState, chatID)
+	args := strings.TrimSpace(message.CommandArguments())
+
+	current, exists := botState.GetUserQuietHours(chatID)
+	if !exists {
+		msg := tgbotapi.NewMessage(chatID, translate(lang, "start_first"))
+		msg.ReplyMarkup = createMainKeyboard(lang)
+		sendMessage(bot, msg)
+		return
+	}
+
+	var text string
+	switch {
+	case args == "":
+		if current.Enabled() {
+			text = translate(lang, "quiet_current", current.Start, current.End, current.Timezone)
+		} else {
+			text = translate(lang, "quiet_none")
+		}
+		text += "\n\n" + translate(lang, "quiet_usage") + "\n" + translate(lang, "quiet_off_hint")
+	case strings.EqualFold(args, "off"):
+		botState.SetUserQuietHours(chatID, state.QuietHours{})
+		text = translate(lang, "quiet_disabled")
+	default:
+		quiet, err := parseQuietArgs(args)
+		if err != nil {
+			text = fmt.Sprintf("❌ %v\n\n%s", err, translate(lang, "quiet_usage"))
+			break
+		}
+		botState.SetUserQuietHours(chatID, quiet)
+		text = translate(lang, "quiet_set", quiet.Start, quiet.End, quiet.Timezone)
+	}
+
+	msg := tgbotapi.NewMessage(chatID, text)
+	msg.ReplyMarkup = createMainKeyboard(lang)
+	sendMessage(bot, msg)
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aqaliarept/vuokraovi-bot/state"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+func TestParseQuietArgs(t *testing.T) {
+	quiet, err := parseQuietArgs("22-08 Europe/Stockholm")
+	if err != nil || quiet.Start != 22 || quiet.End != 8 || quiet.Timezone != "Europe/Stockholm" {
+		t.Errorf("parseQuietArgs = %+v, %v", quiet, err)
+	}
+	if quiet, _ := parseQuietArgs("23-6"); quiet.Timezone != defaultQuietTimezone {
+		t.Errorf("timezone = %q, want the default", quiet.Timezone)
+	}
+	for _, args := range []string{"", "22", "late-08", "22-08 Europe/Helsinki extra", "22-25"} {
+		if _, err := parseQuietArgs(args); err == nil {
+			t.Errorf("parseQuietArgs(%q) accepted bad input", args)
+		}
+	}
+}
+
+func TestNotifyUsersHoldsOffersDuringQuietHours(t *testing.T) {
+	bot, fake := newFakeTelegram(t)
+	botState := newTestBotState(t)
+	botState.AddUser(&tgbotapi.User{FirstName: "Sleeping"}, 1)
+	now := time.Now().UTC()
+	quiet, _ := state.NewQuietHours(now.Hour(), (now.Hour()+2)%24, "UTC")
+	botState.SetUserQuietHours(1, quiet)
+
+	offers := botState.UpdateOffers([]state.RentalOffer{testOffer("https://example.com/a", "800 €/kk")})
+	notifyUsers(bot, botState, offers)
+	if sent := fake.calls("sendMessage"); len(sent) != 0 {
+		t.Fatalf("sent %d messages during quiet hours, want none", len(sent))
+	}
+
+	// The held back offers are delivered with the first update after the window
+	botState.SetUserQuietHours(1, state.QuietHours{})
+	notifyUsers(bot, botState, nil)
+	sent := fake.calls("sendMessage")
+	if len(sent) != 1 || !strings.Contains(sent[0].Get("text"), "Testikatu 1") {
+		t.Fatalf("sent %v, want the held back offer", sent)
+	}
+}
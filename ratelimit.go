@@ -0,0 +1,124 @@
+package main
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+const (
+	// defaultMessagesPerSecond stays below Telegram's limit of about 30 messages per second
+	defaultMessagesPerSecond = 25
+	// maxSendAttempts is how often a message is sent when Telegram asks to retry later
+	// or the send fails with a transient error
+	maxSendAttempts = 3
+)
+
+// rateLimiter is a token bucket shared by all goroutines sending messages
+type rateLimiter struct {
+	mutex  sync.Mutex
+	rate   float64 // tokens added per second, 0 disables the limit
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+// newRateLimiter creates a limiter allowing perSecond events per second with bursts of the same size
+func newRateLimiter(perSecond float64) *rateLimiter {
+	burst := perSecond
+	if burst < 1 {
+		burst = 1
+	}
+	return &rateLimiter{
+		rate:   perSecond,
+		burst:  burst,
+		tokens: burst,
+		last:   time.Now(),
+	}
+}
+
+// Wait blocks until the next event is allowed
+func (l *rateLimiter) Wait() {
+	if l.rate <= 0 {
+		return
+	}
+
+	l.mutex.Lock()
+	now := time.Now()
+	l.tokens += now.Sub(l.last).Seconds() * l.rate
+	if l.tokens > l.burst {
+		l.tokens = l.burst
+	}
+	l.last = now
+
+	// Take a token, going into debt reserves a slot in the future
+	l.tokens--
+	var wait time.Duration
+	if l.tokens < 0 {
+		wait = time.Duration(-l.tokens / l.rate * float64(time.Second))
+	}
+	l.mutex.Unlock()
+
+	time.Sleep(wait)
+}
+
+// messageLimiter paces all messages sent by the bot
+var messageLimiter = newRateLimiter(defaultMessagesPerSecond)
+
+// sendRetryDelay is the pause before resending a message after a transient error,
+// doubled for every further attempt
+var sendRetryDelay = time.Second
+
+// sendMessage sends a message through the shared rate limiter.
+// When Telegram answers with 429 Too Many Requests, the message is sent again
+// after the requested delay. Network errors and server errors are retried with
+// a growing delay.
+func sendMessage(bot *tgbotapi.BotAPI, c tgbotapi.Chattable) (tgbotapi.Message, error) {
+	delay := sendRetryDelay
+	for attempt := 1; ; attempt++ {
+		messageLimiter.Wait()
+		sent, err := bot.Send(c)
+		if err == nil || attempt >= maxSendAttempts {
+			return sent, err
+		}
+
+		var apiErr *tgbotapi.Error
+		switch {
+		case errors.As(err, &apiErr) && apiErr.RetryAfter > 0:
+			log.Printf("Telegram rate limit hit, retrying in %ds", apiErr.RetryAfter)
+			time.Sleep(time.Duration(apiErr.RetryAfter) * time.Second)
+		case isTransientSendError(err):
+			log.Printf("Error sending message, retrying in %v: %v", delay, err)
+			time.Sleep(delay)
+			delay *= 2
+		default:
+			return sent, err
+		}
+	}
+}
+
+// isTransientSendError reports whether sending may succeed when tried again: the
+// request did not reach Telegram or Telegram failed to handle it
+func isTransientSendError(err error) bool {
+	var apiErr *tgbotapi.Error
+	if errors.As(err, &apiErr) {
+		return apiErr.Code >= 500
+	}
+	return err != nil
+}
+
+// isChatUnreachable reports whether Telegram refused a message for good because the
+// user blocked the bot, deleted their account or the chat no longer exists
+func isChatUnreachable(err error) bool {
+	var apiErr *tgbotapi.Error
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	return apiErr.Code == http.StatusForbidden ||
+		apiErr.Code == http.StatusBadRequest && strings.Contains(strings.ToLower(apiErr.Message), "chat not found")
+}
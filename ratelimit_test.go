@@ -0,0 +1,131 @@
+package main
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aqaliarept/vuokraovi-bot/state"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+func TestRateLimiterBoundsPace(t *testing.T) {
+	const perSecond, events = 200, 300
+	limiter := newRateLimiter(perSecond)
+
+	start := time.Now()
+	var wg sync.WaitGroup
+	for g := 0; g < 4; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < events/4; i++ {
+				limiter.Wait()
+			}
+		}()
+	}
+	wg.Wait()
+
+	// The burst passes right away, the rest at the configured rate
+	if min, elapsed := time.Duration(events-perSecond)*time.Second/perSecond, time.Since(start); elapsed < min-10*time.Millisecond {
+		t.Errorf("%d events took %v, want at least %v", events, elapsed, min)
+	}
+}
+
+func TestRateLimiterDisabled(t *testing.T) {
+	limiter := newRateLimiter(0)
+	start := time.Now()
+	for i := 0; i < 1000; i++ {
+		limiter.Wait()
+	}
+	if elapsed := time.Since(start); elapsed > 100*time.Millisecond {
+		t.Errorf("a disabled limiter took %v", elapsed)
+	}
+}
+
+func TestSendMessageRetriesAfterTooManyRequests(t *testing.T) {
+	bot, fake := newFakeTelegram(t)
+	fake.rateLimited = 1
+
+	start := time.Now()
+	if _, err := sendMessage(bot, tgbotapi.NewMessage(1, "hello")); err != nil {
+		t.Fatalf("sendMessage: %v", err)
+	}
+	if sent := fake.calls("sendMessage"); len(sent) != 2 {
+		t.Errorf("sent %d times, want a retry after the 429", len(sent))
+	}
+	if elapsed := time.Since(start); elapsed < time.Second {
+		t.Errorf("retried after %v, want the requested second", elapsed)
+	}
+}
+
+func TestSendMessageRetriesTransientErrors(t *testing.T) {
+	bot, fake := newFakeTelegram(t)
+	botState := newTestBotState(t)
+	botState.AddUser(&tgbotapi.User{FirstName: "Test"}, 1)
+	fake.serverErrors = 2
+
+	offer := testOffer("https://example.com/a", "800 €/kk")
+	notifyUsers(bot, botState, []state.RentalOffer{offer})
+
+	if sent := fake.calls("sendMessage"); len(sent) != 3 {
+		t.Errorf("sent %d times, want two retries after the 502s", len(sent))
+	}
+	user, _ := botState.GetUser(1)
+	if !user.Notifications || !user.SeenOffers.Has(offer.Link) {
+		t.Errorf("user = %+v, want the offer delivered and notifications left on", user)
+	}
+}
+
+func TestBlockedUserIsFlaggedAndRemoved(t *testing.T) {
+	bot, fake := newFakeTelegram(t)
+	botState := newTestBotState(t)
+	botState.AddUser(&tgbotapi.User{FirstName: "Blocked"}, 1)
+	botState.AddUser(&tgbotapi.User{FirstName: "Other"}, 2)
+	fake.blocked = true
+
+	offer := testOffer("https://example.com/a", "800 €/kk")
+	notifyUsers(bot, botState, []state.RentalOffer{offer})
+
+	if sent := fake.calls("sendMessage"); len(sent) != 2 {
+		t.Errorf("sent %d times, want one attempt per user without retries after the 403", len(sent))
+	}
+	user, _ := botState.GetUser(1)
+	if !user.Unreachable || len(user.PendingOffers) != 0 {
+		t.Errorf("user = %+v, want the user flagged and nothing queued", user)
+	}
+
+	// Flagged users are not messaged again before they are removed
+	fake.reset()
+	notifyUsers(bot, botState, []state.RentalOffer{testOffer("https://example.com/b", "900 €/kk")})
+	if sent := fake.calls("sendMessage"); len(sent) != 0 {
+		t.Errorf("sent %v to users who blocked the bot", sent)
+	}
+
+	if removed, err := botState.RemoveUnreachableUsers(); err != nil || removed != 2 {
+		t.Fatalf("RemoveUnreachableUsers = %d, %v, want 2 users removed", removed, err)
+	}
+	if _, exists := botState.GetUser(1); exists {
+		t.Error("the blocked user was not removed")
+	}
+}
+
+func TestIsChatUnreachable(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{&tgbotapi.Error{Code: 403, Message: "Forbidden: bot was blocked by the user"}, true},
+		{&tgbotapi.Error{Code: 403, Message: "Forbidden: user is deactivated"}, true},
+		{&tgbotapi.Error{Code: 400, Message: "Bad Request: chat not found"}, true},
+		{&tgbotapi.Error{Code: 400, Message: "Bad Request: can't parse entities"}, false},
+		{&tgbotapi.Error{Code: 502, Message: "Bad Gateway"}, false},
+		{errors.New("connection reset by peer"), false},
+	}
+	for _, tt := range tests {
+		if got := isChatUnreachable(tt.err); got != tt.want {
+			t.Errorf("isChatUnreachable(%v) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
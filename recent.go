@@ -0,0 +1,75 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aqaliarept/vuokraovi-bot/state"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// defaultRecentDays is the listing age used by /recent without arguments
+const defaultRecentDays = 1
+
+// recentOffers returns the offers first seen within the last days, newest first.
+// Offers without a FirstSeen timestamp are never considered recent.
+func recentOffers(offers map[string]state.RentalOffer, days int, now time.Time) []state.RentalOffer {
+	cutoff := now.Add(-time.Duration(days) * 24 * time.Hour)
+
+	recent := make([]state.RentalOffer, 0)
+	for _, offer := range offers {
+		if offer.FirstSeen.IsZero() || offer.FirstSeen.Before(cutoff) {
+			continue
+		}
+		recent = append(recent, offer)
+	}
+	sort.Slice(recent, func(i, j int) bool {
+		return recent[i].FirstSeen.After(recent[j].FirstSeen)
+	})
+	return recent
+}
+
+// parseRecentDays parses the /recent argument, defaulting to defaultRecentDays
+func parseRecentDays(args string) (int, error) {
+	args = strings.TrimSpace(args)
+	if args == "" {
+		return defaultRecentDays, nil
+	}
+	days, err := strconv.Atoi(args)
+	if err != nil || days < 1 {
+		return 0, fmt.Errorf("invalid number of days %q", args)
+	}
+	return days, nil
+}
+
+// handleRecentCommand handles the /recent command
+func handleRecentCommand(bot *tgbotapi.BotAPI, botState *state.BotState, message *tgbotapi.Message) {
+	chatID := message.Chat.ID
+	lang := userLanguage(botState, chatID)
+
+	days, err := parseRecentDays(message.CommandArguments())
+	if err != nil {
+		msg := tgbotapi.NewMessage(chatID, fmt.Sprintf("❌ %v\n\n%s", err, translate(lang, "recent_usage")))
+		msg.ReplyMarkup = createMainKeyboard(lang)
+		sendMessage(bot, msg)
+		return
+	}
+
+	offers := recentOffers(botState.GetKnownOffers(), days, time.Now())
+	if len(offers) == 0 {
+		text := translate(lang, "recent_none_day")
+		if days > 1 {
+			text = translate(lang, "recent_none_days", days)
+		}
+		msg := tgbotapi.NewMessage(chatID, text)
+		msg.ReplyMarkup = createMainKeyboard(lang)
+		sendMessage(bot, msg)
+		return
+	}
+
+	sendMessage(bot, tgbotapi.NewMessage(chatID, translate(lang, "recent_found", len(offers), days)))
+	sendOffersList(bot, offers, chatID, lang)
+}
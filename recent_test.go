@@ -0,0 +1,91 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aqaliarept/vuokraovi-bot/state"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+func TestRecentOffersAgeBoundaries(t *testing.T) {
+	now := time.Date(2024, 5, 10, 12, 0, 0, 0, time.UTC)
+	day := 24 * time.Hour
+	seen := func(link string, age time.Duration) state.RentalOffer {
+		offer := testOffer(link, "900 €/kk")
+		offer.FirstSeen = now.Add(-age)
+		return offer
+	}
+	offers := map[string]state.RentalOffer{
+		"edge":     seen("edge", day),
+		"over":     seen("over", day+time.Second),
+		"fresh":    seen("fresh", time.Hour),
+		"week":     seen("week", 7*day),
+		"untimed":  testOffer("untimed", "900 €/kk"),
+		"tomorrow": seen("tomorrow", -time.Hour),
+	}
+
+	tests := []struct {
+		days int
+		want []string
+	}{
+		{1, []string{"tomorrow", "fresh", "edge"}},
+		{2, []string{"tomorrow", "fresh", "edge", "over"}},
+		{7, []string{"tomorrow", "fresh", "edge", "over", "week"}},
+	}
+	for _, tt := range tests {
+		got := recentOffers(offers, tt.days, now)
+		var links []string
+		for _, offer := range got {
+			links = append(links, offer.Link)
+		}
+		if strings.Join(links, ",") != strings.Join(tt.want, ",") {
+			t.Errorf("recentOffers(%d days) = %v, want %v", tt.days, links, tt.want)
+		}
+	}
+}
+
+func TestParseRecentDays(t *testing.T) {
+	tests := []struct {
+		args    string
+		want    int
+		wantErr bool
+	}{
+		{"", defaultRecentDays, false},
+		{" 3 ", 3, false},
+		{"1", 1, false},
+		{"0", 0, true},
+		{"-2", 0, true},
+		{"week", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := parseRecentDays(tt.args)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("parseRecentDays(%q) = %d, %v", tt.args, got, err)
+		}
+	}
+}
+
+func TestRecentCommandReplies(t *testing.T) {
+	bot, fake := newFakeTelegram(t)
+	botState := newTestBotState(t)
+	botState.AddUser(&tgbotapi.User{FirstName: "Test"}, 1)
+
+	handleMessage(bot, botState, userMessage(1, "/recent 3"), BotConfig{})
+	if sent := fake.calls("sendMessage"); len(sent) != 1 || sent[0].Get("text") != translate("en", "recent_none_days", 3) {
+		t.Fatalf("sent %v, want the no recent offers message", sent)
+	}
+
+	botState.ApplyOffers(numberedOffers(2))
+	botState.SetUserLanguage(1, "fi")
+	fake.reset()
+	handleMessage(bot, botState, userMessage(1, "/recent"), BotConfig{})
+	sent := fake.calls("sendMessage")
+	if len(sent) != 2 || sent[0].Get("text") != translate("fi", "recent_found", 2, 1) {
+		t.Fatalf("sent %v, want the Finnish header followed by the offers", sent)
+	}
+	if text := sent[1].Get("text"); strings.Count(text, "Lisätty juuri nyt") != 2 {
+		t.Errorf("offers %q, want both offers listed just now in Finnish", text)
+	}
+}
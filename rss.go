@@ -0,0 +1,174 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aqaliarept/vuokraovi-bot/state"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// rssFeed is the root element of an RSS 2.0 document
+type rssFeed struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+// rssChannel describes the feed and holds its items
+type rssChannel struct {
+	Title         string    `xml:"title"`
+	Link          string    `xml:"link"`
+	Description   string    `xml:"description"`
+	LastBuildDate string    `xml:"lastBuildDate,omitempty"`
+	Items         []rssItem `xml:"item"`
+}
+
+// rssItem is a single offer in the feed
+type rssItem struct {
+	Title       string  `xml:"title"`
+	Link        string  `xml:"link"`
+	Description string  `xml:"description"`
+	GUID        rssGUID `xml:"guid"`
+	PubDate     string  `xml:"pubDate,omitempty"`
+}
+
+// rssGUID identifies an item across feed updates
+type rssGUID struct {
+	Value       string `xml:",chardata"`
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+}
+
+// buildRSSFeed renders the offers as an RSS 2.0 document
+func buildRSSFeed(title string, offers []state.RentalOffer, buildDate time.Time) ([]byte, error) {
+	feed := rssFeed{
+		Version: "2.0",
+		Channel: rssChannel{
+			Title:       title,
+			Link:        "https://www.vuokraovi.com",
+			Description: "Rental offers from Vuokraovi.com",
+			Items:       make([]rssItem, 0, len(offers)),
+		},
+	}
+	if !buildDate.IsZero() {
+		feed.Channel.LastBuildDate = buildDate.Format(time.RFC1123Z)
+	}
+
+	for _, offer := range offers {
+		item := rssItem{
+			Title:       offer.Title,
+			Link:        offer.Link,
+			Description: rssDescription(offer),
+			GUID:        rssGUID{Value: offer.Link, IsPermaLink: true},
+		}
+		if item.Title == "" {
+			item.Title = offer.Address
+		}
+		if !offer.AvailableFrom.IsZero() {
+			item.PubDate = offer.AvailableFrom.Format(time.RFC1123Z)
+		}
+		feed.Channel.Items = append(feed.Channel.Items, item)
+	}
+
+	data, err := xml.MarshalIndent(feed, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode RSS feed: %w", err)
+	}
+	return append([]byte(xml.Header), data...), nil
+}
+
+// rssDescription summarizes an offer for the item description
+func rssDescription(offer state.RentalOffer) string {
+	parts := []string{}
+	for _, value := range []string{offer.Address, offer.Price, offer.Size, offer.Rooms, offer.Available} {
+		if value != "" {
+			parts = append(parts, value)
+		}
+	}
+	return strings.Join(parts, " | ")
+}
+
+// feedToken derives the secret part of a user's feed URL from the bot token
+func feedToken(chatID int64, secret string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "feed:%d", chatID)
+	return hex.EncodeToString(mac.Sum(nil))[:32]
+}
+
+// feedPath returns the path of a user's feed
+func feedPath(chatID int64, secret string) string {
+	return fmt.Sprintf("/feed/%d/%s.rss", chatID, feedToken(chatID, secret))
+}
+
+// feedHandler serves the per-user RSS feeds at /feed/<chat ID>/<token>.rss.
+// Each feed lists the known offers matching the user's active profiles, or their
+// filters when no profile is active, like the notifications do.
+func feedHandler(botState *state.BotState, secret string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/feed/"), "/")
+		if len(parts) != 2 {
+			http.NotFound(w, r)
+			return
+		}
+		chatID, err := strconv.ParseInt(parts[0], 10, 64)
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		token := strings.TrimSuffix(parts[1], ".rss")
+		if !hmac.Equal([]byte(token), []byte(feedToken(chatID, secret))) {
+			http.NotFound(w, r)
+			return
+		}
+
+		if _, exists := botState.GetUser(chatID); !exists {
+			http.NotFound(w, r)
+			return
+		}
+		offers := matchingOffers(botState, chatID, sortedKnownOffers(botState))
+
+		data, err := buildRSSFeed("Vuokraovi rental offers", offers, botState.GetLastUpdated())
+		if err != nil {
+			log.Printf("Error building feed for user %d: %v", chatID, err)
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/rss+xml; charset=utf-8")
+		w.Write(data)
+	})
+}
+
+// startFeedServer serves the RSS feeds on the configured address
+func startFeedServer(botState *state.BotState, config BotConfig) {
+	mux := http.NewServeMux()
+	mux.Handle("/feed/", feedHandler(botState, config.Token))
+
+	log.Printf("Serving RSS feeds on %s", config.FeedAddr)
+	if err := http.ListenAndServe(config.FeedAddr, mux); err != nil {
+		log.Printf("Error serving RSS feeds: %v", err)
+	}
+}
+
+// handleFeedCommand handles the /feed command
+func handleFeedCommand(bot *tgbotapi.BotAPI, botState *state.BotState, message *tgbotapi.Message, config BotConfig) {
+	chatID := message.Chat.ID
+	lang := userLanguage(botState, chatID)
+
+	text := translate(lang, "feed_disabled")
+	if config.FeedAddr != "" && config.FeedURL != "" {
+		text = translate(lang, "feed_link", strings.TrimRight(config.FeedURL, "/")+feedPath(chatID, config.Token))
+	}
+
+	msg := tgbotapi.NewMessage(chatID, text)
+	msg.DisableWebPagePreview = true
+	msg.ReplyMarkup = createMainKeyboard(lang)
+	sendMessage(bot, msg)
+}
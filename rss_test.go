@@ -0,0 +1,121 @@
+package main
+
+import (
+	"encoding/xml"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aqaliarept/vuokraovi-bot/state"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+func TestBuildRSSFeedStructure(t *testing.T) {
+	june := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	offers := []state.RentalOffer{
+		{Title: "Koti & piha <3", Address: "Katu 1, Helsinki", Price: "900 €/kk", Size: "40 m²", Rooms: "2h+k", Link: "https://example.com/1", AvailableFrom: june},
+		{Address: "Katu 2, Tampere", Price: "700 €/kk", Link: "https://example.com/2"},
+	}
+
+	data, err := buildRSSFeed("Test feed", offers, june)
+	if err != nil {
+		t.Fatalf("buildRSSFeed: %v", err)
+	}
+	if !strings.HasPrefix(string(data), xml.Header) {
+		t.Error("the feed lacks the XML declaration")
+	}
+
+	var feed rssFeed
+	if err := xml.Unmarshal(data, &feed); err != nil {
+		t.Fatalf("the feed is not valid XML: %v", err)
+	}
+	if feed.Version != "2.0" || feed.Channel.Title != "Test feed" || feed.Channel.LastBuildDate != june.Format(time.RFC1123Z) {
+		t.Errorf("unexpected channel: version %q, %+v", feed.Version, feed.Channel)
+	}
+	if len(feed.Channel.Items) != 2 {
+		t.Fatalf("got %d items, want 2", len(feed.Channel.Items))
+	}
+
+	first := feed.Channel.Items[0]
+	if first.Title != "Koti & piha <3" || first.Link != "https://example.com/1" || !first.GUID.IsPermaLink {
+		t.Errorf("unexpected first item: %+v", first)
+	}
+	if first.Description != "Katu 1, Helsinki | 900 €/kk | 40 m² | 2h+k" {
+		t.Errorf("description = %q", first.Description)
+	}
+	if first.PubDate != "Sat, 01 Jun 2024 00:00:00 +0000" {
+		t.Errorf("pubDate = %q, want the availability date", first.PubDate)
+	}
+
+	second := feed.Channel.Items[1]
+	if second.Title != "Katu 2, Tampere" || second.PubDate != "" {
+		t.Errorf("an offer without title or date should use its address and no pubDate: %+v", second)
+	}
+}
+
+func TestFeedHandlerChecksToken(t *testing.T) {
+	botState := newTestBotState(t)
+	botState.AddUser(&tgbotapi.User{FirstName: "Test"}, 42)
+	botState.ApplyOffers([]state.RentalOffer{testOffer("https://example.com/1", "800 €/kk")})
+	srv := httptest.NewServer(feedHandler(botState, "secret"))
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + feedPath(42, "secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || !strings.Contains(string(body), "https://example.com/1") {
+		t.Errorf("feed: status %d, body %s", resp.StatusCode, body)
+	}
+
+	for _, path := range []string{feedPath(42, "other secret"), feedPath(7, "secret"), "/feed/42"} {
+		resp, err := http.Get(srv.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusNotFound {
+			t.Errorf("GET %s: status %d, want 404", path, resp.StatusCode)
+		}
+	}
+}
+
+func TestFeedHandlerUsesActiveProfiles(t *testing.T) {
+	botState := newTestBotState(t)
+	botState.AddUser(&tgbotapi.User{FirstName: "Test"}, 42)
+	botState.SetUserFilters(42, state.Filters{City: "Helsinki"})
+	botState.SaveProfile(42, "espoo", state.Filters{City: "Espoo"})
+	botState.ApplyOffers(profileOffers())
+	srv := httptest.NewServer(feedHandler(botState, "secret"))
+	defer srv.Close()
+
+	feedLinks := func() string {
+		resp, err := http.Get(srv.URL + feedPath(42, "secret"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		var feed rssFeed
+		if err := xml.NewDecoder(resp.Body).Decode(&feed); err != nil {
+			t.Fatalf("invalid feed: %v", err)
+		}
+		var links []string
+		for _, item := range feed.Channel.Items {
+			links = append(links, item.Link)
+		}
+		return strings.Join(links, " ")
+	}
+
+	if got := feedLinks(); got != "https://example.com/espoo" {
+		t.Errorf("feed with an active profile lists %q, want the Espoo offer", got)
+	}
+	botState.SetProfileActive(42, "espoo", false)
+	if got := feedLinks(); got != "https://example.com/helsinki" {
+		t.Errorf("feed without active profiles lists %q, want the offer matching the filters", got)
+	}
+}
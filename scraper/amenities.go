@@ -0,0 +1,65 @@
+package scraper
+
+import (
+	"strings"
+	"unicode"
+)
+
+// AmenityKeywords maps each amenity to the lowercase words and abbreviations the
+// room descriptions use for it (e.g. "3h+k+s" has a sauna). Amenities are detected
+// and filtered by these names.
+var AmenityKeywords = map[string][]string{
+	"sauna":   {"sauna", "s", "bastu"},
+	"parveke": {"parveke", "parv", "p", "balkong", "balcony"},
+	"kt":      {"kt", "keittotila", "kitchenette", "pentry"},
+	"terassi": {"terassi", "ter", "terrass", "terrace"},
+	"vh":      {"vh", "vaatehuone", "walk-in closet"},
+	"piha":    {"piha", "gård", "yard"},
+}
+
+// AmenityName returns the amenity a word refers to, accepting the amenity names
+// as well as their keywords in any case
+func AmenityName(word string) (string, bool) {
+	word = strings.ToLower(strings.TrimSpace(word))
+	if _, ok := AmenityKeywords[word]; ok {
+		return word, true
+	}
+	for amenity, keywords := range AmenityKeywords {
+		for _, keyword := range keywords {
+			if word == keyword {
+				return amenity, true
+			}
+		}
+	}
+	return "", false
+}
+
+// DetectAmenities returns the amenities whose keywords appear in a room description
+// such as "1h + alk + kt + ransk.parveke", in the order of the description.
+// Keywords are matched case-insensitively against whole words, so "s" only matches
+// the sauna abbreviation and not every word containing an s.
+func DetectAmenities(rooms string, keywords map[string][]string) []string {
+	lower := strings.ToLower(rooms)
+	words := strings.FieldsFunc(lower, func(r rune) bool {
+		return !unicode.IsLetter(r) && r != '-'
+	})
+	phrases := append(words, lower)
+
+	var amenities []string
+	seen := map[string]bool{}
+	for _, word := range phrases {
+		for amenity, names := range keywords {
+			if seen[amenity] {
+				continue
+			}
+			for _, name := range names {
+				if word == name || (strings.Contains(name, " ") && strings.Contains(word, name)) {
+					amenities = append(amenities, amenity)
+					seen[amenity] = true
+					break
+				}
+			}
+		}
+	}
+	return amenities
+}
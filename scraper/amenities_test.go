@@ -0,0 +1,48 @@
+package scraper
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDetectAmenities(t *testing.T) {
+	tests := []struct {
+		rooms string
+		want  []string
+	}{
+		{"1h + alk + kt + ransk.parveke", []string{"kt", "parveke"}},
+		{"3h+k+s", []string{"sauna"}},
+		{"3H+K+S+PARV", []string{"sauna", "parveke"}},
+		{"2h, k, Sauna, Lasitettu parveke", []string{"sauna", "parveke"}},
+		{"4h+k+kph+vh+terassi", []string{"vh", "terassi"}},
+		{"3 rum, kök, bastu, balkong", []string{"sauna", "parveke"}},
+		{"2 rooms, kitchen, walk-in closet", []string{"vh"}},
+		{"2h+k+kph", nil},
+		{"saunaton kaksio", nil},
+		{"", nil},
+	}
+	for _, tt := range tests {
+		if got := DetectAmenities(tt.rooms, AmenityKeywords); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("DetectAmenities(%q) = %v, want %v", tt.rooms, got, tt.want)
+		}
+	}
+}
+
+func TestDetectAmenitiesUsesGivenKeywords(t *testing.T) {
+	keywords := map[string][]string{"takka": {"takka", "tk"}}
+	if got := DetectAmenities("3h+k+s+TK", keywords); !reflect.DeepEqual(got, []string{"takka"}) {
+		t.Errorf("DetectAmenities = %v, want [takka]", got)
+	}
+}
+
+func TestAmenityName(t *testing.T) {
+	tests := map[string]string{"Sauna": "sauna", "s": "sauna", "BALCONY": "parveke", " kt ": "kt"}
+	for word, want := range tests {
+		if got, ok := AmenityName(word); !ok || got != want {
+			t.Errorf("AmenityName(%q) = %q, %v, want %q", word, got, ok, want)
+		}
+	}
+	if _, ok := AmenityName("pool"); ok {
+		t.Error("AmenityName accepted an unknown amenity")
+	}
+}
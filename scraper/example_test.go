@@ -0,0 +1,33 @@
+package scraper_test
+
+import (
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/aqaliarept/vuokraovi-bot/scraper"
+)
+
+// The example fetches the first result page of the search saved in form_data.txt
+// and prints the offers found.
+func ExampleWebSite_FetchRentalOffers() {
+	formData, err := os.ReadFile("form_data.txt")
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	site, err := scraper.New(false)
+	if err != nil {
+		log.Fatal(err)
+	}
+	site.Locale = "en"
+	site.MaxOffers = 10
+
+	offers, err := site.FetchRentalOffers(string(formData), 1)
+	if err != nil {
+		log.Fatal(err)
+	}
+	for _, offer := range offers {
+		fmt.Printf("%s, %s: %s\n", offer.Address, offer.Price, offer.Link)
+	}
+}
@@ -0,0 +1,67 @@
+package scraper
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// RentalOffer is a rental listing parsed from a result page
+type RentalOffer struct {
+	Title          string    `json:"title"`
+	Address        string    `json:"address"`
+	Price          string    `json:"price"`
+	PriceEUR       float64   `json:"price_eur,omitempty"`
+	PriceIsMonthly bool      `json:"price_is_monthly"`
+	BuildingType   string    `json:"building_type,omitempty"`
+	Size           string    `json:"size"`
+	SizeSqm        float64   `json:"size_sqm,omitempty"`
+	Rooms          string    `json:"rooms"`
+	RoomCount      int       `json:"room_count,omitempty"`
+	Amenities      []string  `json:"amenities,omitempty"`
+	Available      string    `json:"available"`
+	AvailableFrom  time.Time `json:"available_from"`
+	AvailableNow   bool      `json:"available_now,omitempty"`
+	Lat            float64   `json:"lat,omitempty"`
+	Lng            float64   `json:"lng,omitempty"`
+	Link           string    `json:"link"`
+	ImageURL       string    `json:"image_url,omitempty"`
+}
+
+// OfferID returns the ID of an offer, which is the last segment of its link without
+// the query (e.g. "1766680" for https://www.vuokraovi.com/vuokra-asunto/tampere/viiala/rivitalo/1766680)
+func OfferID(link string) string {
+	if pos := strings.Index(link, "?"); pos != -1 {
+		link = link[:pos]
+	}
+	link = strings.TrimRight(link, "/")
+	if pos := strings.LastIndex(link, "/"); pos != -1 {
+		return link[pos+1:]
+	}
+	return link
+}
+
+// pricePattern matches the numeric part of a price such as "1 037,88 €/kk"
+var pricePattern = regexp.MustCompile(`\d+(?:[.,]\d+)?`)
+
+// ParsePrice extracts the numeric value of a price string such as "1 037,88 €/kk"
+func ParsePrice(price string) (float64, bool) {
+	compact := strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) {
+			return -1
+		}
+		return r
+	}, price)
+
+	match := pricePattern.FindString(compact)
+	if match == "" {
+		return 0, false
+	}
+	value, err := strconv.ParseFloat(strings.Replace(match, ",", ".", 1), 64)
+	if err != nil {
+		return 0, false
+	}
+	return value, true
+}
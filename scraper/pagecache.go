@@ -0,0 +1,71 @@
+package scraper
+
+import (
+	"sync"
+	"time"
+)
+
+// PageCache keeps the parsed offers of recently fetched result pages, so that
+// identical requests made shortly after each other skip the site. It is safe for
+// concurrent use and may be shared by several WebSite clients.
+type PageCache struct {
+	mutex   sync.Mutex
+	ttl     time.Duration
+	entries map[string]cachedPage
+	now     func() time.Time // replaced in tests
+}
+
+// cachedPage is a parsed result page
+type cachedPage struct {
+	offers       []RentalOffer
+	nextPageURL  string
+	totalResults int
+	fetched      time.Time
+}
+
+// NewPageCache creates a cache serving pages younger than ttl
+func NewPageCache(ttl time.Duration) *PageCache {
+	return &PageCache{
+		ttl:     ttl,
+		entries: make(map[string]cachedPage),
+		now:     time.Now,
+	}
+}
+
+// pageCacheKey identifies a request by its method, URL and form body
+func pageCacheKey(method, targetURL, formData string) string {
+	return method + " " + targetURL + "\n" + formData
+}
+
+// get returns a copy of the cached page of a request if it is still fresh
+func (c *PageCache) get(key string) (cachedPage, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok {
+		return cachedPage{}, false
+	}
+	if c.now().Sub(entry.fetched) >= c.ttl {
+		delete(c.entries, key)
+		return cachedPage{}, false
+	}
+	entry.offers = append([]RentalOffer(nil), entry.offers...)
+	return entry, true
+}
+
+// put stores the parsed page of a request and drops the expired pages
+func (c *PageCache) put(key string, page cachedPage) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	now := c.now()
+	for k, entry := range c.entries {
+		if now.Sub(entry.fetched) >= c.ttl {
+			delete(c.entries, k)
+		}
+	}
+	page.offers = append([]RentalOffer(nil), page.offers...)
+	page.fetched = now
+	c.entries[key] = page
+}
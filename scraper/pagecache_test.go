@@ -0,0 +1,113 @@
+package scraper
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestPageCacheSkipsRequestsWithinTTL(t *testing.T) {
+	server, requests := paginatedServer(t, 2, 3)
+	cache := NewPageCache(time.Minute)
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	cache.now = func() time.Time { return now }
+
+	fetch := func() []RentalOffer {
+		t.Helper()
+		// Every fetch uses a new client, like the bot does
+		website := newTestWebSite(t, server.URL)
+		website.PageDelay = 0
+		website.Cache = cache
+		offers, err := website.FetchRentalOffers("method=search&type=full", 0)
+		if err != nil {
+			t.Fatalf("FetchRentalOffers: %v", err)
+		}
+		return offers
+	}
+
+	first := fetch()
+	if len(first) != 6 || requests.Load() != 2 {
+		t.Fatalf("first fetch got %d offers in %d requests, want 6 in 2", len(first), requests.Load())
+	}
+
+	now = now.Add(30 * time.Second)
+	second := fetch()
+	if requests.Load() != 2 {
+		t.Errorf("a fetch within the TTL made %d more requests", requests.Load()-2)
+	}
+	if len(second) != len(first) || second[5].Link != first[5].Link {
+		t.Errorf("cached fetch got %d offers, want the same %d", len(second), len(first))
+	}
+
+	now = now.Add(time.Minute)
+	fetch()
+	if requests.Load() != 4 {
+		t.Errorf("got %d requests after the TTL, want the pages fetched again", requests.Load())
+	}
+
+	// Another form body is another request
+	website := newTestWebSite(t, server.URL)
+	website.PageDelay = 0
+	website.Cache = cache
+	website.FetchRentalOffers("method=search&type=full&rooms=2", 1)
+	if requests.Load() != 5 {
+		t.Errorf("got %d requests, want a request for another form body", requests.Load())
+	}
+}
+
+func TestPageCacheHitsWithOfferFilter(t *testing.T) {
+	server, requests := paginatedServer(t, 2, 3)
+	cache := NewPageCache(time.Minute)
+
+	fetch := func(filter func(RentalOffer) bool) []RentalOffer {
+		t.Helper()
+		website := newTestWebSite(t, server.URL)
+		website.PageDelay = 0
+		website.Cache = cache
+		website.OfferFilter = filter
+		offers, err := website.FetchRentalOffers("method=search&type=full", 0)
+		if err != nil {
+			t.Fatalf("FetchRentalOffers: %v", err)
+		}
+		return offers
+	}
+
+	all := fetch(nil)
+	dropFirst := func(offer RentalOffer) bool { return offer.Link != all[0].Link && offer.Link != all[3].Link }
+	for i := 0; i < 2; i++ {
+		if got := fetch(dropFirst); len(got) != 4 || got[0].Link != all[1].Link || got[3].Link != all[5].Link {
+			t.Errorf("filtered fetch %d got %v, want the offers but the first of each page", i+1, got)
+		}
+	}
+	if got := fetch(nil); len(got) != len(all) || got[3].Link != all[3].Link {
+		t.Errorf("unfiltered fetch after filtered ones got %d offers, want the cached %d", len(got), len(all))
+	}
+	if requests.Load() != 2 {
+		t.Errorf("got %d requests, want the later fetches served from the cache", requests.Load())
+	}
+}
+
+func TestPageCacheReturnsCopies(t *testing.T) {
+	cache := NewPageCache(time.Minute)
+	key := pageCacheKey("GET", "https://example.com/haku?page=2", "")
+	cache.put(key, cachedPage{offers: []RentalOffer{{Link: "https://example.com/1"}}})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			page, ok := cache.get(key)
+			if !ok || len(page.offers) != 1 {
+				t.Errorf("get = %+v, %v", page, ok)
+				return
+			}
+			page.offers[0].Link = "changed"
+		}()
+	}
+	wg.Wait()
+
+	if page, _ := cache.get(key); page.offers[0].Link != "https://example.com/1" {
+		t.Errorf("the cached page was changed through a copy: %+v", page)
+	}
+}
@@ -0,0 +1,477 @@
+package scraper
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// ParserConfig holds the CSS selectors used to extract rental offers from a page
+type ParserConfig struct {
+	Name         string `json:"name"`
+	Container    string `json:"container"`
+	Image        string `json:"image"`
+	Price        string `json:"price"`
+	Details      string `json:"details"`
+	Availability string `json:"availability"`
+	Link         string `json:"link"`
+	NextPage     string `json:"next_page"`
+	TotalCount   string `json:"total_count"`
+	Pager        string `json:"pager"`
+}
+
+// DefaultParserConfig returns selectors matching the current Vuokraovi search result markup
+func DefaultParserConfig() ParserConfig {
+	return ParserConfig{
+		Name:         "primary",
+		Container:    ".list-item-container",
+		Image:        ".col-1 img",
+		Price:        "span.price",
+		Details:      ".col-2 .list-unstyled",
+		Availability: ".showing-lease-container li",
+		Link:         "a.list-item-link",
+		NextPage:     "link[rel='next']",
+		TotalCount:   "#listNavigation h1 .bold",
+		Pager:        "ul.pagination li a",
+	}
+}
+
+// FallbackParserConfig returns looser selectors tried when the primary ones stop matching
+func FallbackParserConfig() ParserConfig {
+	return ParserConfig{
+		Name:         "fallback",
+		Container:    ".list-item-container",
+		Image:        "img[alt]",
+		Price:        ".rent, [itemprop='price']",
+		Details:      ".list-unstyled",
+		Availability: ".showing-lease-container li",
+		Link:         "a[href*='/kohde/'], a[href*='/vuokra-asunto/']",
+		NextPage:     "link[rel='next'], a[rel='next']",
+		TotalCount:   "#listNavigation h1",
+		Pager:        ".pagination a",
+	}
+}
+
+// LoadParserConfig reads selector overrides from a JSON file.
+// Selectors missing from the file keep their default values.
+func LoadParserConfig(path string) (ParserConfig, error) {
+	config := DefaultParserConfig()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return config, fmt.Errorf("error reading parser config from %s: %w", path, err)
+	}
+	if err := json.Unmarshal(data, &config); err != nil {
+		return config, fmt.Errorf("error parsing parser config %s: %w", path, err)
+	}
+
+	return config, nil
+}
+
+// blockedPageTitles are lowercase fragments of titles used by challenge and block pages
+var blockedPageTitles = []string{
+	"just a moment",
+	"attention required",
+	"access denied",
+	"are you a robot",
+	"captcha",
+	"request blocked",
+}
+
+// blockedPageMarkers are elements only present on challenge and block pages
+const blockedPageMarkers = "#challenge-form, #challenge-running, .cf-browser-verification, .g-recaptcha, .h-captcha, #px-captcha"
+
+// isBlockedPage reports whether the document is an interstitial challenge or block page
+// instead of search results
+func isBlockedPage(doc *goquery.Document) bool {
+	title := strings.ToLower(strings.TrimSpace(doc.Find("title").First().Text()))
+	for _, marker := range blockedPageTitles {
+		if strings.Contains(title, marker) {
+			return true
+		}
+	}
+	return doc.Find(blockedPageMarkers).Length() > 0
+}
+
+// missingPriceFraction returns the fraction of offers that have no price
+func missingPriceFraction(offers []RentalOffer) float64 {
+	if len(offers) == 0 {
+		return 0
+	}
+	missing := 0
+	for _, offer := range offers {
+		if offer.Price == "" {
+			missing++
+		}
+	}
+	return float64(missing) / float64(len(offers))
+}
+
+// extractRentalOffers extracts rental offers from the HTML document
+func extractRentalOffers(doc *goquery.Document, baseURL string, config ParserConfig) []RentalOffer {
+	var offers []RentalOffer
+
+	// Check if we have any listings
+	listingCount := doc.Find(config.Container).Length()
+	if listingCount == 0 {
+		log.Println("Warning: No rental listings found in the HTML document")
+		// Check if there's an error message or empty results message
+		errorMsg := doc.Find(".error-message, .no-results-message").Text()
+		if errorMsg != "" {
+			log.Printf("Message from page: %s", strings.TrimSpace(errorMsg))
+		}
+	}
+
+	doc.Find(config.Container).Each(func(i int, s *goquery.Selection) {
+		offer := extractSingleOffer(s, baseURL, config)
+
+		// If we have enough information, add the offer to our list
+		if offer.Size != "" || offer.Rooms != "" || offer.Price != "" {
+			offers = append(offers, offer)
+		} else {
+			log.Printf("Warning: Skipping offer #%d due to insufficient data", i+1)
+		}
+	})
+
+	return offers
+}
+
+// totalCountPattern matches the result count of a search, such as "16 asuntoa"
+// or "1 234 kohdetta" with spaces as thousands separators
+var totalCountPattern = regexp.MustCompile(`\d[\d\s\x{a0}]*`)
+
+// extractTotalCount returns the total number of offers matching the search as
+// shown above the results, or 0 when the page does not show it
+func extractTotalCount(doc *goquery.Document, config ParserConfig) int {
+	if config.TotalCount == "" {
+		return 0
+	}
+	text := doc.Find(config.TotalCount).First().Text()
+	match := totalCountPattern.FindString(text)
+	digits := strings.Map(func(r rune) rune {
+		if r >= '0' && r <= '9' {
+			return r
+		}
+		return -1
+	}, match)
+	count, err := strconv.Atoi(digits)
+	if err != nil {
+		return 0
+	}
+	return count
+}
+
+// extractPageCount returns the last page number linked by the pager, or 0 when
+// the page has no pager. Pagers may skip pages ("1 2 3 … 7"), so the highest
+// number is used.
+func extractPageCount(doc *goquery.Document, config ParserConfig) int {
+	if config.Pager == "" {
+		return 0
+	}
+	last := 0
+	doc.Find(config.Pager).Each(func(i int, s *goquery.Selection) {
+		if page, err := strconv.Atoi(strings.TrimSpace(s.Text())); err == nil && page > last {
+			last = page
+		}
+	})
+	return last
+}
+
+// extractSingleOffer extracts a single rental offer from a selection
+func extractSingleOffer(s *goquery.Selection, baseURL string, config ParserConfig) RentalOffer {
+	offer := RentalOffer{}
+
+	// Extract address and title from image
+	extractAddressAndTitle(s, &offer, config)
+
+	// Extract price
+	extractPrice(s, &offer, config)
+
+	// Extract size and room information
+	extractSizeAndRooms(s, &offer, config)
+
+	// Extract availability
+	extractAvailability(s, &offer, config)
+
+	// Extract link and fallback address
+	extractLinkAndFallbackAddress(s, &offer, baseURL, config)
+
+	// Extract the map coordinates
+	extractCoordinates(s, &offer)
+
+	return offer
+}
+
+// extractCoordinates reads the map coordinates some listing containers carry in
+// data-latitude and data-longitude attributes. Both stay zero when either is
+// missing or invalid.
+func extractCoordinates(s *goquery.Selection, offer *RentalOffer) {
+	lat, latOK := coordinateAttr(s, "data-latitude", 90)
+	lng, lngOK := coordinateAttr(s, "data-longitude", 180)
+	if latOK && lngOK {
+		offer.Lat, offer.Lng = lat, lng
+	}
+}
+
+// coordinateAttr parses a coordinate attribute, accepting a decimal comma and
+// rejecting values outside of ±limit
+func coordinateAttr(s *goquery.Selection, name string, limit float64) (float64, bool) {
+	value, exists := s.Attr(name)
+	if !exists {
+		return 0, false
+	}
+	coordinate, err := strconv.ParseFloat(strings.Replace(strings.TrimSpace(value), ",", ".", 1), 64)
+	if err != nil || coordinate < -limit || coordinate > limit {
+		return 0, false
+	}
+	return coordinate, true
+}
+
+// extractAddressAndTitle extracts address and title from the image
+func extractAddressAndTitle(s *goquery.Selection, offer *RentalOffer, config ParserConfig) {
+	// Find the main property image in the listing
+	imgEl := s.Find(config.Image)
+	if imgEl.Length() > 0 {
+		// Get the first image that's not an icon (icons typically have small dimensions or specific classes)
+		imgEl.Each(func(i int, img *goquery.Selection) {
+			if alt, exists := img.Attr("alt"); exists && alt != "" {
+				// Skip images that are clearly icons (usually have very short alt text)
+				if len(alt) > 5 && !strings.Contains(strings.ToLower(alt), "icon") && offer.Address == "" {
+					offer.Address = alt
+					if src, ok := img.Attr("src"); ok && offer.ImageURL == "" {
+						offer.ImageURL = absoluteImageURL(src)
+					}
+					// Use the first part of the address as the title (street address)
+					parts := strings.Split(alt, ",")
+					if len(parts) > 0 {
+						offer.Title = strings.TrimSpace(parts[0])
+					}
+				}
+			}
+		})
+	}
+}
+
+// absoluteImageURL turns protocol-relative image sources into https URLs
+func absoluteImageURL(src string) string {
+	src = strings.TrimSpace(src)
+	if strings.HasPrefix(src, "//") {
+		return "https:" + src
+	}
+	return src
+}
+
+// extractPrice extracts the price from the selection
+func extractPrice(s *goquery.Selection, offer *RentalOffer, config ParserConfig) {
+	priceEl := s.Find(config.Price)
+	if priceEl.Length() > 0 {
+		offer.Price = strings.TrimSpace(priceEl.First().Text())
+		offer.PriceEUR, _ = ParsePrice(offer.Price)
+		offer.PriceIsMonthly = isMonthlyPrice(offer.Price)
+	}
+}
+
+// monthlyPriceSuffixes mark a price as monthly rent in the site locales
+var monthlyPriceSuffixes = []string{"/kk", "/month", "/mån"}
+
+// isMonthlyPrice reports whether a price is a monthly rent ("900 €/kk") rather than
+// a one-off figure such as a deposit or a price without a period
+func isMonthlyPrice(price string) bool {
+	compact := strings.ToLower(strings.Join(strings.Fields(price), ""))
+	for _, suffix := range monthlyPriceSuffixes {
+		if strings.Contains(compact, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// extractSizeAndRooms extracts size and room information from the selection
+func extractSizeAndRooms(s *goquery.Selection, offer *RentalOffer, config ParserConfig) {
+	col2El := s.Find(config.Details).First()
+	if col2El.Length() > 0 {
+		// First li typically contains housing type and size (e.g., "kerrostalo, 34 m²")
+		sizeText := strings.TrimSpace(col2El.Find("li").First().Text())
+		if strings.Contains(sizeText, "m²") {
+			// Split only on the first comma, the size itself may use a decimal comma ("72,5 m²")
+			parts := strings.SplitN(sizeText, ",", 2)
+			if len(parts) > 1 {
+				offer.BuildingType = normalizeBuildingType(parts[0])
+				offer.Size = strings.TrimSpace(parts[1])
+				offer.SizeSqm, _ = ParsePrice(offer.Size)
+			} else {
+				offer.BuildingType = BuildingUnknown
+			}
+		}
+
+		// Second li typically contains room information (e.g., "1h + alk + kt + ransk.parveke")
+		if col2El.Find("li").Length() > 1 {
+			roomsText := strings.TrimSpace(col2El.Find("li").Eq(1).Text())
+			offer.Rooms = roomsText
+			offer.RoomCount = parseRoomCount(roomsText)
+			offer.Amenities = DetectAmenities(roomsText, AmenityKeywords)
+		}
+	}
+}
+
+// Building types an offer is normalized to, named after the Finnish housing types
+const (
+	BuildingApartment     = "kerrostalo"
+	BuildingRowHouse      = "rivitalo"
+	BuildingSemiDetached  = "paritalo"
+	BuildingDetached      = "omakotitalo"
+	BuildingSeparate      = "erillistalo"
+	BuildingGalleryAccess = "luhtitalo"
+	BuildingWooden        = "puutalo"
+	BuildingUnknown       = "unknown"
+)
+
+// buildingTypeNames maps the lowercase housing type names of every site locale
+// to the building types
+var buildingTypeNames = map[string]string{
+	"kerrostalo":           BuildingApartment,
+	"flervåningshus":       BuildingApartment,
+	"höghus":               BuildingApartment,
+	"block of flats":       BuildingApartment,
+	"apartment building":   BuildingApartment,
+	"rivitalo":             BuildingRowHouse,
+	"radhus":               BuildingRowHouse,
+	"row house":            BuildingRowHouse,
+	"terraced house":       BuildingRowHouse,
+	"paritalo":             BuildingSemiDetached,
+	"parhus":               BuildingSemiDetached,
+	"semi-detached house":  BuildingSemiDetached,
+	"omakotitalo":          BuildingDetached,
+	"egnahemshus":          BuildingDetached,
+	"detached house":       BuildingDetached,
+	"erillistalo":          BuildingSeparate,
+	"fristående hus":       BuildingSeparate,
+	"separate house":       BuildingSeparate,
+	"luhtitalo":            BuildingGalleryAccess,
+	"loftgångshus":         BuildingGalleryAccess,
+	"gallery access house": BuildingGalleryAccess,
+	"puutalo-osake":        BuildingWooden,
+	"puutalo":              BuildingWooden,
+	"trähus":               BuildingWooden,
+	"wooden house":         BuildingWooden,
+}
+
+// normalizeBuildingType returns the building type of a housing type name as shown
+// before the size, or BuildingUnknown for names it does not know
+func normalizeBuildingType(name string) string {
+	if buildingType, ok := buildingTypeNames[strings.ToLower(strings.TrimSpace(name))]; ok {
+		return buildingType
+	}
+	return BuildingUnknown
+}
+
+// roomCountPattern matches the room count at the start of a room description (e.g. "3h+k+s")
+var roomCountPattern = regexp.MustCompile(`^(\d+)\s*(?:h|mh|rum|rooms?)\b`)
+
+// parseRoomCount extracts the number of rooms from a room description
+func parseRoomCount(rooms string) int {
+	lower := strings.ToLower(strings.TrimSpace(rooms))
+	if match := roomCountPattern.FindStringSubmatch(lower); match != nil {
+		count, _ := strconv.Atoi(match[1])
+		return count
+	}
+	// Studios are described without a room count
+	if strings.HasPrefix(lower, "yksiö") || strings.HasPrefix(lower, "1 rum") || strings.HasPrefix(lower, "studio") {
+		return 1
+	}
+	return 0
+}
+
+// extractAvailability extracts availability information from the selection
+func extractAvailability(s *goquery.Selection, offer *RentalOffer, config ParserConfig) {
+	availEl := s.Find(config.Availability)
+	if availEl.Length() > 0 {
+		offer.Available = strings.TrimSpace(availEl.Text())
+		offer.AvailableFrom, offer.AvailableNow = parseAvailableFrom(offer.Available)
+	}
+}
+
+// availableDatePattern matches Finnish d.m.yyyy dates (e.g. "Vapautuu 1.6.2024")
+var availableDatePattern = regexp.MustCompile(`(\d{1,2})\.(\d{1,2})\.(\d{4})`)
+
+// immediateAvailabilityWords are the words used for offers that are available right away
+var immediateAvailabilityWords = []string{"heti", "immediately", "omedelbart"}
+
+// parseAvailableFrom parses the move-in date from the availability text and
+// reports whether the offer is available right away. The zero time is returned
+// when the text contains no date, including for immediately available offers, so
+// that their date does not change from one scrape to the next.
+func parseAvailableFrom(text string) (time.Time, bool) {
+	if match := availableDatePattern.FindStringSubmatch(text); match != nil {
+		day, _ := strconv.Atoi(match[1])
+		month, _ := strconv.Atoi(match[2])
+		year, _ := strconv.Atoi(match[3])
+		if month >= 1 && month <= 12 && day >= 1 && day <= 31 {
+			date := time.Date(year, time.Month(month), day, 0, 0, 0, 0, time.Local)
+			// Reject dates like 31.2. that time.Date would normalize
+			if date.Day() == day {
+				return date, false
+			}
+		}
+	}
+
+	lower := strings.ToLower(text)
+	for _, word := range immediateAvailabilityWords {
+		if strings.Contains(lower, word) {
+			return time.Time{}, true
+		}
+	}
+
+	return time.Time{}, false
+}
+
+// extractLinkAndFallbackAddress extracts the link and fallback address from the selection
+func extractLinkAndFallbackAddress(s *goquery.Selection, offer *RentalOffer, baseURL string, config ParserConfig) {
+	linkEl := s.Find(config.Link)
+	if href, exists := linkEl.Attr("href"); exists {
+		if !strings.HasPrefix(href, "http") {
+			href = baseURL + href
+		}
+		offer.Link = href
+
+		// If we don't have an address, try to extract location from the link
+		if offer.Address == "" {
+			extractAddressFromLink(offer, href)
+		}
+	}
+}
+
+// extractAddressFromLink extracts address information from the link
+func extractAddressFromLink(offer *RentalOffer, href string) {
+	// Parse the URL path to extract location information
+	parsedURL, err := url.Parse(href)
+	if err == nil {
+		pathParts := strings.Split(strings.Trim(parsedURL.Path, "/"), "/")
+
+		// The URL structure typically follows a pattern like:
+		// /vuokra-asunto/[city]/[district]/[type]/[id]
+		if len(pathParts) >= 4 {
+			// Extract city and district from URL path
+			cityIndex := 1     // Typically the second element in the path
+			districtIndex := 2 // Typically the third element in the path
+
+			if cityIndex < len(pathParts) && districtIndex < len(pathParts) {
+				city := strings.Title(pathParts[cityIndex])
+				district := strings.Title(pathParts[districtIndex])
+
+				if offer.Title == "" {
+					offer.Title = district
+				}
+				offer.Address = district + ", " + city
+			}
+		}
+	}
+}
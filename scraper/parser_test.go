@@ -0,0 +1,280 @@
+package scraper
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+func TestParseAvailableFrom(t *testing.T) {
+	tests := []struct {
+		text string
+		want time.Time
+		now  bool
+	}{
+		{"Vapautuu 1.6.2024", time.Date(2024, 6, 1, 0, 0, 0, 0, time.Local), false},
+		{"Vapaa 15.12.2024 alkaen", time.Date(2024, 12, 15, 0, 0, 0, 0, time.Local), false},
+		{"01.07.2025", time.Date(2025, 7, 1, 0, 0, 0, 0, time.Local), false},
+		{"Heti", time.Time{}, true},
+		{"Vapautuu heti", time.Time{}, true},
+		{"Immediately", time.Time{}, true},
+		{"Ledig omedelbart", time.Time{}, true},
+		{"Sopimuksen mukaan", time.Time{}, false},
+		{"", time.Time{}, false},
+		{"Vapautuu 31.2.2024", time.Time{}, false},
+		{"Vapautuu 1.13.2024", time.Time{}, false},
+	}
+
+	for _, tt := range tests {
+		if got, now := parseAvailableFrom(tt.text); !got.Equal(tt.want) || now != tt.now {
+			t.Errorf("parseAvailableFrom(%q) = %v, %v, want %v, %v", tt.text, got, now, tt.want, tt.now)
+		}
+	}
+}
+
+// fallbackFixture is a listing whose price uses markup only the fallback selectors know
+const fallbackFixture = `<html><head><title>Vuokra-asunnot</title></head><body>
+<div class="list-item-container">
+  <a class="list-item-link" href="/vuokra-asunto/helsinki/kallio/kerrostalo/123">
+    <div class="col-1"><img alt="Testikatu 1, Helsinki" src="//img.example.com/1.jpg"></div>
+    <span class="rent">900 €/kk</span>
+    <div class="col-2"><ul class="list-unstyled"><li>kerrostalo, 40 m²</li><li>2h+k</li></ul></div>
+  </a>
+</div>
+</body></html>`
+
+// parseFixture parses an HTML fixture
+func parseFixture(t *testing.T, html string) *goquery.Document {
+	t.Helper()
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(html))
+	if err != nil {
+		t.Fatalf("error parsing fixture: %v", err)
+	}
+	return doc
+}
+
+func TestExtractWithFallbackUsesFallbackSelectors(t *testing.T) {
+	website, err := New(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc := parseFixture(t, fallbackFixture)
+
+	if primary := extractRentalOffers(doc, website.baseURL, website.Parser); len(primary) != 1 || primary[0].Price != "" {
+		t.Fatalf("primary selectors should miss the price, got %+v", primary)
+	}
+
+	offers := website.extractWithFallback(doc)
+	if len(offers) != 1 {
+		t.Fatalf("got %d offers, want 1", len(offers))
+	}
+	if offers[0].Price != "900 €/kk" || offers[0].PriceEUR != 900 {
+		t.Errorf("Price = %q (%g), want 900 €/kk from the fallback selectors", offers[0].Price, offers[0].PriceEUR)
+	}
+	if offers[0].Link != "https://www.vuokraovi.com/vuokra-asunto/helsinki/kallio/kerrostalo/123" {
+		t.Errorf("Link = %q", offers[0].Link)
+	}
+}
+
+func TestExtractWithFallbackDisabled(t *testing.T) {
+	website, err := New(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	website.FallbackThreshold = 0
+
+	offers := website.extractWithFallback(parseFixture(t, fallbackFixture))
+	if len(offers) != 1 || offers[0].Price != "" {
+		t.Errorf("with the fallback disabled the primary result should be kept, got %+v", offers)
+	}
+}
+
+// blockedFixture is a challenge page served instead of search results
+const blockedFixture = `<html><head><title>Just a moment...</title></head><body>
+<div id="challenge-running">Checking your browser before accessing www.vuokraovi.com.</div>
+<form id="challenge-form" action="/cdn-cgi/challenge" method="POST"></form>
+</body></html>`
+
+func TestIsBlockedPage(t *testing.T) {
+	tests := []struct {
+		name string
+		html string
+		want bool
+	}{
+		{"challenge page", blockedFixture, true},
+		{"captcha marker", `<html><head><title>Vuokraovi</title></head><body><div class="g-recaptcha"></div></body></html>`, true},
+		{"access denied title", `<html><head><title>Access Denied</title></head><body></body></html>`, true},
+		{"empty results", `<html><head><title>Vuokra-asunnot</title></head><body><p class="no-results-message">Ei tuloksia</p></body></html>`, false},
+		{"listing page", fallbackFixture, false},
+	}
+
+	for _, tt := range tests {
+		if got := isBlockedPage(parseFixture(t, tt.html)); got != tt.want {
+			t.Errorf("%s: isBlockedPage = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestExtractRentalOffersFollowsSelectorOverride(t *testing.T) {
+	doc := parseFixture(t, fallbackFixture)
+
+	config := DefaultParserConfig()
+	config.Price = "span.rent"
+	offers := extractRentalOffers(doc, "https://www.vuokraovi.com", config)
+	if len(offers) != 1 || offers[0].Price != "900 €/kk" {
+		t.Fatalf("offers = %+v, want the price from the overridden selector", offers)
+	}
+
+	config.Container = ".no-such-container"
+	if offers := extractRentalOffers(doc, "https://www.vuokraovi.com", config); len(offers) != 0 {
+		t.Errorf("got %d offers with a container selector matching nothing, want 0", len(offers))
+	}
+}
+
+func TestLoadParserConfigKeepsDefaultsForMissingSelectors(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "selectors.json")
+	os.WriteFile(path, []byte(`{"price": "span.rent", "container": ".listing"}`), 0644)
+
+	config, err := LoadParserConfig(path)
+	if err != nil {
+		t.Fatalf("LoadParserConfig: %v", err)
+	}
+	defaults := DefaultParserConfig()
+	if config.Price != "span.rent" || config.Container != ".listing" {
+		t.Errorf("overrides not applied: %+v", config)
+	}
+	if config.Link != defaults.Link || config.Details != defaults.Details || config.NextPage != defaults.NextPage {
+		t.Errorf("missing selectors should keep their defaults: %+v", config)
+	}
+
+	os.WriteFile(path, []byte(`{"price": `), 0644)
+	if _, err := LoadParserConfig(path); err == nil {
+		t.Error("LoadParserConfig accepted invalid JSON")
+	}
+}
+
+func TestExtractRentalOffersFromSamplePage(t *testing.T) {
+	data, err := os.ReadFile(filepath.Join("..", "test.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	offers := extractRentalOffers(parseFixture(t, string(data)), "https://www.vuokraovi.com", DefaultParserConfig())
+	if len(offers) == 0 {
+		t.Fatal("no offers extracted from test.html")
+	}
+	for _, offer := range offers {
+		if offer.Link == "" || offer.Price == "" {
+			t.Errorf("offer is missing its link or price: %+v", offer)
+		}
+	}
+}
+
+func TestExtractTotalCount(t *testing.T) {
+	data, err := os.ReadFile(filepath.Join("..", "test.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := extractTotalCount(parseFixture(t, string(data)), DefaultParserConfig()); got != 16 {
+		t.Errorf("total count of test.html = %d, want 16", got)
+	}
+
+	tests := []struct {
+		html string
+		want int
+	}{
+		{`<div id="listNavigation"><h1>Hakuehdoilla löytyy <span class="bold">1 234 kohdetta</span></h1></div>`, 1234},
+		{"<div id=\"listNavigation\"><h1>Found <span class=\"bold\">2 048 listings</span></h1></div>", 2048},
+		{`<div id="listNavigation"><h1>Hakuehdoilla löytyy <span class="bold">ei asuntoja</span></h1></div>`, 0},
+		{`<div class="list-item-container"></div>`, 0},
+	}
+	for _, tt := range tests {
+		if got := extractTotalCount(parseFixture(t, tt.html), DefaultParserConfig()); got != tt.want {
+			t.Errorf("extractTotalCount(%s) = %d, want %d", tt.html, got, tt.want)
+		}
+	}
+}
+
+func TestExtractSizeAndRoomsBuildingType(t *testing.T) {
+	tests := []struct {
+		sizeLine string
+		want     string
+		wantSize string
+	}{
+		{"kerrostalo, 34 m²", BuildingApartment, "34 m²"},
+		{"Rivitalo, 72,5 m²", BuildingRowHouse, "72,5 m²"},
+		{"paritalo, 80 m²", BuildingSemiDetached, "80 m²"},
+		{"omakotitalo, 120 m²", BuildingDetached, "120 m²"},
+		{"erillistalo, 95 m²", BuildingSeparate, "95 m²"},
+		{"luhtitalo, 40 m²", BuildingGalleryAccess, "40 m²"},
+		{"puutalo-osake, 55 m²", BuildingWooden, "55 m²"},
+		{"radhus, 60 m²", BuildingRowHouse, "60 m²"},
+		{"detached house, 110 m²", BuildingDetached, "110 m²"},
+		{"linna, 900 m²", BuildingUnknown, "900 m²"},
+		{"34 m²", BuildingUnknown, ""},
+	}
+	for _, tt := range tests {
+		html := `<div class="list-item-container"><div class="col-2"><ul class="list-unstyled"><li>` + tt.sizeLine + `</li><li>2h+k</li></ul></div></div>`
+		var offer RentalOffer
+		extractSizeAndRooms(parseFixture(t, html).Selection, &offer, DefaultParserConfig())
+		if offer.BuildingType != tt.want || offer.Size != tt.wantSize {
+			t.Errorf("%q: building type %q, size %q, want %q, %q", tt.sizeLine, offer.BuildingType, offer.Size, tt.want, tt.wantSize)
+		}
+	}
+}
+
+func TestIsMonthlyPrice(t *testing.T) {
+	tests := []struct {
+		price string
+		want  bool
+	}{
+		{"900 €/kk", true},
+		{"1 250 € / kk", true},
+		{"900 €/KK", true},
+		{"900 €/month", true},
+		{"900 €/mån", true},
+		{"1 800 €", false},
+		{"Vakuus 1 800 €", false},
+		{"450 €/vko", false},
+		{"kk-vuokra sovittavissa", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if got := isMonthlyPrice(tt.price); got != tt.want {
+			t.Errorf("isMonthlyPrice(%q) = %v, want %v", tt.price, got, tt.want)
+		}
+	}
+}
+
+func TestExtractCoordinatesFromDataAttributes(t *testing.T) {
+	tests := []struct {
+		attrs   string
+		wantLat float64
+		wantLng float64
+	}{
+		{`data-latitude="60.1699" data-longitude="24.9384"`, 60.1699, 24.9384},
+		{`data-latitude="61,4978" data-longitude="23,7610"`, 61.4978, 23.761},
+		{`data-latitude="60.1699"`, 0, 0},
+		{`data-latitude="" data-longitude=""`, 0, 0},
+		{`data-latitude="160.1" data-longitude="24.9"`, 0, 0},
+		{``, 0, 0},
+	}
+	for _, tt := range tests {
+		html := `<div class="list-item-container" ` + tt.attrs + `>
+  <a class="list-item-link" href="/vuokra-asunto/helsinki/kallio/kerrostalo/1">
+    <span class="price">900 €/kk</span>
+  </a>
+</div>`
+		offers := extractRentalOffers(parseFixture(t, html), "https://www.vuokraovi.com", DefaultParserConfig())
+		if len(offers) != 1 {
+			t.Fatalf("%s: extracted %d offers, want 1", tt.attrs, len(offers))
+		}
+		if offers[0].Lat != tt.wantLat || offers[0].Lng != tt.wantLng {
+			t.Errorf("%s: coordinates %v, %v, want %v, %v", tt.attrs, offers[0].Lat, offers[0].Lng, tt.wantLat, tt.wantLng)
+		}
+	}
+}
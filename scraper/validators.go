@@ -0,0 +1,112 @@
+package scraper
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// validatorMaxAge is how long the validators of a page no longer requested are kept
+const validatorMaxAge = 7 * 24 * time.Hour
+
+// Validators remembers the ETag and Last-Modified headers of fetched result pages
+// together with their parsed offers. Later requests for a page ask the server
+// whether it changed, and a 304 Not Modified answer reuses the offers instead of
+// downloading and parsing the page again. It is safe for concurrent use and can be
+// saved to a file to survive restarts.
+type Validators struct {
+	mutex   sync.Mutex
+	entries map[string]validatedPage
+	now     func() time.Time // replaced in tests
+}
+
+// validatedPage is a parsed result page with the validators it was served with
+type validatedPage struct {
+	ETag         string        `json:"etag,omitempty"`
+	LastModified string        `json:"last_modified,omitempty"`
+	Offers       []RentalOffer `json:"offers"`
+	NextPageURL  string        `json:"next_page_url,omitempty"`
+	TotalResults int           `json:"total_results,omitempty"`
+	Used         time.Time     `json:"used"`
+}
+
+// NewValidators creates an empty validator store
+func NewValidators() *Validators {
+	return &Validators{
+		entries: make(map[string]validatedPage),
+		now:     time.Now,
+	}
+}
+
+// get returns a copy of the validated page of a request
+func (v *Validators) get(key string) (validatedPage, bool) {
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+
+	entry, ok := v.entries[key]
+	if !ok {
+		return validatedPage{}, false
+	}
+	entry.Used = v.now()
+	v.entries[key] = entry
+	entry.Offers = append([]RentalOffer(nil), entry.Offers...)
+	return entry, true
+}
+
+// put stores the validated page of a request
+func (v *Validators) put(key string, page validatedPage) {
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+
+	page.Offers = append([]RentalOffer(nil), page.Offers...)
+	page.Used = v.now()
+	v.entries[key] = page
+}
+
+// Save writes the validators to a file, dropping the pages not requested for a week
+func (v *Validators) Save(path string) error {
+	v.mutex.Lock()
+	now := v.now()
+	for key, entry := range v.entries {
+		if now.Sub(entry.Used) > validatorMaxAge {
+			delete(v.entries, key)
+		}
+	}
+	data, err := json.MarshalIndent(v.entries, "", "  ")
+	v.mutex.Unlock()
+	if err != nil {
+		return fmt.Errorf("error marshaling page validators: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("error creating page validator directory: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("error writing page validators to %s: %w", path, err)
+	}
+	return nil
+}
+
+// Load reads the validators from a file written by Save. A missing file is not an error.
+func (v *Validators) Load(path string) error {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("error reading page validators from %s: %w", path, err)
+	}
+
+	entries := make(map[string]validatedPage)
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return fmt.Errorf("error parsing page validators from %s: %w", path, err)
+	}
+
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+	v.entries = entries
+	return nil
+}
@@ -0,0 +1,148 @@
+package scraper
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"sync/atomic"
+	"testing"
+)
+
+// conditionalServer answers the search POST with a first page linking to a second
+// one tagged with the ETag in etag. The second page is answered with 304 Not
+// Modified when the request already has the ETag.
+func conditionalServer(t *testing.T, etag *atomic.Value) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var notModified atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		current := etag.Load().(string)
+		if r.Method == "POST" {
+			if r.Header.Get("If-None-Match") != "" {
+				t.Errorf("the search POST was sent with If-None-Match %q", r.Header.Get("If-None-Match"))
+			}
+			w.Header().Set("ETag", current)
+			fmt.Fprint(w, listingPage(1, 3, "/haku?page=2"))
+			return
+		}
+		if r.Header.Get("If-None-Match") == current {
+			notModified.Add(1)
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", current)
+		fmt.Fprint(w, listingPage(4, 3, ""))
+	}))
+	t.Cleanup(server.Close)
+	return server, &notModified
+}
+
+func TestFetchRentalOffersReusesNotModifiedPages(t *testing.T) {
+	var etag atomic.Value
+	etag.Store(`"v1"`)
+	server, notModified := conditionalServer(t, &etag)
+	website := newTestWebSite(t, server.URL)
+	website.PageDelay = 0
+	website.Validators = NewValidators()
+
+	first, err := website.FetchRentalOffers("method=search&type=full", 0)
+	if err != nil {
+		t.Fatalf("first fetch: %v", err)
+	}
+	second, err := website.FetchRentalOffers("method=search&type=full", 0)
+	if err != nil {
+		t.Fatalf("second fetch: %v", err)
+	}
+	if notModified.Load() != 1 {
+		t.Fatalf("server answered 304 %d times, want 1", notModified.Load())
+	}
+	if len(second) != 6 || !reflect.DeepEqual(first, second) {
+		t.Errorf("offers of the unchanged page = %v, want %v", second, first)
+	}
+
+	// A changed page is downloaded again
+	etag.Store(`"v2"`)
+	if _, err := website.FetchRentalOffers("method=search&type=full", 0); err != nil {
+		t.Fatalf("fetch after change: %v", err)
+	}
+	if notModified.Load() != 1 {
+		t.Errorf("the changed page was answered with 304")
+	}
+}
+
+func TestValidatorsSurviveRestart(t *testing.T) {
+	var etag atomic.Value
+	etag.Store(`"v1"`)
+	server, notModified := conditionalServer(t, &etag)
+	path := filepath.Join(t.TempDir(), "page_validators.json")
+
+	website := newTestWebSite(t, server.URL)
+	website.PageDelay = 0
+	website.Validators = NewValidators()
+	if _, err := website.FetchRentalOffers("method=search&type=full", 0); err != nil {
+		t.Fatalf("fetch: %v", err)
+	}
+	if err := website.Validators.Save(path); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	restarted := newTestWebSite(t, server.URL)
+	restarted.PageDelay = 0
+	restarted.Validators = NewValidators()
+	if err := restarted.Validators.Load(path); err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	offers, err := restarted.FetchRentalOffers("method=search&type=full", 0)
+	if err != nil {
+		t.Fatalf("fetch after restart: %v", err)
+	}
+	if notModified.Load() != 1 || len(offers) != 6 {
+		t.Errorf("got %d offers with %d 304 answers, want 6 offers, 3 of them from a 304", len(offers), notModified.Load())
+	}
+}
+
+func TestRefusedValidatorsAreACacheMiss(t *testing.T) {
+	var refused atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") != "" {
+			refused.Add(1)
+			w.WriteHeader(http.StatusPreconditionFailed)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		fmt.Fprint(w, listingPage(1, 3, ""))
+	}))
+	t.Cleanup(server.Close)
+	website := newTestWebSite(t, server.URL)
+	website.Validators = NewValidators()
+
+	for i := 0; i < 2; i++ {
+		offers, _, err := website.fetchAndParse(2, server.URL+"/haku?page=2", "GET", "")
+		if err != nil || len(offers) != 3 {
+			t.Fatalf("fetch %d = %d offers, %v, want the page", i+1, len(offers), err)
+		}
+	}
+	if refused.Load() != 1 {
+		t.Errorf("server refused the validators %d times, want once", refused.Load())
+	}
+}
+
+func TestUnconditionalNotModifiedIsAnError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotModified)
+	}))
+	t.Cleanup(server.Close)
+	website := newTestWebSite(t, server.URL)
+	website.Retry = fastRetries
+
+	if _, err := website.fetchPage(server.URL, "GET", ""); err == nil {
+		t.Error("a 304 answer to an unconditional request was accepted")
+	}
+}
+
+func TestValidatorsLoadMissingFile(t *testing.T) {
+	if err := NewValidators().Load(filepath.Join(t.TempDir(), "missing.json")); err != nil {
+		t.Errorf("Load of a missing file: %v", err)
+	}
+}
@@ -0,0 +1,950 @@
+// Package scraper fetches rental offers from Vuokraovi.com and parses them from the
+// result pages. It is used by the command line tool and the Telegram bot and can
+// be imported by other Go programs.
+package scraper
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"log/slog"
+	"math/rand"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// WebSite is a client for the site. It keeps the session cookies between requests,
+// so one client should be used per search.
+type WebSite struct {
+	client  *http.Client
+	jar     *recordingJar
+	baseURL string
+	verbose bool
+
+	// UserAgents are rotated round-robin, one per request
+	UserAgents []string
+	uaIndex    uint32
+
+	// Timeout bounds a single request including reading the body (0 = no timeout)
+	Timeout time.Duration
+
+	// Retry controls how transient request failures are retried
+	Retry RetryPolicy
+
+	// Locale is the site language used for requests ("fi", "sv" or "en"). It also
+	// sets the Accept-Language header, like a browser set up for the locale does.
+	Locale string
+
+	// Headers are sent with every request, replacing the default headers of the
+	// same name (nil = defaults only)
+	Headers map[string]string
+
+	// Parser holds the selectors used to extract offers
+	Parser ParserConfig
+
+	// FallbackParser holds the selectors tried when Parser misses too many prices
+	FallbackParser ParserConfig
+
+	// FallbackThreshold is the fraction of offers without a price above which
+	// the fallback selectors are tried (0 disables the fallback)
+	FallbackThreshold float64
+
+	// MaxOffers stops collecting offers once this many are gathered (0 = no limit)
+	MaxOffers int
+
+	// PageCap stops pagination after this many pages whatever the page limit, so a
+	// pager that never ends cannot keep the scraper busy (0 = DefaultPageCap)
+	PageCap int
+
+	// Cache serves recently fetched pages without requesting them again (nil = no cache)
+	Cache *PageCache
+
+	// Validators makes requests conditional on the ETag and Last-Modified of the
+	// previous response, reusing its offers when the page did not change (nil =
+	// always download)
+	Validators *Validators
+
+	// OfferFilter drops the offers it returns false for before they are collected,
+	// e.g. listings of a specific agency (nil keeps all offers)
+	OfferFilter func(RentalOffer) bool
+
+	// PageDelay is the pause between two result pages, to be nice to the server
+	PageDelay time.Duration
+	sleep     func(time.Duration) // pauses between pages, replaced in tests
+
+	// TotalResults is the number of offers matching the last search as reported
+	// by the site, which may exceed the offers fetched when a limit applies
+	// (0 when the site did not report it)
+	TotalResults int
+}
+
+// New creates a client for the site with the default settings. Verbose clients log
+// every request and page they scrape.
+func New(verbose bool) (*WebSite, error) {
+	cookies, err := cookiejar.New(nil)
+	if err != nil {
+		return nil, fmt.Errorf("error creating cookie jar: %w", err)
+	}
+	jar := newRecordingJar(cookies)
+
+	client := &http.Client{
+		Jar: jar,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= 10 {
+				return fmt.Errorf("too many redirects")
+			}
+			return nil
+		},
+	}
+
+	return &WebSite{
+		client:     client,
+		jar:        jar,
+		baseURL:    "https://www.vuokraovi.com",
+		verbose:    verbose,
+		UserAgents: []string{defaultUserAgent},
+
+		Timeout:           30 * time.Second,
+		Retry:             DefaultRetryPolicy,
+		Locale:            "fi",
+		Parser:            DefaultParserConfig(),
+		FallbackParser:    FallbackParserConfig(),
+		FallbackThreshold: 0.5,
+		PageDelay:         500 * time.Millisecond,
+		sleep:             time.Sleep,
+	}, nil
+}
+
+// DefaultPageCap is the number of pages fetched at most when PageCap is not set
+const DefaultPageCap = 200
+
+// defaultUserAgent is sent when no other user agents are configured
+const defaultUserAgent = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/91.0.4472.124 Safari/537.36"
+
+// LoadUserAgents reads user agent strings from a file, one per line.
+// Empty lines and lines starting with # are ignored.
+func LoadUserAgents(path string) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading user agents from %s: %w", path, err)
+	}
+
+	var agents []string
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		agents = append(agents, line)
+	}
+
+	if len(agents) == 0 {
+		return nil, fmt.Errorf("no user agents found in %s", path)
+	}
+	return agents, nil
+}
+
+// nextUserAgent returns the user agent for the next request
+func (w *WebSite) nextUserAgent() string {
+	if len(w.UserAgents) == 0 {
+		return defaultUserAgent
+	}
+	i := atomic.AddUint32(&w.uaIndex, 1) - 1
+	return w.UserAgents[int(i%uint32(len(w.UserAgents)))]
+}
+
+// acceptLanguages maps the supported locales to their Accept-Language headers
+var acceptLanguages = map[string]string{
+	"fi": "fi-FI,fi;q=0.9,en;q=0.5",
+	"sv": "sv-FI,sv;q=0.9,en;q=0.5",
+	"en": "en-US,en;q=0.5",
+}
+
+// ValidateLocale checks that the locale is supported by the site
+func ValidateLocale(locale string) error {
+	if _, ok := acceptLanguages[locale]; !ok {
+		return fmt.Errorf("unsupported locale %q (valid values: fi, sv, en)", locale)
+	}
+	return nil
+}
+
+// locale returns the configured locale, falling back to Finnish
+func (w *WebSite) locale() string {
+	if _, ok := acceptLanguages[w.Locale]; ok {
+		return w.Locale
+	}
+	return "fi"
+}
+
+// InitialURL returns the search URL the form data is posted to
+func (w *WebSite) InitialURL() string {
+	return w.baseURL + "/haku/vuokra-asunnot?locale=" + w.locale()
+}
+
+// SetProxy routes all requests through the given http://, https:// or socks5:// proxy.
+// An empty URL keeps the default behavior.
+func (w *WebSite) SetProxy(proxyURL string) error {
+	if proxyURL == "" {
+		return nil
+	}
+
+	parsed, err := url.Parse(proxyURL)
+	if err != nil {
+		return fmt.Errorf("invalid proxy URL: %w", err)
+	}
+
+	switch parsed.Scheme {
+	case "http", "https", "socks5", "socks5h":
+	default:
+		return fmt.Errorf("unsupported proxy scheme %q (use http, https or socks5)", parsed.Scheme)
+	}
+	if parsed.Host == "" {
+		return fmt.Errorf("invalid proxy URL %q: missing host", proxyURL)
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = http.ProxyURL(parsed)
+	w.client.Transport = transport
+
+	return nil
+}
+
+// recordingJar is a cookie jar that also remembers the attributes of the cookies it
+// stores, which http.CookieJar does not return, so that they can be persisted
+type recordingJar struct {
+	http.CookieJar
+
+	mutex   sync.Mutex
+	cookies map[string]map[string]http.Cookie // host -> domain, path and name -> cookie
+}
+
+// newRecordingJar wraps a cookie jar
+func newRecordingJar(jar http.CookieJar) *recordingJar {
+	return &recordingJar{
+		CookieJar: jar,
+		cookies:   make(map[string]map[string]http.Cookie),
+	}
+}
+
+// SetCookies stores the cookies in the jar and records them for the host of u
+func (j *recordingJar) SetCookies(u *url.URL, cookies []*http.Cookie) {
+	j.CookieJar.SetCookies(u, cookies)
+
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	now := time.Now()
+	hostCookies := j.cookies[u.Host]
+	if hostCookies == nil {
+		hostCookies = make(map[string]http.Cookie)
+		j.cookies[u.Host] = hostCookies
+	}
+	for _, cookie := range cookies {
+		key := cookie.Domain + ";" + cookie.Path + ";" + cookie.Name
+		recorded := *cookie
+		// Max-Age takes precedence over Expires, store it as an absolute expiry
+		if recorded.MaxAge > 0 {
+			recorded.Expires = now.Add(time.Duration(recorded.MaxAge) * time.Second)
+			recorded.MaxAge = 0
+		}
+		if recorded.MaxAge < 0 || cookieExpired(recorded.Expires, now) {
+			delete(hostCookies, key)
+			continue
+		}
+		hostCookies[key] = recorded
+	}
+}
+
+// recorded returns the unexpired cookies stored for each host
+func (j *recordingJar) recorded(now time.Time) map[string][]http.Cookie {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	result := make(map[string][]http.Cookie)
+	for host, cookies := range j.cookies {
+		for _, cookie := range cookies {
+			if !cookieExpired(cookie.Expires, now) {
+				result[host] = append(result[host], cookie)
+			}
+		}
+	}
+	return result
+}
+
+// cookieExpired reports whether a cookie expiring at expires has expired at now.
+// Session cookies have no expiry and never expire.
+func cookieExpired(expires, now time.Time) bool {
+	return !expires.IsZero() && !expires.After(now)
+}
+
+// storedCookie is the on-disk representation of a cookie
+type storedCookie struct {
+	Name     string    `json:"name"`
+	Value    string    `json:"value"`
+	Domain   string    `json:"domain,omitempty"`
+	Path     string    `json:"path,omitempty"`
+	Expires  time.Time `json:"expires"` // zero for session cookies
+	Secure   bool      `json:"secure,omitempty"`
+	HttpOnly bool      `json:"http_only,omitempty"`
+}
+
+// SaveCookies writes the unexpired cookies of the session to a JSON file keyed by host
+func (w *WebSite) SaveCookies(path string) error {
+	stored := make(map[string][]storedCookie)
+	for host, cookies := range w.jar.recorded(time.Now()) {
+		for _, cookie := range cookies {
+			stored[host] = append(stored[host], storedCookie{
+				Name:     cookie.Name,
+				Value:    cookie.Value,
+				Domain:   cookie.Domain,
+				Path:     cookie.Path,
+				Expires:  cookie.Expires,
+				Secure:   cookie.Secure,
+				HttpOnly: cookie.HttpOnly,
+			})
+		}
+	}
+
+	data, err := json.MarshalIndent(stored, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error marshaling cookies: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("error creating cookie directory: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		return fmt.Errorf("error writing cookies to %s: %w", path, err)
+	}
+
+	return nil
+}
+
+// LoadCookies populates the cookie jar from a file written by SaveCookies.
+// Expired cookies are skipped, and a missing file is not an error.
+func (w *WebSite) LoadCookies(path string) error {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("error reading cookies from %s: %w", path, err)
+	}
+
+	var stored map[string][]storedCookie
+	if err := json.Unmarshal(data, &stored); err != nil {
+		return fmt.Errorf("error parsing cookies from %s: %w", path, err)
+	}
+
+	scheme := "https"
+	if siteURL, err := url.Parse(w.baseURL); err == nil && siteURL.Scheme != "" {
+		scheme = siteURL.Scheme
+	}
+
+	now := time.Now()
+	for host, cookies := range stored {
+		hostURL := &url.URL{Scheme: scheme, Host: host, Path: "/"}
+		jarCookies := make([]*http.Cookie, 0, len(cookies))
+		for _, cookie := range cookies {
+			if cookieExpired(cookie.Expires, now) {
+				continue
+			}
+			cookiePath := cookie.Path
+			if cookiePath == "" {
+				cookiePath = "/"
+			}
+			jarCookies = append(jarCookies, &http.Cookie{
+				Name:     cookie.Name,
+				Value:    cookie.Value,
+				Domain:   cookie.Domain,
+				Path:     cookiePath,
+				Expires:  cookie.Expires,
+				Secure:   cookie.Secure,
+				HttpOnly: cookie.HttpOnly,
+			})
+		}
+		w.jar.SetCookies(hostURL, jarCookies)
+	}
+
+	return nil
+}
+
+func (w *WebSite) logRequest(method, url string) {
+	if w.verbose {
+		log.Printf("[%s] %s", method, url)
+	}
+}
+
+// ErrBlocked is returned when the site serves a challenge or block page instead of results
+var ErrBlocked = errors.New("request blocked by the site")
+
+// RetryPolicy controls how failed requests are retried
+type RetryPolicy struct {
+	MaxAttempts int           // total number of attempts, including the first one
+	BaseDelay   time.Duration // delay before the first retry, doubled on every further retry
+	MaxDelay    time.Duration // upper bound for a single delay (0 = unbounded)
+
+	// MaxRetryAfterWait caps the total time spent waiting on 429 Retry-After responses
+	MaxRetryAfterWait time.Duration
+	// MaxRateLimitRetries caps how often a 429 response is retried; these retries
+	// do not consume attempts (0 = 429 responses are not retried)
+	MaxRateLimitRetries int
+}
+
+// DefaultRetryPolicy is the retry policy used by New
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 3,
+	BaseDelay:   time.Second,
+	MaxDelay:    30 * time.Second,
+
+	MaxRetryAfterWait:   2 * time.Minute,
+	MaxRateLimitRetries: 5,
+}
+
+// backoff returns the delay before the given retry (starting at 1), with jitter
+func (p RetryPolicy) backoff(retry int) time.Duration {
+	delay := p.BaseDelay << (retry - 1)
+	if p.MaxDelay > 0 && (delay > p.MaxDelay || delay <= 0) {
+		delay = p.MaxDelay
+	}
+
+	// Pick a random delay between half and the full backoff
+	half := delay / 2
+	if half <= 0 {
+		return delay
+	}
+	return half + time.Duration(rand.Int63n(int64(half)))
+}
+
+// statusError is returned when the server responds with an unexpected status code
+type statusError struct {
+	code       int
+	retryAfter string // value of the Retry-After header, if any
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("unexpected status code: %d", e.code)
+}
+
+// parseRetryAfter parses a Retry-After header given either in seconds or as an HTTP date
+func parseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, false
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	if date, err := http.ParseTime(value); err == nil {
+		wait := date.Sub(now)
+		if wait < 0 {
+			wait = 0
+		}
+		return wait, true
+	}
+
+	return 0, false
+}
+
+// isRetryable reports whether a failed request should be retried.
+// Connection errors and 5xx gateway/server errors are retried, 4xx are not.
+func isRetryable(err error) bool {
+	var se *statusError
+	if errors.As(err, &se) {
+		switch se.code {
+		case http.StatusInternalServerError, http.StatusBadGateway,
+			http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+			return true
+		}
+		return false
+	}
+	return true
+}
+
+// FetchRentalOffers posts the search form data and collects the offers of the result
+// pages, following the pagination up to maxPages pages (0 = no limit)
+func (w *WebSite) FetchRentalOffers(formData string, maxPages int) ([]RentalOffer, error) {
+	initialURL := w.InitialURL()
+	if w.verbose {
+		log.Printf("Sending initial POST request to %s", initialURL)
+	}
+
+	offers, nextPageURL, err := w.fetchAndParse(1, initialURL, "POST", formData)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching initial page: %w", err)
+	}
+
+	seen := make(map[string]bool)
+	allOffers := appendUnseen(nil, w.filterOffers(offers), seen)
+
+	pageCap := w.PageCap
+	if pageCap <= 0 {
+		pageCap = DefaultPageCap
+	}
+
+	// Follow pagination links until the end or until max pages or max offers is reached
+	visited := make(map[string]bool)
+	pageNum := 2
+	for nextPageURL != "" {
+		if w.MaxOffers > 0 && len(allOffers) >= w.MaxOffers {
+			if w.verbose {
+				log.Printf("Reached maximum number of offers (%d). Stopping pagination.", w.MaxOffers)
+			}
+			break
+		}
+
+		// Check if we've reached the maximum number of pages
+		if maxPages > 0 && pageNum > maxPages {
+			if w.verbose {
+				log.Printf("Reached maximum number of pages (%d). Stopping pagination.", maxPages)
+			}
+			break
+		}
+
+		// Guard against a pager that never ends, whatever the page limit
+		if pageNum > pageCap {
+			log.Printf("Warning: reached the safety cap of %d pages, stopping pagination", pageCap)
+			break
+		}
+		key := pageKey(nextPageURL)
+		if visited[key] {
+			log.Printf("Warning: page %d links back to %s, stopping pagination", pageNum-1, nextPageURL)
+			break
+		}
+		visited[key] = true
+
+		// Pause before every page but the first, to be nice to the server
+		if w.PageDelay > 0 {
+			w.sleep(w.PageDelay)
+		}
+
+		if w.verbose {
+			log.Printf("Fetching page %d: %s", pageNum, nextPageURL)
+		}
+
+		pageOffers, newNextPageURL, err := w.fetchAndParse(pageNum, nextPageURL, "GET", "")
+		if errors.Is(err, ErrBlocked) {
+			return nil, fmt.Errorf("error fetching page %d: %w", pageNum, err)
+		}
+		if err != nil {
+			log.Printf("Error fetching page %d: %v", pageNum, err)
+			break
+		}
+
+		allOffers = appendUnseen(allOffers, w.filterOffers(pageOffers), seen)
+		nextPageURL = newNextPageURL
+		pageNum++
+	}
+
+	if w.MaxOffers > 0 && len(allOffers) > w.MaxOffers {
+		allOffers = allOffers[:w.MaxOffers]
+	}
+	return allOffers, nil
+}
+
+// pageKey identifies the page a URL points to, ignoring the fragment and the
+// order of the query parameters, so a pager linking back to a page it already
+// sent is noticed however it spells the link
+func pageKey(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+	u.Fragment = ""
+	u.Host = strings.ToLower(u.Host)
+	u.RawQuery = u.Query().Encode()
+	return u.String()
+}
+
+// filterOffers returns the offers of a page kept by OfferFilter
+func (w *WebSite) filterOffers(offers []RentalOffer) []RentalOffer {
+	if w.OfferFilter == nil {
+		return offers
+	}
+	// A new slice, as the offers may be shared with the page cache
+	kept := make([]RentalOffer, 0, len(offers))
+	for _, offer := range offers {
+		if w.OfferFilter(offer) {
+			kept = append(kept, offer)
+		}
+	}
+	return kept
+}
+
+// appendUnseen appends the offers whose ID is not in seen yet and records them there,
+// so a listing shifting to the next page while scraping is collected only once.
+// Offers without a link cannot be told apart and are always kept.
+func appendUnseen(offers, page []RentalOffer, seen map[string]bool) []RentalOffer {
+	for _, offer := range page {
+		if offer.Link != "" {
+			id := OfferID(offer.Link)
+			if seen[id] {
+				continue
+			}
+			seen[id] = true
+		}
+		offers = append(offers, offer)
+	}
+	return offers
+}
+
+// DiscoverPageCount posts the initial search and returns the number of result pages
+// according to the pager, without fetching the other pages. Results without a pager
+// fit on a single page, and a search without results has no pages.
+func (w *WebSite) DiscoverPageCount(formData string) (int, error) {
+	body, err := w.fetchPage(w.InitialURL(), "POST", formData)
+	if err != nil {
+		return 0, fmt.Errorf("error fetching initial page: %w", err)
+	}
+
+	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(body))
+	if err != nil {
+		return 0, fmt.Errorf("error parsing HTML: %w", err)
+	}
+	if isBlockedPage(doc) {
+		return 0, fmt.Errorf("error fetching initial page: %w", ErrBlocked)
+	}
+
+	for _, config := range []ParserConfig{w.Parser, w.FallbackParser} {
+		if count := extractPageCount(doc, config); count > 0 {
+			return count, nil
+		}
+	}
+	if doc.Find(w.Parser.Container).Length() > 0 {
+		return 1, nil
+	}
+	return 0, nil
+}
+
+func (w *WebSite) fetchAndParse(page int, targetURL, method, formData string) ([]RentalOffer, string, error) {
+	key := pageCacheKey(method, targetURL, formData)
+	if w.Cache != nil {
+		if cached, ok := w.Cache.get(key); ok {
+			if w.verbose {
+				log.Printf("[%s] %s (cached)", method, targetURL)
+			}
+			if page == 1 {
+				w.TotalResults = cached.totalResults
+			}
+			return cached.offers, cached.nextPageURL, nil
+		}
+	}
+
+	// Only result pages fetched with GET are validated, never the search POST
+	var known *validatedPage
+	if w.Validators != nil && method == "GET" {
+		if validated, ok := w.Validators.get(key); ok {
+			known = &validated
+		}
+	}
+
+	w.logRequest(method, targetURL)
+	start := time.Now()
+
+	result, err := w.fetchConditional(targetURL, method, formData, known)
+	if err != nil {
+		return nil, "", err
+	}
+	if result.notModified {
+		if w.verbose {
+			log.Printf("[%s] %s (not modified)", method, targetURL)
+		}
+		if page == 1 {
+			w.TotalResults = known.TotalResults
+		}
+		w.cachePage(key, page, known.Offers, known.NextPageURL)
+		return known.Offers, known.NextPageURL, nil
+	}
+	body := result.body
+
+	// Parse the HTML document
+	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(body))
+	if err != nil {
+		return nil, "", fmt.Errorf("error parsing HTML: %w", err)
+	}
+
+	if isBlockedPage(doc) {
+		return nil, "", ErrBlocked
+	}
+
+	// Extract rental offers using the function from parser.go
+	offers := w.extractWithFallback(doc)
+	if page == 1 {
+		w.TotalResults = extractTotalCount(doc, w.Parser)
+		if w.TotalResults == 0 {
+			w.TotalResults = extractTotalCount(doc, w.FallbackParser)
+		}
+	}
+
+	// The figures are logged as attributes so JSON logs can be queried by them
+	if w.verbose {
+		slog.Info("Scraped page", "page", page, "offers_found", len(offers), "duration_ms", time.Since(start).Milliseconds())
+	}
+
+	// Check for pagination link
+	nextPageURL := ""
+	doc.Find(w.Parser.NextPage).Each(func(i int, s *goquery.Selection) {
+		if href, exists := s.Attr("href"); exists {
+			if !strings.HasPrefix(href, "http") {
+				href = w.baseURL + href
+			}
+			nextPageURL = href
+		}
+	})
+
+	if w.Validators != nil && method == "GET" && (result.etag != "" || result.lastModified != "") {
+		validated := validatedPage{ETag: result.etag, LastModified: result.lastModified, Offers: offers, NextPageURL: nextPageURL}
+		if page == 1 {
+			validated.TotalResults = w.TotalResults
+		}
+		w.Validators.put(key, validated)
+	}
+	w.cachePage(key, page, offers, nextPageURL)
+	return offers, nextPageURL, nil
+}
+
+// cachePage stores a parsed page in the page cache, if there is one
+func (w *WebSite) cachePage(key string, page int, offers []RentalOffer, nextPageURL string) {
+	if w.Cache == nil {
+		return
+	}
+	cached := cachedPage{offers: offers, nextPageURL: nextPageURL}
+	if page == 1 {
+		cached.totalResults = w.TotalResults
+	}
+	w.Cache.put(key, cached)
+}
+
+// extractWithFallback extracts offers with the primary selectors and retries with
+// the fallback selectors when too many offers are missing a price
+func (w *WebSite) extractWithFallback(doc *goquery.Document) []RentalOffer {
+	offers := extractRentalOffers(doc, w.baseURL, w.Parser)
+
+	missing := missingPriceFraction(offers)
+	if w.FallbackThreshold <= 0 || missing <= w.FallbackThreshold {
+		return offers
+	}
+
+	log.Printf("Warning: %.0f%% of offers are missing a price with the %s selectors, trying %s selectors",
+		missing*100, w.Parser.Name, w.FallbackParser.Name)
+
+	fallbackOffers := extractRentalOffers(doc, w.baseURL, w.FallbackParser)
+	if len(fallbackOffers) > 0 && missingPriceFraction(fallbackOffers) < missing {
+		log.Printf("Using %s selectors (%d offers)", w.FallbackParser.Name, len(fallbackOffers))
+		return fallbackOffers
+	}
+
+	log.Printf("The %s selectors did not improve extraction, keeping %s results", w.FallbackParser.Name, w.Parser.Name)
+	return offers
+}
+
+// fetchPage requests a page and returns its body, retrying transient failures
+// according to the retry policy
+func (w *WebSite) fetchPage(targetURL, method, formData string) ([]byte, error) {
+	result, err := w.fetchConditional(targetURL, method, formData, nil)
+	return result.body, err
+}
+
+// fetchResult is a fetched page with the validators it was served with
+type fetchResult struct {
+	body         []byte
+	etag         string
+	lastModified string
+	notModified  bool // the server answered 304 Not Modified to a conditional request
+}
+
+// fetchConditional fetches a page like fetchPage. With the validators of a previous
+// response the request is conditional, and an unchanged page is reported as not
+// modified instead of being downloaded.
+func (w *WebSite) fetchConditional(targetURL, method, formData string, known *validatedPage) (fetchResult, error) {
+	attempts := w.Retry.MaxAttempts
+	if attempts < 1 {
+		attempts = 1
+	}
+
+	var lastErr error
+	var waitedForRetryAfter time.Duration
+	rateLimited := 0
+	for attempt := 1; attempt <= attempts; {
+		req, err := w.newRequest(targetURL, method, formData)
+		if err != nil {
+			return fetchResult{}, err
+		}
+		if known != nil {
+			if known.ETag != "" {
+				req.Header.Set("If-None-Match", known.ETag)
+			}
+			if known.LastModified != "" {
+				req.Header.Set("If-Modified-Since", known.LastModified)
+			}
+		}
+
+		result, err := w.doRequest(req)
+		if err == nil {
+			return result, nil
+		}
+		lastErr = err
+
+		// Honor Retry-After on 429 responses without consuming an attempt
+		// A server refusing the validators gets the page asked for without them
+		var se *statusError
+		if errors.As(err, &se) && se.code == http.StatusPreconditionFailed && known != nil {
+			log.Printf("Validators of %s %s were refused, fetching it again", method, targetURL)
+			known = nil
+			continue
+		}
+
+		if errors.As(err, &se) && se.code == http.StatusTooManyRequests {
+			rateLimited++
+			if rateLimited > w.Retry.MaxRateLimitRetries {
+				return fetchResult{}, fmt.Errorf("rate limited by server, giving up after %d retries: %w", rateLimited-1, err)
+			}
+			// Never retry right away, a zero or past Retry-After would hammer the server
+			wait, ok := parseRetryAfter(se.retryAfter, time.Now())
+			if minWait := w.Retry.backoff(rateLimited); !ok || wait < minWait {
+				wait = minWait
+			}
+			if waitedForRetryAfter+wait > w.Retry.MaxRetryAfterWait {
+				return fetchResult{}, fmt.Errorf("rate limited by server, giving up after waiting %v: %w", waitedForRetryAfter, err)
+			}
+			log.Printf("Rate limited on %s %s, retrying in %v", method, targetURL, wait)
+			time.Sleep(wait)
+			waitedForRetryAfter += wait
+			continue
+		}
+
+		if !isRetryable(err) {
+			break
+		}
+
+		attempt++
+		if attempt <= attempts {
+			delay := w.Retry.backoff(attempt - 1)
+			log.Printf("Retrying %s %s in %v (attempt %d/%d): %v", method, targetURL, delay, attempt, attempts, lastErr)
+			time.Sleep(delay)
+		}
+	}
+
+	return fetchResult{}, lastErr
+}
+
+// newRequest creates a request with the headers the site expects
+func (w *WebSite) newRequest(targetURL, method, formData string) (*http.Request, error) {
+	var req *http.Request
+	var err error
+
+	if method == "POST" {
+		req, err = http.NewRequest("POST", targetURL, bytes.NewBufferString(formData))
+		if err != nil {
+			return nil, fmt.Errorf("error creating POST request: %w", err)
+		}
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	} else {
+		req, err = http.NewRequest("GET", targetURL, nil)
+		if err != nil {
+			return nil, fmt.Errorf("error creating GET request: %w", err)
+		}
+	}
+
+	// Set common headers
+	req.Header.Set("User-Agent", w.nextUserAgent())
+	req.Header.Set("Accept", "text/html,application/xhtml+xml,application/xml;q=0.9,image/webp,*/*;q=0.8")
+	req.Header.Set("Accept-Language", acceptLanguages[w.locale()])
+	req.Header.Set("Accept-Encoding", "gzip, deflate")
+	for name, value := range w.Headers {
+		req.Header.Set(name, value)
+	}
+
+	return req, nil
+}
+
+// doRequest sends a single request and reads the response body
+func (w *WebSite) doRequest(req *http.Request) (fetchResult, error) {
+	if w.Timeout > 0 {
+		ctx, cancel := context.WithTimeout(req.Context(), w.Timeout)
+		defer cancel()
+		req = req.WithContext(ctx)
+	}
+
+	// Send the request
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return fetchResult{}, fmt.Errorf("error sending request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	// An unchanged page has no body, only conditional requests expect this answer
+	conditional := req.Header.Get("If-None-Match") != "" || req.Header.Get("If-Modified-Since") != ""
+	if resp.StatusCode == http.StatusNotModified && conditional {
+		return fetchResult{notModified: true}, nil
+	}
+
+	// Check response status
+	if resp.StatusCode != http.StatusOK {
+		return fetchResult{}, &statusError{code: resp.StatusCode, retryAfter: resp.Header.Get("Retry-After")}
+	}
+
+	// Read the response body
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fetchResult{}, fmt.Errorf("error reading response body: %w", err)
+	}
+
+	body, err = decodeBody(body, resp.Header.Get("Content-Encoding"))
+	if err != nil {
+		return fetchResult{}, err
+	}
+	return fetchResult{body: body, etag: resp.Header.Get("ETag"), lastModified: resp.Header.Get("Last-Modified")}, nil
+}
+
+// decodeBody decompresses a gzip or deflate encoded response body.
+// Bodies with any other encoding are returned as they are.
+func decodeBody(body []byte, encoding string) ([]byte, error) {
+	var reader io.ReadCloser
+	var err error
+
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "gzip", "x-gzip":
+		reader, err = gzip.NewReader(bytes.NewReader(body))
+		if err != nil {
+			return nil, fmt.Errorf("error creating gzip reader: %w", err)
+		}
+	case "deflate":
+		// Servers send deflate either zlib-wrapped (as specified) or raw
+		reader, err = zlib.NewReader(bytes.NewReader(body))
+		if err != nil {
+			reader = flate.NewReader(bytes.NewReader(body))
+		}
+	default:
+		return body, nil
+	}
+	defer reader.Close()
+
+	decoded, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, fmt.Errorf("error decompressing %s response body: %w", encoding, err)
+	}
+	return decoded, nil
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"github.com/aqaliarept/vuokraovi-bot/state"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// manualSearchCooldown is the minimum time between two /search commands of a user
+const manualSearchCooldown = time.Minute
+
+// searchCooldown limits how often each user can trigger a manual search
+type searchCooldown struct {
+	mutex    sync.Mutex
+	interval time.Duration
+	last     map[int64]time.Time
+}
+
+// newSearchCooldown creates a cooldown allowing one search per interval
+func newSearchCooldown(interval time.Duration) *searchCooldown {
+	return &searchCooldown{
+		interval: interval,
+		last:     make(map[int64]time.Time),
+	}
+}
+
+// Allow reports whether the user may search at the given time and records the search if so.
+// When the search is not allowed, it returns the time left until the next one is.
+func (c *searchCooldown) Allow(chatID int64, now time.Time) (bool, time.Duration) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if last, ok := c.last[chatID]; ok {
+		if wait := c.interval - now.Sub(last); wait > 0 {
+			return false, wait
+		}
+	}
+	c.last[chatID] = now
+	return true, 0
+}
+
+// manualSearches tracks the manual searches of all users
+var manualSearches = newSearchCooldown(manualSearchCooldown)
+
+// handleSearchCommand handles the /search command
+func handleSearchCommand(bot *tgbotapi.BotAPI, botState *state.BotState, message *tgbotapi.Message, config BotConfig) {
+	chatID := message.Chat.ID
+	lang := userLanguage(botState, chatID)
+
+	if ok, wait := manualSearches.Allow(chatID, time.Now()); !ok {
+		msg := tgbotapi.NewMessage(chatID, translate(lang, "search_wait", int(wait.Seconds())+1))
+		msg.ReplyMarkup = createMainKeyboard(lang)
+		sendMessage(bot, msg)
+		return
+	}
+
+	sendMessage(bot, tgbotapi.NewMessage(chatID, translate(lang, "search_started")))
+
+	// Fetching takes a while, keep handling other messages meanwhile
+	go runManualSearch(bot, botState, chatID, config)
+}
+
+// runManualSearch fetches the current offers of the searches the user subscribed to
+// and replies to the chat with the ones matching its filters or profiles. The shared
+// state is left to the periodic update, so other users are notified about new
+// offers as usual.
+func runManualSearch(bot *tgbotapi.BotAPI, botState *state.BotState, chatID int64, config BotConfig) {
+	lang := userLanguage(botState, chatID)
+	user, exists := botState.GetUser(chatID)
+	if !exists {
+		user = &state.UserState{ChatID: chatID}
+	}
+
+	// Fetch like an update does, which shares the session cookies and page validators
+	updateMutex.Lock()
+	offers, err := fetchOffers(subscribedSearchConfig(config, user))
+	updateMutex.Unlock()
+	if err != nil {
+		log.Printf("Error during manual search for user %d: %v", chatID, err)
+		msg := tgbotapi.NewMessage(chatID, translate(lang, "search_failed"))
+		msg.ReplyMarkup = createMainKeyboard(lang)
+		sendMessage(bot, msg)
+		return
+	}
+
+	offers = matchingOffers(botState, chatID, subscribedOffers(user, offers))
+	if len(offers) == 0 {
+		msg := tgbotapi.NewMessage(chatID, translate(lang, "search_no_match"))
+		msg.ReplyMarkup = createMainKeyboard(lang)
+		sendMessage(bot, msg)
+		return
+	}
+
+	sendMessage(bot, tgbotapi.NewMessage(chatID, translate(lang, "search_found", len(offers))))
+	sendOffersList(bot, offers, chatID, lang)
+
+	if config.DryRun {
+		return
+	}
+	// Shown offers are not sent again when the next update finds them
+	for _, offer := range offers {
+		botState.MarkOfferAsSeen(chatID, offer.Link)
+	}
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aqaliarept/vuokraovi-bot/state"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+func TestSearchCooldown(t *testing.T) {
+	cooldown := newSearchCooldown(time.Minute)
+	start := time.Date(2024, 5, 10, 12, 0, 0, 0, time.UTC)
+
+	if ok, _ := cooldown.Allow(1, start); !ok {
+		t.Fatal("the first search was refused")
+	}
+	if ok, wait := cooldown.Allow(1, start.Add(20*time.Second)); ok || wait != 40*time.Second {
+		t.Errorf("search within the cooldown: ok = %v, wait = %v, want refused with 40s left", ok, wait)
+	}
+	if ok, _ := cooldown.Allow(2, start.Add(20*time.Second)); !ok {
+		t.Error("another user's search was refused")
+	}
+	if ok, _ := cooldown.Allow(1, start.Add(time.Minute)); !ok {
+		t.Error("the search after the cooldown was refused")
+	}
+	// A refused search does not restart the cooldown
+	cooldown.Allow(1, start.Add(90*time.Second))
+	if ok, _ := cooldown.Allow(1, start.Add(2*time.Minute)); !ok {
+		t.Error("a refused search extended the cooldown")
+	}
+}
+
+// stubFetch makes updates and searches return the given offers
+func stubFetch(t *testing.T, offers []state.RentalOffer) {
+	t.Helper()
+	original := fetchOffers
+	fetchOffers = func(BotConfig) ([]state.RentalOffer, error) { return offers, nil }
+	t.Cleanup(func() { fetchOffers = original })
+}
+
+func TestManualSearchRepliesOnlyToTheCaller(t *testing.T) {
+	bot, fake := newFakeTelegram(t)
+	botState := newTestBotState(t)
+	botState.AddUser(&tgbotapi.User{FirstName: "Caller"}, 1)
+	botState.AddUser(&tgbotapi.User{FirstName: "Other"}, 2)
+	botState.SetUserFilters(1, state.Filters{MaxPrice: 900})
+	stubFetch(t, []state.RentalOffer{testOffer("https://example.com/a", "800 €/kk"), testOffer("https://example.com/b", "1200 €/kk")})
+
+	runManualSearch(bot, botState, 1, BotConfig{})
+
+	for _, sent := range fake.calls("sendMessage") {
+		if sent.Get("chat_id") != "1" {
+			t.Errorf("message sent to chat %s: %q", sent.Get("chat_id"), sent.Get("text"))
+		}
+	}
+	if len(botState.GetKnownOffers()) != 0 {
+		t.Error("the manual search changed the shared offers")
+	}
+	if user, _ := botState.GetUser(1); !user.SeenOffers.Has("https://example.com/a") || user.SeenOffers.Has("https://example.com/b") {
+		t.Errorf("seen offers = %v, want only the offer matching the filters", user.SeenOffers)
+	}
+
+	// The next update does not announce the offer the caller has already been shown
+	fake.reset()
+	notifyUsers(bot, botState, botState.UpdateOffers([]state.RentalOffer{testOffer("https://example.com/a", "800 €/kk")}))
+	if sent := fake.calls("sendMessage"); len(sent) != 1 || sent[0].Get("chat_id") != "2" {
+		t.Errorf("update sent %v, want a notification for the other user only", sent)
+	}
+}
+
+func TestManualSearchDryRunKeepsState(t *testing.T) {
+	bot, _ := newFakeTelegram(t)
+	botState := newTestBotState(t)
+	botState.AddUser(&tgbotapi.User{FirstName: "Caller"}, 1)
+	stubFetch(t, []state.RentalOffer{testOffer("https://example.com/a", "800 €/kk")})
+
+	runManualSearch(bot, botState, 1, BotConfig{DryRun: true})
+
+	if user, _ := botState.GetUser(1); len(user.SeenOffers) != 0 {
+		t.Errorf("dry run marked offers as seen: %v", user.SeenOffers)
+	}
+}
+
+func TestManualSearchUsesSubscribedSearchesUnderTheUpdateLock(t *testing.T) {
+	bot, fake := newFakeTelegram(t)
+	botState := newTestBotState(t)
+	botState.AddUser(&tgbotapi.User{FirstName: "Caller"}, 1)
+	botState.SetUserSearches(1, []string{"espoo"})
+	config := BotConfig{Searches: []Search{{Name: "helsinki", FormDataFile: "h.txt"}, {Name: "espoo", FormDataFile: "e.txt"}}}
+
+	espoo := testOffer("https://example.com/e", "800 €/kk")
+	espoo.Searches = []string{"espoo"}
+	helsinki := testOffer("https://example.com/h", "800 €/kk")
+	helsinki.Address = "Testikatu 2, Helsinki"
+	helsinki.Searches = []string{"helsinki"}
+
+	var fetched []Search
+	original := fetchOffers
+	fetchOffers = func(config BotConfig) ([]state.RentalOffer, error) {
+		if updateMutex.TryLock() {
+			updateMutex.Unlock()
+			t.Error("the manual search fetched without holding the update lock")
+		}
+		fetched = config.Searches
+		return []state.RentalOffer{espoo, helsinki}, nil
+	}
+	t.Cleanup(func() { fetchOffers = original })
+
+	runManualSearch(bot, botState, 1, config)
+
+	if len(fetched) != 1 || fetched[0].Name != "espoo" {
+		t.Errorf("fetched searches %v, want only espoo", fetched)
+	}
+	if user, _ := botState.GetUser(1); !user.SeenOffers.Has(espoo.Link) || user.SeenOffers.Has(helsinki.Link) {
+		t.Errorf("seen offers = %v, want only the espoo offer", user.SeenOffers)
+	}
+	if sent := fake.calls("sendMessage"); len(sent) == 0 {
+		t.Error("no reply was sent")
+	}
+}
@@ -0,0 +1,210 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/aqaliarept/vuokraovi-bot/state"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Search is a named search of the bot whose form data is read from its own file
+type Search struct {
+	Name         string
+	FormDataFile string
+}
+
+// parseSearch parses a -search value of the form name=path/to/form_data.txt
+func parseSearch(value string) (Search, error) {
+	name, path, ok := strings.Cut(value, "=")
+	name, path = strings.TrimSpace(name), strings.TrimSpace(path)
+	if !ok || name == "" || path == "" {
+		return Search{}, fmt.Errorf("invalid search %q, want name=path", value)
+	}
+	if strings.ContainsAny(name, ", ") {
+		return Search{}, fmt.Errorf("invalid search name %q, names may not contain commas or spaces", name)
+	}
+	return Search{Name: name, FormDataFile: path}, nil
+}
+
+// addSearch adds a search to the list, rejecting names used twice
+func addSearch(searches []Search, value string) ([]Search, error) {
+	search, err := parseSearch(value)
+	if err != nil {
+		return searches, err
+	}
+	if _, exists := findSearch(searches, search.Name); exists {
+		return searches, fmt.Errorf("search %s is defined twice", search.Name)
+	}
+	return append(searches, search), nil
+}
+
+// findSearch looks up a search by its name, ignoring case
+func findSearch(searches []Search, name string) (Search, bool) {
+	for _, search := range searches {
+		if strings.EqualFold(search.Name, name) {
+			return search, true
+		}
+	}
+	return Search{}, false
+}
+
+// botSearches returns the searches fetched in each update: the named searches, or
+// a single unnamed search built from the form data file and the search options
+func botSearches(config BotConfig) []Search {
+	if len(config.Searches) > 0 {
+		return config.Searches
+	}
+	return []Search{{FormDataFile: config.FormDataFile}}
+}
+
+// subscribedSearchConfig narrows the named searches of the config to the ones the
+// user subscribed to. Users without subscriptions, or only with subscriptions to
+// searches no longer configured, keep all searches.
+func subscribedSearchConfig(config BotConfig, user *state.UserState) BotConfig {
+	var subscribed []Search
+	for _, name := range user.Searches {
+		if search, ok := findSearch(config.Searches, name); ok {
+			subscribed = append(subscribed, search)
+		}
+	}
+	if len(subscribed) > 0 {
+		config.Searches = subscribed
+	}
+	return config
+}
+
+// searchFormData returns the form data of a search. The search options apply on
+// top of the files of named searches.
+func searchFormData(config BotConfig, search Search) (string, error) {
+	if search.Name == "" {
+		return loadFormData(config.FormDataFile, config.FormFileSet, config.FormOptions)
+	}
+	formData, err := loadFormData(search.FormDataFile, true, config.FormOptions)
+	if err != nil {
+		return "", fmt.Errorf("search %s: %w", search.Name, err)
+	}
+	return formData, nil
+}
+
+// mergeSearchResults merges the offers found by each search in order, listing an
+// offer found by several searches once and tagging it with the names of all of them
+func mergeSearchResults(searches []Search, results [][]state.RentalOffer) []state.RentalOffer {
+	var merged []state.RentalOffer
+	index := make(map[string]int)
+	for i, offers := range results {
+		name := searches[i].Name
+		for _, offer := range offers {
+			id := state.OfferID(offer.Link)
+			if pos, seen := index[id]; seen {
+				if name != "" {
+					merged[pos].Searches = append(merged[pos].Searches, name)
+				}
+				continue
+			}
+			offer.Searches = nil
+			if name != "" {
+				offer.Searches = []string{name}
+			}
+			index[id] = len(merged)
+			merged = append(merged, offer)
+		}
+	}
+	return merged
+}
+
+// subscribedOffers returns the offers found by the searches the user subscribed to.
+// Users without subscriptions get the offers of all searches, and offers not tagged
+// with a search reach everyone.
+func subscribedOffers(user *state.UserState, offers []state.RentalOffer) []state.RentalOffer {
+	if len(user.Searches) == 0 {
+		return offers
+	}
+	subscribed := make([]state.RentalOffer, 0, len(offers))
+	for _, offer := range offers {
+		if len(offer.Searches) == 0 || sharesSearch(user.Searches, offer.Searches) {
+			subscribed = append(subscribed, offer)
+		}
+	}
+	return subscribed
+}
+
+// sharesSearch reports whether the two lists have a search name in common
+func sharesSearch(a, b []string) bool {
+	for _, x := range a {
+		for _, y := range b {
+			if strings.EqualFold(x, y) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// handleSearchesCommand handles the /searches command, showing the bot's named
+// searches or choosing the ones the user gets new offers of
+func handleSearchesCommand(bot *tgbotapi.BotAPI, botState *state.BotState, message *tgbotapi.Message, config BotConfig) {
+	chatID := message.Chat.ID
+	lang := userLanguage(botState, chatID)
+	args := strings.TrimSpace(message.CommandArguments())
+
+	var text string
+	switch {
+	case len(config.Searches) == 0:
+		text = translate(lang, "searches_none")
+	case len(splitList(args)) == 0:
+		user, _ := botState.GetUser(chatID)
+		text = formatSearches(config.Searches, user, lang)
+	case strings.EqualFold(args, "all"):
+		botState.SetUserSearches(chatID, nil)
+		text = translate(lang, "searches_all")
+	default:
+		names, unknown := resolveSearches(config.Searches, splitList(args))
+		if unknown != "" {
+			text = translate(lang, "searches_unknown", unknown, strings.Join(searchNames(config.Searches), ", "))
+		} else {
+			botState.SetUserSearches(chatID, names)
+			text = translate(lang, "searches_set", strings.Join(names, ", "))
+		}
+	}
+
+	msg := tgbotapi.NewMessage(chatID, text)
+	msg.ReplyMarkup = createMainKeyboard(lang)
+	sendMessage(bot, msg)
+}
+
+// formatSearches lists the searches, marking the ones the user gets new offers of
+func formatSearches(searches []Search, user *state.UserState, lang string) string {
+	text := translate(lang, "searches_header")
+	for _, search := range searches {
+		mark := "▫️"
+		if user == nil || len(user.Searches) == 0 || sharesSearch(user.Searches, []string{search.Name}) {
+			mark = "✅"
+		}
+		text += fmt.Sprintf("%s %s\n", mark, search.Name)
+	}
+	return text + translate(lang, "searches_footer")
+}
+
+// resolveSearches returns the names of the searches the user asked for as the bot
+// spells them, or the first name that is not one of the searches
+func resolveSearches(searches []Search, requested []string) ([]string, string) {
+	var names []string
+	for _, name := range requested {
+		search, ok := findSearch(searches, name)
+		if !ok {
+			return nil, name
+		}
+		names = append(names, search.Name)
+	}
+	return names, ""
+}
+
+// searchNames returns the names of the searches
+func searchNames(searches []Search) []string {
+	names := make([]string, len(searches))
+	for i, search := range searches {
+		names[i] = search.Name
+	}
+	return names
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/aqaliarept/vuokraovi-bot/state"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+func TestAddSearchValidatesSearches(t *testing.T) {
+	searches, err := addSearch(nil, "kallio=forms/kallio.txt")
+	if err != nil || !reflect.DeepEqual(searches, []Search{{Name: "kallio", FormDataFile: "forms/kallio.txt"}}) {
+		t.Fatalf("addSearch = %v, %v", searches, err)
+	}
+	for _, value := range []string{"kallio", "=forms/a.txt", "espoo=", "two words=a.txt", "Kallio=forms/other.txt"} {
+		if _, err := addSearch(searches, value); err == nil {
+			t.Errorf("%q was accepted", value)
+		}
+	}
+}
+
+func TestMergeSearchResultsTagsOffers(t *testing.T) {
+	searches := []Search{{Name: "kallio"}, {Name: "cheap"}}
+	shared := testOffer("https://example.com/vuokra-asunto/helsinki/kallio/kerrostalo/2", "700 €/kk")
+	results := [][]state.RentalOffer{
+		{testOffer("https://example.com/vuokra-asunto/helsinki/kallio/kerrostalo/1", "1200 €/kk"), shared},
+		{shared, testOffer("https://example.com/vuokra-asunto/espoo/tapiola/kerrostalo/3", "650 €/kk")},
+	}
+
+	merged := mergeSearchResults(searches, results)
+
+	want := map[string][]string{
+		"1": {"kallio"},
+		"2": {"kallio", "cheap"},
+		"3": {"cheap"},
+	}
+	if len(merged) != len(want) {
+		t.Fatalf("merged %d offers, want %d", len(merged), len(want))
+	}
+	for _, offer := range merged {
+		if got := offer.Searches; !reflect.DeepEqual(got, want[state.OfferID(offer.Link)]) {
+			t.Errorf("offer %s found by %v, want %v", offer.Link, got, want[state.OfferID(offer.Link)])
+		}
+	}
+
+	single := mergeSearchResults([]Search{{}}, results[:1])
+	if len(single) != 2 || single[0].Searches != nil {
+		t.Errorf("unnamed search = %+v, want untagged offers", single)
+	}
+}
+
+func TestNotifyUsersRoutesOffersBySubscription(t *testing.T) {
+	bot, fake := newFakeTelegram(t)
+	botState := newTestBotState(t)
+	botState.AddUser(&tgbotapi.User{FirstName: "Kallio"}, 1)
+	botState.AddUser(&tgbotapi.User{FirstName: "Espoo"}, 2)
+	botState.AddUser(&tgbotapi.User{FirstName: "Everything"}, 3)
+	botState.SetUserSearches(1, []string{"kallio"})
+	botState.SetUserSearches(2, []string{"espoo"})
+
+	kallio := testOffer("https://example.com/vuokra-asunto/helsinki/kallio/kerrostalo/1", "900 €/kk")
+	kallio.Searches = []string{"kallio"}
+	espoo := testOffer("https://example.com/vuokra-asunto/espoo/tapiola/kerrostalo/2", "800 €/kk")
+	espoo.Searches = []string{"espoo"}
+	offers := []state.RentalOffer{kallio, espoo}
+	botState.ApplyOffers(offers)
+
+	notifyUsers(bot, botState, offers)
+
+	got := map[string][]string{}
+	for _, msg := range fake.calls("sendMessage") {
+		text := msg.Get("text")
+		for _, offer := range offers {
+			if strings.Contains(text, offer.Link) {
+				got[msg.Get("chat_id")] = append(got[msg.Get("chat_id")], state.OfferID(offer.Link))
+			}
+		}
+	}
+	want := map[string][]string{"1": {"1"}, "2": {"2"}, "3": {"1", "2"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("notified offers by chat = %v, want %v", got, want)
+	}
+}
+
+func TestSearchesCommandSetsSubscriptions(t *testing.T) {
+	bot, fake := newFakeTelegram(t)
+	botState := newTestBotState(t)
+	botState.AddUser(&tgbotapi.User{FirstName: "Test"}, 1)
+	config := BotConfig{Searches: []Search{{Name: "kallio"}, {Name: "espoo"}}}
+
+	tests := []struct {
+		text     string
+		reply    string
+		searches []string
+	}{
+		{"/searches Kallio", translate("en", "searches_set", "kallio"), []string{"kallio"}},
+		{"/searches kallio,vantaa", translate("en", "searches_unknown", "vantaa", "kallio, espoo"), []string{"kallio"}},
+		{"/searches all", translate("en", "searches_all"), nil},
+	}
+	for _, tt := range tests {
+		fake.reset()
+		handleMessage(bot, botState, userMessage(1, tt.text), config)
+		if sent := fake.calls("sendMessage"); len(sent) != 1 || sent[0].Get("text") != tt.reply {
+			t.Errorf("%s: sent %v, want %q", tt.text, sent, tt.reply)
+		}
+		if user, _ := botState.GetUser(1); !reflect.DeepEqual(user.Searches, tt.searches) {
+			t.Errorf("%s: searches = %v, want %v", tt.text, user.Searches, tt.searches)
+		}
+	}
+
+	fake.reset()
+	handleMessage(bot, botState, userMessage(1, "/searches"), BotConfig{})
+	if sent := fake.calls("sendMessage"); len(sent) != 1 || sent[0].Get("text") != translate("en", "searches_none") {
+		t.Errorf("sent %v, want the single search note", sent)
+	}
+}
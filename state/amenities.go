@@ -0,0 +1,42 @@
+package state
+
+import (
+	"slices"
+
+	"github.com/aqaliarept/vuokraovi-bot/scraper"
+)
+
+// AmenityKeywords maps each amenity to the lowercase words and abbreviations the
+// room descriptions use for it, see scraper.AmenityKeywords
+var AmenityKeywords = scraper.AmenityKeywords
+
+// AmenityName returns the amenity a word refers to, accepting the amenity names
+// as well as their keywords in any case
+func AmenityName(word string) (string, bool) {
+	return scraper.AmenityName(word)
+}
+
+// DetectAmenities returns the amenities whose keywords appear in a room description,
+// see scraper.DetectAmenities
+func DetectAmenities(rooms string, keywords map[string][]string) []string {
+	return scraper.DetectAmenities(rooms, keywords)
+}
+
+// offerAmenities returns the amenities of an offer, detecting them from the room
+// description for offers stored before amenities were parsed
+func offerAmenities(offer RentalOffer) []string {
+	if offer.Amenities != nil {
+		return offer.Amenities
+	}
+	return DetectAmenities(offer.Rooms, AmenityKeywords)
+}
+
+// HasAmenities reports whether the amenities an offer has include all the required ones
+func HasAmenities(have, required []string) bool {
+	for _, amenity := range required {
+		if !slices.Contains(have, amenity) {
+			return false
+		}
+	}
+	return true
+}
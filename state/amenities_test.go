@@ -0,0 +1,25 @@
+package state
+
+import "testing"
+
+func TestFiltersMatchAmenities(t *testing.T) {
+	parsed := RentalOffer{Rooms: "3h+k+s", Amenities: []string{"sauna", "parveke"}}
+	legacy := RentalOffer{Rooms: "3h+k+s+parveke"}
+
+	tests := []struct {
+		amenities []string
+		offer     RentalOffer
+		want      bool
+	}{
+		{[]string{"sauna"}, parsed, true},
+		{[]string{"sauna", "parveke"}, parsed, true},
+		{[]string{"sauna", "kt"}, parsed, false},
+		{[]string{"parveke"}, legacy, true},
+		{[]string{"terassi"}, legacy, false},
+	}
+	for _, tt := range tests {
+		if got := (Filters{Amenities: tt.amenities}).Matches(tt.offer); got != tt.want {
+			t.Errorf("amenities %v on %q: Matches = %v, want %v", tt.amenities, tt.offer.Rooms, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,9 @@
+package state
+
+// Conversation is a multi-step command waiting for a free text reply of the user:
+// the name of the command, the step it is at and the filters collected so far
+type Conversation struct {
+	Name    string  `json:"name"`
+	Step    string  `json:"step"`
+	Filters Filters `json:"filters"`
+}
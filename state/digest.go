@@ -0,0 +1,65 @@
+package state
+
+import (
+	"fmt"
+	"time"
+)
+
+// Digest modes
+const (
+	DigestOff    = ""
+	DigestHourly = "hourly"
+	DigestDaily  = "daily"
+)
+
+// DigestSchedule configures a user to receive new offers as one summary message
+// on a schedule instead of after every update
+type DigestSchedule struct {
+	Mode     string    `json:"mode,omitempty"`
+	Hour     int       `json:"hour,omitempty"`   // hour of the daily digest
+	Minute   int       `json:"minute,omitempty"` // minute of the daily digest
+	Timezone string    `json:"timezone,omitempty"`
+	LastSent time.Time `json:"last_sent,omitempty"`
+}
+
+// NewDailyDigest validates and creates a digest sent every day at hour:minute in timezone
+func NewDailyDigest(hour, minute int, timezone string, now time.Time) (DigestSchedule, error) {
+	if hour < 0 || hour > 23 || minute < 0 || minute > 59 {
+		return DigestSchedule{}, fmt.Errorf("invalid time %02d:%02d", hour, minute)
+	}
+	if _, err := time.LoadLocation(timezone); err != nil {
+		return DigestSchedule{}, fmt.Errorf("unknown timezone %q", timezone)
+	}
+	return DigestSchedule{Mode: DigestDaily, Hour: hour, Minute: minute, Timezone: timezone, LastSent: now}, nil
+}
+
+// NewHourlyDigest creates a digest sent every hour
+func NewHourlyDigest(now time.Time) DigestSchedule {
+	return DigestSchedule{Mode: DigestHourly, LastSent: now}
+}
+
+// Enabled reports whether digest mode is on
+func (d DigestSchedule) Enabled() bool {
+	return d.Mode != DigestOff
+}
+
+// Due reports whether a digest should be sent at now
+func (d DigestSchedule) Due(now time.Time) bool {
+	switch d.Mode {
+	case DigestHourly:
+		return now.Sub(d.LastSent) >= time.Hour
+	case DigestDaily:
+		loc, err := time.LoadLocation(d.Timezone)
+		if err != nil {
+			loc = time.Local
+		}
+		local := now.In(loc)
+		scheduled := time.Date(local.Year(), local.Month(), local.Day(), d.Hour, d.Minute, 0, 0, loc)
+		if scheduled.After(local) {
+			scheduled = scheduled.AddDate(0, 0, -1)
+		}
+		return d.LastSent.Before(scheduled)
+	default:
+		return false
+	}
+}
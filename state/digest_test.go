@@ -0,0 +1,68 @@
+package state
+
+import (
+	"testing"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+func TestDigestDue(t *testing.T) {
+	start := time.Date(2024, 5, 10, 7, 30, 0, 0, time.UTC)
+	hourly := NewHourlyDigest(start)
+	// 09:00 in Helsinki is 06:00 UTC in summer
+	daily, err := NewDailyDigest(9, 0, "Europe/Helsinki", start)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		digest DigestSchedule
+		now    time.Time
+		want   bool
+	}{
+		{"hourly within the hour", hourly, start.Add(59 * time.Minute), false},
+		{"hourly after an hour", hourly, start.Add(time.Hour), true},
+		{"daily before the next scheduled time", daily, time.Date(2024, 5, 11, 5, 59, 0, 0, time.UTC), false},
+		{"daily at the scheduled time", daily, time.Date(2024, 5, 11, 6, 0, 0, 0, time.UTC), true},
+		{"daily a day late", daily, time.Date(2024, 5, 12, 12, 0, 0, 0, time.UTC), true},
+		{"off", DigestSchedule{}, start.Add(24 * time.Hour), false},
+	}
+
+	for _, tt := range tests {
+		if got := tt.digest.Due(tt.now); got != tt.want {
+			t.Errorf("%s: Due = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	sent := daily
+	sent.LastSent = time.Date(2024, 5, 11, 6, 0, 0, 0, time.UTC)
+	if sent.Due(time.Date(2024, 5, 11, 20, 0, 0, 0, time.UTC)) {
+		t.Error("the daily digest is due again on the day it was sent")
+	}
+}
+
+func TestQueueOffersAccumulatesUntilTaken(t *testing.T) {
+	bs := newTestState(t)
+	bs.AddUser(&tgbotapi.User{FirstName: "Test"}, 1)
+	a := testOffer("https://example.com/a", "900 €/kk")
+	b := testOffer("https://example.com/b", "1000 €/kk")
+	bs.ApplyOffers([]RentalOffer{a, b})
+
+	bs.QueueOffers(1, []RentalOffer{a})
+	bs.QueueOffers(1, []RentalOffer{a, b})
+	// Offers delisted meanwhile are dropped, the others are returned as currently known
+	b.Price = "950 €/kk"
+	bs.ApplyOffers([]RentalOffer{b})
+	bs.SetDelistAfter(1)
+	bs.ApplyOffers([]RentalOffer{b})
+
+	pending := bs.TakePendingOffers(1)
+	if len(pending) != 1 || pending[0].Link != b.Link || pending[0].Price != "950 €/kk" {
+		t.Fatalf("pending = %+v, want offer b once with its current price", pending)
+	}
+	if pending := bs.TakePendingOffers(1); len(pending) != 0 {
+		t.Errorf("offers are still pending after being taken: %v", links(pending))
+	}
+}
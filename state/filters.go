@@ -0,0 +1,103 @@
+package state
+
+import "strings"
+
+// Filters holds the search criteria a user applies to new offers.
+// Zero values mean the criterion is not set.
+type Filters struct {
+	City     string  `json:"city,omitempty"`
+	MinPrice float64 `json:"min_price,omitempty"`
+	MaxPrice float64 `json:"max_price,omitempty"`
+	MinRooms int     `json:"min_rooms,omitempty"`
+	MinSize  float64 `json:"min_size,omitempty"`
+
+	// MaxPricePerSqm is the highest rent per square meter, see RentalOffer.PricePerSqm
+	MaxPricePerSqm float64 `json:"max_price_per_sqm,omitempty"`
+
+	// Cities and districts offers must be in, or must not be in, see OfferPlace
+	IncludeCities    []string `json:"include_cities,omitempty"`
+	ExcludeCities    []string `json:"exclude_cities,omitempty"`
+	IncludeDistricts []string `json:"include_districts,omitempty"`
+	ExcludeDistricts []string `json:"exclude_districts,omitempty"`
+
+	// Amenities the offers must all have, see AmenityKeywords
+	Amenities []string `json:"amenities,omitempty"`
+
+	// NearLat and NearLng are the point offers must be within RadiusKm of.
+	// Offers without coordinates only match when KeepUnlocated is set.
+	NearLat       float64 `json:"near_lat,omitempty"`
+	NearLng       float64 `json:"near_lng,omitempty"`
+	RadiusKm      float64 `json:"radius_km,omitempty"`
+	KeepUnlocated bool    `json:"keep_unlocated,omitempty"`
+}
+
+// IsEmpty reports whether no criterion is set
+func (f Filters) IsEmpty() bool {
+	return f.City == "" && f.MinPrice == 0 && f.MaxPrice == 0 && f.MinRooms == 0 && f.MinSize == 0 &&
+		f.MaxPricePerSqm == 0 && !f.hasPlaces() && len(f.Amenities) == 0 && f.RadiusKm == 0
+}
+
+// hasPlaces reports whether any city or district list is set
+func (f Filters) hasPlaces() bool {
+	return len(f.IncludeCities) > 0 || len(f.ExcludeCities) > 0 || len(f.IncludeDistricts) > 0 || len(f.ExcludeDistricts) > 0
+}
+
+// Matches reports whether the offer satisfies all criteria.
+// Offers missing a value needed by a criterion do not match it.
+func (f Filters) Matches(offer RentalOffer) bool {
+	if f.City != "" && !strings.Contains(strings.ToLower(offer.Address), strings.ToLower(f.City)) {
+		return false
+	}
+	if (f.MinPrice > 0 || f.MaxPrice > 0) && offer.PriceEUR <= 0 {
+		return false
+	}
+	if f.MinPrice > 0 && offer.PriceEUR < f.MinPrice {
+		return false
+	}
+	if f.MaxPrice > 0 && offer.PriceEUR > f.MaxPrice {
+		return false
+	}
+	if f.MinRooms > 0 && offer.RoomCount < f.MinRooms {
+		return false
+	}
+	if f.MinSize > 0 && offer.SizeSqm < f.MinSize {
+		return false
+	}
+	if f.MaxPricePerSqm > 0 {
+		if perSqm := offer.PricePerSqm(); perSqm == 0 || perSqm > f.MaxPricePerSqm {
+			return false
+		}
+	}
+	if f.hasPlaces() {
+		city, district := OfferPlace(offer)
+		if !placeAllowed(city, f.IncludeCities, f.ExcludeCities) || !placeAllowed(district, f.IncludeDistricts, f.ExcludeDistricts) {
+			return false
+		}
+	}
+	if len(f.Amenities) > 0 && !HasAmenities(offerAmenities(offer), f.Amenities) {
+		return false
+	}
+	if f.RadiusKm > 0 {
+		if !offer.HasLocation() {
+			return f.KeepUnlocated
+		}
+		if DistanceKm(f.NearLat, f.NearLng, offer.Lat, offer.Lng) > f.RadiusKm {
+			return false
+		}
+	}
+	return true
+}
+
+// FilterOffers returns the offers matching the filters, preserving their order
+func FilterOffers(offers []RentalOffer, filters Filters) []RentalOffer {
+	if filters.IsEmpty() {
+		return offers
+	}
+	matching := make([]RentalOffer, 0, len(offers))
+	for _, offer := range offers {
+		if filters.Matches(offer) {
+			matching = append(matching, offer)
+		}
+	}
+	return matching
+}
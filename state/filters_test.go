@@ -0,0 +1,74 @@
+package state
+
+import "testing"
+
+func TestFiltersMatchCombinations(t *testing.T) {
+	offer := RentalOffer{Address: "Kalliokatu 1, Helsinki", PriceEUR: 850, RoomCount: 2, SizeSqm: 45}
+	unparsed := RentalOffer{Address: "Kalliokatu 1, Helsinki"}
+
+	tests := []struct {
+		name    string
+		filters Filters
+		offer   RentalOffer
+		want    bool
+	}{
+		{"no filters", Filters{}, offer, true},
+		{"city case insensitive", Filters{City: "helsinki"}, offer, true},
+		{"other city", Filters{City: "Tampere"}, offer, false},
+		{"price within range", Filters{MinPrice: 800, MaxPrice: 900}, offer, true},
+		{"price above maximum", Filters{MaxPrice: 800}, offer, false},
+		{"price below minimum", Filters{MinPrice: 900}, offer, false},
+		{"price on the boundary", Filters{MinPrice: 850, MaxPrice: 850}, offer, true},
+		{"enough rooms and size", Filters{MinRooms: 2, MinSize: 45}, offer, true},
+		{"too few rooms", Filters{MinRooms: 3}, offer, false},
+		{"too small", Filters{MinSize: 50}, offer, false},
+		{"all criteria", Filters{City: "Helsinki", MaxPrice: 900, MinRooms: 2, MinSize: 40}, offer, true},
+		{"all but one criterion", Filters{City: "Helsinki", MaxPrice: 900, MinRooms: 2, MinSize: 60}, offer, false},
+		{"missing price", Filters{MaxPrice: 900}, unparsed, false},
+		{"missing rooms", Filters{MinRooms: 1}, unparsed, false},
+		{"missing values without numeric filters", Filters{City: "Helsinki"}, unparsed, true},
+		{"price per m² below maximum", Filters{MaxPricePerSqm: 20}, offer, true},
+		{"price per m² on the boundary", Filters{MaxPricePerSqm: 850.0 / 45}, offer, true},
+		{"price per m² above maximum", Filters{MaxPricePerSqm: 18}, offer, false},
+		{"missing price per m²", Filters{MaxPricePerSqm: 30}, unparsed, false},
+	}
+
+	for _, tt := range tests {
+		if got := tt.filters.Matches(tt.offer); got != tt.want {
+			t.Errorf("%s: Matches = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestPricePerSqm(t *testing.T) {
+	tests := []struct {
+		name  string
+		offer RentalOffer
+		want  float64
+	}{
+		{"price and size", RentalOffer{PriceEUR: 900, SizeSqm: 45}, 20},
+		{"size zero", RentalOffer{PriceEUR: 900}, 0},
+		{"price unknown", RentalOffer{SizeSqm: 45}, 0},
+	}
+
+	for _, tt := range tests {
+		if got := tt.offer.PricePerSqm(); got != tt.want {
+			t.Errorf("%s: PricePerSqm = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestFilterOffersKeepsOrder(t *testing.T) {
+	offers := []RentalOffer{
+		{Link: "a", PriceEUR: 700},
+		{Link: "b", PriceEUR: 1200},
+		{Link: "c", PriceEUR: 800},
+	}
+
+	if got := links(FilterOffers(offers, Filters{MaxPrice: 1000})); len(got) != 2 || got[0] != "a" || got[1] != "c" {
+		t.Errorf("FilterOffers = %v, want [a c]", got)
+	}
+	if got := FilterOffers(offers, Filters{}); len(got) != 3 {
+		t.Errorf("empty filters kept %d offers, want all 3", len(got))
+	}
+}
@@ -0,0 +1,85 @@
+package state
+
+import (
+	"fmt"
+	"time"
+)
+
+// SetSaveInterval makes the state collect its changes and save them with Flush, at
+// most once per interval when AutoFlush runs, instead of writing the store on
+// every change. 0 saves every change right away, which is the default.
+func (bs *BotState) SetSaveInterval(interval time.Duration) {
+	bs.mutex.Lock()
+	defer bs.mutex.Unlock()
+
+	if interval < 0 {
+		interval = 0
+	}
+	bs.saveInterval = interval
+}
+
+// deferSave marks the state as changed and reports whether saving is left to
+// Flush; the caller must hold the mutex
+func (bs *BotState) deferSave() bool {
+	if bs.saveInterval <= 0 {
+		return false
+	}
+	bs.dirty = true
+	return true
+}
+
+// deleteUser removes a user from the store, or leaves it to the next Flush, which
+// replaces the whole stored state; the caller must hold the mutex
+func (bs *BotState) deleteUser(chatID int64) error {
+	if bs.deferSave() {
+		return nil
+	}
+	if err := bs.store.DeleteUser(chatID); err != nil {
+		return fmt.Errorf("failed to delete user %d: %w", chatID, err)
+	}
+	return nil
+}
+
+// Flush writes the changes collected since the last save, if there are any
+func (bs *BotState) Flush() error {
+	bs.mutex.Lock()
+	defer bs.mutex.Unlock()
+
+	if !bs.dirty {
+		return nil
+	}
+	if err := bs.writeState(); err != nil {
+		return err
+	}
+	bs.dirty = false
+	return nil
+}
+
+// AutoFlush flushes the state every save interval until stop is closed, and once
+// more when it is. Errors are passed to onError. It returns right away when the
+// state saves every change.
+func (bs *BotState) AutoFlush(stop <-chan struct{}, onError func(error)) {
+	bs.mutex.Lock()
+	interval := bs.saveInterval
+	bs.mutex.Unlock()
+	if interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			if err := bs.Flush(); err != nil && onError != nil {
+				onError(err)
+			}
+			return
+		case <-ticker.C:
+			if err := bs.Flush(); err != nil && onError != nil {
+				onError(err)
+			}
+		}
+	}
+}
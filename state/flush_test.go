@@ -0,0 +1,168 @@
+package state
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// countingStore counts the writes reaching the store it wraps
+type countingStore struct {
+	Store
+	mutex  sync.Mutex
+	writes int
+}
+
+func (s *countingStore) count() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.writes++
+}
+
+func (s *countingStore) Writes() int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.writes
+}
+
+func (s *countingStore) Save(snapshot *Snapshot) error {
+	s.count()
+	return s.Store.Save(snapshot)
+}
+
+func (s *countingStore) SaveUser(user *UserState) error {
+	s.count()
+	return s.Store.SaveUser(user)
+}
+
+func (s *countingStore) DeleteUser(chatID int64) error {
+	s.count()
+	return s.Store.DeleteUser(chatID)
+}
+
+func (s *countingStore) SaveOffers(known, delisted map[string]RentalOffer, lastUpdated time.Time) error {
+	s.count()
+	return s.Store.SaveOffers(known, delisted, lastUpdated)
+}
+
+// mutateRapidly adds users and marks offers as seen as a notification cycle would
+func mutateRapidly(bs *BotState) {
+	bs.ApplyOffers([]RentalOffer{testOffer("https://example.com/a", "900 €/kk")})
+	for i := int64(1); i <= 50; i++ {
+		bs.AddUser(&tgbotapi.User{ID: i, FirstName: fmt.Sprint("User ", i)}, i)
+		bs.MarkOfferAsSeen(i, "https://example.com/a")
+		bs.UpdateUserLastNotified(i, time.Now())
+	}
+}
+
+func TestSaveIntervalBatchesWrites(t *testing.T) {
+	dir := t.TempDir()
+	store := &countingStore{Store: NewJSONStore(dir)}
+	bs, err := NewBotStateWithStore(store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bs.SetSaveInterval(time.Hour)
+
+	mutateRapidly(bs)
+	if writes := store.Writes(); writes != 0 {
+		t.Errorf("%d writes before the flush, want none", writes)
+	}
+	if err := bs.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if err := bs.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if writes := store.Writes(); writes != 1 {
+		t.Errorf("%d writes after flushing twice, want 1", writes)
+	}
+
+	// The flushed file holds every change
+	reloaded, err := NewBotStateWithStore(NewJSONStore(dir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if users := reloaded.GetAllUsers(); len(users) != 50 || !users[50].SeenOffers.Has("https://example.com/a") {
+		t.Errorf("reloaded %d users, want all 50 with the seen offer", len(users))
+	}
+}
+
+func TestSynchronousSavesWriteEveryChange(t *testing.T) {
+	store := &countingStore{Store: NewJSONStore(t.TempDir())}
+	bs, err := NewBotStateWithStore(store)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mutateRapidly(bs)
+	if writes := store.Writes(); writes < 150 {
+		t.Errorf("%d writes, want one per change", writes)
+	}
+	if err := bs.Flush(); err != nil || store.Writes() < 150 {
+		t.Errorf("Flush() = %v", err)
+	}
+}
+
+func TestFlushRemovesDeletedUsers(t *testing.T) {
+	dir := t.TempDir()
+	bs, err := NewBotState(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bs.AddUser(&tgbotapi.User{FirstName: "Gone"}, 1)
+	bs.SetSaveInterval(time.Hour)
+	bs.MarkUserUnreachable(1)
+	if removed, err := bs.RemoveUnreachableUsers(); err != nil || removed != 1 {
+		t.Fatalf("RemoveUnreachableUsers() = %d, %v", removed, err)
+	}
+	// Close flushes the pending changes
+	if err := bs.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	reloaded, err := NewBotState(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reloaded.Close()
+	if _, exists := reloaded.GetUser(1); exists {
+		t.Error("the removed user is still stored")
+	}
+}
+
+func TestAutoFlushSavesPeriodically(t *testing.T) {
+	store := &countingStore{Store: NewJSONStore(t.TempDir())}
+	bs, err := NewBotStateWithStore(store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bs.SetSaveInterval(10 * time.Millisecond)
+
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		bs.AutoFlush(stop, func(err error) { t.Error(err) })
+		close(done)
+	}()
+
+	mutateRapidly(bs)
+	deadline := time.Now().Add(5 * time.Second)
+	for store.Writes() == 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if store.Writes() == 0 {
+		t.Error("AutoFlush did not save the changes")
+	}
+
+	writes := store.Writes()
+	bs.AddUser(&tgbotapi.User{FirstName: "Late"}, 99)
+	close(stop)
+	<-done
+	if store.Writes() <= writes {
+		t.Error("AutoFlush did not save the last changes when stopped")
+	}
+}
@@ -0,0 +1,22 @@
+package state
+
+import "math"
+
+// earthRadiusKm is the mean radius of the Earth
+const earthRadiusKm = 6371.0
+
+// DistanceKm returns the great-circle distance between two points given in
+// degrees, using the haversine formula
+func DistanceKm(lat1, lng1, lat2, lng2 float64) float64 {
+	toRadians := func(degrees float64) float64 { return degrees * math.Pi / 180 }
+	dLat := toRadians(lat2 - lat1)
+	dLng := toRadians(lng2 - lng1)
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(toRadians(lat1))*math.Cos(toRadians(lat2))*math.Sin(dLng/2)*math.Sin(dLng/2)
+	return 2 * earthRadiusKm * math.Asin(math.Min(1, math.Sqrt(a)))
+}
+
+// HasLocation reports whether the offer carries map coordinates
+func (o RentalOffer) HasLocation() bool {
+	return o.Lat != 0 || o.Lng != 0
+}
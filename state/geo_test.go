@@ -0,0 +1,61 @@
+package state
+
+import (
+	"math"
+	"testing"
+)
+
+func TestDistanceKm(t *testing.T) {
+	tests := []struct {
+		name                   string
+		lat1, lng1, lat2, lng2 float64
+		want                   float64
+	}{
+		{"same point", 60.17, 24.94, 60.17, 24.94, 0},
+		{"one degree along the equator", 0, 0, 0, 1, 111.19},
+		{"Helsinki to Tampere", 60.1699, 24.9384, 61.4978, 23.7610, 160.84},
+		{"antipodes", 0, 0, 0, 180, math.Pi * earthRadiusKm},
+	}
+	for _, tt := range tests {
+		got := DistanceKm(tt.lat1, tt.lng1, tt.lat2, tt.lng2)
+		if math.Abs(got-tt.want) > 0.01 {
+			t.Errorf("%s: DistanceKm = %.3f, want %.2f", tt.name, got, tt.want)
+		}
+		if back := DistanceKm(tt.lat2, tt.lng2, tt.lat1, tt.lng1); math.Abs(back-got) > 1e-9 {
+			t.Errorf("%s: distance is not symmetric: %v and %v", tt.name, got, back)
+		}
+	}
+}
+
+func TestFiltersMatchDistance(t *testing.T) {
+	center := Filters{NearLat: 60.17, NearLng: 24.94}
+	// About 3.34 km north of the center
+	nearby := RentalOffer{Lat: 60.20, Lng: 24.94}
+	distance := DistanceKm(60.17, 24.94, 60.20, 24.94)
+	unlocated := RentalOffer{Address: "Testikatu 1, Helsinki"}
+
+	within := func(radius float64, keep bool) Filters {
+		f := center
+		f.RadiusKm, f.KeepUnlocated = radius, keep
+		return f
+	}
+
+	tests := []struct {
+		name    string
+		filters Filters
+		offer   RentalOffer
+		want    bool
+	}{
+		{"well inside", within(5, false), nearby, true},
+		{"on the boundary", within(distance, false), nearby, true},
+		{"just outside", within(distance-0.001, false), nearby, false},
+		{"without coordinates", within(5, false), unlocated, false},
+		{"without coordinates kept", within(5, true), unlocated, true},
+		{"no radius ignores the point", center, unlocated, true},
+	}
+	for _, tt := range tests {
+		if got := tt.filters.Matches(tt.offer); got != tt.want {
+			t.Errorf("%s: Matches = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package state
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// ErrLocked is returned when another process holds the lock of a data directory
+var ErrLocked = errors.New("the data directory is used by another process")
+
+// lockFileName is the file locked in the data directory
+const lockFileName = "bot_state.lock"
+
+// DirLock is an advisory lock on a data directory, keeping two bot processes from
+// writing the same state
+type DirLock struct {
+	file *os.File
+}
+
+// LockDir locks the data directory, creating it if needed. It fails with ErrLocked
+// instead of waiting when another process holds the lock.
+func LockDir(dir string) (*DirLock, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create data directory: %w", err)
+	}
+	path := filepath.Join(dir, lockFileName)
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open lock file: %w", err)
+	}
+	if err := lockFile(file); err != nil {
+		file.Close()
+		if errors.Is(err, ErrLocked) {
+			return nil, fmt.Errorf("%s: %w, stop it or use another data directory", dir, ErrLocked)
+		}
+		return nil, fmt.Errorf("failed to lock %s: %w", path, err)
+	}
+	return &DirLock{file: file}, nil
+}
+
+// Unlock releases the lock; it is safe to call more than once and on nil
+func (l *DirLock) Unlock() error {
+	if l == nil || l.file == nil {
+		return nil
+	}
+	file := l.file
+	l.file = nil
+	if err := unlockFile(file); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to unlock data directory: %w", err)
+	}
+	return file.Close()
+}
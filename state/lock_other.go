@@ -0,0 +1,15 @@
+//go:build !unix
+
+package state
+
+import "os"
+
+// lockFile does nothing where flock is not available
+func lockFile(file *os.File) error {
+	return nil
+}
+
+// unlockFile does nothing where flock is not available
+func unlockFile(file *os.File) error {
+	return nil
+}
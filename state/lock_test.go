@@ -0,0 +1,36 @@
+//go:build unix
+
+package state
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestSecondBotStateOnTheSameDirFails(t *testing.T) {
+	dir := t.TempDir()
+	first, err := NewBotState(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := NewBotState(dir); !errors.Is(err, ErrLocked) {
+		t.Fatalf("second NewBotState error = %v, want ErrLocked", err)
+	}
+	if _, err := LockDir(dir); !errors.Is(err, ErrLocked) {
+		t.Errorf("LockDir error = %v, want ErrLocked", err)
+	}
+	// Observers do not write the state and need no lock
+	if _, err := NewReadOnlyBotState(dir); err != nil {
+		t.Errorf("NewReadOnlyBotState: %v", err)
+	}
+
+	if err := first.Close(); err != nil {
+		t.Fatal(err)
+	}
+	second, err := NewBotState(dir)
+	if err != nil {
+		t.Fatalf("NewBotState after Close: %v", err)
+	}
+	second.Close()
+}
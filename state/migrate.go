@@ -0,0 +1,38 @@
+package state
+
+import "fmt"
+
+// CurrentStateVersion is the version of the persisted state written by this build
+const CurrentStateVersion = 2
+
+// migrations upgrade a snapshot from the version used as key to the next one
+var migrations = map[int]func(*Snapshot) error{
+	// Version 0 is the original unversioned format, which has the same shape as version 1
+	0: func(s *Snapshot) error { return nil },
+	// Version 1 stored seen offers as true flags. SeenTimes decodes them as seen at
+	// the zero time, so they only need to be saved again as times.
+	1: func(s *Snapshot) error { return nil },
+}
+
+// migrateSnapshot upgrades a loaded snapshot to CurrentStateVersion.
+// It reports whether any migration was applied.
+func migrateSnapshot(s *Snapshot) (bool, error) {
+	if s.Version > CurrentStateVersion {
+		return false, fmt.Errorf("state version %d is newer than supported version %d", s.Version, CurrentStateVersion)
+	}
+
+	migrated := false
+	for s.Version < CurrentStateVersion {
+		migrate, ok := migrations[s.Version]
+		if !ok {
+			return migrated, fmt.Errorf("no migration from state version %d", s.Version)
+		}
+		if err := migrate(s); err != nil {
+			return migrated, fmt.Errorf("failed to migrate state from version %d: %w", s.Version, err)
+		}
+		s.Version++
+		migrated = true
+	}
+
+	return migrated, nil
+}
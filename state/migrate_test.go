@@ -0,0 +1,141 @@
+package state
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// unversionedState is a bot_state.json written before the state was versioned
+const unversionedState = `{
+  "users": {
+    "42": {"chat_id": 42, "first_name": "Old", "notifications": true, "seen_offers": {"https://example.com/1": true}}
+  },
+  "known_offers": {
+    "https://example.com/1": {"title": "Testikatu 1", "price": "900 €/kk", "link": "https://example.com/1"}
+  },
+  "last_updated": "2024-05-10T12:00:00Z"
+}`
+
+func TestLoadStateMigratesUnversionedFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "bot_state.json")
+	if err := os.WriteFile(path, []byte(unversionedState), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	bs, err := NewBotState(dir)
+	if err != nil {
+		t.Fatalf("NewBotState: %v", err)
+	}
+	if user, exists := bs.GetUser(42); !exists || !user.SeenOffers.Has("https://example.com/1") {
+		t.Errorf("user 42 = %+v, want the old user with its seen offer", user)
+	}
+	if len(bs.GetKnownOffers()) != 1 {
+		t.Errorf("known offers = %v, want the old offer", bs.GetKnownOffers())
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var saved Snapshot
+	if err := json.Unmarshal(data, &saved); err != nil {
+		t.Fatalf("the re-saved file is invalid: %v", err)
+	}
+	if saved.Version != CurrentStateVersion {
+		t.Errorf("saved version = %d, want %d", saved.Version, CurrentStateVersion)
+	}
+	if len(saved.Users) != 1 || len(saved.KnownOffers) != 1 {
+		t.Errorf("the re-saved file lost data: %s", data)
+	}
+}
+
+func TestUnchangedOldOffersGetNewFields(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "bot_state.json"), []byte(unversionedState), 0644); err != nil {
+		t.Fatal(err)
+	}
+	bs, err := NewBotState(dir)
+	if err != nil {
+		t.Fatalf("NewBotState: %v", err)
+	}
+	defer bs.Close()
+
+	// The old listing details parsed again, now with the fields added since
+	parsed := RentalOffer{
+		Title:          "Testikatu 1",
+		Price:          "900 €/kk",
+		Link:           "https://example.com/1",
+		PriceIsMonthly: true,
+		BuildingType:   "kerrostalo",
+		Amenities:      []string{"sauna"},
+		Lat:            60.17,
+		Lng:            24.94,
+	}
+	result := bs.ApplyOffers([]RentalOffer{parsed})
+	if len(result.Unchanged) != 1 || len(result.Changed) != 0 {
+		t.Fatalf("result = %+v, want the offer unchanged", result)
+	}
+
+	known := bs.GetKnownOffers()["https://example.com/1"]
+	if !known.HasLocation() || known.BuildingType != "kerrostalo" || !known.PriceIsMonthly || len(known.Amenities) != 1 {
+		t.Errorf("known offer = %+v, want the new fields filled in", known)
+	}
+	if !(Filters{NearLat: 60.17, NearLng: 24.94, RadiusKm: 1}).Matches(known) {
+		t.Error("the distance filter drops the upgraded offer")
+	}
+}
+
+func TestLoadStateRejectsNewerVersion(t *testing.T) {
+	dir := t.TempDir()
+	newer := `{"version": 99, "users": {}, "known_offers": {}}`
+	if err := os.WriteFile(filepath.Join(dir, "bot_state.json"), []byte(newer), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := NewBotState(dir); err == nil {
+		t.Error("NewBotState accepted a state written by a newer version")
+	}
+}
+
+func TestLoadStateMigratesSeenFlagsToTimes(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "bot_state.json")
+	flags := `{
+  "version": 1,
+  "users": {"42": {"chat_id": 42, "seen_offers": {"https://example.com/1": true}}},
+  "known_offers": {"https://example.com/1": {"title": "Testikatu 1", "link": "https://example.com/1"}}
+}`
+	if err := os.WriteFile(path, []byte(flags), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	bs, err := NewBotState(dir)
+	if err != nil {
+		t.Fatalf("NewBotState: %v", err)
+	}
+	user, _ := bs.GetUser(42)
+	if seenAt, seen := user.SeenOffers["https://example.com/1"]; !seen || !seenAt.IsZero() {
+		t.Errorf("seen offers = %v, want the offer seen at the zero time", user.SeenOffers)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var saved struct {
+		Version int `json:"version"`
+		Users   map[string]struct {
+			SeenOffers map[string]time.Time `json:"seen_offers"`
+		} `json:"users"`
+	}
+	if err := json.Unmarshal(data, &saved); err != nil {
+		t.Fatalf("the seen offers were not saved as times: %v", err)
+	}
+	if saved.Version != 2 || len(saved.Users["42"].SeenOffers) != 1 {
+		t.Errorf("re-saved state = %s, want version 2 with the seen offer", data)
+	}
+}
@@ -0,0 +1,79 @@
+package state
+
+import (
+	"net/url"
+	"strings"
+)
+
+// normalizePlace lowercases a city or district name and joins its words with
+// hyphens like the site's links do, so "Etu Töölö" matches "etu-töölö"
+func normalizePlace(name string) string {
+	return strings.ToLower(strings.Join(strings.Fields(name), "-"))
+}
+
+// OfferPlace returns the normalized city and district of an offer. They are read
+// from the link (/vuokra-asunto/[city]/[district]/[type]/[id]) and otherwise from
+// the address ("Street 1, District, City"). Unknown parts are empty.
+func OfferPlace(offer RentalOffer) (city, district string) {
+	if parsed, err := url.Parse(offer.Link); err == nil {
+		parts := strings.Split(strings.Trim(parsed.Path, "/"), "/")
+		for i, part := range parts {
+			if part != "vuokra-asunto" {
+				continue
+			}
+			rest := parts[i+1:]
+			if len(rest) >= 2 {
+				city = normalizePlace(rest[0])
+			}
+			if len(rest) >= 4 {
+				district = normalizePlace(rest[1])
+			}
+			break
+		}
+	}
+	if city != "" {
+		return city, district
+	}
+
+	parts := strings.Split(offer.Address, ",")
+	if len(parts) >= 2 {
+		city = normalizePlace(parts[len(parts)-1])
+	}
+	if len(parts) >= 3 {
+		district = normalizePlace(parts[len(parts)-2])
+	}
+	return city, district
+}
+
+// NormalizePlaces normalizes a list of city or district names for the filters
+func NormalizePlaces(names []string) []string {
+	var places []string
+	for _, name := range names {
+		if place := normalizePlace(name); place != "" {
+			places = append(places, place)
+		}
+	}
+	return places
+}
+
+// placeAllowed reports whether a place passes an allowlist and a denylist.
+// A place that is not known never passes a non-empty allowlist.
+func placeAllowed(place string, include, exclude []string) bool {
+	if len(include) > 0 && !containsPlace(include, place) {
+		return false
+	}
+	return !containsPlace(exclude, place)
+}
+
+// containsPlace reports whether the list holds the place, ignoring case
+func containsPlace(list []string, place string) bool {
+	if place == "" {
+		return false
+	}
+	for _, item := range list {
+		if normalizePlace(item) == place {
+			return true
+		}
+	}
+	return false
+}
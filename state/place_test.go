@@ -0,0 +1,50 @@
+package state
+
+import "testing"
+
+func TestOfferPlace(t *testing.T) {
+	tests := []struct {
+		offer        RentalOffer
+		wantCity     string
+		wantDistrict string
+	}{
+		{RentalOffer{Link: "https://www.vuokraovi.com/vuokra-asunto/helsinki/kallio/kerrostalo/123"}, "helsinki", "kallio"},
+		{RentalOffer{Link: "https://www.vuokraovi.com/vuokra-asunto/Tampere/Hervanta/rivitalo/5?ref=x"}, "tampere", "hervanta"},
+		{RentalOffer{Link: "https://www.vuokraovi.com/vuokra-asunto/helsinki/1"}, "helsinki", ""},
+		{RentalOffer{Link: "https://example.com/1", Address: "Testikatu 1, Etu Töölö, Helsinki"}, "helsinki", "etu-töölö"},
+		{RentalOffer{Link: "https://example.com/1", Address: "Testikatu 1, Helsinki"}, "helsinki", ""},
+		{RentalOffer{Address: "Testikatu 1"}, "", ""},
+	}
+	for _, tt := range tests {
+		city, district := OfferPlace(tt.offer)
+		if city != tt.wantCity || district != tt.wantDistrict {
+			t.Errorf("OfferPlace(%q, %q) = %q, %q, want %q, %q", tt.offer.Link, tt.offer.Address, city, district, tt.wantCity, tt.wantDistrict)
+		}
+	}
+}
+
+func TestFiltersMatchPlaces(t *testing.T) {
+	kallio := RentalOffer{Link: "https://www.vuokraovi.com/vuokra-asunto/helsinki/kallio/kerrostalo/1"}
+	noDistrict := RentalOffer{Address: "Testikatu 1, Helsinki"}
+
+	tests := []struct {
+		name    string
+		filters Filters
+		offer   RentalOffer
+		want    bool
+	}{
+		{"district included", Filters{IncludeDistricts: []string{"vallila", "kallio"}}, kallio, true},
+		{"district not included", Filters{IncludeDistricts: []string{"vallila"}}, kallio, false},
+		{"district excluded", Filters{ExcludeDistricts: []string{"KALLIO"}}, kallio, false},
+		{"other district excluded", Filters{ExcludeDistricts: []string{"vallila"}}, kallio, true},
+		{"unknown district not included", Filters{IncludeDistricts: []string{"kallio"}}, noDistrict, false},
+		{"unknown district not excluded", Filters{ExcludeDistricts: []string{"kallio"}}, noDistrict, true},
+		{"city included", Filters{IncludeCities: []string{"Helsinki"}}, noDistrict, true},
+		{"city excluded", Filters{ExcludeCities: []string{"helsinki"}}, kallio, false},
+	}
+	for _, tt := range tests {
+		if got := tt.filters.Matches(tt.offer); got != tt.want {
+			t.Errorf("%s: Matches = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package state
+
+import "strings"
+
+// Profile is a named set of search filters. New offers are matched against
+// all active profiles of a user.
+type Profile struct {
+	Name    string  `json:"name"`
+	Filters Filters `json:"filters"`
+	Active  bool    `json:"active"`
+}
+
+// HasActiveProfile reports whether any of the profiles is active
+func HasActiveProfile(profiles []Profile) bool {
+	for _, profile := range profiles {
+		if profile.Active {
+			return true
+		}
+	}
+	return false
+}
+
+// MatchingProfiles returns the names of the active profiles matching the offer
+func MatchingProfiles(profiles []Profile, offer RentalOffer) []string {
+	var names []string
+	for _, profile := range profiles {
+		if profile.Active && profile.Filters.Matches(offer) {
+			names = append(names, profile.Name)
+		}
+	}
+	return names
+}
+
+// FilterOffersByProfiles returns the offers matching at least one active profile
+func FilterOffersByProfiles(offers []RentalOffer, profiles []Profile) []RentalOffer {
+	var matched []RentalOffer
+	for _, offer := range offers {
+		if len(MatchingProfiles(profiles, offer)) > 0 {
+			matched = append(matched, offer)
+		}
+	}
+	return matched
+}
+
+// findProfile returns the index of the profile with the given name, ignoring case, or -1
+func findProfile(profiles []Profile, name string) int {
+	for i, profile := range profiles {
+		if strings.EqualFold(profile.Name, name) {
+			return i
+		}
+	}
+	return -1
+}
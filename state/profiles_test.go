@@ -0,0 +1,88 @@
+package state
+
+import (
+	"testing"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+func TestProfileCRUD(t *testing.T) {
+	bs := newTestState(t)
+	bs.AddUser(&tgbotapi.User{FirstName: "Test"}, 1)
+
+	if created, ok := bs.SaveProfile(1, "espoo", Filters{City: "Espoo"}); !created || !ok {
+		t.Fatalf("SaveProfile = %v, %v, want a new profile", created, ok)
+	}
+	if created, ok := bs.SaveProfile(1, "ESPOO", Filters{City: "Espoo", MaxPrice: 1000}); created || !ok {
+		t.Errorf("SaveProfile with another case = %v, %v, want the profile replaced", created, ok)
+	}
+	bs.SaveProfile(1, "helsinki", Filters{City: "Helsinki"})
+
+	profiles := bs.GetProfiles(1)
+	if len(profiles) != 2 || profiles[0].Filters.MaxPrice != 1000 || !profiles[0].Active {
+		t.Fatalf("profiles = %+v, want the updated espoo profile and helsinki", profiles)
+	}
+
+	if !bs.SetProfileActive(1, "Helsinki", false) || bs.GetProfiles(1)[1].Active {
+		t.Error("the helsinki profile was not deactivated")
+	}
+	if bs.SetProfileActive(1, "tampere", true) {
+		t.Error("an unknown profile was activated")
+	}
+
+	if !bs.DeleteProfile(1, "espoo") {
+		t.Fatal("DeleteProfile did not find the espoo profile")
+	}
+	if profiles := bs.GetProfiles(1); len(profiles) != 1 || profiles[0].Name != "helsinki" {
+		t.Errorf("profiles after delete = %+v, want only helsinki", profiles)
+	}
+	if bs.DeleteProfile(1, "espoo") {
+		t.Error("the deleted profile was deleted again")
+	}
+
+	if _, ok := bs.SaveProfile(2, "espoo", Filters{}); ok {
+		t.Error("a profile was saved for an unknown user")
+	}
+}
+
+func TestGetProfilesReturnsCopy(t *testing.T) {
+	bs := newTestState(t)
+	bs.AddUser(&tgbotapi.User{FirstName: "Test"}, 1)
+	bs.SaveProfile(1, "espoo", Filters{City: "Espoo"})
+
+	profiles := bs.GetProfiles(1)
+	profiles[0].Active = false
+
+	if !bs.GetProfiles(1)[0].Active {
+		t.Error("changing the returned profiles changed the state")
+	}
+}
+
+func TestMultiProfileMatching(t *testing.T) {
+	espoo := testOffer("https://example.com/espoo", "950 €/kk")
+	espoo.Address = "Tapiontori 1, Espoo"
+	helsinki := testOffer("https://example.com/helsinki", "1200 €/kk")
+	tampere := testOffer("https://example.com/tampere", "700 €/kk")
+	tampere.Address = "Hämeenkatu 1, Tampere"
+
+	profiles := []Profile{
+		{Name: "espoo", Filters: Filters{City: "Espoo", MaxPrice: 1000}, Active: true},
+		{Name: "cheap", Filters: Filters{MaxPrice: 1000}, Active: true},
+		{Name: "helsinki", Filters: Filters{City: "Helsinki"}, Active: false},
+	}
+
+	if names := MatchingProfiles(profiles, espoo); len(names) != 2 || names[0] != "espoo" || names[1] != "cheap" {
+		t.Errorf("profiles matching the Espoo offer = %v, want espoo and cheap", names)
+	}
+	if names := MatchingProfiles(profiles, helsinki); len(names) != 0 {
+		t.Errorf("profiles matching the Helsinki offer = %v, want none as its profile is inactive", names)
+	}
+
+	matched := FilterOffersByProfiles([]RentalOffer{espoo, helsinki, tampere}, profiles)
+	if got := links(matched); len(got) != 2 || got[0] != espoo.Link || got[1] != tampere.Link {
+		t.Errorf("matched offers = %v, want the Espoo and Tampere offers", got)
+	}
+	if !HasActiveProfile(profiles) || HasActiveProfile(profiles[2:]) {
+		t.Error("HasActiveProfile does not follow the active flags")
+	}
+}
@@ -0,0 +1,48 @@
+package state
+
+import (
+	"fmt"
+	"time"
+)
+
+// QuietHours is a daily window during which a user does not want to be notified.
+// The window starts at Start and ends at End (hours of day in Timezone) and may
+// cross midnight. Equal hours disable the window.
+type QuietHours struct {
+	Start    int    `json:"start"`
+	End      int    `json:"end"`
+	Timezone string `json:"timezone,omitempty"`
+}
+
+// NewQuietHours validates and creates a quiet hours window
+func NewQuietHours(start, end int, timezone string) (QuietHours, error) {
+	if start < 0 || start > 23 || end < 0 || end > 23 {
+		return QuietHours{}, fmt.Errorf("hours must be between 0 and 23")
+	}
+	if _, err := time.LoadLocation(timezone); err != nil {
+		return QuietHours{}, fmt.Errorf("unknown timezone %q", timezone)
+	}
+	return QuietHours{Start: start, End: end, Timezone: timezone}, nil
+}
+
+// Enabled reports whether the window is set
+func (q QuietHours) Enabled() bool {
+	return q.Start != q.End
+}
+
+// Contains reports whether t falls within the quiet window in the window's timezone
+func (q QuietHours) Contains(t time.Time) bool {
+	if !q.Enabled() {
+		return false
+	}
+	if loc, err := time.LoadLocation(q.Timezone); err == nil {
+		t = t.In(loc)
+	}
+
+	hour := t.Hour()
+	if q.Start < q.End {
+		return hour >= q.Start && hour < q.End
+	}
+	// The window crosses midnight, e.g. 22-08
+	return hour >= q.Start || hour < q.End
+}
@@ -0,0 +1,56 @@
+package state
+
+import (
+	"testing"
+	"time"
+)
+
+func TestQuietHoursContains(t *testing.T) {
+	helsinki, err := time.LoadLocation("Europe/Helsinki")
+	if err != nil {
+		t.Fatal(err)
+	}
+	at := func(hour, minute int) time.Time {
+		return time.Date(2024, 5, 10, hour, minute, 0, 0, helsinki)
+	}
+
+	night, _ := NewQuietHours(22, 8, "Europe/Helsinki")
+	afternoon, _ := NewQuietHours(13, 15, "Europe/Helsinki")
+
+	tests := []struct {
+		name  string
+		quiet QuietHours
+		t     time.Time
+		want  bool
+	}{
+		{"before a midnight crossing window", night, at(21, 59), false},
+		{"start of a midnight crossing window", night, at(22, 0), true},
+		{"after midnight", night, at(3, 0), true},
+		{"last minute of the window", night, at(7, 59), true},
+		{"end of a midnight crossing window", night, at(8, 0), false},
+		{"inside a same day window", afternoon, at(14, 30), true},
+		{"end of a same day window", afternoon, at(15, 0), false},
+		{"before a same day window", afternoon, at(12, 0), false},
+		// 20:30 UTC is 23:30 in Helsinki in summer
+		{"converted to the window's timezone", night, time.Date(2024, 5, 10, 20, 30, 0, 0, time.UTC), true},
+		{"disabled window", QuietHours{}, at(3, 0), false},
+	}
+
+	for _, tt := range tests {
+		if got := tt.quiet.Contains(tt.t); got != tt.want {
+			t.Errorf("%s: Contains(%s) = %v, want %v", tt.name, tt.t.Format("15:04 MST"), got, tt.want)
+		}
+	}
+}
+
+func TestNewQuietHoursValidates(t *testing.T) {
+	if _, err := NewQuietHours(22, 24, "Europe/Helsinki"); err == nil {
+		t.Error("accepted hour 24")
+	}
+	if _, err := NewQuietHours(-1, 8, "Europe/Helsinki"); err == nil {
+		t.Error("accepted a negative hour")
+	}
+	if _, err := NewQuietHours(22, 8, "Mars/Olympus"); err == nil {
+		t.Error("accepted an unknown timezone")
+	}
+}
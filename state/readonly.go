@@ -0,0 +1,121 @@
+package state
+
+import (
+	"errors"
+	"os"
+	"time"
+)
+
+// ErrReadOnly is returned when a read-only bot state is asked to change
+var ErrReadOnly = errors.New("the bot state is read-only")
+
+// readOnlyStore loads the state from another store but refuses to write it
+type readOnlyStore struct {
+	Store
+}
+
+// Save refuses to write the state
+func (s readOnlyStore) Save(*Snapshot) error { return ErrReadOnly }
+
+// SaveUser refuses to write the user
+func (s readOnlyStore) SaveUser(*UserState) error { return ErrReadOnly }
+
+// DeleteUser refuses to delete the user
+func (s readOnlyStore) DeleteUser(int64) error { return ErrReadOnly }
+
+// SaveOffers refuses to write the offers
+func (s readOnlyStore) SaveOffers(map[string]RentalOffer, map[string]RentalOffer, time.Time) error {
+	return ErrReadOnly
+}
+
+// modTimeStore is implemented by stores that can tell when they were last written
+type modTimeStore interface {
+	ModTime() (time.Time, error)
+}
+
+// ModTime returns the modification time of the state file, zero if there is none
+func (s *JSONStore) ModTime() (time.Time, error) {
+	info, err := os.Stat(s.path())
+	if os.IsNotExist(err) {
+		return time.Time{}, nil
+	}
+	if err != nil {
+		return time.Time{}, err
+	}
+	return info.ModTime(), nil
+}
+
+// NewReadOnlyBotState loads the JSON state in saveDir for observing the state another
+// process writes, e.g. for a dashboard. The state is never saved: changes made to
+// it in memory last until the next reload, and the mutators returning an error
+// return ErrReadOnly. Use ReloadIfChanged or Watch to pick up the writer's changes.
+func NewReadOnlyBotState(saveDir string) (*BotState, error) {
+	return NewBotStateWithStore(readOnlyStore{NewJSONStore(saveDir)})
+}
+
+// ReadOnly reports whether the state is never saved
+func (bs *BotState) ReadOnly() bool {
+	_, readOnly := bs.store.(readOnlyStore)
+	return readOnly
+}
+
+// checkWritable returns ErrReadOnly for a read-only state
+func (bs *BotState) checkWritable() error {
+	if bs.ReadOnly() {
+		return ErrReadOnly
+	}
+	return nil
+}
+
+// storeModTime returns the modification time of the store, false for stores that
+// cannot tell it
+func (bs *BotState) storeModTime() (time.Time, bool, error) {
+	store := bs.store
+	if readOnly, ok := store.(readOnlyStore); ok {
+		store = readOnly.Store
+	}
+	modTimer, ok := store.(modTimeStore)
+	if !ok {
+		return time.Time{}, false, nil
+	}
+	modTime, err := modTimer.ModTime()
+	return modTime, true, err
+}
+
+// ReloadIfChanged loads the stored state again if it was written since it was last
+// loaded and reports whether it did. Stores that cannot tell when they were written
+// are reloaded every time.
+func (bs *BotState) ReloadIfChanged() (bool, error) {
+	bs.mutex.Lock()
+	defer bs.mutex.Unlock()
+
+	modTime, known, err := bs.storeModTime()
+	if err != nil {
+		return false, err
+	}
+	if known && modTime.Equal(bs.loadedModTime) {
+		return false, nil
+	}
+	if err := bs.loadState(); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Watch reloads the state every interval when it has changed, until stop is closed.
+// Errors are passed to onError and the state keeps its last loaded version.
+func (bs *BotState) Watch(interval time.Duration, stop <-chan struct{}, onError func(error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			if _, err := bs.ReloadIfChanged(); err != nil && onError != nil {
+				onError(err)
+			}
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package state
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+func TestReadOnlyStateRejectsMutators(t *testing.T) {
+	dir := t.TempDir()
+	botState, err := NewReadOnlyBotState(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !botState.ReadOnly() {
+		t.Fatal("ReadOnly() = false")
+	}
+
+	if err := botState.Import([]byte(`{"users": {}, "known_offers": {}}`)); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("Import error = %v, want ErrReadOnly", err)
+	}
+	if err := botState.CleanupInactiveUsers(); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("CleanupInactiveUsers error = %v, want ErrReadOnly", err)
+	}
+	if _, err := botState.RemoveUnreachableUsers(); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("RemoveUnreachableUsers error = %v, want ErrReadOnly", err)
+	}
+	botState.AddUser(&tgbotapi.User{ID: 1, FirstName: "Test"}, 1)
+	botState.ApplyOffers([]RentalOffer{testOffer("https://example.com/a", "900 €/kk")})
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("a read-only state wrote %d files, e.g. %s", len(entries), entries[0].Name())
+	}
+}
+
+func TestReadOnlyStateReloadsWhenTheFileChanges(t *testing.T) {
+	dir := t.TempDir()
+	writer, err := NewBotState(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writer.AddUser(&tgbotapi.User{ID: 1, FirstName: "Test"}, 1)
+
+	observer, err := NewReadOnlyBotState(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, exists := observer.GetUser(1); !exists {
+		t.Fatal("the observer did not load the existing user")
+	}
+	if reloaded, err := observer.ReloadIfChanged(); err != nil || reloaded {
+		t.Errorf("ReloadIfChanged() = %v, %v without a change", reloaded, err)
+	}
+
+	writer.AddUser(&tgbotapi.User{ID: 2, FirstName: "Other"}, 2)
+	// Make the change visible even on file systems with a coarse mtime
+	later := time.Now().Add(time.Minute)
+	if err := os.Chtimes(filepath.Join(dir, "bot_state.json"), later, later); err != nil {
+		t.Fatal(err)
+	}
+
+	if reloaded, err := observer.ReloadIfChanged(); err != nil || !reloaded {
+		t.Fatalf("ReloadIfChanged() = %v, %v after a change", reloaded, err)
+	}
+	if _, exists := observer.GetUser(2); !exists {
+		t.Error("the observer did not load the new user")
+	}
+}
@@ -0,0 +1,104 @@
+package state
+
+import (
+	"encoding/json"
+	"sort"
+	"time"
+)
+
+// DefaultMaxSeenOffers is the default number of seen links kept per user; the
+// oldest ones are forgotten beyond it
+const DefaultMaxSeenOffers = 5000
+
+// SeenTimes holds the links a user has seen with the time each was first seen
+type SeenTimes map[string]time.Time
+
+// Has reports whether the link has been seen
+func (s SeenTimes) Has(link string) bool {
+	_, seen := s[link]
+	return seen
+}
+
+// UnmarshalJSON decodes the seen links, accepting the true flags stored before the
+// times were recorded as links seen at the zero time
+func (s *SeenTimes) UnmarshalJSON(data []byte) error {
+	var values map[string]json.RawMessage
+	if err := json.Unmarshal(data, &values); err != nil {
+		return err
+	}
+	if values == nil {
+		*s = nil
+		return nil
+	}
+
+	seen := make(SeenTimes, len(values))
+	for link, value := range values {
+		var flag bool
+		if err := json.Unmarshal(value, &flag); err == nil {
+			if flag {
+				seen[link] = time.Time{}
+			}
+			continue
+		}
+		var t time.Time
+		if err := json.Unmarshal(value, &t); err != nil {
+			return err
+		}
+		seen[link] = t
+	}
+	*s = seen
+	return nil
+}
+
+// copySeen returns a copy of the seen links
+func copySeen(seen SeenTimes) SeenTimes {
+	if seen == nil {
+		return nil
+	}
+	seenCopy := make(SeenTimes, len(seen))
+	for k, v := range seen {
+		seenCopy[k] = v
+	}
+	return seenCopy
+}
+
+// markSeen records a link as seen by the user at the given time, keeping the time
+// it was first seen
+func markSeen(user *UserState, link string, now time.Time) {
+	if user.SeenOffers == nil {
+		user.SeenOffers = make(SeenTimes)
+	}
+	if !user.SeenOffers.Has(link) {
+		user.SeenOffers[link] = now
+	}
+}
+
+// orderedSeenOffers returns the links the user has seen from the oldest to the most
+// recent. Links seen at the same time, such as those stored before the times were
+// recorded, are sorted by link.
+func orderedSeenOffers(user *UserState) []string {
+	order := make([]string, 0, len(user.SeenOffers))
+	for link := range user.SeenOffers {
+		order = append(order, link)
+	}
+	sort.Slice(order, func(i, j int) bool {
+		ti, tj := user.SeenOffers[order[i]], user.SeenOffers[order[j]]
+		if !ti.Equal(tj) {
+			return ti.Before(tj)
+		}
+		return order[i] < order[j]
+	})
+	return order
+}
+
+// limitSeenOffers forgets the oldest links the user has seen beyond max (0 keeps
+// them all)
+func limitSeenOffers(user *UserState, max int) {
+	if max <= 0 || len(user.SeenOffers) <= max {
+		return
+	}
+	order := orderedSeenOffers(user)
+	for _, link := range order[:len(order)-max] {
+		delete(user.SeenOffers, link)
+	}
+}
@@ -0,0 +1,113 @@
+package state
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+func TestMarkOfferAsSeenEvictsOldestBeyondCap(t *testing.T) {
+	bs := newTestState(t)
+	bs.SetMaxSeenOffers(3)
+	bs.AddUser(&tgbotapi.User{FirstName: "Test"}, 1)
+
+	var offers []RentalOffer
+	for i := 0; i < 5; i++ {
+		offer := testOffer(fmt.Sprintf("https://example.com/%d", i), "900 €/kk")
+		offer.Address = fmt.Sprintf("Testikatu %d, Helsinki", i)
+		offers = append(offers, offer)
+	}
+	bs.ApplyOffers(offers)
+	for _, offer := range offers {
+		bs.MarkOfferAsSeen(1, offer.Link)
+	}
+	// Seeing an offer again does not make it more recent
+	bs.MarkOfferAsSeen(1, offers[2].Link)
+
+	user, _ := bs.GetUser(1)
+	want := []string{"https://example.com/2", "https://example.com/3", "https://example.com/4"}
+	if got := orderedSeenOffers(user); !reflect.DeepEqual(got, want) {
+		t.Errorf("seen offers = %v, want %v", got, want)
+	}
+
+	reloaded, err := NewBotStateWithStore(bs.store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	user, _ = reloaded.GetUser(1)
+	if got := orderedSeenOffers(user); !reflect.DeepEqual(got, want) {
+		t.Errorf("reloaded seen offers = %v, want %v", got, want)
+	}
+}
+
+func TestMarkOfferAsSeenRecordsTime(t *testing.T) {
+	bs := newTestState(t)
+	bs.AddUser(&tgbotapi.User{FirstName: "Test"}, 1)
+
+	before := time.Now()
+	bs.MarkOfferAsSeen(1, "https://example.com/a?ref=1")
+	after := time.Now()
+
+	user, _ := bs.GetUser(1)
+	seenAt, seen := user.SeenOffers["https://example.com/a"]
+	if !seen || seenAt.Before(before) || seenAt.After(after) {
+		t.Fatalf("seen at %v (seen %v), want between %v and %v", seenAt, seen, before, after)
+	}
+
+	bs.MarkOfferAsSeen(1, "https://example.com/a")
+	if user, _ := bs.GetUser(1); !user.SeenOffers["https://example.com/a"].Equal(seenAt) {
+		t.Errorf("seen at %v after seeing it again, want the first time %v", user.SeenOffers["https://example.com/a"], seenAt)
+	}
+}
+
+func TestCleanUserCapsSeenOffersOnSave(t *testing.T) {
+	known := map[string]RentalOffer{}
+	for _, link := range []string{"a", "b", "c", "d"} {
+		known[link] = testOffer(link, "900 €/kk")
+	}
+	base := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	// "a" and "b" were stored before the times were recorded, so they are the oldest
+	user := &UserState{
+		SeenOffers: SeenTimes{
+			"a":       {},
+			"b":       {},
+			"c?ref=1": base.Add(2 * time.Hour),
+			"c":       base.Add(3 * time.Hour),
+			"d":       base.Add(time.Hour),
+			"gone":    base,
+		},
+	}
+
+	cleaned := cleanUser(user, known, 3)
+	want := SeenTimes{"b": {}, "d": base.Add(time.Hour), "c": base.Add(2 * time.Hour)}
+	if !reflect.DeepEqual(cleaned.SeenOffers, want) {
+		t.Errorf("SeenOffers = %v, want %v", cleaned.SeenOffers, want)
+	}
+	if len(user.SeenOffers) != 6 {
+		t.Errorf("cleanUser changed the original user: %v", user.SeenOffers)
+	}
+
+	if got := cleanUser(user, known, 0).SeenOffers; len(got) != 4 {
+		t.Errorf("SeenOffers without a cap = %v, want all known offers", got)
+	}
+}
+
+func TestSeenTimesDecodesFlags(t *testing.T) {
+	var seen SeenTimes
+	data := `{"a": true, "b": false, "c": "2024-05-01T12:00:00Z"}`
+	if err := json.Unmarshal([]byte(data), &seen); err != nil {
+		t.Fatal(err)
+	}
+	want := SeenTimes{"a": {}, "c": time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)}
+	if !reflect.DeepEqual(seen, want) {
+		t.Errorf("SeenTimes = %v, want %v", seen, want)
+	}
+
+	if err := json.Unmarshal([]byte(`{"a": 1}`), &seen); err == nil {
+		t.Error("a number was accepted as a seen time")
+	}
+}
@@ -0,0 +1,84 @@
+package state
+
+import (
+	"sort"
+	"time"
+)
+
+// PriceSnapshot holds the price of every known offer at a point in time, keyed by link
+type PriceSnapshot map[string]string
+
+// SnapshotDiff lists how the known offers changed since a snapshot
+type SnapshotDiff struct {
+	Added        []RentalOffer // offers known now but not in the snapshot
+	Removed      []RentalOffer // offers in the snapshot that are no longer known
+	PriceChanges []PriceChange // offers whose price differs from the snapshot
+}
+
+// Empty reports whether nothing changed
+func (d SnapshotDiff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.PriceChanges) == 0
+}
+
+// priceSnapshot returns the prices of the known offers. The caller must hold the mutex.
+func (bs *BotState) priceSnapshot() PriceSnapshot {
+	snapshot := make(PriceSnapshot, len(bs.KnownOffers))
+	for link, offer := range bs.KnownOffers {
+		snapshot[link] = offer.Price
+	}
+	return snapshot
+}
+
+// diffSnapshot compares the known offers with a snapshot. The caller must hold the mutex.
+func (bs *BotState) diffSnapshot(snapshot PriceSnapshot) SnapshotDiff {
+	var diff SnapshotDiff
+	for link, offer := range bs.KnownOffers {
+		price, existed := snapshot[link]
+		switch {
+		case !existed:
+			diff.Added = append(diff.Added, offer)
+		case price != offer.Price:
+			diff.PriceChanges = append(diff.PriceChanges, PriceChange{Offer: offer, OldPrice: price, NewPrice: offer.Price})
+		}
+	}
+	for link, price := range snapshot {
+		if _, known := bs.KnownOffers[link]; known {
+			continue
+		}
+		offer, delisted := bs.DelistedOffers[link]
+		if !delisted {
+			offer = RentalOffer{Link: link, Price: price}
+		}
+		diff.Removed = append(diff.Removed, offer)
+	}
+
+	sort.Slice(diff.Added, func(i, j int) bool { return diff.Added[i].Link < diff.Added[j].Link })
+	sort.Slice(diff.Removed, func(i, j int) bool { return diff.Removed[i].Link < diff.Removed[j].Link })
+	sort.Slice(diff.PriceChanges, func(i, j int) bool {
+		return diff.PriceChanges[i].Offer.Link < diff.PriceChanges[j].Offer.Link
+	})
+	return diff
+}
+
+// DiffSnapshot compares the known offers with the user's snapshot and replaces the
+// snapshot with the current offers. It returns the time of the previous snapshot,
+// which is zero when the user had none and the diff is empty.
+func (bs *BotState) DiffSnapshot(chatID int64, now time.Time) (SnapshotDiff, time.Time) {
+	bs.mutex.Lock()
+	defer bs.mutex.Unlock()
+
+	user, exists := bs.Users[chatID]
+	if !exists {
+		return SnapshotDiff{}, time.Time{}
+	}
+
+	var diff SnapshotDiff
+	since := user.PriceSnapshotAt
+	if !since.IsZero() {
+		diff = bs.diffSnapshot(user.PriceSnapshot)
+	}
+	user.PriceSnapshot = bs.priceSnapshot()
+	user.PriceSnapshotAt = now
+	bs.saveUser(chatID)
+	return diff, since
+}
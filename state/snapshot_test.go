@@ -0,0 +1,74 @@
+package state
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+func TestDiffSnapshotReportsChanges(t *testing.T) {
+	bs := newTestState(t)
+	bs.SetDelistAfter(1)
+	bs.AddUser(&tgbotapi.User{FirstName: "Test"}, 1)
+	now := time.Date(2024, 5, 10, 12, 0, 0, 0, time.UTC)
+
+	offer := func(link, address, price string) RentalOffer {
+		o := testOffer("https://example.com/"+link, price)
+		o.Address = address
+		return o
+	}
+	kept := offer("kept", "Katu 1, Helsinki", "900 €/kk")
+	cheaper := offer("cheaper", "Katu 2, Helsinki", "1000 €/kk")
+	gone := offer("gone", "Katu 3, Helsinki", "800 €/kk")
+	bs.ApplyOffers([]RentalOffer{kept, cheaper, gone})
+
+	// The first call only takes the snapshot
+	diff, since := bs.DiffSnapshot(1, now)
+	if !since.IsZero() || !diff.Empty() {
+		t.Fatalf("first diff = %+v since %v, want an empty diff without a previous snapshot", diff, since)
+	}
+	if diff, since := bs.DiffSnapshot(1, now.Add(time.Minute)); !since.Equal(now) || !diff.Empty() {
+		t.Errorf("diff without changes = %+v since %v, want empty since %v", diff, since, now)
+	}
+
+	cheaper.Price = "950 €/kk"
+	added := offer("added", "Katu 4, Helsinki", "700 €/kk")
+	bs.ApplyOffers([]RentalOffer{kept, cheaper, added})
+
+	diff, _ = bs.DiffSnapshot(1, now.Add(time.Hour))
+	if got := links(diff.Added); !reflect.DeepEqual(got, []string{added.Link}) {
+		t.Errorf("added = %v, want %v", got, added.Link)
+	}
+	if got := links(diff.Removed); !reflect.DeepEqual(got, []string{gone.Link}) || diff.Removed[0].Title != gone.Title {
+		t.Errorf("removed = %+v, want the delisted offer %v", diff.Removed, gone.Link)
+	}
+	if len(diff.PriceChanges) != 1 || diff.PriceChanges[0].Offer.Link != cheaper.Link ||
+		diff.PriceChanges[0].OldPrice != "1000 €/kk" || diff.PriceChanges[0].NewPrice != "950 €/kk" {
+		t.Errorf("price changes = %+v, want %v from 1000 to 950", diff.PriceChanges, cheaper.Link)
+	}
+
+	// The report replaced the snapshot
+	if diff, _ := bs.DiffSnapshot(1, now.Add(2*time.Hour)); !diff.Empty() {
+		t.Errorf("diff after reporting = %+v, want empty", diff)
+	}
+}
+
+func TestRecordDeliveryTakesSnapshot(t *testing.T) {
+	bs := newTestState(t)
+	bs.AddUser(&tgbotapi.User{FirstName: "Test"}, 1)
+	a := testOffer("https://example.com/a", "900 €/kk")
+	bs.ApplyOffers([]RentalOffer{a})
+	sent := time.Date(2024, 5, 10, 12, 0, 0, 0, time.UTC)
+	bs.RecordDelivery(1, []string{a.Link}, sent)
+
+	b := testOffer("https://example.com/b", "900 €/kk")
+	b.Address = "Toinenkatu 2, Helsinki"
+	bs.ApplyOffers([]RentalOffer{a, b})
+
+	diff, since := bs.DiffSnapshot(1, sent.Add(time.Hour))
+	if !since.Equal(sent) || !reflect.DeepEqual(links(diff.Added), []string{b.Link}) {
+		t.Errorf("diff = %+v since %v, want offer b added since the notification", diff, since)
+	}
+}
@@ -0,0 +1,294 @@
+package state
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+// SQLiteStore stores the bot state in a SQLite database, one row per user and offer
+type SQLiteStore struct {
+	db *sql.DB
+}
+
+// NewSQLiteStore opens (or creates) the SQLite database at path
+func NewSQLiteStore(path string) (*SQLiteStore, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create data directory: %w", err)
+	}
+
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+	// SQLite allows a single writer, so serialize access through one connection
+	db.SetMaxOpenConns(1)
+
+	schema := []string{
+		`PRAGMA journal_mode = WAL`,
+		`CREATE TABLE IF NOT EXISTS users (
+			chat_id INTEGER PRIMARY KEY,
+			data    TEXT NOT NULL
+		)`,
+		`CREATE TABLE IF NOT EXISTS offers (
+			link     TEXT PRIMARY KEY,
+			delisted INTEGER NOT NULL DEFAULT 0,
+			data     TEXT NOT NULL
+		)`,
+		`CREATE TABLE IF NOT EXISTS meta (
+			key   TEXT PRIMARY KEY,
+			value TEXT NOT NULL
+		)`,
+	}
+	for _, stmt := range schema {
+		if _, err := db.Exec(stmt); err != nil {
+			db.Close()
+			return nil, fmt.Errorf("failed to initialize database: %w", err)
+		}
+	}
+
+	return &SQLiteStore{db: db}, nil
+}
+
+// Load reads all users and offers from the database
+func (s *SQLiteStore) Load() (*Snapshot, error) {
+	snapshot := &Snapshot{
+		Users:          make(map[int64]*UserState),
+		KnownOffers:    make(map[string]RentalOffer),
+		DelistedOffers: make(map[string]RentalOffer),
+	}
+	empty := true
+
+	var lastUpdated string
+	err := s.db.QueryRow(`SELECT value FROM meta WHERE key = 'last_updated'`).Scan(&lastUpdated)
+	switch {
+	case err == sql.ErrNoRows:
+	case err != nil:
+		return nil, fmt.Errorf("failed to read metadata: %w", err)
+	default:
+		empty = false
+		if t, err := time.Parse(time.RFC3339Nano, lastUpdated); err == nil {
+			snapshot.LastUpdated = t
+		}
+	}
+
+	var version int
+	err = s.db.QueryRow(`SELECT value FROM meta WHERE key = 'version'`).Scan(&version)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, fmt.Errorf("failed to read state version: %w", err)
+	}
+	snapshot.Version = version
+
+	rows, err := s.db.Query(`SELECT chat_id, data FROM users`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read users: %w", err)
+	}
+	for rows.Next() {
+		var chatID int64
+		var data string
+		if err := rows.Scan(&chatID, &data); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to read user: %w", err)
+		}
+		var user UserState
+		if err := json.Unmarshal([]byte(data), &user); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to unmarshal user %d: %w", chatID, err)
+		}
+		snapshot.Users[chatID] = &user
+		empty = false
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read users: %w", err)
+	}
+
+	rows, err = s.db.Query(`SELECT link, delisted, data FROM offers`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read offers: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var link, data string
+		var delisted bool
+		if err := rows.Scan(&link, &delisted, &data); err != nil {
+			return nil, fmt.Errorf("failed to read offer: %w", err)
+		}
+		var offer RentalOffer
+		if err := json.Unmarshal([]byte(data), &offer); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal offer %s: %w", link, err)
+		}
+		if delisted {
+			snapshot.DelistedOffers[link] = offer
+		} else {
+			snapshot.KnownOffers[link] = offer
+		}
+		empty = false
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read offers: %w", err)
+	}
+
+	if empty {
+		return nil, nil
+	}
+	return snapshot, nil
+}
+
+// Save replaces the stored users and offers in a single transaction
+func (s *SQLiteStore) Save(snapshot *Snapshot) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM users`); err != nil {
+		return fmt.Errorf("failed to clear users: %w", err)
+	}
+	for chatID, user := range snapshot.Users {
+		data, err := json.Marshal(user)
+		if err != nil {
+			return fmt.Errorf("failed to marshal user %d: %w", chatID, err)
+		}
+		if _, err := tx.Exec(`INSERT INTO users (chat_id, data) VALUES (?, ?)`, chatID, string(data)); err != nil {
+			return fmt.Errorf("failed to save user %d: %w", chatID, err)
+		}
+	}
+
+	if err := writeOffers(tx, snapshot.KnownOffers, snapshot.DelistedOffers); err != nil {
+		return err
+	}
+	if err := writeMeta(tx, snapshot.LastUpdated, snapshot.Version); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// SaveUser inserts or replaces the row of a single user
+func (s *SQLiteStore) SaveUser(user *UserState) error {
+	data, err := json.Marshal(user)
+	if err != nil {
+		return fmt.Errorf("failed to marshal user %d: %w", user.ChatID, err)
+	}
+	if _, err := s.db.Exec(`INSERT OR REPLACE INTO users (chat_id, data) VALUES (?, ?)`, user.ChatID, string(data)); err != nil {
+		return fmt.Errorf("failed to save user %d: %w", user.ChatID, err)
+	}
+	return nil
+}
+
+// DeleteUser deletes the row of a single user
+func (s *SQLiteStore) DeleteUser(chatID int64) error {
+	if _, err := s.db.Exec(`DELETE FROM users WHERE chat_id = ?`, chatID); err != nil {
+		return fmt.Errorf("failed to delete user %d: %w", chatID, err)
+	}
+	return nil
+}
+
+// SaveOffers writes the offers that changed since the last save in a single transaction
+func (s *SQLiteStore) SaveOffers(known, delisted map[string]RentalOffer, lastUpdated time.Time) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := writeOffers(tx, known, delisted); err != nil {
+		return err
+	}
+	if err := writeMeta(tx, lastUpdated, CurrentStateVersion); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// storedOffer is an offer row as found in the offers table
+type storedOffer struct {
+	delisted bool
+	data     string
+}
+
+// writeOffers makes the offers table hold exactly the given offers, touching only
+// the rows that were added, changed or removed
+func writeOffers(tx *sql.Tx, known, delisted map[string]RentalOffer) error {
+	rows, err := tx.Query(`SELECT link, delisted, data FROM offers`)
+	if err != nil {
+		return fmt.Errorf("failed to query offers: %w", err)
+	}
+	stored := make(map[string]storedOffer)
+	for rows.Next() {
+		var link string
+		var row storedOffer
+		if err := rows.Scan(&link, &row.delisted, &row.data); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan offer: %w", err)
+		}
+		stored[link] = row
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to read offers: %w", err)
+	}
+
+	upsert := func(offers map[string]RentalOffer, isDelisted bool) error {
+		for link, offer := range offers {
+			data, err := json.Marshal(offer)
+			if err != nil {
+				return fmt.Errorf("failed to marshal offer %s: %w", link, err)
+			}
+			row, exists := stored[link]
+			delete(stored, link)
+			if exists && row.delisted == isDelisted && row.data == string(data) {
+				continue
+			}
+			if _, err := tx.Exec(`INSERT OR REPLACE INTO offers (link, delisted, data) VALUES (?, ?, ?)`, link, isDelisted, string(data)); err != nil {
+				return fmt.Errorf("failed to save offer %s: %w", link, err)
+			}
+		}
+		return nil
+	}
+	if err := upsert(known, false); err != nil {
+		return err
+	}
+	if err := upsert(delisted, true); err != nil {
+		return err
+	}
+
+	// Whatever is left is no longer part of the state
+	for link := range stored {
+		if _, err := tx.Exec(`DELETE FROM offers WHERE link = ?`, link); err != nil {
+			return fmt.Errorf("failed to delete offer %s: %w", link, err)
+		}
+	}
+	return nil
+}
+
+// writeMeta stores the update time and the state version
+func writeMeta(tx *sql.Tx, lastUpdated time.Time, version int) error {
+	if _, err := tx.Exec(`INSERT OR REPLACE INTO meta (key, value) VALUES ('last_updated', ?)`,
+		lastUpdated.Format(time.RFC3339Nano)); err != nil {
+		return fmt.Errorf("failed to save metadata: %w", err)
+	}
+	if _, err := tx.Exec(`INSERT OR REPLACE INTO meta (key, value) VALUES ('version', ?)`, version); err != nil {
+		return fmt.Errorf("failed to save state version: %w", err)
+	}
+	return nil
+}
+
+// Close closes the database
+func (s *SQLiteStore) Close() error {
+	return s.db.Close()
+}
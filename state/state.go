@@ -3,12 +3,12 @@ package state
 import (
 	"encoding/json"
 	"fmt"
-	"os"
-	"path/filepath"
+	"sort"
 	"strings"
 	"sync"
 	"time"
 
+	"github.com/aqaliarept/vuokraovi-bot/scraper"
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 )
 
@@ -19,40 +19,159 @@ type UserState struct {
 	FirstName     string          `json:"first_name"`
 	LastName      string          `json:"last_name"`
 	LastNotified  time.Time       `json:"last_notified"`
-	SeenOffers    map[string]bool `json:"seen_offers"`
+	SeenOffers    SeenTimes       `json:"seen_offers"`
 	Notifications bool            `json:"notifications"`
+	Filters       Filters         `json:"filters"`
+	Profiles      []Profile       `json:"profiles,omitempty"`
+	Favorites     map[string]bool `json:"favorites,omitempty"`
+	// FavoriteOrder holds the links of the favorites in the order they were added
+	FavoriteOrder []string       `json:"favorite_order,omitempty"`
+	QuietHours    QuietHours     `json:"quiet_hours"`
+	Digest        DigestSchedule `json:"digest"`
+	Language      string         `json:"language,omitempty"`
+	PendingOffers []RentalOffer  `json:"pending_offers,omitempty"`
+	// LastNotification holds the links of the offers sent in the latest notification
+	LastNotification []string `json:"last_notification,omitempty"`
+	// Searches are the names of the searches the user gets new offers of, empty for all
+	Searches []string `json:"searches,omitempty"`
+	// DisplayFields are the offer fields shown in the user's notifications and
+	// /list, empty for all
+	DisplayFields []string `json:"display_fields,omitempty"`
+	// PriceSnapshot holds the known offers at the user's last /diff or notification,
+	// taken at PriceSnapshotAt (zero when there is none yet)
+	PriceSnapshot   PriceSnapshot `json:"price_snapshot,omitempty"`
+	PriceSnapshotAt time.Time     `json:"price_snapshot_at"`
+	// PausedUntil is the end of the user's pause, no new offers are sent before it
+	PausedUntil time.Time `json:"paused_until"`
+	// Unreachable is set when Telegram refused a message because the user blocked
+	// the bot or the chat is gone; such users are removed by the next cleanup
+	Unreachable bool `json:"unreachable,omitempty"`
+	// Conversation is the multi-step command waiting for a reply of the user, nil
+	// when there is none
+	Conversation *Conversation `json:"conversation,omitempty"`
 }
 
 // RentalOffer represents a rental property listing
 type RentalOffer struct {
-	Title     string `json:"title"`
-	Address   string `json:"address"`
-	Price     string `json:"price"`
-	Size      string `json:"size"`
-	Rooms     string `json:"rooms"`
-	Available string `json:"available"`
-	Link      string `json:"link"`
+	Title          string    `json:"title"`
+	Address        string    `json:"address"`
+	Price          string    `json:"price"`
+	PriceEUR       float64   `json:"price_eur,omitempty"`
+	PriceIsMonthly bool      `json:"price_is_monthly,omitempty"`
+	BuildingType   string    `json:"building_type,omitempty"`
+	Size           string    `json:"size"`
+	SizeSqm        float64   `json:"size_sqm,omitempty"`
+	Rooms          string    `json:"rooms"`
+	RoomCount      int       `json:"room_count,omitempty"`
+	Amenities      []string  `json:"amenities,omitempty"`
+	Available      string    `json:"available"`
+	AvailableFrom  time.Time `json:"available_from"`
+	AvailableNow   bool      `json:"available_now,omitempty"`
+	Lat            float64   `json:"lat,omitempty"`
+	Lng            float64   `json:"lng,omitempty"`
+	Link           string    `json:"link"`
+	ImageURL       string    `json:"image_url,omitempty"`
+	Searches       []string  `json:"searches,omitempty"` // names of the searches that found the offer
+
+	PriceHistory   []PricePoint `json:"price_history,omitempty"`
+	MissedUpdates  int          `json:"missed_updates,omitempty"`
+	AlternateLinks []string     `json:"alternate_links,omitempty"` // other links serving the same listing
+	FirstSeen      time.Time    `json:"first_seen,omitempty"`
+}
+
+// PricePoint is a price observed for an offer at a point in time
+type PricePoint struct {
+	Price string    `json:"price"`
+	Time  time.Time `json:"time"`
+}
+
+// PriceChange describes a known offer whose price has changed
+type PriceChange struct {
+	Offer    RentalOffer
+	OldPrice string
+	NewPrice string
+}
+
+// UpdateResult classifies the offers passed to ApplyOffers
+type UpdateResult struct {
+	New       []RentalOffer // offers that have never been seen before
+	Changed   []RentalOffer // known offers whose details have changed
+	Unchanged []RentalOffer // known offers without any changes
+	Relisted  []RentalOffer // offers that were delisted and have reappeared
+
+	PriceChanges []PriceChange // known offers whose price has changed, also listed in Changed
+	Removed      []RentalOffer // known offers delisted after missing too many updates
+	// SeenBy lists the chats that had seen each removed offer, keyed by its link
+	SeenBy map[string][]int64
 }
 
+// DefaultDelistAfter is the default number of consecutive updates an offer may be
+// missing from the results before it is considered delisted
+const DefaultDelistAfter = 3
+
 // BotState represents the state of the bot
 type BotState struct {
-	Users       map[int64]*UserState   `json:"users"`
-	KnownOffers map[string]RentalOffer `json:"known_offers"`
-	LastUpdated time.Time              `json:"last_updated"`
-	mutex       sync.Mutex             `json:"-"`
-	saveDir     string                 `json:"-"`
+	Users          map[int64]*UserState   `json:"users"`
+	KnownOffers    map[string]RentalOffer `json:"known_offers"`
+	DelistedOffers map[string]RentalOffer `json:"delisted_offers"`
+	LastUpdated    time.Time              `json:"last_updated"`
+	mutex          sync.Mutex             `json:"-"`
+	store          Store                  `json:"-"`
+	delistAfter    int                    `json:"-"`
+	maxSeenOffers  int                    `json:"-"`
+	// notifying holds the links claimed for each user's notifications, in memory only
+	notifying map[int64]map[string]time.Time `json:"-"`
+	// loadedModTime is the modification time of the store when it was last loaded
+	loadedModTime time.Time `json:"-"`
+	// lock is the data directory lock taken by NewBotState
+	lock *DirLock `json:"-"`
+	// saveInterval defers saves to Flush when positive, dirty marks unsaved changes
+	saveInterval time.Duration `json:"-"`
+	dirty        bool          `json:"-"`
+}
+
+// NewBotState creates a new bot state persisted as JSON in saveDir. It locks saveDir
+// until Close, failing with ErrLocked if another process uses it.
+func NewBotState(saveDir string) (*BotState, error) {
+	lock, err := LockDir(saveDir)
+	if err != nil {
+		return nil, err
+	}
+	state, err := NewBotStateWithStore(NewJSONStore(saveDir))
+	if err != nil {
+		lock.Unlock()
+		return nil, err
+	}
+	state.lock = lock
+	return state, nil
 }
 
-// NewBotState creates a new bot state
-func NewBotState(saveDir string) *BotState {
+// Close saves the pending changes and releases the data directory lock taken by
+// NewBotState
+func (bs *BotState) Close() error {
+	flushErr := bs.Flush()
+	if err := bs.lock.Unlock(); err != nil {
+		return err
+	}
+	return flushErr
+}
+
+// NewBotStateWithStore creates a new bot state persisted in the given store and
+// loads the stored state into it
+func NewBotStateWithStore(store Store) (*BotState, error) {
 	state := &BotState{
-		Users:       make(map[int64]*UserState),
-		KnownOffers: make(map[string]RentalOffer),
-		LastUpdated: time.Now(),
-		saveDir:     saveDir,
+		Users:          make(map[int64]*UserState),
+		KnownOffers:    make(map[string]RentalOffer),
+		DelistedOffers: make(map[string]RentalOffer),
+		LastUpdated:    time.Now(),
+		store:          store,
+		delistAfter:    DefaultDelistAfter,
+		maxSeenOffers:  DefaultMaxSeenOffers,
+	}
+	if err := state.LoadState(); err != nil {
+		return nil, err
 	}
-	state.LoadState()
-	return state
+	return state, nil
 }
 
 // cleanURL removes query parameters from a URL
@@ -64,83 +183,271 @@ func cleanURL(url string) string {
 	return url[:pos]
 }
 
-// SaveState saves the bot state to disk
+// OfferID returns the ID of an offer, which is the last segment of its link
+// (e.g. "1766680" for https://www.vuokraovi.com/vuokra-asunto/tampere/viiala/rivitalo/1766680)
+func OfferID(link string) string {
+	return scraper.OfferID(link)
+}
+
+// appendPricePoint records a price unless it equals the latest recorded one
+func appendPricePoint(history []PricePoint, price string, t time.Time) []PricePoint {
+	if price == "" {
+		return history
+	}
+	if len(history) > 0 && history[len(history)-1].Price == price {
+		return history
+	}
+	updated := make([]PricePoint, len(history), len(history)+1)
+	copy(updated, history)
+	return append(updated, PricePoint{Price: price, Time: t})
+}
+
+// ParsePrice extracts the numeric value of a price string such as "1 037,88 €/kk"
+func ParsePrice(price string) (float64, bool) {
+	return scraper.ParsePrice(price)
+}
+
+// PricePerSqm returns the rent per square meter, or 0 when the price or the size
+// of the offer is unknown
+func (o RentalOffer) PricePerSqm() float64 {
+	if o.PriceEUR <= 0 || o.SizeSqm <= 0 {
+		return 0
+	}
+	return o.PriceEUR / o.SizeSqm
+}
+
+// offerFingerprint identifies a listing by its content so the same apartment served
+// under different links collapses into one offer. It returns an empty string when
+// the offer has too little data to be told apart from others.
+func offerFingerprint(offer RentalOffer) string {
+	address := strings.ToLower(strings.Join(strings.Fields(offer.Address), " "))
+	if address == "" || (offer.SizeSqm == 0 && offer.PriceEUR == 0) {
+		return ""
+	}
+	return fmt.Sprintf("%s|%g|%d|%g", address, offer.SizeSqm, offer.RoomCount, offer.PriceEUR)
+}
+
+// addAlternateLink records an alternate link unless it is already known
+func addAlternateLink(links []string, link string) []string {
+	for _, l := range links {
+		if l == link {
+			return links
+		}
+	}
+	updated := make([]string, len(links), len(links)+1)
+	copy(updated, links)
+	return append(updated, link)
+}
+
+// sameOffer reports whether two offers carry the same listing details
+func sameOffer(a, b RentalOffer) bool {
+	return a.Title == b.Title &&
+		a.Address == b.Address &&
+		a.Price == b.Price &&
+		a.PriceEUR == b.PriceEUR &&
+		a.Size == b.Size &&
+		a.SizeSqm == b.SizeSqm &&
+		a.Rooms == b.Rooms &&
+		a.RoomCount == b.RoomCount &&
+		a.Available == b.Available &&
+		a.AvailableFrom.Equal(b.AvailableFrom) &&
+		a.AvailableNow == b.AvailableNow &&
+		a.Link == b.Link &&
+		a.ImageURL == b.ImageURL
+}
+
+// saveState replaces the whole stored state; the caller must hold the mutex
 func (bs *BotState) saveState() error {
-	stateCopy := &BotState{
-		Users:       make(map[int64]*UserState, len(bs.Users)),
-		KnownOffers: make(map[string]RentalOffer, len(bs.KnownOffers)),
-		LastUpdated: bs.LastUpdated,
+	if bs.deferSave() {
+		return nil
 	}
+	return bs.writeState()
+}
 
-	// Clean up and validate KnownOffers
+// writeState writes the whole state to the store; the caller must hold the mutex
+func (bs *BotState) writeState() error {
+	if err := bs.store.Save(bs.snapshot()); err != nil {
+		return fmt.Errorf("failed to save bot state: %w", err)
+	}
+
+	return nil
+}
+
+// saveUser stores a single user; the caller must hold the mutex
+func (bs *BotState) saveUser(chatID int64) error {
+	user, exists := bs.Users[chatID]
+	if !exists || user == nil || bs.deferSave() {
+		return nil
+	}
+	if err := bs.store.SaveUser(cleanUser(user, bs.KnownOffers, bs.maxSeenOffers)); err != nil {
+		return fmt.Errorf("failed to save user %d: %w", chatID, err)
+	}
+	return nil
+}
+
+// saveOffers stores the known and delisted offers; the caller must hold the mutex
+func (bs *BotState) saveOffers() error {
+	if bs.deferSave() {
+		return nil
+	}
+	known, delisted := bs.cleanOffers()
+	if err := bs.store.SaveOffers(known, delisted, bs.LastUpdated); err != nil {
+		return fmt.Errorf("failed to save offers: %w", err)
+	}
+	return nil
+}
+
+// snapshot returns a cleaned up copy of the state; the caller must hold the mutex
+func (bs *BotState) snapshot() *Snapshot {
+	known, delisted := bs.cleanOffers()
+	stateCopy := &Snapshot{
+		Version:        CurrentStateVersion,
+		Users:          make(map[int64]*UserState, len(bs.Users)),
+		KnownOffers:    known,
+		DelistedOffers: delisted,
+		LastUpdated:    bs.LastUpdated,
+	}
+
+	for k, v := range bs.Users {
+		if v != nil {
+			stateCopy.Users[k] = cleanUser(v, known, bs.maxSeenOffers)
+		}
+	}
+
+	return stateCopy
+}
+
+// cleanOffers returns copies of the known and delisted offers keyed by their clean
+// links, dropping invalid entries; the caller must hold the mutex
+func (bs *BotState) cleanOffers() (map[string]RentalOffer, map[string]RentalOffer) {
+	known := make(map[string]RentalOffer, len(bs.KnownOffers))
 	for k, v := range bs.KnownOffers {
 		cleanLink := cleanURL(k)
 		if cleanLink != "" && v.Link != "" {
-			stateCopy.KnownOffers[cleanLink] = v
+			known[cleanLink] = v
 		}
 	}
 
-	// Clean up and validate Users
-	for k, v := range bs.Users {
-		if v == nil {
-			continue
-		}
-		userCopy := *v
-		if userCopy.SeenOffers == nil {
-			userCopy.SeenOffers = make(map[string]bool)
-		}
-		validSeenOffers := make(map[string]bool)
-		for link := range userCopy.SeenOffers {
-			cleanLink := cleanURL(link)
-			if _, exists := stateCopy.KnownOffers[cleanLink]; exists {
-				validSeenOffers[cleanLink] = true
-			}
+	delisted := make(map[string]RentalOffer, len(bs.DelistedOffers))
+	for k, v := range bs.DelistedOffers {
+		cleanLink := cleanURL(k)
+		if _, isKnown := known[cleanLink]; cleanLink != "" && !isKnown {
+			delisted[cleanLink] = v
 		}
-		userCopy.SeenOffers = validSeenOffers
-		stateCopy.Users[k] = &userCopy
 	}
 
-	if err := os.MkdirAll(bs.saveDir, 0755); err != nil {
-		return fmt.Errorf("failed to create data directory: %w", err)
-	}
+	return known, delisted
+}
 
-	stateFile := filepath.Join(bs.saveDir, "bot_state.json")
-	data, err := json.MarshalIndent(stateCopy, "", "  ")
-	if err != nil {
-		return fmt.Errorf("failed to marshal bot state: %w", err)
+// copyUser returns a deep copy of the user
+func copyUser(user *UserState) *UserState {
+	userCopy := *user
+	userCopy.SeenOffers = copySeen(user.SeenOffers)
+	userCopy.Favorites = copyFlags(user.Favorites)
+	userCopy.FavoriteOrder = append([]string(nil), user.FavoriteOrder...)
+	userCopy.Profiles = append([]Profile(nil), user.Profiles...)
+	userCopy.PendingOffers = append([]RentalOffer(nil), user.PendingOffers...)
+	userCopy.LastNotification = append([]string(nil), user.LastNotification...)
+	userCopy.Searches = append([]string(nil), user.Searches...)
+	userCopy.DisplayFields = append([]string(nil), user.DisplayFields...)
+	userCopy.PriceSnapshot = make(PriceSnapshot, len(user.PriceSnapshot))
+	for link, price := range user.PriceSnapshot {
+		userCopy.PriceSnapshot[link] = price
+	}
+	if user.Conversation != nil {
+		conversation := *user.Conversation
+		userCopy.Conversation = &conversation
 	}
+	return &userCopy
+}
 
-	if err := os.WriteFile(stateFile, data, 0644); err != nil {
-		return fmt.Errorf("failed to write bot state file: %w", err)
+// copyFlags returns a copy of a set of links
+func copyFlags(flags map[string]bool) map[string]bool {
+	if flags == nil {
+		return nil
+	}
+	flagsCopy := make(map[string]bool, len(flags))
+	for k, v := range flags {
+		flagsCopy[k] = v
 	}
+	return flagsCopy
+}
 
-	return nil
+// cleanUser returns a copy of the user whose seen offers are limited to the known
+// offers and to the most recent maxSeen of them (0 keeps them all)
+func cleanUser(user *UserState, known map[string]RentalOffer, maxSeen int) *UserState {
+	userCopy := copyUser(user)
+	validSeenOffers := make(SeenTimes)
+	for link, seenAt := range user.SeenOffers {
+		cleanLink := cleanURL(link)
+		if _, exists := known[cleanLink]; !exists {
+			continue
+		}
+		if earlier, seen := validSeenOffers[cleanLink]; !seen || seenAt.Before(earlier) {
+			validSeenOffers[cleanLink] = seenAt
+		}
+	}
+	userCopy.SeenOffers = validSeenOffers
+	limitSeenOffers(userCopy, maxSeen)
+	return userCopy
 }
 
-// LoadState loads the bot state from disk
+// LoadState loads the bot state from the store
 func (bs *BotState) LoadState() error {
-	stateFile := filepath.Join(bs.saveDir, "bot_state.json")
-	if _, err := os.Stat(stateFile); os.IsNotExist(err) {
+	bs.mutex.Lock()
+	defer bs.mutex.Unlock()
+
+	return bs.loadState()
+}
+
+// loadState replaces the in-memory state with the stored one; the caller must hold the mutex
+func (bs *BotState) loadState() error {
+	// Take the time before reading, so a write during the read is loaded next time
+	if modTime, known, err := bs.storeModTime(); err == nil && known {
+		bs.loadedModTime = modTime
+	}
+
+	loadedState, err := bs.store.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load bot state: %w", err)
+	}
+
+	if loadedState == nil {
 		bs.Users = make(map[int64]*UserState)
 		bs.KnownOffers = make(map[string]RentalOffer)
+		bs.DelistedOffers = make(map[string]RentalOffer)
 		bs.LastUpdated = time.Now()
 		return nil
 	}
 
-	data, err := os.ReadFile(stateFile)
+	migrated, err := bs.applySnapshot(loadedState)
+	if err != nil {
+		return err
+	}
+
+	// Persist the upgraded state so it is stamped with the current version
+	if migrated && !bs.ReadOnly() {
+		if err := bs.saveState(); err != nil {
+			return fmt.Errorf("failed to save migrated state: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// applySnapshot migrates the snapshot and replaces the in-memory state with it.
+// It reports whether a migration was applied; the caller must hold the mutex.
+func (bs *BotState) applySnapshot(loadedState *Snapshot) (bool, error) {
+	migrated, err := migrateSnapshot(loadedState)
 	if err != nil {
-		return fmt.Errorf("failed to read bot state file: %w", err)
+		return false, err
 	}
 
 	bs.Users = make(map[int64]*UserState)
 	bs.KnownOffers = make(map[string]RentalOffer)
+	bs.DelistedOffers = make(map[string]RentalOffer)
 	bs.LastUpdated = time.Now()
 
-	var loadedState BotState
-	if err := json.Unmarshal(data, &loadedState); err != nil {
-		return fmt.Errorf("failed to unmarshal bot state: %w", err)
-	}
-
 	if loadedState.Users == nil {
 		loadedState.Users = make(map[int64]*UserState)
 	}
@@ -157,34 +464,77 @@ func (bs *BotState) LoadState() error {
 	}
 	bs.KnownOffers = uniqueOffers
 
-	for k, v := range loadedState.Users {
-		if v == nil {
-			continue
-		}
-		userCopy := *v
-		if userCopy.SeenOffers == nil {
-			userCopy.SeenOffers = make(map[string]bool)
+	for k, v := range loadedState.DelistedOffers {
+		cleanLink := cleanURL(k)
+		if _, known := bs.KnownOffers[cleanLink]; cleanLink != "" && !known {
+			bs.DelistedOffers[cleanLink] = v
 		}
-		validSeenOffers := make(map[string]bool)
-		for link := range userCopy.SeenOffers {
-			cleanLink := cleanURL(link)
-			if _, exists := bs.KnownOffers[cleanLink]; exists {
-				validSeenOffers[cleanLink] = true
-			}
+	}
+
+	for k, v := range loadedState.Users {
+		if v != nil {
+			bs.Users[k] = cleanUser(v, bs.KnownOffers, bs.maxSeenOffers)
 		}
-		userCopy.SeenOffers = validSeenOffers
-		bs.Users[k] = &userCopy
 	}
 
 	if !loadedState.LastUpdated.IsZero() {
 		bs.LastUpdated = loadedState.LastUpdated
 	}
 
+	return migrated, nil
+}
+
+// Export serializes the whole state as JSON
+func (bs *BotState) Export() ([]byte, error) {
+	bs.mutex.Lock()
+	defer bs.mutex.Unlock()
+
+	data, err := json.MarshalIndent(bs.snapshot(), "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal bot state: %w", err)
+	}
+	return data, nil
+}
+
+// Import replaces the state with one serialized by Export and saves it.
+// The current state is kept when the data is not a valid export.
+func (bs *BotState) Import(data []byte) error {
+	var snapshot Snapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return fmt.Errorf("failed to parse bot state: %w", err)
+	}
+	if snapshot.Users == nil && snapshot.KnownOffers == nil {
+		return fmt.Errorf("the data is not a bot state export: users and offers are missing")
+	}
+	for chatID, user := range snapshot.Users {
+		if user != nil && user.ChatID != 0 && user.ChatID != chatID {
+			return fmt.Errorf("user %d is stored under chat ID %d", user.ChatID, chatID)
+		}
+	}
+
+	if err := bs.checkWritable(); err != nil {
+		return err
+	}
+
+	bs.mutex.Lock()
+	defer bs.mutex.Unlock()
+
+	if _, err := bs.applySnapshot(&snapshot); err != nil {
+		return err
+	}
+	if err := bs.saveState(); err != nil {
+		return err
+	}
 	return nil
 }
 
 // CleanupInactiveUsers removes users who haven't been active for more than 30 days
+// and users who can no longer be reached
 func (bs *BotState) CleanupInactiveUsers() error {
+	if err := bs.checkWritable(); err != nil {
+		return err
+	}
+
 	bs.mutex.Lock()
 	defer bs.mutex.Unlock()
 
@@ -192,12 +542,75 @@ func (bs *BotState) CleanupInactiveUsers() error {
 	inactiveThreshold := now.AddDate(0, 0, -30)
 
 	for chatID, user := range bs.Users {
-		if user.LastNotified.Before(inactiveThreshold) {
+		if user.Unreachable || user.LastNotified.Before(inactiveThreshold) {
 			delete(bs.Users, chatID)
+			if err := bs.deleteUser(chatID); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// MarkUserUnreachable flags a user whose chat refused a message for removal
+func (bs *BotState) MarkUserUnreachable(chatID int64) bool {
+	bs.mutex.Lock()
+	defer bs.mutex.Unlock()
+
+	if user, exists := bs.Users[chatID]; exists {
+		user.Unreachable = true
+		bs.saveUser(chatID)
+		return true
+	}
+	return false
+}
+
+// RemoveUnreachableUsers removes the users flagged as unreachable and returns how
+// many were removed
+func (bs *BotState) RemoveUnreachableUsers() (int, error) {
+	if err := bs.checkWritable(); err != nil {
+		return 0, err
+	}
+
+	bs.mutex.Lock()
+	defer bs.mutex.Unlock()
+
+	removed := 0
+	for chatID, user := range bs.Users {
+		if !user.Unreachable {
+			continue
+		}
+		delete(bs.Users, chatID)
+		if err := bs.deleteUser(chatID); err != nil {
+			return removed, err
+		}
+		removed++
+	}
+	return removed, nil
+}
+
+// CleanupOldOffers drops delisted offers first seen longer than maxAge ago and
+// returns how many were dropped. Known offers are never dropped: they are still
+// listed or waiting to be delisted, and forgetting them would announce them as
+// new again on the next update. Users' seen offers were pruned on delisting.
+func (bs *BotState) CleanupOldOffers(maxAge time.Duration) int {
+	bs.mutex.Lock()
+	defer bs.mutex.Unlock()
+
+	threshold := time.Now().Add(-maxAge)
+	removed := 0
+	for link, offer := range bs.DelistedOffers {
+		if !offer.FirstSeen.IsZero() && offer.FirstSeen.Before(threshold) {
+			delete(bs.DelistedOffers, link)
+			removed++
 		}
 	}
 
-	return bs.saveState()
+	if removed > 0 {
+		bs.saveOffers()
+	}
+	return removed
 }
 
 // AddUser adds a new user to the bot state
@@ -212,18 +625,44 @@ func (bs *BotState) AddUser(user *tgbotapi.User, chatID int64) *UserState {
 			FirstName:     user.FirstName,
 			LastName:      user.LastName,
 			LastNotified:  time.Time{},
-			SeenOffers:    make(map[string]bool),
+			SeenOffers:    make(SeenTimes),
 			Notifications: true,
 		}
 	} else {
 		bs.Users[chatID].Username = user.UserName
 		bs.Users[chatID].FirstName = user.FirstName
 		bs.Users[chatID].LastName = user.LastName
+		// A user writing to the bot can be reached again
+		bs.Users[chatID].Unreachable = false
 	}
-	bs.saveState()
+	bs.saveUser(chatID)
 	return bs.Users[chatID]
 }
 
+// SetDelistAfter sets how many consecutive updates an offer may be missing
+// before it is delisted (values below 1 delist offers immediately)
+func (bs *BotState) SetDelistAfter(updates int) {
+	bs.mutex.Lock()
+	defer bs.mutex.Unlock()
+
+	if updates < 1 {
+		updates = 1
+	}
+	bs.delistAfter = updates
+}
+
+// SetMaxSeenOffers sets how many seen links are kept per user before the oldest
+// ones are forgotten (0 keeps them all)
+func (bs *BotState) SetMaxSeenOffers(max int) {
+	bs.mutex.Lock()
+	defer bs.mutex.Unlock()
+
+	if max < 0 {
+		max = 0
+	}
+	bs.maxSeenOffers = max
+}
+
 // GetUser gets a user from the bot state
 func (bs *BotState) GetUser(chatID int64) (*UserState, bool) {
 	bs.mutex.Lock()
@@ -233,43 +672,169 @@ func (bs *BotState) GetUser(chatID int64) (*UserState, bool) {
 	return user, exists
 }
 
-// UpdateOffers updates the known offers in the bot state
+// UpdateOffers updates the known offers in the bot state and returns the offers
+// that were not known before, including relisted ones
 func (bs *BotState) UpdateOffers(offers []RentalOffer) []RentalOffer {
+	result := bs.ApplyOffers(offers)
+	return append(result.New, result.Relisted...)
+}
+
+// ApplyOffers updates the known offers in the bot state and classifies every
+// fetched offer in a single pass
+func (bs *BotState) ApplyOffers(offers []RentalOffer) UpdateResult {
 	bs.mutex.Lock()
 	defer bs.mutex.Unlock()
 
-	var newOffers []RentalOffer
+	result := bs.applyOffers(offers, time.Now())
+	bs.saveOffers()
+	saved := make(map[int64]bool)
+	for _, chatIDs := range result.SeenBy {
+		for _, chatID := range chatIDs {
+			if !saved[chatID] {
+				bs.saveUser(chatID)
+				saved[chatID] = true
+			}
+		}
+	}
+	return result
+}
+
+// PreviewOffers classifies the fetched offers like ApplyOffers without changing
+// or persisting the state
+func (bs *BotState) PreviewOffers(offers []RentalOffer) UpdateResult {
+	bs.mutex.Lock()
+	defer bs.mutex.Unlock()
+
+	scratch := &BotState{
+		Users:          make(map[int64]*UserState),
+		KnownOffers:    make(map[string]RentalOffer, len(bs.KnownOffers)),
+		DelistedOffers: make(map[string]RentalOffer, len(bs.DelistedOffers)),
+		delistAfter:    bs.delistAfter,
+	}
+	for link, offer := range bs.KnownOffers {
+		scratch.KnownOffers[link] = offer
+	}
+	for link, offer := range bs.DelistedOffers {
+		scratch.DelistedOffers[link] = offer
+	}
+	return scratch.applyOffers(offers, time.Now())
+}
+
+// applyOffers merges the fetched offers into the state and classifies them;
+// the caller must hold the mutex and save the state
+func (bs *BotState) applyOffers(offers []RentalOffer, now time.Time) UpdateResult {
+	var result UpdateResult
 	currentOffers := make(map[string]bool)
 
-	// Process new offers and track current ones
+	// Index the known offers by content to catch listings served under another link
+	fingerprints := make(map[string]string, len(bs.KnownOffers))
+	for link, offer := range bs.KnownOffers {
+		if fp := offerFingerprint(offer); fp != "" {
+			fingerprints[fp] = link
+		}
+	}
+
+	// Process fetched offers and track current ones
 	for _, offer := range offers {
 		cleanLink := cleanURL(offer.Link)
-		if cleanLink != "" {
-			currentOffers[cleanLink] = true
-			offerCopy := offer
-			offerCopy.Link = cleanLink
+		if cleanLink == "" || currentOffers[cleanLink] {
+			continue
+		}
+		offerCopy := offer
+		offerCopy.Link = cleanLink
 
-			if _, exists := bs.KnownOffers[cleanLink]; !exists {
-				newOffers = append(newOffers, offerCopy)
+		// Collapse a duplicate of a known offer into it, keeping the link as an alternate
+		if _, exists := bs.KnownOffers[cleanLink]; !exists {
+			if primary, ok := fingerprints[offerFingerprint(offerCopy)]; ok && primary != cleanLink {
+				known := bs.KnownOffers[primary]
+				known.AlternateLinks = addAlternateLink(known.AlternateLinks, cleanLink)
+				known.MissedUpdates = 0
+				bs.KnownOffers[primary] = known
+				if !currentOffers[primary] {
+					currentOffers[primary] = true
+					result.Unchanged = append(result.Unchanged, known)
+				}
+				continue
+			}
+		}
+		currentOffers[cleanLink] = true
+
+		if known, exists := bs.KnownOffers[cleanLink]; exists {
+			if known.FirstSeen.IsZero() {
+				// Offers stored before first-seen tracking start aging now
+				known.FirstSeen = now
+			}
+			offerCopy.PriceHistory = known.PriceHistory
+			offerCopy.AlternateLinks = known.AlternateLinks
+			offerCopy.FirstSeen = known.FirstSeen
+			if sameOffer(known, offerCopy) {
+				// Keep the freshly parsed offer, so fields added after the offer was
+				// stored, like the coordinates and amenities, get filled in
 				bs.KnownOffers[cleanLink] = offerCopy
+				result.Unchanged = append(result.Unchanged, offerCopy)
+				continue
 			}
+			if known.Price != offerCopy.Price {
+				if len(offerCopy.PriceHistory) == 0 && known.Price != "" {
+					offerCopy.PriceHistory = []PricePoint{{Price: known.Price}}
+				}
+				offerCopy.PriceHistory = appendPricePoint(offerCopy.PriceHistory, offerCopy.Price, now)
+				result.PriceChanges = append(result.PriceChanges, PriceChange{
+					Offer:    offerCopy,
+					OldPrice: known.Price,
+					NewPrice: offerCopy.Price,
+				})
+			}
+			result.Changed = append(result.Changed, offerCopy)
+			bs.KnownOffers[cleanLink] = offerCopy
+		} else if delistedOffer, delisted := bs.DelistedOffers[cleanLink]; delisted {
+			offerCopy.PriceHistory = appendPricePoint(delistedOffer.PriceHistory, offerCopy.Price, now)
+			offerCopy.FirstSeen = delistedOffer.FirstSeen
+			if offerCopy.FirstSeen.IsZero() {
+				offerCopy.FirstSeen = now
+			}
+			result.Relisted = append(result.Relisted, offerCopy)
+			bs.KnownOffers[cleanLink] = offerCopy
+			delete(bs.DelistedOffers, cleanLink)
+		} else {
+			offerCopy.PriceHistory = appendPricePoint(nil, offerCopy.Price, now)
+			offerCopy.FirstSeen = now
+			result.New = append(result.New, offerCopy)
+			bs.KnownOffers[cleanLink] = offerCopy
+		}
+		if fp := offerFingerprint(offerCopy); fp != "" {
+			fingerprints[fp] = cleanLink
 		}
 	}
 
-	// Remove offers that are no longer present
-	for link := range bs.KnownOffers {
-		if !currentOffers[link] {
-			delete(bs.KnownOffers, link)
-			// Also remove this offer from users' seen offers
-			for _, user := range bs.Users {
+	// Move offers that have been missing for too many updates to the delisted set
+	for link, offer := range bs.KnownOffers {
+		if currentOffers[link] {
+			continue
+		}
+		offer.MissedUpdates++
+		if offer.MissedUpdates < bs.delistAfter {
+			bs.KnownOffers[link] = offer
+			continue
+		}
+		offer.MissedUpdates = 0
+		delete(bs.KnownOffers, link)
+		bs.DelistedOffers[link] = offer
+		result.Removed = append(result.Removed, offer)
+		// Also remove this offer from users' seen offers
+		for chatID, user := range bs.Users {
+			if user.SeenOffers.Has(link) {
+				if result.SeenBy == nil {
+					result.SeenBy = make(map[string][]int64)
+				}
+				result.SeenBy[link] = append(result.SeenBy[link], chatID)
 				delete(user.SeenOffers, link)
 			}
 		}
 	}
 
-	bs.LastUpdated = time.Now()
-	bs.saveState()
-	return newOffers
+	bs.LastUpdated = now
+	return result
 }
 
 // ResetUserState resets a user's state
@@ -278,9 +843,10 @@ func (bs *BotState) ResetUserState(chatID int64) {
 	defer bs.mutex.Unlock()
 
 	if user, exists := bs.Users[chatID]; exists {
-		user.SeenOffers = make(map[string]bool)
+		user.SeenOffers = make(SeenTimes)
 		user.LastNotified = time.Time{}
-		bs.saveState()
+		delete(bs.notifying, chatID)
+		bs.saveUser(chatID)
 	}
 }
 
@@ -310,7 +876,7 @@ func (bs *BotState) SetUserNotifications(chatID int64, enabled bool) bool {
 
 	if user, exists := bs.Users[chatID]; exists {
 		user.Notifications = enabled
-		bs.saveState()
+		bs.saveUser(chatID)
 		return true
 	}
 	return false
@@ -327,18 +893,67 @@ func (bs *BotState) GetUserNotifications(chatID int64) (bool, bool) {
 	return false, false
 }
 
-// MarkOfferAsSeen marks an offer as seen by a user
+// MarkOfferAsSeen marks an offer as seen by a user, recording when it was first seen
 func (bs *BotState) MarkOfferAsSeen(chatID int64, offerLink string) {
 	bs.mutex.Lock()
 	defer bs.mutex.Unlock()
 
 	if user, exists := bs.Users[chatID]; exists {
-		if user.SeenOffers == nil {
-			user.SeenOffers = make(map[string]bool)
+		markSeen(user, cleanURL(offerLink), time.Now())
+		limitSeenOffers(user, bs.maxSeenOffers)
+	}
+	bs.saveUser(chatID)
+}
+
+// RecordDelivery records that the offers were delivered to a user at t: they are
+// marked as seen, remembered as the latest notification for /undo and the user's
+// last notified time is updated, all under one lock and saved together
+func (bs *BotState) RecordDelivery(chatID int64, offerLinks []string, t time.Time) {
+	bs.mutex.Lock()
+	defer bs.mutex.Unlock()
+
+	user, exists := bs.Users[chatID]
+	if !exists {
+		return
+	}
+	user.LastNotified = t
+	user.LastNotification = make([]string, len(offerLinks))
+	for i, link := range offerLinks {
+		user.LastNotification[i] = cleanURL(link)
+		markSeen(user, user.LastNotification[i], t)
+	}
+	limitSeenOffers(user, bs.maxSeenOffers)
+	// Later /diff calls report the changes since this notification
+	user.PriceSnapshot = bs.priceSnapshot()
+	user.PriceSnapshotAt = t
+	bs.saveUser(chatID)
+}
+
+// UndoLastNotification marks the offers of the user's latest notification as
+// unseen again and queues the ones still listed for the next notification.
+// It returns the number of offers queued.
+func (bs *BotState) UndoLastNotification(chatID int64) int {
+	bs.mutex.Lock()
+	defer bs.mutex.Unlock()
+
+	user, exists := bs.Users[chatID]
+	if !exists || len(user.LastNotification) == 0 {
+		return 0
+	}
+
+	var offers []RentalOffer
+	for _, link := range user.LastNotification {
+		delete(user.SeenOffers, link)
+		// The user asked for the offers again, so they are not suppressed
+		delete(bs.notifying[chatID], link)
+		if offer, known := bs.KnownOffers[link]; known {
+			offers = append(offers, offer)
 		}
-		user.SeenOffers[cleanURL(offerLink)] = true
 	}
-	bs.saveState()
+	queueOffers(user, offers)
+	user.LastNotification = nil
+	bs.saveUser(chatID)
+	return len(offers)
 }
 
 // UpdateUserLastNotified updates the last notified timestamp for a user
@@ -349,7 +964,417 @@ func (bs *BotState) UpdateUserLastNotified(chatID int64, t time.Time) {
 	if user, exists := bs.Users[chatID]; exists {
 		user.LastNotified = t
 	}
-	bs.saveState()
+	bs.saveUser(chatID)
+}
+
+// SetUserFilters sets the search filters for a user
+func (bs *BotState) SetUserFilters(chatID int64, filters Filters) bool {
+	bs.mutex.Lock()
+	defer bs.mutex.Unlock()
+
+	if user, exists := bs.Users[chatID]; exists {
+		user.Filters = filters
+		bs.saveUser(chatID)
+		return true
+	}
+	return false
+}
+
+// SetUserSearches sets the searches a user gets new offers of (nil for all)
+func (bs *BotState) SetUserSearches(chatID int64, searches []string) bool {
+	bs.mutex.Lock()
+	defer bs.mutex.Unlock()
+
+	if user, exists := bs.Users[chatID]; exists {
+		user.Searches = searches
+		bs.saveUser(chatID)
+		return true
+	}
+	return false
+}
+
+// SetUserDisplayFields sets the offer fields shown to a user, nil shows all
+func (bs *BotState) SetUserDisplayFields(chatID int64, fields []string) bool {
+	bs.mutex.Lock()
+	defer bs.mutex.Unlock()
+
+	if user, exists := bs.Users[chatID]; exists {
+		user.DisplayFields = fields
+		bs.saveUser(chatID)
+		return true
+	}
+	return false
+}
+
+// GetUserFilters gets the search filters for a user
+func (bs *BotState) GetUserFilters(chatID int64) (Filters, bool) {
+	bs.mutex.Lock()
+	defer bs.mutex.Unlock()
+
+	if user, exists := bs.Users[chatID]; exists {
+		return user.Filters, true
+	}
+	return Filters{}, false
+}
+
+// SaveProfile creates or replaces the named search profile of a user and activates it.
+// It reports whether a new profile was created.
+func (bs *BotState) SaveProfile(chatID int64, name string, filters Filters) (bool, bool) {
+	bs.mutex.Lock()
+	defer bs.mutex.Unlock()
+
+	user, exists := bs.Users[chatID]
+	if !exists {
+		return false, false
+	}
+
+	profile := Profile{Name: name, Filters: filters, Active: true}
+	created := false
+	if i := findProfile(user.Profiles, name); i >= 0 {
+		user.Profiles[i] = profile
+	} else {
+		user.Profiles = append(user.Profiles, profile)
+		created = true
+	}
+	bs.saveUser(chatID)
+	return created, true
+}
+
+// SetProfileActive activates or deactivates the named search profile of a user
+func (bs *BotState) SetProfileActive(chatID int64, name string, active bool) bool {
+	bs.mutex.Lock()
+	defer bs.mutex.Unlock()
+
+	user, exists := bs.Users[chatID]
+	if !exists {
+		return false
+	}
+	i := findProfile(user.Profiles, name)
+	if i < 0 {
+		return false
+	}
+	user.Profiles[i].Active = active
+	bs.saveUser(chatID)
+	return true
+}
+
+// DeleteProfile removes the named search profile of a user
+func (bs *BotState) DeleteProfile(chatID int64, name string) bool {
+	bs.mutex.Lock()
+	defer bs.mutex.Unlock()
+
+	user, exists := bs.Users[chatID]
+	if !exists {
+		return false
+	}
+	i := findProfile(user.Profiles, name)
+	if i < 0 {
+		return false
+	}
+	user.Profiles = append(user.Profiles[:i], user.Profiles[i+1:]...)
+	bs.saveUser(chatID)
+	return true
+}
+
+// GetProfiles returns a copy of the search profiles of a user
+func (bs *BotState) GetProfiles(chatID int64) []Profile {
+	bs.mutex.Lock()
+	defer bs.mutex.Unlock()
+
+	user, exists := bs.Users[chatID]
+	if !exists {
+		return nil
+	}
+	return append([]Profile(nil), user.Profiles...)
+}
+
+// findOfferByID looks up a known or delisted offer by its ID; the caller must hold the mutex
+func (bs *BotState) findOfferByID(offerID string) (RentalOffer, bool) {
+	for link, offer := range bs.KnownOffers {
+		if OfferID(link) == offerID {
+			return offer, true
+		}
+	}
+	for link, offer := range bs.DelistedOffers {
+		if OfferID(link) == offerID {
+			return offer, true
+		}
+	}
+	return RentalOffer{}, false
+}
+
+// FindOffer looks up a known or delisted offer by its ID
+func (bs *BotState) FindOffer(offerID string) (RentalOffer, bool) {
+	bs.mutex.Lock()
+	defer bs.mutex.Unlock()
+	return bs.findOfferByID(offerID)
+}
+
+// FindKnownOffer looks up a currently listed offer by its ID
+func (bs *BotState) FindKnownOffer(offerID string) (RentalOffer, bool) {
+	bs.mutex.Lock()
+	defer bs.mutex.Unlock()
+
+	for link, offer := range bs.KnownOffers {
+		if OfferID(link) == offerID {
+			return offer, true
+		}
+	}
+	return RentalOffer{}, false
+}
+
+// AddFavorite adds the offer with the given ID to the user's favorites.
+// It returns false if the user or the offer does not exist.
+func (bs *BotState) AddFavorite(chatID int64, offerID string) (RentalOffer, bool) {
+	bs.mutex.Lock()
+	defer bs.mutex.Unlock()
+
+	user, exists := bs.Users[chatID]
+	if !exists {
+		return RentalOffer{}, false
+	}
+	offer, found := bs.findOfferByID(offerID)
+	if !found {
+		return RentalOffer{}, false
+	}
+
+	if user.Favorites == nil {
+		user.Favorites = make(map[string]bool)
+	}
+	link := cleanURL(offer.Link)
+	if !user.Favorites[link] {
+		user.Favorites[link] = true
+		user.FavoriteOrder = append(user.FavoriteOrder, link)
+	}
+	bs.saveUser(chatID)
+	return offer, true
+}
+
+// RemoveFavorite removes the offer with the given ID from the user's favorites.
+// It returns false if the offer was not a favorite.
+func (bs *BotState) RemoveFavorite(chatID int64, offerID string) bool {
+	bs.mutex.Lock()
+	defer bs.mutex.Unlock()
+
+	user, exists := bs.Users[chatID]
+	if !exists {
+		return false
+	}
+	for link := range user.Favorites {
+		if OfferID(link) == offerID {
+			delete(user.Favorites, link)
+			for i, ordered := range user.FavoriteOrder {
+				if ordered == link {
+					user.FavoriteOrder = append(user.FavoriteOrder[:i:i], user.FavoriteOrder[i+1:]...)
+					break
+				}
+			}
+			bs.saveUser(chatID)
+			return true
+		}
+	}
+	return false
+}
+
+// favoriteLinks returns the links of the user's favorites in the order they were
+// added. Favorites saved before the order was kept come last, sorted by link.
+func favoriteLinks(user *UserState) []string {
+	links := make([]string, 0, len(user.Favorites))
+	ordered := make(map[string]bool, len(user.FavoriteOrder))
+	for _, link := range user.FavoriteOrder {
+		if user.Favorites[link] && !ordered[link] {
+			ordered[link] = true
+			links = append(links, link)
+		}
+	}
+	var unordered []string
+	for link := range user.Favorites {
+		if !ordered[link] {
+			unordered = append(unordered, link)
+		}
+	}
+	sort.Strings(unordered)
+	return append(links, unordered...)
+}
+
+// GetFavorites returns the user's favorite offers in the order they were added,
+// including ones that have been delisted
+func (bs *BotState) GetFavorites(chatID int64) []RentalOffer {
+	bs.mutex.Lock()
+	defer bs.mutex.Unlock()
+
+	user, exists := bs.Users[chatID]
+	if !exists {
+		return nil
+	}
+	favorites := make([]RentalOffer, 0, len(user.Favorites))
+	for _, link := range favoriteLinks(user) {
+		if offer, ok := bs.KnownOffers[link]; ok {
+			favorites = append(favorites, offer)
+		} else if offer, ok := bs.DelistedOffers[link]; ok {
+			favorites = append(favorites, offer)
+		}
+	}
+	return favorites
+}
+
+// SetUserLanguage sets the language of a user
+func (bs *BotState) SetUserLanguage(chatID int64, language string) bool {
+	bs.mutex.Lock()
+	defer bs.mutex.Unlock()
+
+	if user, exists := bs.Users[chatID]; exists {
+		user.Language = language
+		bs.saveUser(chatID)
+		return true
+	}
+	return false
+}
+
+// GetUserLanguage gets the language of a user, empty if not set
+func (bs *BotState) GetUserLanguage(chatID int64) string {
+	bs.mutex.Lock()
+	defer bs.mutex.Unlock()
+
+	if user, exists := bs.Users[chatID]; exists {
+		return user.Language
+	}
+	return ""
+}
+
+// SetUserQuietHours sets the quiet hours of a user
+func (bs *BotState) SetUserQuietHours(chatID int64, quiet QuietHours) bool {
+	bs.mutex.Lock()
+	defer bs.mutex.Unlock()
+
+	if user, exists := bs.Users[chatID]; exists {
+		user.QuietHours = quiet
+		bs.saveUser(chatID)
+		return true
+	}
+	return false
+}
+
+// SetUserConversation stores the conversation a user is in, nil ends it
+func (bs *BotState) SetUserConversation(chatID int64, conversation *Conversation) bool {
+	bs.mutex.Lock()
+	defer bs.mutex.Unlock()
+
+	if user, exists := bs.Users[chatID]; exists {
+		user.Conversation = conversation
+		bs.saveUser(chatID)
+		return true
+	}
+	return false
+}
+
+// SetUserPause pauses the notifications of a user until the given time, the zero
+// time resumes them
+func (bs *BotState) SetUserPause(chatID int64, until time.Time) bool {
+	bs.mutex.Lock()
+	defer bs.mutex.Unlock()
+
+	if user, exists := bs.Users[chatID]; exists {
+		user.PausedUntil = until
+		bs.saveUser(chatID)
+		return true
+	}
+	return false
+}
+
+// GetUserQuietHours returns the quiet hours of a user
+func (bs *BotState) GetUserQuietHours(chatID int64) (QuietHours, bool) {
+	bs.mutex.Lock()
+	defer bs.mutex.Unlock()
+
+	if user, exists := bs.Users[chatID]; exists {
+		return user.QuietHours, true
+	}
+	return QuietHours{}, false
+}
+
+// SetUserDigest sets the digest schedule of a user
+func (bs *BotState) SetUserDigest(chatID int64, digest DigestSchedule) bool {
+	bs.mutex.Lock()
+	defer bs.mutex.Unlock()
+
+	if user, exists := bs.Users[chatID]; exists {
+		user.Digest = digest
+		bs.saveUser(chatID)
+		return true
+	}
+	return false
+}
+
+// GetUserDigest returns the digest schedule of a user
+func (bs *BotState) GetUserDigest(chatID int64) (DigestSchedule, bool) {
+	bs.mutex.Lock()
+	defer bs.mutex.Unlock()
+
+	if user, exists := bs.Users[chatID]; exists {
+		return user.Digest, true
+	}
+	return DigestSchedule{}, false
+}
+
+// MarkDigestSent records when the user's last digest was sent
+func (bs *BotState) MarkDigestSent(chatID int64, t time.Time) {
+	bs.mutex.Lock()
+	defer bs.mutex.Unlock()
+
+	if user, exists := bs.Users[chatID]; exists {
+		user.Digest.LastSent = t
+		bs.saveUser(chatID)
+	}
+}
+
+// QueueOffers adds offers to the user's pending offers, which are delivered later
+func (bs *BotState) QueueOffers(chatID int64, offers []RentalOffer) {
+	bs.mutex.Lock()
+	defer bs.mutex.Unlock()
+
+	user, exists := bs.Users[chatID]
+	if !exists || len(offers) == 0 {
+		return
+	}
+	queueOffers(user, offers)
+	bs.saveUser(chatID)
+}
+
+// queueOffers adds the offers that are not pending yet to the user's pending offers
+func queueOffers(user *UserState, offers []RentalOffer) {
+	queued := make(map[string]bool, len(user.PendingOffers))
+	for _, offer := range user.PendingOffers {
+		queued[cleanURL(offer.Link)] = true
+	}
+	for _, offer := range offers {
+		if link := cleanURL(offer.Link); !queued[link] {
+			user.PendingOffers = append(user.PendingOffers, offer)
+			queued[link] = true
+		}
+	}
+}
+
+// TakePendingOffers returns and clears the user's pending offers.
+// Offers that are no longer known are dropped.
+func (bs *BotState) TakePendingOffers(chatID int64) []RentalOffer {
+	bs.mutex.Lock()
+	defer bs.mutex.Unlock()
+
+	user, exists := bs.Users[chatID]
+	if !exists || len(user.PendingOffers) == 0 {
+		return nil
+	}
+
+	pending := make([]RentalOffer, 0, len(user.PendingOffers))
+	for _, offer := range user.PendingOffers {
+		if current, ok := bs.KnownOffers[cleanURL(offer.Link)]; ok {
+			pending = append(pending, current)
+		}
+	}
+	user.PendingOffers = nil
+	bs.saveUser(chatID)
+	return pending
 }
 
 // GetUserNotificationsEnabled returns whether a user has notifications enabled
@@ -363,6 +1388,34 @@ func (bs *BotState) GetUserNotificationsEnabled(chatID int64) bool {
 	return false
 }
 
+// UserStats holds the personal statistics of a user
+type UserStats struct {
+	SeenOffers    int
+	Favorites     int
+	Notifications bool
+	LastNotified  time.Time
+	KnownOffers   int
+}
+
+// GetUserStats returns the personal statistics of a user, read under the lock so
+// that they are consistent with each other
+func (bs *BotState) GetUserStats(chatID int64) (UserStats, bool) {
+	bs.mutex.Lock()
+	defer bs.mutex.Unlock()
+
+	user, exists := bs.Users[chatID]
+	if !exists {
+		return UserStats{}, false
+	}
+	return UserStats{
+		SeenOffers:    len(user.SeenOffers),
+		Favorites:     len(user.Favorites),
+		Notifications: user.Notifications,
+		LastNotified:  user.LastNotified,
+		KnownOffers:   len(bs.KnownOffers),
+	}, true
+}
+
 // GetAllUsers returns a copy of all users
 func (bs *BotState) GetAllUsers() map[int64]*UserState {
 	bs.mutex.Lock()
@@ -370,8 +1423,7 @@ func (bs *BotState) GetAllUsers() map[int64]*UserState {
 
 	users := make(map[int64]*UserState, len(bs.Users))
 	for k, v := range bs.Users {
-		userCopy := *v
-		users[k] = &userCopy
+		users[k] = copyUser(v)
 	}
 	return users
 }
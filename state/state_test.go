@@ -0,0 +1,659 @@
+package state
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// newTestState creates an empty bot state persisted in a temporary directory
+func newTestState(t *testing.T) *BotState {
+	t.Helper()
+	botState, err := NewBotState(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return botState
+}
+
+// testOffer creates an offer with the given link and price
+func testOffer(link, price string) RentalOffer {
+	value, _ := ParsePrice(price)
+	return RentalOffer{
+		Title:    "Testikatu 1",
+		Address:  "Testikatu 1, Helsinki",
+		Price:    price,
+		PriceEUR: value,
+		Size:     "40 m²",
+		SizeSqm:  40,
+		Rooms:    "2h+k",
+		Link:     link,
+	}
+}
+
+// links returns the links of the offers
+func links(offers []RentalOffer) []string {
+	result := make([]string, len(offers))
+	for i, offer := range offers {
+		result[i] = offer.Link
+	}
+	return result
+}
+
+func TestApplyOffersClassifiesNewOffers(t *testing.T) {
+	bs := newTestState(t)
+
+	result := bs.ApplyOffers([]RentalOffer{
+		testOffer("https://example.com/a?ref=1", "900 €/kk"),
+		testOffer("https://example.com/b", "1000 €/kk"),
+	})
+
+	if got := links(result.New); len(got) != 2 || got[0] != "https://example.com/a" || got[1] != "https://example.com/b" {
+		t.Fatalf("New = %v, want both offers with clean links", got)
+	}
+	if len(result.Changed) != 0 || len(result.Unchanged) != 0 || len(result.Relisted) != 0 {
+		t.Errorf("unexpected classification: %+v", result)
+	}
+	if len(bs.GetKnownOffers()) != 2 {
+		t.Errorf("known offers = %d, want 2", len(bs.GetKnownOffers()))
+	}
+}
+
+func TestApplyOffersClassifiesUnchangedOffers(t *testing.T) {
+	bs := newTestState(t)
+	offer := testOffer("https://example.com/a", "900 €/kk")
+	bs.ApplyOffers([]RentalOffer{offer})
+
+	result := bs.ApplyOffers([]RentalOffer{offer})
+
+	if got := links(result.Unchanged); len(got) != 1 || got[0] != offer.Link {
+		t.Fatalf("Unchanged = %v, want [%s]", got, offer.Link)
+	}
+	if len(result.New) != 0 || len(result.Changed) != 0 || len(result.Relisted) != 0 {
+		t.Errorf("unexpected classification: %+v", result)
+	}
+}
+
+func TestApplyOffersClassifiesChangedOffers(t *testing.T) {
+	bs := newTestState(t)
+	offer := testOffer("https://example.com/a", "900 €/kk")
+	bs.ApplyOffers([]RentalOffer{offer})
+
+	offer.Available = "Vapautuu 1.6.2024"
+	result := bs.ApplyOffers([]RentalOffer{offer})
+
+	if got := links(result.Changed); len(got) != 1 || got[0] != offer.Link {
+		t.Fatalf("Changed = %v, want [%s]", got, offer.Link)
+	}
+	if len(result.New) != 0 || len(result.Unchanged) != 0 || len(result.Relisted) != 0 {
+		t.Errorf("unexpected classification: %+v", result)
+	}
+	if got := bs.GetKnownOffers()[offer.Link].Available; got != offer.Available {
+		t.Errorf("stored Available = %q, want %q", got, offer.Available)
+	}
+}
+
+func TestApplyOffersClassifiesRelistedOffers(t *testing.T) {
+	bs := newTestState(t)
+	bs.SetDelistAfter(1)
+	offer := testOffer("https://example.com/a", "900 €/kk")
+	bs.ApplyOffers([]RentalOffer{offer})
+
+	if result := bs.ApplyOffers(nil); len(result.Removed) != 1 {
+		t.Fatalf("Removed = %v, want the offer to be delisted", links(result.Removed))
+	}
+
+	result := bs.ApplyOffers([]RentalOffer{offer})
+	if got := links(result.Relisted); len(got) != 1 || got[0] != offer.Link {
+		t.Fatalf("Relisted = %v, want [%s]", got, offer.Link)
+	}
+	if len(result.New) != 0 || len(result.Changed) != 0 || len(result.Unchanged) != 0 {
+		t.Errorf("unexpected classification: %+v", result)
+	}
+}
+
+func TestUpdateOffersReturnsNewAndRelistedOffers(t *testing.T) {
+	bs := newTestState(t)
+	bs.SetDelistAfter(1)
+	a := testOffer("https://example.com/a", "900 €/kk")
+	b := testOffer("https://example.com/b", "1000 €/kk")
+	b.Address = "Toinenkatu 2, Helsinki"
+
+	bs.UpdateOffers([]RentalOffer{a})
+	bs.UpdateOffers(nil)
+
+	got := links(bs.UpdateOffers([]RentalOffer{a, b}))
+	if len(got) != 2 || got[0] != b.Link || got[1] != a.Link {
+		t.Errorf("UpdateOffers = %v, want the new offer followed by the relisted one", got)
+	}
+}
+
+// TestLoadStateDuringUpdates reloads the state while it is being updated; run it
+// with -race to check LoadState holds the mutex
+func TestLoadStateDuringUpdates(t *testing.T) {
+	bs := newTestState(t)
+	bs.AddUser(&tgbotapi.User{FirstName: "Test"}, 1)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(3)
+		go func(i int) {
+			defer wg.Done()
+			link := fmt.Sprintf("https://example.com/%d", i)
+			bs.ApplyOffers([]RentalOffer{testOffer(link, "900 €/kk")})
+			bs.MarkOfferAsSeen(1, link)
+		}(i)
+		go func() {
+			defer wg.Done()
+			if err := bs.LoadState(); err != nil {
+				t.Errorf("LoadState: %v", err)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			bs.GetKnownOffers()
+			bs.GetUserNotifications(1)
+		}()
+	}
+	wg.Wait()
+
+	if _, exists := bs.GetUser(1); !exists {
+		t.Error("the user was lost while reloading")
+	}
+}
+
+func TestApplyOffersRecordsPriceChanges(t *testing.T) {
+	tests := []struct {
+		name     string
+		newPrice string
+		changed  bool
+	}{
+		{"price drop", "850 €/kk", true},
+		{"price rise", "950 €/kk", true},
+		{"no change", "900 €/kk", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bs := newTestState(t)
+			bs.ApplyOffers([]RentalOffer{testOffer("https://example.com/a", "900 €/kk")})
+
+			result := bs.ApplyOffers([]RentalOffer{testOffer("https://example.com/a", tt.newPrice)})
+			history := bs.GetKnownOffers()["https://example.com/a"].PriceHistory
+
+			if !tt.changed {
+				if len(result.PriceChanges) != 0 || len(history) != 1 {
+					t.Errorf("PriceChanges = %+v, history = %+v, want no change recorded", result.PriceChanges, history)
+				}
+				return
+			}
+			if len(result.PriceChanges) != 1 {
+				t.Fatalf("PriceChanges = %+v, want one change", result.PriceChanges)
+			}
+			if change := result.PriceChanges[0]; change.OldPrice != "900 €/kk" || change.NewPrice != tt.newPrice {
+				t.Errorf("change = %s -> %s, want 900 €/kk -> %s", change.OldPrice, change.NewPrice, tt.newPrice)
+			}
+			if len(history) != 2 || history[0].Price != "900 €/kk" || history[1].Price != tt.newPrice {
+				t.Errorf("history = %+v, want both prices", history)
+			}
+		})
+	}
+}
+
+func TestGetAllUsersReturnsIndependentCopies(t *testing.T) {
+	bs := newTestState(t)
+	bs.AddUser(&tgbotapi.User{FirstName: "Test"}, 1)
+	bs.ApplyOffers([]RentalOffer{testOffer("https://example.com/a", "900 €/kk")})
+	bs.MarkOfferAsSeen(1, "https://example.com/a")
+
+	users := bs.GetAllUsers()
+	bs.MarkOfferAsSeen(1, "https://example.com/b")
+
+	if !users[1].SeenOffers.Has("https://example.com/a") || users[1].SeenOffers.Has("https://example.com/b") {
+		t.Errorf("the copy follows later changes: %v", users[1].SeenOffers)
+	}
+}
+
+func TestApplyOffersDelistsAfterMissedUpdates(t *testing.T) {
+	bs := newTestState(t)
+	bs.SetDelistAfter(2)
+	bs.AddUser(&tgbotapi.User{FirstName: "Seen"}, 1)
+	bs.AddUser(&tgbotapi.User{FirstName: "Unseen"}, 2)
+	offer := testOffer("https://example.com/a", "900 €/kk")
+	bs.ApplyOffers([]RentalOffer{offer})
+	bs.MarkOfferAsSeen(1, offer.Link)
+
+	// Missing once and reappearing keeps the offer known
+	if result := bs.ApplyOffers(nil); len(result.Removed) != 0 {
+		t.Fatalf("Removed = %v after one missed update, want none", links(result.Removed))
+	}
+	if result := bs.ApplyOffers([]RentalOffer{offer}); len(result.Unchanged) != 1 {
+		t.Fatalf("the reappearing offer was not kept: %+v", result)
+	}
+
+	bs.ApplyOffers(nil)
+	result := bs.ApplyOffers(nil)
+	if got := links(result.Removed); len(got) != 1 || got[0] != offer.Link {
+		t.Fatalf("Removed = %v, want [%s]", got, offer.Link)
+	}
+	if seenBy := result.SeenBy[offer.Link]; len(seenBy) != 1 || seenBy[0] != 1 {
+		t.Errorf("SeenBy = %v, want only chat 1", seenBy)
+	}
+	if user, _ := bs.GetUser(1); user.SeenOffers.Has(offer.Link) {
+		t.Error("the delisted offer is still marked as seen")
+	}
+}
+
+func TestFavoritesAddRemoveList(t *testing.T) {
+	bs := newTestState(t)
+	bs.AddUser(&tgbotapi.User{FirstName: "Test"}, 1)
+	bs.ApplyOffers([]RentalOffer{
+		testOffer("https://example.com/asunto/101", "900 €/kk"),
+		testOffer("https://example.com/asunto/102", "1000 €/kk"),
+	})
+
+	if _, ok := bs.AddFavorite(1, "999"); ok {
+		t.Error("AddFavorite accepted an unknown offer ID")
+	}
+	if _, ok := bs.AddFavorite(2, "101"); ok {
+		t.Error("AddFavorite accepted an unknown user")
+	}
+	if offer, ok := bs.AddFavorite(1, "101"); !ok || offer.Link != "https://example.com/asunto/101" {
+		t.Fatalf("AddFavorite = %+v, %v, want offer 101", offer, ok)
+	}
+	bs.AddFavorite(1, "102")
+	if got := bs.GetFavorites(1); len(got) != 2 {
+		t.Fatalf("favorites = %v, want both offers", links(got))
+	}
+
+	if !bs.RemoveFavorite(1, "102") {
+		t.Error("RemoveFavorite did not find offer 102")
+	}
+	if bs.RemoveFavorite(1, "102") {
+		t.Error("RemoveFavorite removed offer 102 twice")
+	}
+	if got := links(bs.GetFavorites(1)); len(got) != 1 || got[0] != "https://example.com/asunto/101" {
+		t.Errorf("favorites = %v, want only offer 101", got)
+	}
+}
+
+func TestFavoritesKeepInsertionOrder(t *testing.T) {
+	bs := newTestState(t)
+	bs.AddUser(&tgbotapi.User{FirstName: "Test"}, 1)
+	var offers []RentalOffer
+	for _, id := range []string{"101", "102", "103"} {
+		offer := testOffer("https://example.com/asunto/"+id, "900 €/kk")
+		offer.Address = "Testikatu " + id + ", Helsinki"
+		offers = append(offers, offer)
+	}
+	bs.ApplyOffers(offers)
+
+	for _, id := range []string{"103", "101", "102", "101"} {
+		bs.AddFavorite(1, id)
+	}
+	bs.RemoveFavorite(1, "101")
+	bs.AddFavorite(1, "101")
+
+	want := []string{"https://example.com/asunto/103", "https://example.com/asunto/102", "https://example.com/asunto/101"}
+	if got := links(bs.GetFavorites(1)); !reflect.DeepEqual(got, want) {
+		t.Errorf("favorites = %v, want %v", got, want)
+	}
+}
+
+func TestResetKeepsFavorites(t *testing.T) {
+	bs := newTestState(t)
+	bs.SetDelistAfter(1)
+	bs.AddUser(&tgbotapi.User{FirstName: "Test"}, 1)
+	offer := testOffer("https://example.com/asunto/101", "900 €/kk")
+	bs.ApplyOffers([]RentalOffer{offer})
+	bs.MarkOfferAsSeen(1, offer.Link)
+	bs.AddFavorite(1, "101")
+
+	bs.ResetUserState(1)
+	if user, _ := bs.GetUser(1); len(user.SeenOffers) != 0 {
+		t.Errorf("seen offers after reset = %v, want none", user.SeenOffers)
+	}
+	if got := bs.GetFavorites(1); len(got) != 1 {
+		t.Fatalf("favorites after reset = %v, want offer 101", links(got))
+	}
+
+	// Favorites stay available after the offer is delisted
+	bs.ApplyOffers(nil)
+	if got := bs.GetFavorites(1); len(got) != 1 {
+		t.Errorf("favorites after delisting = %v, want offer 101", links(got))
+	}
+}
+
+func TestGetUserStats(t *testing.T) {
+	bs := newTestState(t)
+	bs.AddUser(&tgbotapi.User{FirstName: "Test"}, 1)
+	bs.ApplyOffers([]RentalOffer{testOffer("https://example.com/a", "900 €/kk"), testOffer("https://example.com/b", "950 €/kk")})
+	bs.MarkOfferAsSeen(1, "https://example.com/a")
+	bs.AddFavorite(1, OfferID("https://example.com/b"))
+	bs.SetUserNotifications(1, false)
+
+	stats, exists := bs.GetUserStats(1)
+	want := UserStats{SeenOffers: 1, Favorites: 1, Notifications: false, KnownOffers: 2}
+	if !exists || stats != want {
+		t.Errorf("GetUserStats = %+v, %v, want %+v", stats, exists, want)
+	}
+	if _, exists := bs.GetUserStats(2); exists {
+		t.Error("GetUserStats reported stats of an unknown user")
+	}
+}
+
+func TestApplyOffersCollapsesListingsDifferingOnlyBySlug(t *testing.T) {
+	bs := newTestState(t)
+	first := testOffer("https://www.vuokraovi.com/vuokra-asunto/helsinki/kallio/123", "900 €/kk")
+	second := testOffer("https://www.vuokraovi.com/vuokra-asunto/helsinki/sornainen/123", "900 €/kk")
+
+	result := bs.ApplyOffers([]RentalOffer{first, second})
+
+	if len(result.New) != 1 || result.New[0].Link != first.Link {
+		t.Fatalf("new offers = %v, want only the first link", links(result.New))
+	}
+	known := bs.GetKnownOffers()
+	if len(known) != 1 {
+		t.Fatalf("known offers = %d, want the duplicates collapsed into one", len(known))
+	}
+	if alternates := known[first.Link].AlternateLinks; len(alternates) != 1 || alternates[0] != second.Link {
+		t.Errorf("alternate links = %v, want the second slug", alternates)
+	}
+}
+
+func TestApplyOffersCollapsesDuplicateOfKnownOffer(t *testing.T) {
+	bs := newTestState(t)
+	first := testOffer("https://example.com/kallio/123", "900 €/kk")
+	second := testOffer("https://example.com/sornainen/123", "900 €/kk")
+	bs.ApplyOffers([]RentalOffer{first})
+
+	// Only the other slug is served now, which keeps the known offer listed
+	result := bs.ApplyOffers([]RentalOffer{second})
+
+	if len(result.New) != 0 || len(result.Unchanged) != 1 || result.Unchanged[0].Link != first.Link {
+		t.Errorf("result = new %v, unchanged %v, want the known offer unchanged", links(result.New), links(result.Unchanged))
+	}
+	if known := bs.GetKnownOffers(); len(known) != 1 || len(known[first.Link].AlternateLinks) != 1 {
+		t.Errorf("known offers = %v, want one offer with an alternate link", known)
+	}
+}
+
+func TestApplyOffersKeepsListingsWithDifferentContent(t *testing.T) {
+	bs := newTestState(t)
+	cheaper := testOffer("https://example.com/a", "900 €/kk")
+	pricier := testOffer("https://example.com/b", "950 €/kk")
+	noAddress := testOffer("https://example.com/c", "900 €/kk")
+	noAddress.Address = ""
+	alsoNoAddress := testOffer("https://example.com/d", "900 €/kk")
+	alsoNoAddress.Address = ""
+
+	result := bs.ApplyOffers([]RentalOffer{cheaper, pricier, noAddress, alsoNoAddress})
+
+	if len(result.New) != 4 {
+		t.Errorf("new offers = %v, want all four offers", links(result.New))
+	}
+}
+
+func TestCleanupOldOffersExpiresOnlyOldDelistedOffers(t *testing.T) {
+	dir := t.TempDir()
+	bs, err := NewBotState(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	maxAge := 90 * 24 * time.Hour
+	now := time.Now()
+	aged := func(link string, age time.Duration) RentalOffer {
+		offer := testOffer(link, "900 €/kk")
+		offer.Address = link
+		offer.FirstSeen = now.Add(-age)
+		return offer
+	}
+
+	bs.mutex.Lock()
+	bs.DelistedOffers["https://example.com/expired"] = aged("https://example.com/expired", maxAge+time.Hour)
+	bs.DelistedOffers["https://example.com/retained"] = aged("https://example.com/retained", maxAge-time.Hour)
+	bs.DelistedOffers["https://example.com/untracked"] = RentalOffer{Link: "https://example.com/untracked"}
+	missing := aged("https://example.com/missing", 2*maxAge)
+	missing.MissedUpdates = 1
+	bs.KnownOffers[missing.Link] = missing
+	bs.KnownOffers["https://example.com/listed"] = aged("https://example.com/listed", 2*maxAge)
+	bs.mutex.Unlock()
+
+	if removed := bs.CleanupOldOffers(maxAge); removed != 1 {
+		t.Errorf("removed %d offers, want only the expired delisted one", removed)
+	}
+	bs.Close()
+
+	reloaded, err := NewBotState(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := reloaded.DelistedOffers["https://example.com/expired"]; ok {
+		t.Error("the expired delisted offer is still stored")
+	}
+	for _, link := range []string{"https://example.com/retained", "https://example.com/untracked"} {
+		if _, ok := reloaded.DelistedOffers[link]; !ok {
+			t.Errorf("delisted offer %s was dropped", link)
+		}
+	}
+	if known := reloaded.GetKnownOffers(); len(known) != 2 {
+		t.Errorf("known offers = %d, want both old known offers kept", len(known))
+	}
+}
+
+func TestCleanupOldOffersDoesNotReannounceKnownOffers(t *testing.T) {
+	bs := newTestState(t)
+	offer := testOffer("https://example.com/a", "900 €/kk")
+	bs.ApplyOffers([]RentalOffer{offer})
+	// Missing from one update, but not yet delisted
+	bs.ApplyOffers(nil)
+
+	if removed := bs.CleanupOldOffers(0); removed != 0 {
+		t.Errorf("removed %d offers, want known offers kept", removed)
+	}
+	if result := bs.ApplyOffers([]RentalOffer{offer}); len(result.New) != 0 || len(result.Relisted) != 0 {
+		t.Errorf("the offer was announced again: new %v, relisted %v", links(result.New), links(result.Relisted))
+	}
+}
+
+func TestApplyOffersKeepsFirstSeen(t *testing.T) {
+	bs := newTestState(t)
+	offer := testOffer("https://example.com/a", "900 €/kk")
+	bs.ApplyOffers([]RentalOffer{offer})
+	firstSeen := bs.GetKnownOffers()[offer.Link].FirstSeen
+	if firstSeen.IsZero() {
+		t.Fatal("FirstSeen was not set for a new offer")
+	}
+
+	// Neither an unchanged nor a changed listing moves the first-seen time
+	time.Sleep(time.Millisecond)
+	bs.ApplyOffers([]RentalOffer{offer})
+	changed := testOffer("https://example.com/a", "850 €/kk")
+	changed.FirstSeen = time.Now()
+	bs.ApplyOffers([]RentalOffer{changed})
+
+	if got := bs.GetKnownOffers()[offer.Link].FirstSeen; !got.Equal(firstSeen) {
+		t.Errorf("FirstSeen = %v, want it kept at %v", got, firstSeen)
+	}
+}
+
+func TestPreviewOffersLeavesStateUnchanged(t *testing.T) {
+	bs := newTestState(t)
+	bs.ApplyOffers([]RentalOffer{testOffer("https://example.com/a", "900 €/kk")})
+
+	result := bs.PreviewOffers([]RentalOffer{testOffer("https://example.com/a", "850 €/kk"), testOffer("https://example.com/b", "800 €/kk")})
+
+	if len(result.PriceChanges) != 1 {
+		t.Errorf("price changes = %v, want the preview to report the change", result.PriceChanges)
+	}
+	known := bs.GetKnownOffers()
+	if len(known) != 1 || known["https://example.com/a"].Price != "900 €/kk" {
+		t.Errorf("known offers = %v, want them unchanged", known)
+	}
+}
+
+func TestExportImportRoundTrip(t *testing.T) {
+	bs := newTestState(t)
+	bs.SetDelistAfter(1)
+	bs.AddUser(&tgbotapi.User{UserName: "anna", FirstName: "Anna"}, 1)
+	bs.AddUser(&tgbotapi.User{FirstName: "Ben"}, 2)
+	bs.ApplyOffers([]RentalOffer{testOffer("https://example.com/a", "900 €/kk"), testOffer("https://example.com/b", "950 €/kk")})
+	bs.ApplyOffers([]RentalOffer{testOffer("https://example.com/a", "850 €/kk")})
+	bs.MarkOfferAsSeen(1, "https://example.com/a")
+	bs.AddFavorite(1, OfferID("https://example.com/a"))
+	bs.SetUserFilters(1, Filters{City: "Helsinki", MaxPrice: 1000})
+	bs.SaveProfile(2, "espoo", Filters{City: "Espoo"})
+	bs.SetUserLanguage(2, "fi")
+	bs.UpdateUserLastNotified(1, time.Date(2024, 5, 1, 9, 30, 0, 0, time.UTC))
+
+	data, err := bs.Export()
+	if err != nil {
+		t.Fatalf("Export: %v", err)
+	}
+
+	dir := t.TempDir()
+	restored, err := NewBotState(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	restored.AddUser(&tgbotapi.User{FirstName: "Replaced"}, 3)
+	if err := restored.Import(data); err != nil {
+		t.Fatalf("Import: %v", err)
+	}
+
+	// A second export of the imported state is identical to the first one
+	again, err := restored.Export()
+	if err != nil {
+		t.Fatalf("Export: %v", err)
+	}
+	if string(again) != string(data) {
+		t.Errorf("the imported state differs from the exported one:\n%s\nwant:\n%s", again, data)
+	}
+
+	// The import is persisted
+	restored.Close()
+	reloaded, err := NewBotState(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, exists := reloaded.GetUser(3); exists {
+		t.Error("the user replaced by the import was reloaded")
+	}
+	if user, _ := reloaded.GetUser(1); !user.SeenOffers.Has("https://example.com/a") || user.Filters.MaxPrice != 1000 || len(user.Favorites) != 1 {
+		t.Errorf("user 1 = %+v, want the seen offer, filters and favorite kept", user)
+	}
+	if profiles := reloaded.GetProfiles(2); len(profiles) != 1 || reloaded.GetUserLanguage(2) != "fi" {
+		t.Errorf("user 2 profiles = %v, language %q, want them kept", profiles, reloaded.GetUserLanguage(2))
+	}
+	known := reloaded.GetKnownOffers()
+	if len(known) != 1 || known["https://example.com/a"].Price != "850 €/kk" || len(known["https://example.com/a"].PriceHistory) != 2 {
+		t.Errorf("known offers = %v, want offer a with its price history", known)
+	}
+	if _, ok := reloaded.DelistedOffers["https://example.com/b"]; !ok {
+		t.Error("the delisted offer was not kept")
+	}
+}
+
+func TestImportRejectsInvalidData(t *testing.T) {
+	bs := newTestState(t)
+	bs.AddUser(&tgbotapi.User{FirstName: "Kept"}, 1)
+
+	for _, data := range []string{
+		"not json",
+		`{"version": 3}`,
+		`{"users": {"1": {"chat_id": 2}}}`,
+	} {
+		if err := bs.Import([]byte(data)); err == nil {
+			t.Errorf("Import(%s) accepted invalid data", data)
+		}
+	}
+	if _, exists := bs.GetUser(1); !exists {
+		t.Error("a rejected import changed the state")
+	}
+}
+
+func TestUndoLastNotificationQueuesListedOffers(t *testing.T) {
+	bs := newTestState(t)
+	bs.SetDelistAfter(1)
+	bs.AddUser(&tgbotapi.User{FirstName: "Test"}, 1)
+	a := testOffer("https://example.com/a", "900 €/kk")
+	b := testOffer("https://example.com/b", "1000 €/kk")
+	b.Address = "Toinenkatu 2, Helsinki"
+	bs.ApplyOffers([]RentalOffer{a, b})
+	bs.RecordDelivery(1, []string{a.Link + "?ref=1", b.Link}, time.Now())
+	// b is delisted before the undo, so only a can be sent again
+	bs.ApplyOffers([]RentalOffer{a})
+
+	if got := bs.UndoLastNotification(1); got != 1 {
+		t.Fatalf("UndoLastNotification = %d, want 1 offer queued", got)
+	}
+	user, _ := bs.GetUser(1)
+	if user.SeenOffers.Has(a.Link) || len(user.LastNotification) != 0 {
+		t.Errorf("user = %+v, want a unseen and the notification forgotten", user)
+	}
+	if got := links(bs.TakePendingOffers(1)); len(got) != 1 || got[0] != a.Link {
+		t.Errorf("pending offers = %v, want [%s]", got, a.Link)
+	}
+	if got := bs.UndoLastNotification(1); got != 0 {
+		t.Errorf("second UndoLastNotification = %d, want 0", got)
+	}
+}
+
+func TestUnreachableUsersAreRemoved(t *testing.T) {
+	bs := newTestState(t)
+	bs.AddUser(&tgbotapi.User{FirstName: "Blocked"}, 1)
+	bs.AddUser(&tgbotapi.User{FirstName: "Back"}, 2)
+	bs.UpdateUserLastNotified(1, time.Now())
+	bs.UpdateUserLastNotified(2, time.Now())
+	bs.MarkUserUnreachable(1)
+	bs.MarkUserUnreachable(2)
+	// Writing to the bot again clears the flag
+	bs.AddUser(&tgbotapi.User{FirstName: "Back"}, 2)
+
+	if err := bs.CleanupInactiveUsers(); err != nil {
+		t.Fatal(err)
+	}
+	if _, exists := bs.GetUser(1); exists {
+		t.Error("the unreachable user was not removed")
+	}
+	if user, exists := bs.GetUser(2); !exists || user.Unreachable {
+		t.Errorf("user 2 = %+v, want the returning user kept", user)
+	}
+
+	reloaded, err := NewBotStateWithStore(bs.store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, exists := reloaded.GetUser(1); exists {
+		t.Error("the unreachable user is still stored")
+	}
+}
+
+func TestConversationIsPersisted(t *testing.T) {
+	dir := t.TempDir()
+	bs, err := NewBotState(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bs.AddUser(&tgbotapi.User{FirstName: "Test"}, 1)
+	bs.SetUserConversation(1, &Conversation{Name: "onboarding", Step: "price", Filters: Filters{City: "Espoo"}})
+	bs.Close()
+
+	reloaded, err := NewBotState(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	user, _ := reloaded.GetUser(1)
+	if user.Conversation == nil || user.Conversation.Step != "price" || user.Conversation.Filters.City != "Espoo" {
+		t.Fatalf("conversation after reload = %+v", user.Conversation)
+	}
+
+	reloaded.SetUserConversation(1, nil)
+	if user, _ := reloaded.GetUser(1); user.Conversation != nil {
+		t.Errorf("conversation not ended: %+v", user.Conversation)
+	}
+}
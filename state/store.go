@@ -0,0 +1,231 @@
+package state
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Snapshot is the persisted form of the bot state
+type Snapshot struct {
+	Version        int                    `json:"version"`
+	Users          map[int64]*UserState   `json:"users"`
+	KnownOffers    map[string]RentalOffer `json:"known_offers"`
+	DelistedOffers map[string]RentalOffer `json:"delisted_offers"`
+	LastUpdated    time.Time              `json:"last_updated"`
+}
+
+// Store persists snapshots of the bot state
+type Store interface {
+	// Load returns the stored snapshot, or nil if nothing has been stored yet
+	Load() (*Snapshot, error)
+	// Save replaces the stored state with the snapshot
+	Save(snapshot *Snapshot) error
+	// SaveUser stores a single user, replacing its previous version
+	SaveUser(user *UserState) error
+	// DeleteUser removes a single user
+	DeleteUser(chatID int64) error
+	// SaveOffers replaces the stored known and delisted offers
+	SaveOffers(known, delisted map[string]RentalOffer, lastUpdated time.Time) error
+	// Close releases the resources held by the store
+	Close() error
+}
+
+// JSONStore stores the bot state in a single JSON file. The file can only be
+// written as a whole, so the store keeps its own copy of the stored state to
+// apply single user and offer updates to.
+type JSONStore struct {
+	dir      string
+	mutex    sync.Mutex
+	snapshot *Snapshot
+}
+
+// NewJSONStore creates a store writing bot_state.json into dir
+func NewJSONStore(dir string) *JSONStore {
+	return &JSONStore{dir: dir}
+}
+
+// path returns the location of the state file
+func (s *JSONStore) path() string {
+	return filepath.Join(s.dir, "bot_state.json")
+}
+
+// Load reads the state file
+func (s *JSONStore) Load() (*Snapshot, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	snapshot, err := s.read()
+	if err != nil || snapshot == nil {
+		return nil, err
+	}
+
+	// Keep a separate copy, so the caller may modify the returned snapshot
+	s.snapshot, err = s.read()
+	if err != nil {
+		return nil, err
+	}
+	return snapshot, nil
+}
+
+// read parses the state file
+func (s *JSONStore) read() (*Snapshot, error) {
+	data, err := os.ReadFile(s.path())
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read bot state file: %w", err)
+	}
+
+	var snapshot Snapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal bot state: %w", err)
+	}
+	return &snapshot, nil
+}
+
+// Save writes the state file
+func (s *JSONStore) Save(snapshot *Snapshot) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if err := s.write(snapshot); err != nil {
+		return err
+	}
+	s.snapshot = snapshot
+	return nil
+}
+
+// SaveUser writes the state file with the user replaced
+func (s *JSONStore) SaveUser(user *UserState) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if err := s.cached(); err != nil {
+		return err
+	}
+	s.snapshot.Users[user.ChatID] = user
+	return s.write(s.snapshot)
+}
+
+// DeleteUser writes the state file without the user
+func (s *JSONStore) DeleteUser(chatID int64) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if err := s.cached(); err != nil {
+		return err
+	}
+	delete(s.snapshot.Users, chatID)
+	return s.write(s.snapshot)
+}
+
+// SaveOffers writes the state file with the offers replaced
+func (s *JSONStore) SaveOffers(known, delisted map[string]RentalOffer, lastUpdated time.Time) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if err := s.cached(); err != nil {
+		return err
+	}
+	s.snapshot.KnownOffers = known
+	s.snapshot.DelistedOffers = delisted
+	s.snapshot.LastUpdated = lastUpdated
+	return s.write(s.snapshot)
+}
+
+// cached makes sure the stored state is in memory, reading the state file if it
+// has not been loaded yet; the caller must hold the mutex
+func (s *JSONStore) cached() error {
+	if s.snapshot == nil {
+		snapshot, err := s.read()
+		if err != nil {
+			return err
+		}
+		if snapshot == nil {
+			snapshot = &Snapshot{}
+		}
+		s.snapshot = snapshot
+	}
+
+	s.snapshot.Version = CurrentStateVersion
+	if s.snapshot.Users == nil {
+		s.snapshot.Users = make(map[int64]*UserState)
+	}
+	return nil
+}
+
+// write marshals the snapshot into the state file; the caller must hold the mutex
+func (s *JSONStore) write(snapshot *Snapshot) error {
+	if err := os.MkdirAll(s.dir, 0755); err != nil {
+		return fmt.Errorf("failed to create data directory: %w", err)
+	}
+
+	data, err := json.MarshalIndent(snapshot, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal bot state: %w", err)
+	}
+
+	if err := writeFileAtomic(s.path(), 0644, writeBytes(data)); err != nil {
+		return fmt.Errorf("failed to write bot state file: %w", err)
+	}
+
+	return nil
+}
+
+// writeBytes returns a write function writing data
+func writeBytes(data []byte) func(io.Writer) error {
+	return func(w io.Writer) error {
+		_, err := w.Write(data)
+		return err
+	}
+}
+
+// writeFileAtomic lets write fill a temporary file in the same directory, syncs it
+// and renames it over path, so path never holds a partially written file
+func writeFileAtomic(path string, perm os.FileMode, write func(io.Writer) error) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	tmpName := tmp.Name()
+
+	// Remove the temporary file unless it has been renamed into place
+	committed := false
+	defer func() {
+		if !committed {
+			os.Remove(tmpName)
+		}
+	}()
+
+	if err := write(tmp); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmpName, perm); err != nil {
+		return err
+	}
+	if err := os.Rename(tmpName, path); err != nil {
+		return err
+	}
+
+	committed = true
+	return nil
+}
+
+// Close is a no-op for the JSON store
+func (s *JSONStore) Close() error {
+	return nil
+}
@@ -0,0 +1,165 @@
+package state
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// testStores opens every store implementation in dir; opening the same dir again
+// returns a store reading what the previous one wrote
+var testStores = map[string]func(t *testing.T, dir string) Store{
+	"json": func(t *testing.T, dir string) Store {
+		return NewJSONStore(dir)
+	},
+	"sqlite": func(t *testing.T, dir string) Store {
+		store, err := NewSQLiteStore(filepath.Join(dir, "bot_state.db"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return store
+	},
+}
+
+// openState creates a bot state backed by a store opened in dir
+func openState(t *testing.T, open func(*testing.T, string) Store, dir string) (*BotState, Store) {
+	t.Helper()
+	store := open(t, dir)
+	botState, err := NewBotStateWithStore(store)
+	if err != nil {
+		t.Fatalf("NewBotStateWithStore: %v", err)
+	}
+	return botState, store
+}
+
+func TestStoresPersistUsersAndOffers(t *testing.T) {
+	for name, open := range testStores {
+		t.Run(name, func(t *testing.T) {
+			dir := t.TempDir()
+			botState, store := openState(t, open, dir)
+			botState.AddUser(&tgbotapi.User{FirstName: "Anna"}, 1)
+			botState.AddUser(&tgbotapi.User{FirstName: "Ben"}, 2)
+			botState.ApplyOffers([]RentalOffer{testOffer("https://example.com/1", "800 €/kk"), testOffer("https://example.com/2", "900 €/kk")})
+			botState.SetUserFilters(1, Filters{MaxPrice: 850})
+			botState.MarkOfferAsSeen(1, "https://example.com/1?ref=list")
+			botState.AddFavorite(2, "2")
+			store.Close()
+
+			reloaded, store := openState(t, open, dir)
+			defer store.Close()
+			if got := len(reloaded.GetKnownOffers()); got != 2 {
+				t.Errorf("known offers = %d, want 2", got)
+			}
+			if filters, _ := reloaded.GetUserFilters(1); filters.MaxPrice != 850 {
+				t.Errorf("filters = %+v, want the max price of user 1", filters)
+			}
+			if user, _ := reloaded.GetUser(1); !user.SeenOffers.Has("https://example.com/1") || user.FirstName != "Anna" {
+				t.Errorf("user 1 = %+v, want Anna having seen offer 1", user)
+			}
+			if favorites := reloaded.GetFavorites(2); len(favorites) != 1 || favorites[0].Link != "https://example.com/2" {
+				t.Errorf("favorites of user 2 = %v, want offer 2", favorites)
+			}
+		})
+	}
+}
+
+func TestStoresPersistDelistedOffersAndRemovedUsers(t *testing.T) {
+	for name, open := range testStores {
+		t.Run(name, func(t *testing.T) {
+			dir := t.TempDir()
+			botState, store := openState(t, open, dir)
+			botState.SetDelistAfter(1)
+			botState.AddUser(&tgbotapi.User{FirstName: "Active"}, 1)
+			botState.AddUser(&tgbotapi.User{FirstName: "Inactive"}, 2)
+			botState.UpdateUserLastNotified(1, time.Now())
+			botState.ApplyOffers([]RentalOffer{testOffer("https://example.com/1", "800 €/kk"), testOffer("https://example.com/2", "900 €/kk")})
+			botState.MarkOfferAsSeen(1, "https://example.com/2")
+			botState.ApplyOffers([]RentalOffer{testOffer("https://example.com/1", "800 €/kk")})
+			if err := botState.CleanupInactiveUsers(); err != nil {
+				t.Fatalf("CleanupInactiveUsers: %v", err)
+			}
+			store.Close()
+
+			reloaded, store := openState(t, open, dir)
+			defer store.Close()
+			if _, exists := reloaded.GetUser(2); exists {
+				t.Error("the inactive user was not removed from the store")
+			}
+			if user, exists := reloaded.GetUser(1); !exists || len(user.SeenOffers) != 0 {
+				t.Errorf("user 1 = %+v, want no seen offers after offer 2 was delisted", user)
+			}
+			if _, known := reloaded.GetKnownOffers()["https://example.com/2"]; known {
+				t.Error("the delisted offer is still known")
+			}
+			if _, delisted := reloaded.DelistedOffers["https://example.com/2"]; !delisted {
+				t.Error("the delisted offer was not stored")
+			}
+		})
+	}
+}
+
+func TestStoresReplaceWholeStateOnImport(t *testing.T) {
+	for name, open := range testStores {
+		t.Run(name, func(t *testing.T) {
+			dir := t.TempDir()
+			botState, store := openState(t, open, dir)
+			botState.AddUser(&tgbotapi.User{FirstName: "Old"}, 1)
+			botState.ApplyOffers([]RentalOffer{testOffer("https://example.com/1", "800 €/kk")})
+
+			data, _ := json.Marshal(&Snapshot{
+				Version:     CurrentStateVersion,
+				Users:       map[int64]*UserState{2: {ChatID: 2, FirstName: "New"}},
+				KnownOffers: map[string]RentalOffer{"https://example.com/2": testOffer("https://example.com/2", "900 €/kk")},
+			})
+			if err := botState.Import(data); err != nil {
+				t.Fatalf("Import: %v", err)
+			}
+			// A single user write after the import must not bring back the old state
+			botState.SetUserLanguage(2, "fi")
+			store.Close()
+
+			reloaded, store := openState(t, open, dir)
+			defer store.Close()
+			if _, exists := reloaded.GetUser(1); exists {
+				t.Error("the user replaced by the import is still stored")
+			}
+			if reloaded.GetUserLanguage(2) != "fi" {
+				t.Error("the imported user was not updated")
+			}
+			if offers := reloaded.GetKnownOffers(); len(offers) != 1 {
+				t.Errorf("known offers = %v, want only the imported one", offers)
+			}
+		})
+	}
+}
+
+func TestWriteFileAtomicKeepsPreviousFileOnFailedWrite(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "bot_state.json")
+	if err := writeFileAtomic(path, 0644, writeBytes([]byte(`{"version": 1}`))); err != nil {
+		t.Fatalf("writeFileAtomic: %v", err)
+	}
+
+	// The writer dies halfway through, like a process killed mid-write
+	failing := func(w io.Writer) error {
+		w.Write([]byte(`{"vers`))
+		return errors.New("disk full")
+	}
+	if err := writeFileAtomic(path, 0644, failing); err == nil {
+		t.Fatal("writeFileAtomic reported success for a failed write")
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil || string(data) != `{"version": 1}` {
+		t.Errorf("state file = %q (%v), want the previous contents", data, err)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Errorf("temporary file left behind: %v", entries)
+	}
+}
@@ -0,0 +1,50 @@
+package state
+
+import "time"
+
+// NotifySuppressWindow is how long an offer sent to a user is not sent to them again.
+// It covers updates that run close together, before the first delivery is saved.
+const NotifySuppressWindow = 10 * time.Minute
+
+// ClaimNotification returns the offers that were not claimed for the user within
+// NotifySuppressWindow and claims them, so an update running at the same time
+// does not send them again. Claims are kept in memory only.
+func (bs *BotState) ClaimNotification(chatID int64, offers []RentalOffer, now time.Time) []RentalOffer {
+	bs.mutex.Lock()
+	defer bs.mutex.Unlock()
+
+	if bs.notifying == nil {
+		bs.notifying = make(map[int64]map[string]time.Time)
+	}
+	claimed := bs.notifying[chatID]
+	if claimed == nil {
+		claimed = make(map[string]time.Time)
+		bs.notifying[chatID] = claimed
+	}
+	for link, at := range claimed {
+		if now.Sub(at) >= NotifySuppressWindow {
+			delete(claimed, link)
+		}
+	}
+
+	fresh := make([]RentalOffer, 0, len(offers))
+	for _, offer := range offers {
+		if _, ok := claimed[offer.Link]; ok {
+			continue
+		}
+		claimed[offer.Link] = now
+		fresh = append(fresh, offer)
+	}
+	return fresh
+}
+
+// ReleaseNotification drops the claims of offers that were not sent after all, so
+// the next update may send them
+func (bs *BotState) ReleaseNotification(chatID int64, offers []RentalOffer) {
+	bs.mutex.Lock()
+	defer bs.mutex.Unlock()
+
+	for _, offer := range offers {
+		delete(bs.notifying[chatID], offer.Link)
+	}
+}
@@ -0,0 +1,34 @@
+package state
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestClaimNotificationSuppressesRecentOffers(t *testing.T) {
+	bs := newTestState(t)
+	now := time.Date(2024, 5, 10, 12, 0, 0, 0, time.UTC)
+	a := testOffer("https://example.com/a", "900 €/kk")
+	b := testOffer("https://example.com/b", "900 €/kk")
+	c := testOffer("https://example.com/c", "900 €/kk")
+
+	if got := links(bs.ClaimNotification(1, []RentalOffer{a, b}, now)); !reflect.DeepEqual(got, []string{a.Link, b.Link}) {
+		t.Fatalf("first claim = %v, want both offers", got)
+	}
+	if got := links(bs.ClaimNotification(1, []RentalOffer{b, c}, now.Add(time.Minute))); !reflect.DeepEqual(got, []string{c.Link}) {
+		t.Errorf("overlapping claim = %v, want only the new offer", got)
+	}
+	if got := bs.ClaimNotification(2, []RentalOffer{a}, now); len(got) != 1 {
+		t.Errorf("claims of another user suppressed the offer for user 2")
+	}
+
+	bs.ReleaseNotification(1, []RentalOffer{b})
+	if got := links(bs.ClaimNotification(1, []RentalOffer{a, b}, now.Add(2*time.Minute))); !reflect.DeepEqual(got, []string{b.Link}) {
+		t.Errorf("claim after release = %v, want the released offer", got)
+	}
+
+	if got := bs.ClaimNotification(1, []RentalOffer{a}, now.Add(NotifySuppressWindow)); len(got) != 1 {
+		t.Errorf("offer still suppressed after the window")
+	}
+}
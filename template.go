@@ -0,0 +1,88 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"os"
+	"text/template"
+
+	"github.com/aqaliarept/vuokraovi-bot/state"
+)
+
+// defaultNotificationTemplate renders an offer of a new offers notification
+const defaultNotificationTemplate = `*{{.Title}}*
+📍 {{.Address}}
+💰 {{.Price}}
+🛏 {{.Rooms}}
+📐 {{.Size}}
+{{if .Available}}📅 {{.Available}}
+{{end}}{{if .Tags}}{{.Tags}}
+{{end}}🔗 [{{.ViewDetails}}]({{.Link}})
+
+`
+
+var (
+	// defaultNotification is the parsed default notification template
+	defaultNotification = template.Must(parseNotificationTemplate(defaultNotificationTemplate))
+	// notificationTemplate renders each offer of the new offers notifications
+	notificationTemplate = defaultNotification
+)
+
+// notificationData is passed to the notification template. The offer's text fields
+// are already escaped for MarkdownV2, so templates may use them as they are.
+type notificationData struct {
+	state.RentalOffer
+	ID          string // offer ID used by /fav, /map and /offer
+	Tags        string // names of the user's profiles matching the offer
+	ViewDetails string // translated label of the details link
+}
+
+// parseNotificationTemplate parses a notification template and checks that it
+// renders an offer, so unknown fields are reported at startup
+func parseNotificationTemplate(text string) (*template.Template, error) {
+	tmpl, err := template.New("notification").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid notification template: %w", err)
+	}
+	if err := tmpl.Execute(new(bytes.Buffer), notificationData{}); err != nil {
+		return nil, fmt.Errorf("invalid notification template: %w", err)
+	}
+	return tmpl, nil
+}
+
+// loadNotificationTemplate reads a notification template from a file
+func loadNotificationTemplate(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("error reading notification template: %w", err)
+	}
+	return string(data), nil
+}
+
+// renderNotification renders an offer of a notification with the template, falling
+// back to the default layout when the template fails
+func renderNotification(tmpl *template.Template, offer state.RentalOffer, tags, lang string) string {
+	escaped := offer
+	escaped.Title = escapeMarkdownV2(offer.Title)
+	escaped.Address = escapeMarkdownV2(offer.Address)
+	escaped.Price = escapeMarkdownV2(offer.Price)
+	escaped.Rooms = escapeMarkdownV2(offer.Rooms)
+	escaped.Size = escapeMarkdownV2(offer.Size)
+	escaped.Available = escapeMarkdownV2(offer.Available)
+	escaped.Link = escapeMarkdownV2URL(offer.Link)
+	data := notificationData{
+		RentalOffer: escaped,
+		ID:          escapeMarkdownV2(state.OfferID(offer.Link)),
+		Tags:        escapeMarkdownV2(tags),
+		ViewDetails: escapeMarkdownV2(translate(lang, "view_details")),
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		log.Printf("Error rendering the notification of %s, using the default layout: %v", offer.Link, err)
+		buf.Reset()
+		defaultNotification.Execute(&buf, data)
+	}
+	return buf.String()
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/aqaliarept/vuokraovi-bot/state"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+func TestDefaultNotificationTemplate(t *testing.T) {
+	offer := testOffer("https://example.com/vuokra-asunto/helsinki/kallio/kerrostalo/123", "900 €/kk")
+	offer.Title = "Katu 1 (A)"
+	offer.Available = "1.6.2024"
+
+	got := renderNotification(defaultNotification, offer, "🏷 koti", "en")
+	want := "*Katu 1 \\(A\\)*\n" +
+		"📍 " + escapeMarkdownV2(offer.Address) + "\n" +
+		"💰 900 €/kk\n" +
+		"🛏 " + escapeMarkdownV2(offer.Rooms) + "\n" +
+		"📐 " + offer.Size + "\n" +
+		"📅 1\\.6\\.2024\n" +
+		"🏷 koti\n" +
+		"🔗 [View Details](" + offer.Link + ")\n\n"
+	if got != want {
+		t.Errorf("rendered %q, want %q", got, want)
+	}
+
+	offer.Available = ""
+	if got := renderNotification(defaultNotification, offer, "", "en"); strings.Contains(got, "📅") || strings.Contains(got, "🏷") {
+		t.Errorf("rendered %q, want no availability or tag lines", got)
+	}
+}
+
+func TestCustomNotificationTemplate(t *testing.T) {
+	tmpl, err := parseNotificationTemplate("{{.Price}} | {{.Title}} | {{.ID}}\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	previous := notificationTemplate
+	notificationTemplate = tmpl
+	defer func() { notificationTemplate = previous }()
+
+	bot, fake := newFakeTelegram(t)
+	botState := newTestBotState(t)
+	botState.AddUser(&tgbotapi.User{FirstName: "Test"}, 1)
+	offer := testOffer("https://example.com/vuokra-asunto/helsinki/kallio/kerrostalo/123", "900 €/kk")
+	offer.Title = "Katu_1"
+	botState.ApplyOffers([]state.RentalOffer{offer})
+
+	notifyUsers(bot, botState, []state.RentalOffer{offer})
+
+	sent := fake.calls("sendMessage")
+	if len(sent) != 1 || !strings.HasSuffix(sent[0].Get("text"), "900 €/kk | Katu\\_1 | 123\n") {
+		t.Errorf("sent %v, want the offer rendered with the custom template", sent)
+	}
+}
+
+func TestParseNotificationTemplateRejectsInvalidTemplates(t *testing.T) {
+	for _, text := range []string{"{{.Title", "{{.Unknown}}", "{{if .Title}}"} {
+		if _, err := parseNotificationTemplate(text); err == nil {
+			t.Errorf("%q was accepted", text)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"sort"
+
+	"github.com/aqaliarept/vuokraovi-bot/state"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// topCount is the number of cheapest and of most expensive offers /top lists
+const topCount = 3
+
+// topOffers returns the cheapest offers, cheapest first, and the most expensive of
+// the rest, most expensive first. Offers without a known price are skipped, and no
+// offer is listed twice when there are few of them.
+func topOffers(offers []state.RentalOffer, count int) ([]state.RentalOffer, []state.RentalOffer) {
+	priced := make([]state.RentalOffer, 0, len(offers))
+	for _, offer := range offers {
+		if offer.PriceEUR > 0 {
+			priced = append(priced, offer)
+		}
+	}
+	sort.Slice(priced, func(i, j int) bool {
+		if priced[i].PriceEUR != priced[j].PriceEUR {
+			return priced[i].PriceEUR < priced[j].PriceEUR
+		}
+		return priced[i].Link < priced[j].Link
+	})
+
+	cheapest := priced
+	if len(cheapest) > count {
+		cheapest = cheapest[:count]
+	}
+	rest := priced[len(cheapest):]
+	expensive := make([]state.RentalOffer, 0, count)
+	for i := len(rest) - 1; i >= 0 && len(expensive) < count; i-- {
+		expensive = append(expensive, rest[i])
+	}
+	return cheapest, expensive
+}
+
+// handleTopCommand handles the /top command, listing the cheapest and the most
+// expensive current offers matching the user's filters
+func handleTopCommand(bot *tgbotapi.BotAPI, botState *state.BotState, message *tgbotapi.Message) {
+	chatID := message.Chat.ID
+	lang := userLanguage(botState, chatID)
+
+	known := botState.GetKnownOffers()
+	offers := make([]state.RentalOffer, 0, len(known))
+	for _, offer := range known {
+		offers = append(offers, offer)
+	}
+	cheapest, expensive := topOffers(matchingOffers(botState, chatID, offers), topCount)
+	if len(cheapest) == 0 {
+		msg := tgbotapi.NewMessage(chatID, translate(lang, "top_none"))
+		msg.ReplyMarkup = createMainKeyboard(lang)
+		sendMessage(bot, msg)
+		return
+	}
+
+	sendMessage(bot, tgbotapi.NewMessage(chatID, translate(lang, "top_cheapest", len(cheapest))))
+	sendOffersList(bot, cheapest, chatID, lang)
+	if len(expensive) > 0 {
+		sendMessage(bot, tgbotapi.NewMessage(chatID, translate(lang, "top_expensive", len(expensive))))
+		sendOffersList(bot, expensive, chatID, lang)
+	}
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/aqaliarept/vuokraovi-bot/state"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// pricedOffers creates offers with distinct addresses and the given prices
+func pricedOffers(prices map[string]string) []state.RentalOffer {
+	var offers []state.RentalOffer
+	for link, price := range prices {
+		offer := testOffer("https://example.com/"+link, price)
+		offer.Address = "Testikatu " + link + ", Helsinki"
+		offers = append(offers, offer)
+	}
+	return offers
+}
+
+func TestTopOffersPicksCheapestAndMostExpensive(t *testing.T) {
+	offers := pricedOffers(map[string]string{
+		"a": "700 €/kk", "b": "1 500 €/kk", "c": "850 €/kk", "d": "1 200 €/kk",
+		"e": "990 €/kk", "f": "2 100 €/kk", "g": "650 €/kk", "h": "1 000 €/kk",
+		"unknown": "Kysy hintaa",
+	})
+
+	cheapest, expensive := topOffers(offers, 3)
+	link := func(id string) string { return "https://example.com/" + id }
+	if got, want := linksOf(cheapest), []string{link("g"), link("a"), link("c")}; !reflect.DeepEqual(got, want) {
+		t.Errorf("cheapest = %v, want %v", got, want)
+	}
+	if got, want := linksOf(expensive), []string{link("f"), link("b"), link("d")}; !reflect.DeepEqual(got, want) {
+		t.Errorf("most expensive = %v, want %v", got, want)
+	}
+}
+
+func TestTopOffersDoesNotRepeatFewOffers(t *testing.T) {
+	offers := pricedOffers(map[string]string{"a": "700 €/kk", "b": "800 €/kk", "c": "900 €/kk", "d": "1 000 €/kk"})
+
+	cheapest, expensive := topOffers(offers, 3)
+	if len(cheapest) != 3 || len(expensive) != 1 || expensive[0].Link != "https://example.com/d" {
+		t.Errorf("got %v and %v, want the three cheapest and then only offer d", linksOf(cheapest), linksOf(expensive))
+	}
+}
+
+func TestTopCommandUsesFilters(t *testing.T) {
+	bot, fake := newFakeTelegram(t)
+	botState := newTestBotState(t)
+	botState.AddUser(&tgbotapi.User{FirstName: "Test"}, 1)
+	botState.SetUserFilters(1, state.Filters{MaxPrice: 1000})
+	botState.ApplyOffers(pricedOffers(map[string]string{"cheap": "700 €/kk", "dear": "1 800 €/kk"}))
+
+	handleMessage(bot, botState, userMessage(1, "/top"), BotConfig{})
+
+	var text string
+	for _, values := range fake.calls("sendMessage") {
+		text += values.Get("text") + "\n"
+	}
+	if !strings.Contains(text, "https://example.com/cheap") || strings.Contains(text, "https://example.com/dear") {
+		t.Errorf("/top ignored the filters:\n%s", text)
+	}
+}
+
+// linksOf returns the links of the offers
+func linksOf(offers []state.RentalOffer) []string {
+	links := make([]string, len(offers))
+	for i, offer := range offers {
+		links[i] = offer.Link
+	}
+	return links
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"sort"
+
+	"github.com/aqaliarept/vuokraovi-bot/state"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// waitingOffers returns the known offers matching the user's filters or active
+// profiles that the user has not seen yet, newest first
+func waitingOffers(botState *state.BotState, user *state.UserState) []state.RentalOffer {
+	known := botState.GetKnownOffers()
+	offers := make([]state.RentalOffer, 0, len(known))
+	for _, offer := range known {
+		offers = append(offers, offer)
+	}
+	offers = unseenOffers(user, matchingOffers(botState, user.ChatID, offers))
+	sort.Slice(offers, func(i, j int) bool {
+		if !offers[i].FirstSeen.Equal(offers[j].FirstSeen) {
+			return offers[i].FirstSeen.After(offers[j].FirstSeen)
+		}
+		return offers[i].Link < offers[j].Link
+	})
+	return offers
+}
+
+// handleUnseenCommand handles the /unseen command, listing the matching offers the
+// user has not seen without marking them seen
+func handleUnseenCommand(bot *tgbotapi.BotAPI, botState *state.BotState, message *tgbotapi.Message) {
+	chatID := message.Chat.ID
+	lang := userLanguage(botState, chatID)
+
+	user, exists := botState.GetUser(chatID)
+	if !exists {
+		return
+	}
+
+	offers := waitingOffers(botState, user)
+	if len(offers) == 0 {
+		msg := tgbotapi.NewMessage(chatID, translate(lang, "unseen_none"))
+		msg.ReplyMarkup = createMainKeyboard(lang)
+		sendMessage(bot, msg)
+		return
+	}
+
+	sendMessage(bot, tgbotapi.NewMessage(chatID, translate(lang, "unseen_found", len(offers))))
+	sendOffersList(bot, offers, chatID, lang)
+}
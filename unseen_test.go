@@ -0,0 +1,70 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/aqaliarept/vuokraovi-bot/state"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+func TestUnseenListsMatchingOffersNotSeen(t *testing.T) {
+	bot, fake := newFakeTelegram(t)
+	botState := newTestBotState(t)
+	botState.AddUser(&tgbotapi.User{FirstName: "Test"}, 1)
+	botState.SetUserFilters(1, state.Filters{MaxPrice: 1000})
+
+	offer := func(link, address, price string) state.RentalOffer {
+		o := testOffer("https://example.com/"+link, price)
+		o.Address = address
+		return o
+	}
+	botState.ApplyOffers([]state.RentalOffer{
+		offer("seen-match", "Katu 1, Helsinki", "800 €/kk"),
+		offer("unseen-match", "Katu 2, Helsinki", "900 €/kk"),
+		offer("seen-nomatch", "Katu 3, Helsinki", "1500 €/kk"),
+		offer("unseen-nomatch", "Katu 4, Helsinki", "1600 €/kk"),
+	})
+	botState.MarkOfferAsSeen(1, "https://example.com/seen-match")
+	botState.MarkOfferAsSeen(1, "https://example.com/seen-nomatch")
+
+	handleMessage(bot, botState, userMessage(1, "/unseen"), BotConfig{})
+
+	var texts []string
+	for _, values := range fake.calls("sendMessage") {
+		texts = append(texts, values["text"][0])
+	}
+	all := strings.Join(texts, "\n")
+	if !strings.Contains(all, translate("en", "unseen_found", 1)) {
+		t.Errorf("missing the count of unseen offers:\n%s", all)
+	}
+	if !strings.Contains(all, "https://example.com/unseen-match") {
+		t.Errorf("the unseen matching offer was not listed:\n%s", all)
+	}
+	for _, link := range []string{"seen-match", "seen-nomatch", "unseen-nomatch"} {
+		if strings.Contains(all, "https://example.com/"+link) {
+			t.Errorf("%s was listed:\n%s", link, all)
+		}
+	}
+
+	// Listing does not mark the offer seen
+	user, _ := botState.GetUser(1)
+	if user.SeenOffers.Has("https://example.com/unseen-match") {
+		t.Error("/unseen marked the listed offer as seen")
+	}
+}
+
+func TestUnseenWithEverythingSeen(t *testing.T) {
+	bot, fake := newFakeTelegram(t)
+	botState := newTestBotState(t)
+	botState.AddUser(&tgbotapi.User{FirstName: "Test"}, 1)
+	botState.ApplyOffers([]state.RentalOffer{testOffer("https://example.com/a", "900 €/kk")})
+	botState.MarkOfferAsSeen(1, "https://example.com/a")
+
+	handleMessage(bot, botState, userMessage(1, "/unseen"), BotConfig{})
+
+	sent := fake.calls("sendMessage")
+	if len(sent) != 1 || sent[0]["text"][0] != translate("en", "unseen_none") {
+		t.Errorf("replied %v, want the nothing unseen message", sent)
+	}
+}